@@ -27,9 +27,12 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/cmd/agent/common/signals"
 	"github.com/DataDog/datadog-agent/cmd/agent/gui"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
 	"github.com/DataDog/datadog-agent/pkg/flare"
 	"github.com/DataDog/datadog-agent/pkg/logs"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
@@ -57,7 +60,11 @@ func SetupHandlers(r *mux.Router) *mux.Router {
 	r.HandleFunc("/stop", stopAgent).Methods("POST")
 	r.HandleFunc("/status", getStatus).Methods("GET")
 	r.HandleFunc("/stream-logs", streamLogs).Methods("POST")
+	r.HandleFunc("/logs/reload", reloadLogsConfig).Methods("POST")
 	r.HandleFunc("/dogstatsd-stats", getDogstatsdStats).Methods("GET")
+	r.HandleFunc("/dogstatsd-context-limits", getDogstatsdContextLimits).Methods("GET")
+	r.HandleFunc("/dogstatsd-parse-errors", getDogstatsdParseErrors).Methods("GET")
+	r.HandleFunc("/dogstatsd-capture", startDogstatsdCapture).Methods("POST")
 	r.HandleFunc("/status/formatted", getFormattedStatus).Methods("GET")
 	r.HandleFunc("/status/health", getHealth).Methods("GET")
 	r.HandleFunc("/{component}/status", componentStatusGetterHandler).Methods("GET")
@@ -257,6 +264,29 @@ func streamLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func reloadLogsConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	fileConfigs, err := providers.NewFileConfigProvider(common.ConfSearchPaths).Collect()
+	if err != nil {
+		log.Errorf("Unable to collect the configuration files: %v", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	result, err := logs.Reload(fileConfigs)
+	if err != nil {
+		log.Errorf("Unable to reload the logs configuration: %v", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	j, _ := json.Marshal(result)
+	w.Write(j)
+}
+
 func getDogstatsdStats(w http.ResponseWriter, r *http.Request) {
 	log.Info("Got a request for the Dogstatsd stats.")
 
@@ -302,6 +332,87 @@ func getDogstatsdStats(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonStats)
 }
 
+func getDogstatsdContextLimits(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request for the Dogstatsd context limits report.")
+
+	w.Header().Set("Content-Type", "application/json")
+	if !config.Datadog.GetBool("use_dogstatsd") {
+		body, _ := json.Marshal(map[string]string{
+			"error":      "Dogstatsd not enabled in the Agent configuration",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	jsonStats, err := json.Marshal(aggregator.GetContextLimitsStats())
+	if err != nil {
+		log.Errorf("Error getting marshalled context limits report: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	w.Write(jsonStats)
+}
+
+func startDogstatsdCapture(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request to capture the Dogstatsd traffic.")
+
+	w.Header().Set("Content-Type", "application/json")
+	if !config.Datadog.GetBool("use_dogstatsd") || common.DSD == nil {
+		body, _ := json.Marshal(map[string]string{"error": "Dogstatsd not enabled in the Agent configuration"})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	duration, err := time.ParseDuration(r.FormValue("duration"))
+	if err != nil {
+		body, _ := json.Marshal(map[string]string{"error": fmt.Sprintf("invalid capture duration: %v", err)})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	path, err := common.DSD.TCapture.Start(duration)
+	if err != nil {
+		log.Errorf("Unable to start the Dogstatsd traffic capture: %v", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	body, _ := json.Marshal(map[string]string{"path": path})
+	w.Write(body)
+}
+
+func getDogstatsdParseErrors(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request for the Dogstatsd parse errors report.")
+
+	w.Header().Set("Content-Type", "application/json")
+	if !config.Datadog.GetBool("use_dogstatsd") {
+		body, _ := json.Marshal(map[string]string{
+			"error":      "Dogstatsd not enabled in the Agent configuration",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	jsonStats, err := json.Marshal(dogstatsd.GetParseErrorsStats())
+	if err != nil {
+		log.Errorf("Error getting marshalled parse errors report: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	w.Write(jsonStats)
+}
+
 func getFormattedStatus(w http.ResponseWriter, r *http.Request) {
 	log.Info("Got a request for the formatted status. Making formatted status.")
 	s, err := status.GetAndFormatStatus()
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var dsdCaptureDuration time.Duration
+
+func init() {
+	AgentCmd.AddCommand(dogstatsdCaptureCmd)
+	dogstatsdCaptureCmd.Flags().DurationVarP(&dsdCaptureDuration, "duration", "d", time.Minute, "duration of the capture")
+}
+
+var dogstatsdCaptureCmd = &cobra.Command{
+	Use:   "dogstatsd-capture",
+	Short: "Capture the traffic received by dogstatsd",
+	Long: `Records the packets received by the dogstatsd server of a running agent, with their origin and
+their reception time, for the given duration. The capture can be replayed with the dogstatsd-replay command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		err := common.SetupConfigWithoutSecrets(confFilePath, "")
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return startDogstatsdCapture()
+	},
+}
+
+func startDogstatsdCapture() error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+
+	// Set session token
+	err := util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/dogstatsd-capture", ipcAddress, config.Datadog.GetInt("cmd_port"))
+
+	form := url.Values{"duration": {dsdCaptureDuration.String()}}
+	r, err := util.DoPost(c, urlstr, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			err = fmt.Errorf(e)
+		}
+		return fmt.Errorf("could not start the capture: %v", err)
+	}
+
+	var result map[string]string
+	if err = json.Unmarshal(r, &result); err != nil {
+		return fmt.Errorf("unexpected response from the agent: %v", err)
+	}
+
+	fmt.Printf("Capturing the dogstatsd traffic for %s in %s\n", dsdCaptureDuration, color.GreenString(result["path"]))
+	return nil
+}
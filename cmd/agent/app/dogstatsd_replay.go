@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dsdReplayNoDelay      bool
+	dsdReplayUDP          bool
+	dsdReplayIgnoreOrigin bool
+)
+
+func init() {
+	AgentCmd.AddCommand(dogstatsdReplayCmd)
+	dogstatsdReplayCmd.Flags().BoolVarP(&dsdReplayNoDelay, "no-delay", "", false, "send the packets without reproducing the delays between them")
+	dogstatsdReplayCmd.Flags().BoolVarP(&dsdReplayUDP, "udp", "", false, "send the packets to the UDP port even if the UDS socket is enabled")
+	dogstatsdReplayCmd.Flags().BoolVarP(&dsdReplayIgnoreOrigin, "ignore-origin", "", false, "send the packets whose origin can't be detected again instead of stopping the replay")
+}
+
+var dogstatsdReplayCmd = &cobra.Command{
+	Use:   "dogstatsd-replay <capture file>...",
+	Short: "Replay a capture of the dogstatsd traffic",
+	Long: `Sends the packets of the dogstatsd-capture files to the local dogstatsd server, in order and with
+the delays between them. The packets are sent to the UDS socket when it is enabled, along with their
+credentials so that their origin is detected again, which requires the CAP_SYS_ADMIN capability and
+the processes which sent them to be running. Otherwise they are sent to the UDP port. The replay stops
+on the first packet whose origin can't be detected again, unless --ignore-origin is set.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		err := common.SetupConfigWithoutSecrets(confFilePath, "")
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return replayDogstatsdCapture(args)
+	},
+}
+
+func replayDogstatsdCapture(paths []string) error {
+	var sender *replay.Sender
+	var err error
+	if socketPath := config.Datadog.GetString("dogstatsd_socket"); socketPath != "" && !dsdReplayUDP {
+		fmt.Printf("Replaying the capture to %s\n", socketPath)
+		sender, err = replay.NewUDSSender(socketPath, dsdReplayIgnoreOrigin)
+	} else {
+		address := net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("dogstatsd_port"))
+		fmt.Printf("Replaying the capture to %s\n", address)
+		sender, err = replay.NewUDPSender(address, dsdReplayIgnoreOrigin)
+	}
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	sent, err := replay.Replay(paths, sender, !dsdReplayNoDelay)
+	if err != nil {
+		return fmt.Errorf("replay interrupted after %d packets: %v", sent, err)
+	}
+	fmt.Printf("%s packets replayed\n", color.GreenString("%d", sent))
+	return nil
+}
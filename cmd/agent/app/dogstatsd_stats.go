@@ -13,6 +13,7 @@ import (
 	"os"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
@@ -24,6 +25,8 @@ import (
 
 var (
 	dsdStatsFilePath string
+	dsdContextLimits bool
+	dsdParseErrors   bool
 )
 
 func init() {
@@ -31,6 +34,8 @@ func init() {
 	dogstatsdStatsCmd.Flags().BoolVarP(&jsonStatus, "json", "j", false, "print out raw json")
 	dogstatsdStatsCmd.Flags().BoolVarP(&prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
 	dogstatsdStatsCmd.Flags().StringVarP(&dsdStatsFilePath, "file", "o", "", "Output the dogstatsd-stats command to a file")
+	dogstatsdStatsCmd.Flags().BoolVarP(&dsdContextLimits, "context-limits", "", false, "print the report of the samples dropped and the tag keys stripped by the context limits")
+	dogstatsdStatsCmd.Flags().BoolVarP(&dsdParseErrors, "parse-errors", "", false, "print the report of the invalid messages by reason and by origin")
 }
 
 var dogstatsdStatsCmd = &cobra.Command{
@@ -67,7 +72,13 @@ func requestDogstatsdStats() error {
 	if err != nil {
 		return err
 	}
-	urlstr := fmt.Sprintf("https://%v:%v/agent/dogstatsd-stats", ipcAddress, config.Datadog.GetInt("cmd_port"))
+	endpoint, format := "dogstatsd-stats", dogstatsd.FormatDebugStats
+	if dsdContextLimits {
+		endpoint, format = "dogstatsd-context-limits", aggregator.FormatContextLimitsStats
+	} else if dsdParseErrors {
+		endpoint, format = "dogstatsd-parse-errors", dogstatsd.FormatParseErrorsStats
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/%s", ipcAddress, config.Datadog.GetInt("cmd_port"), endpoint)
 
 	// Set session token
 	e = util.SetAuthToken()
@@ -102,7 +113,7 @@ func requestDogstatsdStats() error {
 	} else if jsonStatus {
 		s = string(r)
 	} else {
-		s, e = dogstatsd.FormatDebugStats(r)
+		s, e = format(r)
 		if e != nil {
 			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
 			return nil
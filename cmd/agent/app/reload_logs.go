@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	AgentCmd.AddCommand(reloadLogsCmd)
+}
+
+var reloadLogsCmd = &cobra.Command{
+	Use:   "reload-logs",
+	Short: "Reload the logs configuration of a running agent",
+	Long: `Applies the global processing rules of datadog.yaml and the logs configurations of the conf.d
+directory without restarting the agent. Only the sources whose configuration changed are restarted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupConfig(); err != nil {
+			return err
+		}
+		return reloadLogs()
+	},
+}
+
+func reloadLogs() error {
+	c := util.GetClient(false)
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/logs/reload", ipcAddress, config.Datadog.GetInt("cmd_port"))
+
+	r, err := util.DoPost(c, urlstr, "application/json", bytes.NewBuffer([]byte{}))
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			err = fmt.Errorf(e)
+		}
+		return fmt.Errorf("could not reload the logs configuration: %v", err)
+	}
+
+	var result logs.ReloadResult
+	if err = json.Unmarshal(r, &result); err != nil {
+		return fmt.Errorf("unexpected response from the agent: %v", err)
+	}
+
+	fmt.Printf("Logs configuration reloaded: %d global processing rules, %s sources added, %s sources removed\n",
+		result.ProcessingRules,
+		color.GreenString("%d", result.AddedSources),
+		color.RedString("%d", result.RemovedSources))
+	return nil
+}
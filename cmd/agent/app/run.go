@@ -332,7 +332,7 @@ func StartAgent() error {
 	options := forwarder.NewOptions(keysPerDomain)
 	options.EnabledFeatures = forwarder.SetFeature(options.EnabledFeatures, forwarder.CoreFeatures)
 
-	common.Forwarder = forwarder.NewDefaultForwarder(options)
+	common.Forwarder = forwarder.NewForwarder("core", options)
 	log.Debugf("Starting forwarder")
 	common.Forwarder.Start() //nolint:errcheck
 	log.Debugf("Forwarder started")
@@ -341,6 +341,9 @@ func StartAgent() error {
 	s := serializer.NewSerializer(common.Forwarder)
 	agg := aggregator.InitAggregator(s, hostname)
 	agg.AddAgentStartupTelemetry(version.AgentVersion)
+	if config.Datadog.GetBool("aggregator_openmetrics_endpoint_enabled") {
+		http.Handle("/openmetrics", aggregator.OpenMetricsHandler(agg))
+	}
 
 	// start dogstatsd
 	if config.Datadog.GetBool("use_dogstatsd") {
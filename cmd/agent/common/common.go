@@ -45,6 +45,9 @@ var (
 	// MainCtxCancel cancels the main agent context
 	MainCtxCancel context.CancelFunc
 
+	// ConfSearchPaths are the paths where the configuration files of the checks and the logs are searched
+	ConfSearchPaths []string
+
 	// utility variables
 	_here, _ = executable.Folder()
 )
@@ -40,11 +40,11 @@ func LoadComponents(confdPath string) {
 	}
 
 	// setup autodiscovery
-	confSearchPaths := []string{
+	ConfSearchPaths = []string{
 		confdPath,
 		filepath.Join(GetDistPath(), "conf.d"),
 		"",
 	}
 
-	AC = setupAutoDiscovery(confSearchPaths, metaScheduler)
+	AC = setupAutoDiscovery(ConfSearchPaths, metaScheduler)
 }
@@ -161,7 +161,7 @@ func run(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		log.Error("Misconfiguration of agent endpoints: ", err)
 	}
-	f := forwarder.NewDefaultForwarder(forwarder.NewOptions(keysPerDomain))
+	f := forwarder.NewForwarder("cluster_agent", forwarder.NewOptions(keysPerDomain))
 	f.Start() //nolint:errcheck
 	s := serializer.NewSerializer(f)
 
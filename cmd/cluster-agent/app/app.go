@@ -191,7 +191,7 @@ func start(cmd *cobra.Command, args []string) error {
 	// * The metrics reported are reported as stale so that there is no "lie" about the accuracy of the reported metrics.
 	// Serving stale data is better than serving no data at all.
 	forwarderOpts.DisableAPIKeyChecking = true
-	f := forwarder.NewDefaultForwarder(forwarderOpts)
+	f := forwarder.NewForwarder("cluster_agent", forwarderOpts)
 	f.Start() //nolint:errcheck
 	s := serializer.NewSerializer(f)
 
@@ -162,6 +162,17 @@ func (l *Collector) runCheck(c checks.Check, results *api.WeightedQueue) {
 	}
 }
 
+// newForwarder returns the forwarder of the payloads, the payloads written locally in offline mode are decoded
+func newForwarder(name string, opts *forwarder.Options) forwarder.Forwarder {
+	fwd := forwarder.NewForwarder(name, opts)
+	if fileForwarder, ok := fwd.(*forwarder.FileForwarder); ok {
+		fileForwarder.SetProcessPayloadDecoder(func(payload []byte) (interface{}, error) {
+			return model.DecodeMessage(payload)
+		})
+	}
+	return fwd
+}
+
 func (l *Collector) run(exit chan struct{}) error {
 	eps := make([]string, 0, len(l.cfg.APIEndpoints))
 	for _, e := range l.cfg.APIEndpoints {
@@ -221,12 +232,12 @@ func (l *Collector) run(exit chan struct{}) error {
 	processForwarderOpts := forwarder.NewOptions(api.KeysPerDomains(l.cfg.APIEndpoints))
 	processForwarderOpts.DisableAPIKeyChecking = true
 	processForwarderOpts.RetryQueuePayloadsTotalMaxSize = l.cfg.ProcessQueueBytes // Allow more in-flight requests than the default
-	processForwarder := forwarder.NewDefaultForwarder(processForwarderOpts)
+	processForwarder := newForwarder("process_agent", processForwarderOpts)
 
 	podForwarderOpts := forwarder.NewOptions(api.KeysPerDomains(l.cfg.Orchestrator.OrchestratorEndpoints))
 	podForwarderOpts.DisableAPIKeyChecking = true
 	podForwarderOpts.RetryQueuePayloadsTotalMaxSize = l.cfg.ProcessQueueBytes // Allow more in-flight requests than the default
-	podForwarder := forwarder.NewDefaultForwarder(podForwarderOpts)
+	podForwarder := newForwarder("process_agent_orchestrator", podForwarderOpts)
 
 	if err := processForwarder.Start(); err != nil {
 		return fmt.Errorf("error starting forwarder: %s", err)
@@ -237,7 +237,7 @@ func start(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		log.Error("Misconfiguration of agent endpoints: ", err)
 	}
-	f := forwarder.NewDefaultForwarder(forwarder.NewOptions(keysPerDomain))
+	f := forwarder.NewForwarder("security_agent", forwarder.NewOptions(keysPerDomain))
 	f.Start() //nolint:errcheck
 	s := serializer.NewSerializer(f)
 
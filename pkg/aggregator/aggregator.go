@@ -147,6 +147,12 @@ func init() {
 	aggregatorExpvars.Set("ServiceCheck", &aggregatorServiceCheck)
 	aggregatorExpvars.Set("Event", &aggregatorEvent)
 	aggregatorExpvars.Set("HostnameUpdate", &aggregatorHostnameUpdate)
+	aggregatorExpvars.Set("ContextLimits", expvar.Func(func() interface{} {
+		return GetContextLimitsStats()
+	}))
+	aggregatorExpvars.Set("HistogramOverrides", expvar.Func(func() interface{} {
+		return metrics.GetHistogramOverridesStatus()
+	}))
 }
 
 // InitAggregator returns the Singleton instance
@@ -183,7 +189,7 @@ func StopDefaultAggregator() {
 // BufferedAggregator aggregates metrics in buckets for dogstatsd Metrics
 type BufferedAggregator struct {
 	bufferedMetricIn       chan []metrics.MetricSample
-	bufferedMetricInWithTs chan []metrics.MetricSample
+	bufferedMetricWithTsIn chan []metrics.MetricSample
 	bufferedServiceCheckIn chan []*metrics.ServiceCheck
 	bufferedEventIn        chan []*metrics.Event
 
@@ -198,7 +204,11 @@ type BufferedAggregator struct {
 	// Used by the Dogstatsd Batcher.
 	MetricSamplePool *metrics.MetricSamplePool
 
-	statsdSampler      TimeSampler
+	// statsdWorkers are the dogstatsd pipelines, each one aggregates a shard of the contexts
+	statsdWorkers []*timeSamplerWorker
+	// sharder spreads the samples received by the aggregator between the statsdWorkers,
+	// it is only used by the run goroutine
+	sharder            *ContextSharder
 	checkSamplers      map[check.ID]*CheckSampler
 	serviceChecks      metrics.ServiceChecks
 	events             metrics.Events
@@ -232,9 +242,28 @@ func NewBufferedAggregator(s serializer.MetricSerializer, hostname string, flush
 		agentName = flavor.HerokuAgent
 	}
 
+	pipelineCount := config.Datadog.GetInt("dogstatsd_pipeline_count")
+	if pipelineCount < 1 {
+		log.Warnf("Invalid dogstatsd_pipeline_count %d, using a single pipeline", pipelineCount)
+		pipelineCount = 1
+	}
+	metricSamplePool := metrics.NewMetricSamplePool(MetricSamplePoolBatchSize)
+	statsdWorkers := make([]*timeSamplerWorker, 0, pipelineCount)
+	for i := 0; i < pipelineCount; i++ {
+		statsdWorkers = append(statsdWorkers, newTimeSamplerWorker(pipelineCount, bufferSize, metricSamplePool))
+	}
+	// the batches of samples sent to the buffered channels are sharded between the pipelines
+	// by the run goroutine, a single pipeline receives them directly
+	bufferedMetricIn := statsdWorkers[0].samplesIn
+	bufferedMetricWithTsIn := statsdWorkers[0].samplesWithTsIn
+	if pipelineCount > 1 {
+		bufferedMetricIn = make(chan []metrics.MetricSample, bufferSize)
+		bufferedMetricWithTsIn = make(chan []metrics.MetricSample, bufferSize)
+	}
+
 	aggregator := &BufferedAggregator{
-		bufferedMetricIn:       make(chan []metrics.MetricSample, bufferSize),
-		bufferedMetricInWithTs: make(chan []metrics.MetricSample, bufferSize),
+		bufferedMetricIn:       bufferedMetricIn,
+		bufferedMetricWithTsIn: bufferedMetricWithTsIn,
 		bufferedServiceCheckIn: make(chan []*metrics.ServiceCheck, bufferSize),
 		bufferedEventIn:        make(chan []*metrics.Event, bufferSize),
 
@@ -245,9 +274,10 @@ func NewBufferedAggregator(s serializer.MetricSerializer, hostname string, flush
 		checkMetricIn:          make(chan senderMetricSample, bufferSize),
 		checkHistogramBucketIn: make(chan senderHistogramBucket, bufferSize),
 
-		MetricSamplePool: metrics.NewMetricSamplePool(MetricSamplePoolBatchSize),
+		MetricSamplePool: metricSamplePool,
 
-		statsdSampler:           *NewTimeSampler(bucketSize),
+		statsdWorkers:           statsdWorkers,
+		sharder:                 NewContextSharder(pipelineCount),
 		checkSamplers:           make(map[check.ID]*CheckSampler),
 		flushInterval:           flushInterval,
 		serializer:              s,
@@ -274,10 +304,18 @@ func AddRecurrentSeries(newSerie *metrics.Serie) {
 // IsInputQueueEmpty returns true if every input channel for the aggregator are
 // empty. This is mainly useful for tests and benchmark
 func (agg *BufferedAggregator) IsInputQueueEmpty() bool {
-	if len(agg.checkMetricIn)+len(agg.serviceCheckIn)+len(agg.eventIn)+len(agg.checkHistogramBucketIn) == 0 {
-		return true
+	if len(agg.checkMetricIn)+len(agg.serviceCheckIn)+len(agg.eventIn)+len(agg.checkHistogramBucketIn) != 0 {
+		return false
+	}
+	if len(agg.bufferedMetricIn)+len(agg.bufferedMetricWithTsIn) != 0 {
+		return false
 	}
-	return false
+	for _, w := range agg.statsdWorkers {
+		if len(w.samplesIn)+len(w.samplesWithTsIn) != 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // GetChannels returns a channel which can be subsequently used to send MetricSamples, Event or ServiceCheck
@@ -286,13 +324,29 @@ func (agg *BufferedAggregator) GetChannels() (chan *metrics.MetricSample, chan m
 }
 
 // GetBufferedChannels returns a channel which can be subsequently used to send MetricSamples, Event or ServiceCheck
+// The MetricSamples sent to this channel are sharded by context between the dogstatsd pipelines by the aggregator,
+// see GetDogstatsdPipelines to send them to the pipelines directly.
 func (agg *BufferedAggregator) GetBufferedChannels() (chan []metrics.MetricSample, chan []*metrics.Event, chan []*metrics.ServiceCheck) {
 	return agg.bufferedMetricIn, agg.bufferedEventIn, agg.bufferedServiceCheckIn
 }
 
 // GetBufferedMetricsWithTsChannel returns the channel to send MetricSamples containing their timestamp.
+// The MetricSamples sent to this channel are sharded by context between the dogstatsd pipelines by the aggregator.
 func (agg *BufferedAggregator) GetBufferedMetricsWithTsChannel() chan []metrics.MetricSample {
-	return agg.bufferedMetricInWithTs
+	return agg.bufferedMetricWithTsIn
+}
+
+// GetDogstatsdPipelines returns, for every dogstatsd pipeline, the channels to send the MetricSamples
+// to aggregate at their arrival time and the ones containing their timestamp. The samples are sharded
+// by context between the pipelines with a ContextSharder.
+func (agg *BufferedAggregator) GetDogstatsdPipelines() ([]chan []metrics.MetricSample, []chan []metrics.MetricSample) {
+	samplesIn := make([]chan []metrics.MetricSample, 0, len(agg.statsdWorkers))
+	samplesWithTsIn := make([]chan []metrics.MetricSample, 0, len(agg.statsdWorkers))
+	for _, w := range agg.statsdWorkers {
+		samplesIn = append(samplesIn, w.samplesIn)
+		samplesWithTsIn = append(samplesWithTsIn, w.samplesWithTsIn)
+	}
+	return samplesIn, samplesWithTsIn
 }
 
 // SetHostname sets the hostname that the aggregator uses by default on all the data it sends
@@ -391,9 +445,34 @@ func (agg *BufferedAggregator) addEvent(e metrics.Event) {
 	agg.events = append(agg.events, &e)
 }
 
-// addSample adds the metric sample
+// addSample adds the metric sample to the dogstatsd pipeline aggregating its context
 func (agg *BufferedAggregator) addSample(metricSample *metrics.MetricSample, timestamp float64) {
-	agg.statsdSampler.addSample(metricSample, timestamp)
+	shard := agg.sharder.Shard(metricSample)
+	agg.statsdWorkers[shard].addSample(metricSample, timestamp)
+}
+
+// shardSamples splits a batch of samples received on the buffered channels into one batch per
+// dogstatsd pipeline, sends them to the pipelines and puts the received batch back into the pool.
+func (agg *BufferedAggregator) shardSamples(ms []metrics.MetricSample, withTimestamp bool) {
+	batches := make([][]metrics.MetricSample, len(agg.statsdWorkers))
+	for i := 0; i < len(ms); i++ {
+		shard := agg.sharder.Shard(&ms[i])
+		if batches[shard] == nil {
+			batches[shard] = agg.MetricSamplePool.GetBatch()[:0]
+		}
+		batches[shard] = append(batches[shard], ms[i])
+	}
+	for shard, batch := range batches {
+		if batch == nil {
+			continue
+		}
+		if withTimestamp {
+			agg.statsdWorkers[shard].samplesWithTsIn <- batch
+		} else {
+			agg.statsdWorkers[shard].samplesIn <- batch
+		}
+	}
+	agg.MetricSamplePool.PutBatch(ms)
 }
 
 // GetSeriesAndSketches grabs all the series & sketches from the queue and clears the queue
@@ -403,7 +482,7 @@ func (agg *BufferedAggregator) GetSeriesAndSketches(before time.Time) (metrics.S
 	agg.mu.Lock()
 	defer agg.mu.Unlock()
 
-	series, sketches := agg.statsdSampler.flush(float64(before.UnixNano()) / float64(time.Second))
+	series, sketches := flushTimeSamplerWorkers(agg.statsdWorkers, float64(before.UnixNano())/float64(time.Second))
 	for _, checkSampler := range agg.checkSamplers {
 		s, sk := checkSampler.flush()
 		series = append(series, s...)
@@ -412,6 +491,27 @@ func (agg *BufferedAggregator) GetSeriesAndSketches(before time.Time) (metrics.S
 	return series, sketches
 }
 
+// snapshotSeriesAndSketches returns the series & sketches pending in the samplers, as
+// GetSeriesAndSketches but without flushing them
+func (agg *BufferedAggregator) snapshotSeriesAndSketches() (metrics.Series, metrics.SketchSeriesList) {
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	for _, w := range agg.statsdWorkers {
+		s, sk := w.snapshot()
+		series = append(series, s...)
+		sketches = append(sketches, sk...)
+	}
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	for _, checkSampler := range agg.checkSamplers {
+		s, sk := checkSampler.snapshot()
+		series = append(series, s...)
+		sketches = append(sketches, sk...)
+	}
+	return series, sketches
+}
+
 func (agg *BufferedAggregator) pushSketches(start time.Time, sketches metrics.SketchSeriesList) {
 	log.Debugf("Flushing %d sketches to the forwarder", len(sketches))
 	err := agg.serializer.SendSketch(sketches)
@@ -669,10 +769,23 @@ func (agg *BufferedAggregator) run() {
 		}
 	}
 
+	for _, w := range agg.statsdWorkers {
+		go w.run()
+	}
+
+	// a single pipeline reads the buffered samples itself, see NewBufferedAggregator
+	var bufferedMetricIn, bufferedMetricWithTsIn chan []metrics.MetricSample
+	if len(agg.statsdWorkers) > 1 {
+		bufferedMetricIn, bufferedMetricWithTsIn = agg.bufferedMetricIn, agg.bufferedMetricWithTsIn
+	}
+
 	for {
 		select {
 		case <-agg.stopChan:
 			log.Info("Stopping aggregator")
+			for _, w := range agg.statsdWorkers {
+				w.stop()
+			}
 			return
 		case <-agg.health.C:
 		case <-agg.TickerChan:
@@ -692,6 +805,10 @@ func (agg *BufferedAggregator) run() {
 			aggregatorDogstatsdMetricSample.Add(1)
 			tlmProcessed.Inc("dogstatsd_metrics")
 			agg.addSample(metric, timeNowNano())
+		case ms := <-bufferedMetricIn:
+			agg.shardSamples(ms, false)
+		case ms := <-bufferedMetricWithTsIn:
+			agg.shardSamples(ms, true)
 		case event := <-agg.eventIn:
 			aggregatorEvent.Add(1)
 			tlmProcessed.Inc("events")
@@ -700,20 +817,6 @@ func (agg *BufferedAggregator) run() {
 			aggregatorServiceCheck.Add(1)
 			tlmProcessed.Inc("service_checks")
 			agg.addServiceCheck(serviceCheck)
-		case ms := <-agg.bufferedMetricInWithTs:
-			aggregatorDogstatsdMetricSample.Add(int64(len(ms)))
-			tlmProcessed.Add(float64(len(ms)), "dogstatsd_metrics")
-			for i := 0; i < len(ms); i++ {
-				agg.addSample(&ms[i], ms[i].Timestamp/float64(time.Second))
-			}
-			agg.MetricSamplePool.PutBatch(ms)
-		case ms := <-agg.bufferedMetricIn:
-			aggregatorDogstatsdMetricSample.Add(int64(len(ms)))
-			tlmProcessed.Add(float64(len(ms)), "dogstatsd_metrics")
-			for i := 0; i < len(ms); i++ {
-				agg.addSample(&ms[i], timeNowNano())
-			}
-			agg.MetricSamplePool.PutBatch(ms)
 		case serviceChecks := <-agg.bufferedServiceCheckIn:
 			aggregatorServiceCheck.Add(int64(len(serviceChecks)))
 			tlmProcessed.Add(float64(len(serviceChecks)), "service_checks")
@@ -165,6 +165,15 @@ func (cs *CheckSampler) commit(timestamp float64) {
 	cs.contextResolver.expireContexts(timestamp - defaultExpiry)
 }
 
+// snapshot returns the series and sketches committed since the last flush, without flushing them
+func (cs *CheckSampler) snapshot() (metrics.Series, metrics.SketchSeriesList) {
+	series := make(metrics.Series, len(cs.series))
+	copy(series, cs.series)
+	sketches := make(metrics.SketchSeriesList, len(cs.sketches))
+	copy(sketches, cs.sketches)
+	return series, sketches
+}
+
 func (cs *CheckSampler) flush() (metrics.Series, metrics.SketchSeriesList) {
 	// series
 	series := cs.series
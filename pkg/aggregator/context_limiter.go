@@ -0,0 +1,410 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// ContextLimitPolicyDrop drops the samples of the new contexts of a metric over its limit
+	ContextLimitPolicyDrop = "drop"
+	// ContextLimitPolicyStripTags strips the tag key with the most distinct values from the
+	// samples of a metric over its limit
+	ContextLimitPolicyStripTags = "strip_tags"
+
+	// number of offenders listed in the context limits report
+	contextLimitsTopOffenders = 10
+	// maximum number of metric names and tag keys accounted in the report, to
+	// keep the report itself from growing with the cardinality it watches
+	contextLimitsMaxReportEntries = 1000
+)
+
+var (
+	tlmContextsDropped = telemetry.NewCounter("aggregator", "contexts_dropped",
+		[]string{"reason"}, "Count of samples dropped because their context was over a context limit")
+	tlmTagKeysStripped = telemetry.NewCounter("aggregator", "tag_keys_stripped",
+		nil, "Count of tag keys stripped from a metric because it was over its context limit")
+
+	contextLimitStats = newContextLimitsStats()
+)
+
+// contextLimiter enforces the limits on the number of contexts tracked by a ContextResolver
+type contextLimiter struct {
+	globalLimit        int
+	defaultMetricLimit int
+	metricLimits       map[string]int
+	policy             string
+
+	metrics map[string]*limitedMetric
+}
+
+// limitedMetric holds the contexts accounting of a metric name
+type limitedMetric struct {
+	contexts int
+	// contexts created since a tag key was last stripped, the limit applies to them
+	contextsSinceStrip int
+	strippedTagKeys    map[string]struct{}
+	// contextsByTagValue counts the contexts carrying each tag, by tag key, to find the
+	// tag key with the most distinct values without going through all the contexts.
+	// The stripped tag keys are not counted.
+	contextsByTagValue map[string]map[string]int
+}
+
+// newContextLimiterFromConfig returns a contextLimiter configured from the agent
+// configuration, or nil when no limit is set. The limits are split between the
+// shards of the contexts, each one enforcing its share of the limits.
+func newContextLimiterFromConfig(shards int) *contextLimiter {
+	metricLimits := make(map[string]int)
+	for name, value := range config.Datadog.GetStringMap("dogstatsd_context_limit_metric_overrides") {
+		limit, err := toContextLimit(value)
+		if err != nil {
+			log.Errorf("Invalid context limit for metric %q: %v", name, err)
+			continue
+		}
+		metricLimits[name] = shardContextLimit(limit, shards)
+	}
+
+	policy := config.Datadog.GetString("dogstatsd_context_limit_policy")
+	if policy != ContextLimitPolicyDrop && policy != ContextLimitPolicyStripTags {
+		log.Warnf("Unknown dogstatsd_context_limit_policy %q, falling back to %q", policy, ContextLimitPolicyDrop)
+		policy = ContextLimitPolicyDrop
+	}
+
+	return newContextLimiter(
+		shardContextLimit(config.Datadog.GetInt("dogstatsd_context_limit"), shards),
+		shardContextLimit(config.Datadog.GetInt("dogstatsd_context_limit_per_metric"), shards),
+		metricLimits,
+		policy,
+	)
+}
+
+// newContextLimiter returns a contextLimiter, or nil when none of the limits is set.
+// A limit of 0 means unlimited.
+func newContextLimiter(globalLimit, defaultMetricLimit int, metricLimits map[string]int, policy string) *contextLimiter {
+	hasLimit := globalLimit > 0 || defaultMetricLimit > 0
+	for _, limit := range metricLimits {
+		hasLimit = hasLimit || limit > 0
+	}
+	if !hasLimit {
+		return nil
+	}
+
+	return &contextLimiter{
+		globalLimit:        globalLimit,
+		defaultMetricLimit: defaultMetricLimit,
+		metricLimits:       metricLimits,
+		policy:             policy,
+		metrics:            make(map[string]*limitedMetric),
+	}
+}
+
+// shardContextLimit returns the share of a limit enforced by one of the shards, rounded up
+func shardContextLimit(limit int, shards int) int {
+	if limit <= 0 || shards <= 1 {
+		return limit
+	}
+	return (limit + shards - 1) / shards
+}
+
+func toContextLimit(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("unexpected type %T", value)
+	}
+}
+
+// metricLimit returns the context limit of a metric name, 0 when unlimited
+func (l *contextLimiter) metricLimit(name string) int {
+	if limit, ok := l.metricLimits[name]; ok {
+		return limit
+	}
+	return l.defaultMetricLimit
+}
+
+// isOverGlobalLimit returns whether tracking one more context would exceed the global limit
+func (l *contextLimiter) isOverGlobalLimit(trackedContexts int) bool {
+	return l.globalLimit > 0 && trackedContexts >= l.globalLimit
+}
+
+// isOverMetricLimit returns whether tracking one more context for the metric would exceed its limit
+func (l *contextLimiter) isOverMetricLimit(name string) bool {
+	limit := l.metricLimit(name)
+	if limit <= 0 {
+		return false
+	}
+	m, ok := l.metrics[name]
+	return ok && m.contextsSinceStrip >= limit
+}
+
+// strippedTagKeys returns the tag keys stripped from the samples of a metric, nil if none
+func (l *contextLimiter) strippedTagKeys(name string) map[string]struct{} {
+	if m, ok := l.metrics[name]; ok {
+		return m.strippedTagKeys
+	}
+	return nil
+}
+
+// stripTagKey registers a tag key to strip from the samples of a metric. The metric
+// is given a new budget of contexts since its new contexts no longer carry the key.
+func (l *contextLimiter) stripTagKey(name, tagKey string) {
+	m := l.getMetric(name)
+	if m.strippedTagKeys == nil {
+		m.strippedTagKeys = make(map[string]struct{})
+	}
+	m.strippedTagKeys[tagKey] = struct{}{}
+	m.contextsSinceStrip = 0
+	delete(m.contextsByTagValue, tagKey)
+
+	log.Warnf("Metric %q reached its limit of %d contexts, stripping the tag key %q from its samples", name, l.metricLimit(name), tagKey)
+	tlmTagKeysStripped.Inc()
+	contextLimitStats.tagKeyStripped(tagKey)
+}
+
+// contextAdded accounts a new context of a metric, with its tags
+func (l *contextLimiter) contextAdded(name string, tags []string) {
+	m := l.getMetric(name)
+	m.contexts++
+	m.contextsSinceStrip++
+
+	if l.policy != ContextLimitPolicyStripTags {
+		return
+	}
+	if m.contextsByTagValue == nil {
+		m.contextsByTagValue = make(map[string]map[string]int)
+	}
+	for _, tag := range tags {
+		key := tagKey(tag)
+		if _, stripped := m.strippedTagKeys[key]; stripped {
+			continue
+		}
+		values, ok := m.contextsByTagValue[key]
+		if !ok {
+			values = make(map[string]int)
+			m.contextsByTagValue[key] = values
+		}
+		values[tag]++
+	}
+}
+
+// contextRemoved accounts an expired context of a metric, with its tags. Once all its
+// contexts are expired, the metric is forgotten along with its stripped tag keys.
+func (l *contextLimiter) contextRemoved(name string, tags []string) {
+	m, ok := l.metrics[name]
+	if !ok {
+		return
+	}
+	m.contexts--
+	if m.contexts <= 0 {
+		delete(l.metrics, name)
+		return
+	}
+	if m.contextsSinceStrip > m.contexts {
+		m.contextsSinceStrip = m.contexts
+	}
+
+	for _, tag := range tags {
+		key := tagKey(tag)
+		// the contexts created before a tag key was stripped still carry it
+		values, ok := m.contextsByTagValue[key]
+		if !ok {
+			continue
+		}
+		values[tag]--
+		if values[tag] <= 0 {
+			delete(values, tag)
+		}
+		if len(values) == 0 {
+			delete(m.contextsByTagValue, key)
+		}
+	}
+}
+
+// highestCardinalityTagKey returns the tag key with the most distinct values among the
+// contexts of a metric, or an empty string when its contexts have no tag left to strip
+func (l *contextLimiter) highestCardinalityTagKey(name string) string {
+	m, ok := l.metrics[name]
+	if !ok {
+		return ""
+	}
+	highestKey, highestCardinality := "", 0
+	for key, values := range m.contextsByTagValue {
+		if len(values) > highestCardinality || (len(values) == highestCardinality && key < highestKey) {
+			highestKey, highestCardinality = key, len(values)
+		}
+	}
+	return highestKey
+}
+
+// contextDropped accounts a sample dropped because of a limit
+func (l *contextLimiter) contextDropped(name, reason string) {
+	tlmContextsDropped.Inc(reason)
+	contextLimitStats.contextDropped(name)
+}
+
+func (l *contextLimiter) getMetric(name string) *limitedMetric {
+	m, ok := l.metrics[name]
+	if !ok {
+		m = &limitedMetric{}
+		l.metrics[name] = m
+	}
+	return m
+}
+
+// stripTags removes in place the tags whose key is in tagKeys, and returns the resulting slice
+func stripTags(tags []string, tagKeys map[string]struct{}) []string {
+	if len(tagKeys) == 0 {
+		return tags
+	}
+	n := 0
+	for _, tag := range tags {
+		if _, found := tagKeys[tagKey(tag)]; !found {
+			tags[n] = tag
+			n++
+		}
+	}
+	return tags[:n]
+}
+
+// tagKey returns the key of a "key:value" tag, or the tag itself when it has no value
+func tagKey(tag string) string {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// ContextLimitsStats holds the report of the samples dropped and of the tag keys stripped
+// because of the context limits
+type ContextLimitsStats struct {
+	DroppedSamples  uint64 `json:"dropped_samples"`
+	StrippedTagKeys uint64 `json:"stripped_tag_keys"`
+	// TopMetrics lists the metric names with the most dropped samples
+	TopMetrics []ContextLimitsOffender `json:"top_metrics"`
+	// TopTagKeys lists the tag keys stripped from the most metrics
+	TopTagKeys []ContextLimitsOffender `json:"top_tag_keys"`
+}
+
+// ContextLimitsOffender is an entry of the context limits report
+type ContextLimitsOffender struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+type contextLimitsStats struct {
+	sync.Mutex
+	droppedSamples    uint64
+	strippedTagKeys   uint64
+	droppedByMetric   map[string]uint64
+	strippedByTagKeys map[string]uint64
+}
+
+func newContextLimitsStats() *contextLimitsStats {
+	return &contextLimitsStats{
+		droppedByMetric:   make(map[string]uint64),
+		strippedByTagKeys: make(map[string]uint64),
+	}
+}
+
+func (s *contextLimitsStats) contextDropped(name string) {
+	s.Lock()
+	defer s.Unlock()
+	s.droppedSamples++
+	incrementBounded(s.droppedByMetric, name)
+}
+
+func (s *contextLimitsStats) tagKeyStripped(tagKey string) {
+	s.Lock()
+	defer s.Unlock()
+	s.strippedTagKeys++
+	incrementBounded(s.strippedByTagKeys, tagKey)
+}
+
+func (s *contextLimitsStats) get() ContextLimitsStats {
+	s.Lock()
+	defer s.Unlock()
+	return ContextLimitsStats{
+		DroppedSamples:  s.droppedSamples,
+		StrippedTagKeys: s.strippedTagKeys,
+		TopMetrics:      topOffenders(s.droppedByMetric, contextLimitsTopOffenders),
+		TopTagKeys:      topOffenders(s.strippedByTagKeys, contextLimitsTopOffenders),
+	}
+}
+
+func incrementBounded(counts map[string]uint64, name string) {
+	if _, found := counts[name]; found || len(counts) < contextLimitsMaxReportEntries {
+		counts[name]++
+	}
+}
+
+func topOffenders(counts map[string]uint64, n int) []ContextLimitsOffender {
+	offenders := make([]ContextLimitsOffender, 0, len(counts))
+	for name, count := range counts {
+		offenders = append(offenders, ContextLimitsOffender{Name: name, Count: count})
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Count != offenders[j].Count {
+			return offenders[i].Count > offenders[j].Count
+		}
+		return offenders[i].Name < offenders[j].Name
+	})
+	if len(offenders) > n {
+		offenders = offenders[:n]
+	}
+	return offenders
+}
+
+// GetContextLimitsStats returns the report of the context limits
+func GetContextLimitsStats() ContextLimitsStats {
+	return contextLimitStats.get()
+}
+
+// FormatContextLimitsStats returns a printable version of the context limits report
+func FormatContextLimitsStats(stats []byte) (string, error) {
+	var report ContextLimitsStats
+	if err := json.Unmarshal(stats, &report); err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "Samples dropped over a context limit: %d\n", report.DroppedSamples)
+	fmt.Fprintf(buf, "Tag keys stripped over a context limit: %d\n", report.StrippedTagKeys)
+
+	formatOffenders(buf, "Metric", "Dropped samples", report.TopMetrics)
+	formatOffenders(buf, "Tag key", "Stripped from metrics", report.TopTagKeys)
+
+	return buf.String(), nil
+}
+
+func formatOffenders(buf *bytes.Buffer, nameHeader, countHeader string, offenders []ContextLimitsOffender) {
+	if len(offenders) == 0 {
+		return
+	}
+	header := fmt.Sprintf("%-40s | %-20s\n", nameHeader, countHeader)
+	buf.WriteString("\n" + header)
+	buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+	for _, offender := range offenders {
+		fmt.Fprintf(buf, "%-40s | %-20d\n", offender.Name, offender.Count)
+	}
+}
@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build test
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func newLimitedContextResolver(globalLimit, metricLimit int, policy string) *ContextResolver {
+	contextLimitStats = newContextLimitsStats()
+	cr := newContextResolver()
+	cr.limiter = newContextLimiter(globalLimit, metricLimit, map[string]int{"my.unlimited.metric": 0}, policy)
+	return cr
+}
+
+func limitedSample(name string, tags ...string) *metrics.MetricSample {
+	return &metrics.MetricSample{
+		Name:       name,
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		Tags:       tags,
+		SampleRate: 1,
+	}
+}
+
+func TestNewContextLimiterWithoutLimits(t *testing.T) {
+	assert.Nil(t, newContextLimiter(0, 0, map[string]int{"my.metric": 0}, ContextLimitPolicyDrop))
+	assert.NotNil(t, newContextLimiter(0, 0, map[string]int{"my.metric": 10}, ContextLimitPolicyDrop))
+}
+
+func TestContextLimitPerMetricDrop(t *testing.T) {
+	cr := newLimitedContextResolver(0, 2, ContextLimitPolicyDrop)
+
+	_, ok := cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:1"), 1)
+	assert.True(t, ok)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:2"), 1)
+	assert.True(t, ok)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:3"), 1)
+	assert.False(t, ok)
+
+	// known contexts and other metrics are still accepted
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:1"), 2)
+	assert.True(t, ok)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.other.metric", "request_id:3"), 2)
+	assert.True(t, ok)
+	for i := 0; i < 5; i++ {
+		_, ok = cr.trackContextWithinLimits(limitedSample("my.unlimited.metric", fmt.Sprintf("request_id:%d", i)), 2)
+		assert.True(t, ok)
+	}
+	assert.Len(t, cr.contextsByKey, 8)
+
+	stats := GetContextLimitsStats()
+	assert.Equal(t, uint64(1), stats.DroppedSamples)
+	assert.Equal(t, []ContextLimitsOffender{{Name: "my.metric", Count: 1}}, stats.TopMetrics)
+}
+
+func TestContextLimitGlobal(t *testing.T) {
+	cr := newLimitedContextResolver(2, 0, ContextLimitPolicyStripTags)
+
+	_, ok := cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:1"), 1)
+	assert.True(t, ok)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.other.metric"), 1)
+	assert.True(t, ok)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.third.metric"), 1)
+	assert.False(t, ok)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:1"), 2)
+	assert.True(t, ok)
+
+	// the global limit never strips tags
+	assert.Equal(t, uint64(0), GetContextLimitsStats().StrippedTagKeys)
+
+	// expired contexts free up room
+	cr.expireContexts(2)
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.third.metric"), 3)
+	assert.True(t, ok)
+}
+
+func TestContextLimitPerMetricStripTags(t *testing.T) {
+	cr := newLimitedContextResolver(0, 2, ContextLimitPolicyStripTags)
+
+	cr.trackContextWithinLimits(limitedSample("my.metric", "env:prod", "request_id:1"), 1)
+	cr.trackContextWithinLimits(limitedSample("my.metric", "env:prod", "request_id:2"), 1)
+
+	contextKey, ok := cr.trackContextWithinLimits(limitedSample("my.metric", "env:prod", "request_id:3"), 1)
+	require.True(t, ok)
+	assert.Equal(t, []string{"env:prod"}, cr.contextsByKey[contextKey].Tags)
+
+	// the following samples share the context without the stripped tag key
+	otherKey, ok := cr.trackContextWithinLimits(limitedSample("my.metric", "request_id:4", "env:prod"), 1)
+	require.True(t, ok)
+	assert.Equal(t, contextKey, otherKey)
+
+	// the metric gets a new budget of contexts before stripping another key
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "env:staging", "request_id:5"), 1)
+	require.True(t, ok)
+	assert.Len(t, cr.contextsByKey, 4)
+	contextKey, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "env:dev", "request_id:6"), 1)
+	require.True(t, ok)
+	assert.Empty(t, cr.contextsByKey[contextKey].Tags)
+
+	// no tag left to strip
+	cr.limiter.metrics["my.metric"].contextsSinceStrip = 2
+	_, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "service:web"), 1)
+	assert.False(t, ok)
+
+	stats := GetContextLimitsStats()
+	assert.Equal(t, uint64(1), stats.DroppedSamples)
+	assert.Equal(t, uint64(2), stats.StrippedTagKeys)
+	assert.Equal(t, []ContextLimitsOffender{{"env", 1}, {"request_id", 1}}, stats.TopTagKeys)
+
+	// once all its contexts expired, the metric gets its tag keys back
+	cr.expireContexts(2)
+	assert.Empty(t, cr.limiter.metrics)
+	contextKey, ok = cr.trackContextWithinLimits(limitedSample("my.metric", "env:prod", "request_id:7"), 3)
+	require.True(t, ok)
+	assert.Equal(t, []string{"env:prod", "request_id:7"}, cr.contextsByKey[contextKey].Tags)
+}
+
+func TestContextLimitTagCardinality(t *testing.T) {
+	cr := newLimitedContextResolver(0, 10, ContextLimitPolicyStripTags)
+
+	cr.trackContextWithinLimits(limitedSample("my.metric", "env:prod", "request_id:1"), 1)
+	cr.trackContextWithinLimits(limitedSample("my.metric", "env:prod", "request_id:2"), 1)
+	cr.trackContextWithinLimits(limitedSample("my.metric", "env:staging", "request_id:2", "request_id:2"), 2)
+	cr.trackContextWithinLimits(limitedSample("my.metric", "env:dev"), 2)
+	assert.Equal(t, map[string]map[string]int{
+		"env":        {"env:prod": 2, "env:staging": 1, "env:dev": 1},
+		"request_id": {"request_id:1": 1, "request_id:2": 2},
+	}, cr.limiter.metrics["my.metric"].contextsByTagValue)
+	assert.Equal(t, "env", cr.limiter.highestCardinalityTagKey("my.metric"))
+
+	// the counts follow the expired contexts
+	cr.expireContexts(2)
+	assert.Equal(t, map[string]map[string]int{
+		"env":        {"env:staging": 1, "env:dev": 1},
+		"request_id": {"request_id:2": 1},
+	}, cr.limiter.metrics["my.metric"].contextsByTagValue)
+	assert.Equal(t, "env", cr.limiter.highestCardinalityTagKey("my.metric"))
+
+	// the stripped tag keys are not counted anymore
+	cr.limiter.stripTagKey("my.metric", "env")
+	assert.Equal(t, "request_id", cr.limiter.highestCardinalityTagKey("my.metric"))
+	cr.expireContexts(3)
+	assert.Empty(t, cr.limiter.metrics)
+	assert.Equal(t, "", cr.limiter.highestCardinalityTagKey("my.metric"))
+}
+
+func TestContextLimitStripTagsDropsWithoutTags(t *testing.T) {
+	cr := newLimitedContextResolver(0, 1, ContextLimitPolicyStripTags)
+
+	_, ok := cr.trackContextWithinLimits(limitedSample("my.metric"), 1)
+	assert.True(t, ok)
+	_, ok = cr.trackContextWithinLimits(&metrics.MetricSample{Name: "my.metric", Host: "other-host", Mtype: metrics.GaugeType}, 1)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), GetContextLimitsStats().DroppedSamples)
+}
+
+func TestTimeSamplerDropsSamplesOverContextLimit(t *testing.T) {
+	sampler := NewTimeSampler(10)
+	sampler.contextResolver = newLimitedContextResolver(0, 1, ContextLimitPolicyDrop)
+
+	sampler.addSample(limitedSample("my.metric", "request_id:1"), 12345.0)
+	sampler.addSample(limitedSample("my.metric", "request_id:2"), 12345.0)
+
+	series, _ := sampler.flush(12360.0)
+	require.Len(t, series, 1)
+	assert.Equal(t, []string{"request_id:1"}, series[0].Tags)
+}
+
+func TestFormatContextLimitsStats(t *testing.T) {
+	counts := map[string]uint64{"a": 1, "b": 5, "c": 3, "d": 3}
+	assert.Equal(t, []ContextLimitsOffender{{"b", 5}, {"c", 3}, {"d", 3}}, topOffenders(counts, 3))
+
+	data, err := json.Marshal(ContextLimitsStats{
+		DroppedSamples: 12,
+		TopMetrics:     []ContextLimitsOffender{{"my.metric", 12}},
+	})
+	require.NoError(t, err)
+
+	formatted, err := FormatContextLimitsStats(data)
+	require.NoError(t, err)
+	assert.Contains(t, formatted, "Samples dropped over a context limit: 12")
+	assert.Contains(t, formatted, "my.metric")
+	assert.NotContains(t, formatted, "Tag key ")
+}
+
+func TestShardContextLimit(t *testing.T) {
+	assert.Equal(t, 0, shardContextLimit(0, 4))
+	assert.Equal(t, 10, shardContextLimit(10, 1))
+	assert.Equal(t, 3, shardContextLimit(10, 4))
+	assert.Equal(t, 5, shardContextLimit(10, 2))
+}
@@ -24,6 +24,8 @@ type ContextResolver struct {
 	contextsByKey map[ckey.ContextKey]*Context
 	lastSeenByKey map[ckey.ContextKey]float64
 	keyGenerator  *ckey.KeyGenerator
+	// limiter bounds the number of contexts tracked, nil when unlimited
+	limiter *contextLimiter
 	// buffer slice allocated once per ContextResolver to combine and sort
 	// tags, origin detection tags and k8s tags.
 	tagsSliceBuffer []string
@@ -48,6 +50,56 @@ func (cr *ContextResolver) trackContext(metricSampleContext metrics.MetricSample
 	cr.tagsSliceBuffer = metricSampleContext.GetTags(cr.tagsSliceBuffer)
 	contextKey := cr.generateContextKey(metricSampleContext, cr.tagsSliceBuffer)
 
+	cr.track(contextKey, metricSampleContext, currentTimestamp)
+
+	cr.tagsSliceBuffer = cr.tagsSliceBuffer[0:0] // reset tags buffer
+	return contextKey
+}
+
+// trackContextWithinLimits behaves like trackContext, while enforcing the context limits
+// of the resolver. It returns false when the sample must be dropped because its context
+// is over a limit.
+func (cr *ContextResolver) trackContextWithinLimits(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) (ckey.ContextKey, bool) {
+	if cr.limiter == nil {
+		return cr.trackContext(metricSampleContext, currentTimestamp), true
+	}
+	defer func() { cr.tagsSliceBuffer = cr.tagsSliceBuffer[0:0] }() // reset tags buffer
+
+	name := metricSampleContext.GetName()
+	cr.tagsSliceBuffer = metricSampleContext.GetTags(cr.tagsSliceBuffer)
+	cr.tagsSliceBuffer = stripTags(cr.tagsSliceBuffer, cr.limiter.strippedTagKeys(name))
+	contextKey := cr.generateContextKey(metricSampleContext, cr.tagsSliceBuffer)
+
+	if _, found := cr.contextsByKey[contextKey]; !found {
+		if cr.limiter.isOverGlobalLimit(len(cr.contextsByKey)) {
+			cr.limiter.contextDropped(name, "global_limit")
+			return contextKey, false
+		}
+		if cr.limiter.isOverMetricLimit(name) {
+			if cr.limiter.policy != ContextLimitPolicyStripTags {
+				cr.limiter.contextDropped(name, "metric_limit")
+				return contextKey, false
+			}
+			tagKey := cr.limiter.highestCardinalityTagKey(name)
+			if tagKey == "" {
+				cr.limiter.contextDropped(name, "metric_limit")
+				return contextKey, false
+			}
+			cr.limiter.stripTagKey(name, tagKey)
+			cr.tagsSliceBuffer = stripTags(cr.tagsSliceBuffer, cr.limiter.strippedTagKeys(name))
+			contextKey = cr.generateContextKey(metricSampleContext, cr.tagsSliceBuffer)
+		}
+		if _, found := cr.contextsByKey[contextKey]; !found {
+			cr.limiter.contextAdded(name, cr.tagsSliceBuffer)
+		}
+	}
+
+	cr.track(contextKey, metricSampleContext, currentTimestamp)
+	return contextKey, true
+}
+
+// track tracks the context of the metricSample under contextKey, with the tags of tagsSliceBuffer
+func (cr *ContextResolver) track(contextKey ckey.ContextKey, metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) {
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
 		// making a copy of tags for the context since tagsSliceBuffer
 		// will be reused later. This allow us to allocate one slice
@@ -60,10 +112,11 @@ func (cr *ContextResolver) trackContext(metricSampleContext metrics.MetricSample
 			Host: metricSampleContext.GetHost(),
 		}
 	}
-	cr.lastSeenByKey[contextKey] = currentTimestamp
-
-	cr.tagsSliceBuffer = cr.tagsSliceBuffer[0:0] // reset tags buffer
-	return contextKey
+	// samples can carry a timestamp older than the last one seen for the context,
+	// they must not shorten its lifetime
+	if lastSeen, ok := cr.lastSeenByKey[contextKey]; !ok || lastSeen < currentTimestamp {
+		cr.lastSeenByKey[contextKey] = currentTimestamp
+	}
 }
 
 // updateTrackedContext updates the last seen timestamp on a given context key
@@ -91,6 +144,10 @@ func (cr *ContextResolver) expireContexts(expireTimestamp float64) []ckey.Contex
 
 	// Delete expired context keys
 	for _, expiredContextKey := range expiredContextKeys {
+		if cr.limiter != nil {
+			context := cr.contextsByKey[expiredContextKey]
+			cr.limiter.contextRemoved(context.Name, context.Tags)
+		}
 		delete(cr.contextsByKey, expiredContextKey)
 		delete(cr.lastSeenByKey, expiredContextKey)
 	}
@@ -134,3 +134,23 @@ func TestExpireContexts(t *testing.T) {
 	_, ok = contextResolver.contextsByKey[contextKey2]
 	assert.True(t, ok)
 }
+
+func TestTrackContextWithOlderTimestamp(t *testing.T) {
+	mSample := metrics.MetricSample{
+		Name:       "my.metric.name",
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"foo", "bar"},
+		SampleRate: 1,
+	}
+	contextResolver := newContextResolver()
+
+	contextKey := contextResolver.trackContext(&mSample, 6)
+	// a sample carrying an older timestamp doesn't shorten the lifetime of the context
+	contextResolver.trackContext(&mSample, 2)
+	assert.Equal(t, float64(6), contextResolver.lastSeenByKey[contextKey])
+
+	assert.Len(t, contextResolver.expireContexts(5), 0)
+	_, ok := contextResolver.contextsByKey[contextKey]
+	assert.True(t, ok)
+}
@@ -0,0 +1,291 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// openMetricsQuantiles are the quantiles of the distributions exposed by their summary
+var openMetricsQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+var openMetricsSketchConfig = quantile.Default()
+
+// OpenMetrics types of the metric families
+const (
+	openMetricsGauge   = "gauge"
+	openMetricsCounter = "counter"
+	openMetricsSummary = "summary"
+)
+
+// openMetricsExposition groups the series and sketches pending in the samplers by
+// metric family, to render them in the OpenMetrics text format
+type openMetricsExposition struct {
+	families map[string]*openMetricsFamily
+}
+
+// openMetricsFamily holds the samples sharing a sanitized metric name
+type openMetricsFamily struct {
+	name    string
+	mType   string
+	samples map[string]*openMetricsSample
+}
+
+// openMetricsSample holds the value of a label set of a family
+type openMetricsSample struct {
+	labels string
+	value  float64
+	// timestamp of the latest point of a gauge
+	ts float64
+	// merged sketches of a summary
+	sketch *quantile.Sketch
+}
+
+func newOpenMetricsExposition() *openMetricsExposition {
+	return &openMetricsExposition{families: make(map[string]*openMetricsFamily)}
+}
+
+// sample returns the sample of the family and label set, or nil if the family has
+// another type
+func (e *openMetricsExposition) sample(name string, mType string, labels string) *openMetricsSample {
+	family, ok := e.families[name]
+	if !ok {
+		family = &openMetricsFamily{name: name, mType: mType, samples: make(map[string]*openMetricsSample)}
+		e.families[name] = family
+	} else if family.mType != mType {
+		// a family has a single type, the series of another type are skipped
+		return nil
+	}
+
+	sample, ok := family.samples[labels]
+	if !ok {
+		sample = &openMetricsSample{labels: labels}
+		family.samples[labels] = sample
+	}
+	return sample
+}
+
+// addSeries adds the series to the exposition. The counts and the rates are exposed as
+// counters summing the points pending, a rate point being converted to the count over its
+// interval. The other types are exposed as gauges with the value of their latest point.
+func (e *openMetricsExposition) addSeries(series metrics.Series) {
+	for _, serie := range series {
+		if len(serie.Points) == 0 {
+			continue
+		}
+		name := sanitizeOpenMetricsName(serie.Name)
+		labels := openMetricsLabels(serie.Tags, serie.Host, serie.Device)
+
+		switch serie.MType {
+		case metrics.APICountType, metrics.APIRateType:
+			// the samples of a counter family get the suffix
+			sample := e.sample(strings.TrimSuffix(name, "_total"), openMetricsCounter, labels)
+			if sample == nil {
+				continue
+			}
+			for _, point := range serie.Points {
+				if serie.MType == metrics.APIRateType && serie.Interval > 0 {
+					sample.value += point.Value * float64(serie.Interval)
+				} else {
+					sample.value += point.Value
+				}
+			}
+		default:
+			sample := e.sample(name, openMetricsGauge, labels)
+			if sample == nil {
+				continue
+			}
+			for _, point := range serie.Points {
+				if point.Ts >= sample.ts {
+					sample.ts = point.Ts
+					sample.value = point.Value
+				}
+			}
+		}
+	}
+}
+
+// addSketches adds the distributions to the exposition as summaries, their quantiles,
+// sum and count are computed from the merge of the sketches pending
+func (e *openMetricsExposition) addSketches(sketches metrics.SketchSeriesList) {
+	for _, sketchSerie := range sketches {
+		name := sanitizeOpenMetricsName(sketchSerie.Name)
+		sample := e.sample(name, openMetricsSummary, openMetricsLabels(sketchSerie.Tags, sketchSerie.Host, ""))
+		if sample == nil {
+			continue
+		}
+		for _, point := range sketchSerie.Points {
+			if point.Sketch == nil {
+				continue
+			}
+			if sample.sketch == nil {
+				sample.sketch = &quantile.Sketch{}
+			}
+			sample.sketch.Merge(openMetricsSketchConfig, point.Sketch)
+		}
+	}
+}
+
+// write renders the exposition in the OpenMetrics text format
+func (e *openMetricsExposition) write(buf *bytes.Buffer) {
+	names := make([]string, 0, len(e.families))
+	for name := range e.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := e.families[name]
+		buf.WriteString("# TYPE " + family.name + " " + family.mType + "\n")
+
+		labelSets := make([]string, 0, len(family.samples))
+		for labels := range family.samples {
+			labelSets = append(labelSets, labels)
+		}
+		sort.Strings(labelSets)
+
+		for _, labels := range labelSets {
+			sample := family.samples[labels]
+			switch family.mType {
+			case openMetricsCounter:
+				writeOpenMetricsSample(buf, family.name+"_total", labels, "", sample.value)
+			case openMetricsSummary:
+				if sample.sketch == nil {
+					continue
+				}
+				for _, q := range openMetricsQuantiles {
+					quantileLabel := `quantile="` + strconv.FormatFloat(q, 'g', -1, 64) + `"`
+					writeOpenMetricsSample(buf, family.name, labels, quantileLabel, sample.sketch.Quantile(openMetricsSketchConfig, q))
+				}
+				writeOpenMetricsSample(buf, family.name+"_sum", labels, "", sample.sketch.Basic.Sum)
+				writeOpenMetricsSample(buf, family.name+"_count", labels, "", float64(sample.sketch.Basic.Cnt))
+			default:
+				writeOpenMetricsSample(buf, family.name, labels, "", sample.value)
+			}
+		}
+	}
+	buf.WriteString("# EOF\n")
+}
+
+// writeOpenMetricsSample writes a sample line, the extra label being added to the label set
+func writeOpenMetricsSample(buf *bytes.Buffer, name string, labels string, extraLabel string, value float64) {
+	buf.WriteString(name)
+	if labels != "" || extraLabel != "" {
+		buf.WriteByte('{')
+		buf.WriteString(labels)
+		if labels != "" && extraLabel != "" {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(extraLabel)
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+// OpenMetricsHandler returns an http.Handler serving the series and sketches pending in the
+// samplers of the aggregator in the OpenMetrics text format, without flushing them
+func OpenMetricsHandler(agg *BufferedAggregator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		series, sketches := agg.snapshotSeriesAndSketches()
+
+		exposition := newOpenMetricsExposition()
+		exposition.addSeries(series)
+		exposition.addSketches(sketches)
+
+		buf := bytes.NewBuffer(nil)
+		exposition.write(buf)
+		w.Header().Set("Content-Type", openMetricsContentType)
+		w.Write(buf.Bytes()) //nolint:errcheck
+	})
+}
+
+// openMetricsLabels formats the tags, host and device of a serie as a sorted list of labels,
+// without the enclosing braces. The values of the tags sharing a key are joined with a comma
+// and the tags without value are exposed with the "true" value.
+func openMetricsLabels(tags []string, host string, device string) string {
+	values := make(map[string][]string, len(tags)+2)
+	for _, tag := range tags {
+		key, value := tag, "true"
+		if i := strings.IndexByte(tag, ':'); i >= 0 {
+			key, value = tag[:i], tag[i+1:]
+		}
+		key = sanitizeOpenMetricsLabelName(key)
+		values[key] = append(values[key], value)
+	}
+	if host != "" {
+		values["host"] = []string{host}
+	}
+	if device != "" {
+		values["device"] = []string{device}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteString(`="`)
+		sort.Strings(values[key])
+		b.WriteString(escapeOpenMetricsLabelValue(strings.Join(values[key], ",")))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// sanitizeOpenMetricsName replaces the characters not allowed in a metric name with an underscore
+func sanitizeOpenMetricsName(name string) string {
+	return sanitizeOpenMetrics(name, true)
+}
+
+// sanitizeOpenMetricsLabelName replaces the characters not allowed in a label name with an underscore
+func sanitizeOpenMetricsLabelName(name string) string {
+	return sanitizeOpenMetrics(name, false)
+}
+
+func sanitizeOpenMetrics(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9') || (allowColon && c == ':')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return "_" + name[:1] + string(b[1:])
+	}
+	return string(b)
+}
+
+var openMetricsLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeOpenMetricsLabelValue(value string) string {
+	return openMetricsLabelValueEscaper.Replace(value)
+}
@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build test
+
+package aggregator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+)
+
+func TestOpenMetricsExposition(t *testing.T) {
+	sketch1 := &quantile.Sketch{}
+	sketch1.Insert(quantile.Default(), 1, 2, 3)
+	sketch2 := &quantile.Sketch{}
+	sketch2.Insert(quantile.Default(), 4)
+
+	exposition := newOpenMetricsExposition()
+	exposition.addSeries(metrics.Series{
+		{
+			Name:   "my.gauge",
+			Points: []metrics.Point{{Ts: 20, Value: 2}, {Ts: 10, Value: 1}},
+			Tags:   []string{"env:prod", "role:b", "role:a", "canary"},
+			Host:   "my-host",
+			MType:  metrics.APIGaugeType,
+		},
+		{
+			Name:   "my.requests.total",
+			Points: []metrics.Point{{Ts: 10, Value: 3}, {Ts: 20, Value: 4}},
+			Tags:   []string{`path:/a"b`},
+			MType:  metrics.APICountType,
+		},
+		// a dogstatsd counter is sent as a rate over its interval
+		{
+			Name:     "1st.counter-metric",
+			Points:   []metrics.Point{{Ts: 10, Value: 0.5}},
+			Tags:     []string{"kube.namespace:default"},
+			MType:    metrics.APIRateType,
+			Interval: 10,
+		},
+		// a family has a single type
+		{
+			Name:   "my.gauge",
+			Points: []metrics.Point{{Ts: 30, Value: 10}},
+			MType:  metrics.APICountType,
+		},
+	})
+	exposition.addSketches(metrics.SketchSeriesList{
+		{
+			Name: "my.distribution",
+			Tags: []string{"env:prod"},
+			Points: []metrics.SketchPoint{
+				{Ts: 10, Sketch: sketch1},
+				{Ts: 20, Sketch: sketch2},
+			},
+		},
+	})
+
+	buf := bytes.NewBuffer(nil)
+	exposition.write(buf)
+	assert.Equal(t, `# TYPE _1st_counter_metric counter
+_1st_counter_metric_total{kube_namespace="default"} 5
+# TYPE my_distribution summary
+my_distribution{env="prod",quantile="0.5"} 3.0065620324196973
+my_distribution{env="prod",quantile="0.75"} 3.0065620324196973
+my_distribution{env="prod",quantile="0.95"} 3.9743952640439537
+my_distribution{env="prod",quantile="0.99"} 3.9743952640439537
+my_distribution_sum{env="prod"} 10
+my_distribution_count{env="prod"} 4
+# TYPE my_gauge gauge
+my_gauge{canary="true",env="prod",host="my-host",role="a,b"} 2
+# TYPE my_requests counter
+my_requests_total{path="/a\"b"} 7
+# EOF
+`, buf.String())
+
+	// the sketches are merged into a copy
+	assert.Equal(t, int64(3), sketch1.Basic.Cnt)
+}
+
+func TestOpenMetricsHandler(t *testing.T) {
+	agg := NewBufferedAggregator(nil, "hostname", time.Hour)
+	now := timeNowNano()
+	agg.addSample(&metrics.MetricSample{Name: "my.counter", Value: 3, Mtype: metrics.CounterType, SampleRate: 1}, now)
+	agg.addSample(&metrics.MetricSample{Name: "my.counter", Value: 2, Mtype: metrics.CounterType, SampleRate: 1}, now)
+	agg.addSample(&metrics.MetricSample{Name: "my.gauge", Value: 1, Mtype: metrics.GaugeType, Tags: []string{"a:b"}, SampleRate: 1}, now)
+	agg.addSample(&metrics.MetricSample{Name: "my.distribution", Value: 5, Mtype: metrics.DistributionType, SampleRate: 1}, now)
+
+	checkSampler := newCheckSampler()
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.check.count", Value: 4, Mtype: metrics.CountType, Timestamp: now})
+	checkSampler.commit(now)
+	agg.checkSamplers["my_check"] = checkSampler
+
+	expected := `# TYPE my_check_count counter
+my_check_count_total 4
+# TYPE my_counter counter
+my_counter_total 5
+# TYPE my_distribution summary
+my_distribution{quantile="0.5"} 5
+my_distribution{quantile="0.75"} 5
+my_distribution{quantile="0.95"} 5
+my_distribution{quantile="0.99"} 5
+my_distribution_sum 5
+my_distribution_count 1
+# TYPE my_gauge gauge
+my_gauge{a="b"} 1
+# EOF
+`
+	// the samplers are not flushed by the scrapes
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		OpenMetricsHandler(agg).ServeHTTP(recorder, httptest.NewRequest("GET", "/openmetrics", nil))
+
+		resp := recorder.Result()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, openMetricsContentType, resp.Header.Get("Content-Type"))
+		assert.Equal(t, expected, string(body))
+	}
+
+	series, sketches := agg.GetSeriesAndSketches(time.Now().Add(time.Hour))
+	assert.Len(t, series, 3)
+	assert.Len(t, sketches, 1)
+}
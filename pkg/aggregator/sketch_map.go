@@ -35,6 +35,27 @@ func (m sketchMap) insert(ts int64, ck ckey.ContextKey, v float64, sampleRate fl
 	return true
 }
 
+// insertBins merges the pre-aggregated bins into the sketch for the given (ts, contextKey),
+// their counts are scaled by the sample rate. The non-finite values are skipped.
+func (m sketchMap) insertBins(ts int64, ck ckey.ContextKey, bins []metrics.DistributionBin, sampleRate float64) bool {
+	// bounds enforcement, same as quantile.Agent.Insert
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+
+	var s *quantile.Agent
+	for _, bin := range bins {
+		if math.IsInf(bin.Value, 0) || math.IsNaN(bin.Value) {
+			continue
+		}
+		if s == nil {
+			s = m.getOrCreate(ts, ck)
+		}
+		s.InsertN(bin.Value, uint(float64(bin.Count)/sampleRate))
+	}
+	return s != nil
+}
+
 func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower float64, upper float64, count uint) bool {
 	if math.IsInf(lower, 0) || math.IsNaN(lower) {
 		return false
@@ -84,3 +105,15 @@ func (m sketchMap) flushBefore(beforeTs int64, f func(ckey.ContextKey, metrics.S
 		delete(m, ts)
 	}
 }
+
+// snapshot calls f for every sketch of the map, without removing them
+func (m sketchMap) snapshot(f func(ckey.ContextKey, metrics.SketchPoint)) {
+	for ts, byCtx := range m {
+		for ck, as := range byCtx {
+			f(ck, metrics.SketchPoint{
+				Sketch: as.Finish(),
+				Ts:     ts,
+			})
+		}
+	}
+}
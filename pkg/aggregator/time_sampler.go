@@ -34,12 +34,21 @@ type TimeSampler struct {
 
 // NewTimeSampler returns a newly initialized TimeSampler
 func NewTimeSampler(interval int64) *TimeSampler {
+	return newTimeSamplerShard(interval, 1)
+}
+
+// newTimeSamplerShard returns a newly initialized TimeSampler aggregating one out of
+// shards shards of the contexts, the context limits are split between the shards.
+func newTimeSamplerShard(interval int64, shards int) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
+	contextResolver := newContextResolver()
+	contextResolver.limiter = newContextLimiterFromConfig(shards)
+
 	return &TimeSampler{
 		interval:                    interval,
-		contextResolver:             newContextResolver(),
+		contextResolver:             contextResolver,
 		metricsByTimestamp:          map[int64]metrics.ContextMetrics{},
 		counterLastSampledByContext: map[ckey.ContextKey]float64{},
 		sketchMap:                   make(sketchMap),
@@ -56,13 +65,20 @@ func (s *TimeSampler) isBucketStillOpen(bucketStartTimestamp, timestamp int64) b
 
 // Add the metricSample to the correct bucket
 func (s *TimeSampler) addSample(metricSample *metrics.MetricSample, timestamp float64) {
-	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
+	// Keep track of the context, the sample is dropped when its context is over a limit
+	contextKey, ok := s.contextResolver.trackContextWithinLimits(metricSample, timestamp)
+	if !ok {
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
 	case metrics.DistributionType:
-		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
+		if len(metricSample.Bins) > 0 {
+			s.sketchMap.insertBins(bucketStart, contextKey, metricSample.Bins, metricSample.SampleRate)
+		} else {
+			s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
+		}
 	default:
 		// If it's a new bucket, initialize it
 		bucketMetrics, ok := s.metricsByTimestamp[bucketStart]
@@ -70,8 +86,9 @@ func (s *TimeSampler) addSample(metricSample *metrics.MetricSample, timestamp fl
 			bucketMetrics = metrics.MakeContextMetrics()
 			s.metricsByTimestamp[bucketStart] = bucketMetrics
 		}
-		// Update LastSampled timestamp for counters
-		if metricSample.Mtype == metrics.CounterType {
+		// Update LastSampled timestamp for counters, a sample can be older than the last one when it
+		// carries its own timestamp
+		if metricSample.Mtype == metrics.CounterType && s.counterLastSampledByContext[contextKey] < timestamp {
 			s.counterLastSampledByContext[contextKey] = timestamp
 		}
 
@@ -97,10 +114,8 @@ func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.Sketc
 }
 
 func (s *TimeSampler) flushSeries(cutoffTime int64) metrics.Series {
-	var series []*metrics.Serie
 	var rawSeries []*metrics.Serie
 
-	serieBySignature := make(map[SerieSignature]*metrics.Serie)
 	// Map to hold the expired contexts that will need to be deleted after the flush so that we stop sending zeros
 	counterContextsToDelete := map[ckey.ContextKey]struct{}{}
 
@@ -135,6 +150,15 @@ func (s *TimeSampler) flushSeries(cutoffTime int64) metrics.Series {
 		delete(s.counterLastSampledByContext, context)
 	}
 
+	return s.resolveSeries(rawSeries)
+}
+
+// resolveSeries populates the series with the name, tags and host of their context and
+// merges the points of the series sharing a signature
+func (s *TimeSampler) resolveSeries(rawSeries []*metrics.Serie) metrics.Series {
+	var series []*metrics.Serie
+	serieBySignature := make(map[SerieSignature]*metrics.Serie)
+
 	for _, serie := range rawSeries {
 		serieSignature := SerieSignature{serie.MType, serie.ContextKey, serie.NameSuffix}
 
@@ -191,6 +215,30 @@ func (s *TimeSampler) flush(timestamp float64) (metrics.Series, metrics.SketchSe
 	return series, sketches
 }
 
+// snapshot returns the series and sketches of the buckets not flushed yet, including
+// the ones still open, without flushing them
+func (s *TimeSampler) snapshot() (metrics.Series, metrics.SketchSeriesList) {
+	var rawSeries []*metrics.Serie
+	for bucketTimestamp, contextMetrics := range s.metricsByTimestamp {
+		rawSeries = append(rawSeries, contextMetrics.Snapshot(float64(bucketTimestamp))...)
+	}
+	series := s.resolveSeries(rawSeries)
+
+	pointsByCtx := make(map[ckey.ContextKey][]metrics.SketchPoint)
+	s.sketchMap.snapshot(func(ck ckey.ContextKey, p metrics.SketchPoint) {
+		if p.Sketch == nil {
+			return
+		}
+		pointsByCtx[ck] = append(pointsByCtx[ck], p)
+	})
+	sketches := make(metrics.SketchSeriesList, 0, len(pointsByCtx))
+	for ck, points := range pointsByCtx {
+		sketches = append(sketches, s.newSketchSeries(ck, points))
+	}
+
+	return series, sketches
+}
+
 // flushContextMetrics flushes the passed contextMetrics, handles its errors, and returns its series
 func (s *TimeSampler) flushContextMetrics(timestamp int64, contextMetrics metrics.ContextMetrics) []*metrics.Serie {
 	series, errors := contextMetrics.Flush(float64(timestamp))
@@ -64,6 +64,44 @@ func TestBucketSampling(t *testing.T) {
 	}
 }
 
+func TestBucketSamplingWithLateSample(t *testing.T) {
+	sampler := NewTimeSampler(10)
+
+	mSample := metrics.MetricSample{
+		Name:       "my.metric.name",
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"foo", "bar"},
+		SampleRate: 1,
+	}
+	sampler.addSample(&mSample, 12345.0)
+	series, _ := sampler.flush(12360.0)
+	assert.Equal(t, 1, len(series))
+
+	// a sample carrying its own timestamp lands in the bucket of this timestamp,
+	// even when this bucket was already flushed
+	lateSample := mSample
+	lateSample.Value = 2
+	sampler.addSample(&mSample, 12365.0)
+	sampler.addSample(&lateSample, 12335.0)
+
+	series, _ = sampler.flush(12365.0)
+
+	expectedSerie := &metrics.Serie{
+		Name:       "my.metric.name",
+		Tags:       []string{"foo", "bar"},
+		Points:     []metrics.Point{{Ts: 12330.0, Value: lateSample.Value}},
+		MType:      metrics.APIGaugeType,
+		Interval:   10,
+		NameSuffix: "",
+	}
+
+	assert.Equal(t, 1, len(sampler.metricsByTimestamp))
+	if assert.Equal(t, 1, len(series)) {
+		metrics.AssertSerieEqual(t, expectedSerie, series[0])
+	}
+}
+
 func TestContextSampling(t *testing.T) {
 	sampler := NewTimeSampler(10)
 
@@ -372,6 +410,46 @@ func TestSketchBucketSampling(t *testing.T) {
 	assert.Equal(t, 1, sampler.sketchMap.Len())
 }
 
+func TestSketchBins(t *testing.T) {
+	sampler := NewTimeSampler(10)
+
+	mSample := metrics.MetricSample{
+		Name:  "test.metric.name",
+		Mtype: metrics.DistributionType,
+		Tags:  []string{"a", "b"},
+		Bins: []metrics.DistributionBin{
+			{Value: 1, Count: 2},
+			{Value: math.NaN(), Count: 1},
+			{Value: 2, Count: 1},
+		},
+		SampleRate: 0.5,
+	}
+	sampler.addSample(&mSample, 10001)
+	sampler.addSample(&metrics.MetricSample{
+		Name:       "test.metric.name",
+		Value:      3,
+		Mtype:      metrics.DistributionType,
+		Tags:       []string{"a", "b"},
+		SampleRate: 1,
+	}, 10002)
+
+	_, flushed := sampler.flush(10010.0)
+	// the counts of the bins are scaled by the sample rate, the NaN bin is skipped
+	expSketch := &quantile.Sketch{}
+	expSketch.Insert(quantile.Default(), 1, 1, 1, 1, 2, 2, 3)
+
+	require.Len(t, flushed, 1)
+	metrics.AssertSketchSeriesApproxEqual(t, metrics.SketchSeries{
+		Name:     "test.metric.name",
+		Tags:     []string{"a", "b"},
+		Interval: 10,
+		Points: []metrics.SketchPoint{
+			{Ts: 10000, Sketch: expSketch},
+		},
+		ContextKey: generateContextKey(&mSample),
+	}, flushed[0], 1e-9)
+}
+
 func TestSketchContextSampling(t *testing.T) {
 	sampler := NewTimeSampler(10)
 
@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// timeSamplerWorker aggregates in its own goroutine the dogstatsd samples of a
+// shard of the contexts, see ContextSharder.
+type timeSamplerWorker struct {
+	// samples aggregated at their arrival time
+	samplesIn chan []metrics.MetricSample
+	// samples aggregated at the timestamp provided by the client
+	samplesWithTsIn chan []metrics.MetricSample
+
+	// mu protects the sampler, it is locked once per batch of samples and
+	// while the sampler is flushed
+	mu      sync.Mutex
+	sampler *TimeSampler
+
+	metricSamplePool *metrics.MetricSamplePool
+	stopChan         chan struct{}
+}
+
+func newTimeSamplerWorker(shards int, bufferSize int, metricSamplePool *metrics.MetricSamplePool) *timeSamplerWorker {
+	return &timeSamplerWorker{
+		samplesIn:        make(chan []metrics.MetricSample, bufferSize),
+		samplesWithTsIn:  make(chan []metrics.MetricSample, bufferSize),
+		sampler:          newTimeSamplerShard(bucketSize, shards),
+		metricSamplePool: metricSamplePool,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+func (w *timeSamplerWorker) run() {
+	for {
+		select {
+		case <-w.stopChan:
+			return
+		case ms := <-w.samplesWithTsIn:
+			w.addSamples(ms, true)
+		case ms := <-w.samplesIn:
+			w.addSamples(ms, false)
+		}
+	}
+}
+
+func (w *timeSamplerWorker) stop() {
+	w.stopChan <- struct{}{}
+}
+
+// addSamples aggregates a batch of samples and puts it back into the pool
+func (w *timeSamplerWorker) addSamples(ms []metrics.MetricSample, withTimestamp bool) {
+	aggregatorDogstatsdMetricSample.Add(int64(len(ms)))
+	tlmProcessed.Add(float64(len(ms)), "dogstatsd_metrics")
+
+	w.mu.Lock()
+	if withTimestamp {
+		for i := 0; i < len(ms); i++ {
+			w.sampler.addSample(&ms[i], ms[i].Timestamp/float64(time.Second))
+		}
+	} else {
+		now := timeNowNano()
+		for i := 0; i < len(ms); i++ {
+			w.sampler.addSample(&ms[i], now)
+		}
+	}
+	w.mu.Unlock()
+
+	w.metricSamplePool.PutBatch(ms)
+}
+
+func (w *timeSamplerWorker) addSample(metricSample *metrics.MetricSample, timestamp float64) {
+	w.mu.Lock()
+	w.sampler.addSample(metricSample, timestamp)
+	w.mu.Unlock()
+}
+
+func (w *timeSamplerWorker) flush(timestamp float64) (metrics.Series, metrics.SketchSeriesList) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sampler.flush(timestamp)
+}
+
+// snapshot returns the series and sketches pending in the sampler, without flushing them
+func (w *timeSamplerWorker) snapshot() (metrics.Series, metrics.SketchSeriesList) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sampler.snapshot()
+}
+
+// flushTimeSamplerWorkers flushes the workers in parallel and merges their series and
+// sketches. Their contexts being distinct, the merge is a concatenation.
+func flushTimeSamplerWorkers(workers []*timeSamplerWorker, timestamp float64) (metrics.Series, metrics.SketchSeriesList) {
+	if len(workers) == 1 {
+		return workers[0].flush(timestamp)
+	}
+
+	seriesByWorker := make([]metrics.Series, len(workers))
+	sketchesByWorker := make([]metrics.SketchSeriesList, len(workers))
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func(i int, w *timeSamplerWorker) {
+			defer wg.Done()
+			seriesByWorker[i], sketchesByWorker[i] = w.flush(timestamp)
+		}(i, w)
+	}
+	wg.Wait()
+
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	for i := range workers {
+		series = append(series, seriesByWorker[i]...)
+		sketches = append(sketches, sketchesByWorker[i]...)
+	}
+	return series, sketches
+}
+
+// ContextSharder computes the index of the dogstatsd pipeline aggregating the context of a sample.
+// All the samples of a context must be sent to the same pipeline, so the shard is derived from the
+// context key of the sample, computed from its sorted and deduplicated tags like in the ContextResolver.
+// This struct is not safe for concurrent use.
+type ContextSharder struct {
+	pipelineCount int
+	keyGenerator  *ckey.KeyGenerator
+	// buffer slice allocated once per ContextSharder to combine and sort
+	// tags, origin detection tags and k8s tags.
+	tagsSliceBuffer []string
+}
+
+// NewContextSharder returns a ContextSharder spreading the contexts over pipelineCount pipelines
+func NewContextSharder(pipelineCount int) *ContextSharder {
+	return &ContextSharder{
+		pipelineCount:   pipelineCount,
+		keyGenerator:    ckey.NewKeyGenerator(),
+		tagsSliceBuffer: make([]string, 0, 128),
+	}
+}
+
+// Shard returns the index of the pipeline aggregating the context of the sample. The tags
+// of the sample are left untouched since they can be shared by samples already sent to a pipeline.
+func (s *ContextSharder) Shard(sample *metrics.MetricSample) int {
+	if s.pipelineCount <= 1 {
+		return 0
+	}
+	s.tagsSliceBuffer = sample.GetTags(s.tagsSliceBuffer)
+	key := s.keyGenerator.Generate(sample.GetName(), sample.GetHost(), s.tagsSliceBuffer)
+	s.tagsSliceBuffer = s.tagsSliceBuffer[0:0] // reset tags buffer
+	return int(uint64(key) % uint64(s.pipelineCount))
+}
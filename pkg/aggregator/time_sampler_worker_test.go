@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestContextSharder(t *testing.T) {
+	sample := &metrics.MetricSample{Name: "my.metric.name", Host: "myhost", Tags: []string{"foo", "bar", "baz:1"}}
+	other := &metrics.MetricSample{Name: "my.metric.name", Host: "myhost", Tags: []string{"baz:1", "foo", "bar", "foo"}}
+
+	assert.Equal(t, 0, NewContextSharder(0).Shard(sample))
+	assert.Equal(t, 0, NewContextSharder(1).Shard(sample))
+
+	// the shard is derived from the context key, independently of the order and
+	// duplicates of the tags, and the tags are left untouched
+	for pipelineCount := 2; pipelineCount <= 8; pipelineCount++ {
+		sharder := NewContextSharder(pipelineCount)
+		shard := sharder.Shard(sample)
+		assert.True(t, shard >= 0 && shard < pipelineCount)
+		assert.Equal(t, shard, sharder.Shard(other))
+
+		contextKey := newContextResolver().trackContext(sample, 0)
+		assert.Equal(t, int(uint64(contextKey)%uint64(pipelineCount)), shard)
+	}
+	assert.Equal(t, []string{"foo", "bar", "baz:1"}, sample.Tags)
+	assert.Equal(t, []string{"baz:1", "foo", "bar", "foo"}, other.Tags)
+
+	// the contexts are spread over all the pipelines
+	sharder := NewContextSharder(4)
+	used := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		used[sharder.Shard(&metrics.MetricSample{Name: fmt.Sprintf("my.metric.%d", i)})] = true
+	}
+	assert.Len(t, used, 4)
+}
+
+func TestShardSamples(t *testing.T) {
+	pool := metrics.NewMetricSamplePool(16)
+	agg := &BufferedAggregator{
+		MetricSamplePool: pool,
+		statsdWorkers: []*timeSamplerWorker{
+			newTimeSamplerWorker(3, 10, pool),
+			newTimeSamplerWorker(3, 10, pool),
+			newTimeSamplerWorker(3, 10, pool),
+		},
+		sharder: NewContextSharder(3),
+	}
+
+	batch := pool.GetBatch()
+	for i := 0; i < 12; i++ {
+		batch[i] = metrics.MetricSample{Name: fmt.Sprintf("my.metric.%d", i), Value: 1, Mtype: metrics.GaugeType, SampleRate: 1, Timestamp: 12345.0 * 1e9}
+	}
+	agg.shardSamples(batch[:12], true)
+
+	// every pipeline receives the samples of its own contexts
+	sharder := NewContextSharder(3)
+	received := 0
+	for shard, w := range agg.statsdWorkers {
+		assert.Len(t, w.samplesIn, 0)
+		if len(w.samplesWithTsIn) == 0 {
+			continue
+		}
+		require.Len(t, w.samplesWithTsIn, 1)
+		for _, sample := range <-w.samplesWithTsIn {
+			assert.Equal(t, shard, sharder.Shard(&sample))
+			received++
+		}
+	}
+	assert.Equal(t, 12, received)
+}
+
+func TestFlushTimeSamplerWorkers(t *testing.T) {
+	pool := metrics.NewMetricSamplePool(16)
+	workers := []*timeSamplerWorker{
+		newTimeSamplerWorker(3, 10, pool),
+		newTimeSamplerWorker(3, 10, pool),
+		newTimeSamplerWorker(3, 10, pool),
+	}
+	sharder := NewContextSharder(len(workers))
+
+	for i := 0; i < 30; i++ {
+		sample := metrics.MetricSample{
+			Name:       fmt.Sprintf("my.metric.%d", i),
+			Value:      1,
+			Mtype:      metrics.GaugeType,
+			SampleRate: 1,
+		}
+		batch := pool.GetBatch()
+		batch[0] = sample
+		workers[sharder.Shard(&sample)].addSamples(batch[:1], false)
+		sample.Mtype = metrics.DistributionType
+		sample.Name += ".dist"
+		workers[sharder.Shard(&sample)].addSample(&sample, 12345.0)
+	}
+
+	series, sketches := flushTimeSamplerWorkers(workers, timeNowNano()+bucketSize)
+	require.Len(t, series, 30)
+	require.Len(t, sketches, 30)
+
+	names := make(map[string]bool)
+	for _, serie := range series {
+		names[serie.Name] = true
+	}
+	assert.Len(t, names, 30)
+
+	// the workers have been flushed
+	series, sketches = flushTimeSamplerWorkers(workers, timeNowNano()+bucketSize)
+	assert.Len(t, series, 0)
+	assert.Len(t, sketches, 0)
+}
+
+func TestTimeSamplerWorkerRun(t *testing.T) {
+	pool := metrics.NewMetricSamplePool(16)
+	w := newTimeSamplerWorker(1, 10, pool)
+	go w.run()
+	defer w.stop()
+
+	batch := pool.GetBatch()
+	batch[0] = metrics.MetricSample{Name: "my.metric.name", Value: 1, Mtype: metrics.GaugeType, SampleRate: 1, Timestamp: 12345.0 * 1e9}
+	w.samplesWithTsIn <- batch[:1]
+
+	// the samples are aggregated at their own timestamp
+	var series metrics.Series
+	require.Eventually(t, func() bool {
+		flushed, _ := w.flush(12360.0)
+		series = append(series, flushed...)
+		return len(series) > 0
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, series, 1)
+	assert.Equal(t, "my.metric.name", series[0].Name)
+	assert.Equal(t, 12340.0, series[0].Points[0].Ts)
+}
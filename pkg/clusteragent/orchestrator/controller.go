@@ -139,7 +139,7 @@ func newController(ctx ControllerContext) (*Controller, error) {
 		clusterName:             ctx.ClusterName,
 		clusterID:               clusterID,
 		orchestratorConfig:      orchestratorCfg,
-		forwarder:               forwarder.NewDefaultForwarder(podForwarderOpts),
+		forwarder:               forwarder.NewForwarder("cluster_agent_orchestrator", podForwarderOpts),
 		isLeaderFunc:            ctx.IsLeaderFunc,
 	}
 
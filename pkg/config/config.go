@@ -133,6 +133,30 @@ type MetricMapping struct {
 	Tags      map[string]string `mapstructure:"tags" json:"tags"`
 }
 
+// TagRule represents a rule filtering the tags of the DogStatsD metrics it matches
+type TagRule struct {
+	Match       string           `mapstructure:"match" json:"match"`
+	MatchType   string           `mapstructure:"match_type" json:"match_type"`
+	DropTags    []string         `mapstructure:"drop_tags" json:"drop_tags"`
+	KeepTags    []string         `mapstructure:"keep_tags" json:"keep_tags"`
+	RewriteTags []TagRewriteRule `mapstructure:"rewrite_tags" json:"rewrite_tags"`
+}
+
+// TagRewriteRule represents a rewrite of the values of a tag
+type TagRewriteRule struct {
+	Tag     string `mapstructure:"tag" json:"tag"`
+	Match   string `mapstructure:"match" json:"match"`
+	Replace string `mapstructure:"replace" json:"replace"`
+}
+
+// HistogramOverride represents the aggregates and percentiles of the histograms whose
+// metric name matches
+type HistogramOverride struct {
+	Match       string   `mapstructure:"match" json:"match"`
+	Aggregates  []string `mapstructure:"aggregates" json:"aggregates"`
+	Percentiles []string `mapstructure:"percentiles" json:"percentiles"`
+}
+
 // Warnings represent the warnings in the config
 type Warnings struct {
 	TraceMallocEnabledWithPy2 bool
@@ -299,13 +323,29 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("proc_root", "/proc")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	_ = config.BindEnv("histogram_overrides")
+	config.SetEnvKeyTransformer("histogram_overrides", func(in string) interface{} {
+		var overrides []HistogramOverride
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"histogram_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
+	// Serve the series pending in the aggregator in the OpenMetrics format on the expvar server
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint_enabled", false)
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	// Serializer
 	config.BindEnvAndSetDefault("enable_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_service_checks_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_events_stream_payload_serialization", true)
+	// Stream the protobuf series sent when use_v2_api.series is enabled, instead of marshaling and splitting them at once
+	config.BindEnvAndSetDefault("enable_protobuf_stream_payload_serialization", true)
+	// Compression of the payloads: "zlib", "zstd" (when the agent is built with the zstd tag), "gzip" or "none".
+	// The payloads fall back to the compression the agent is built with when the intake rejects the configured one.
+	config.BindEnvAndSetDefault("serializer_compressor_kind", "zlib")
+	config.BindEnvAndSetDefault("serializer_compressor_level", -1) // -1 is the default level of the compressor
 
 	// Warning: do not change the two following values. Your payloads will get dropped by Datadog's intake.
 	config.BindEnvAndSetDefault("serializer_max_payload_size", 2*megaByte+megaByte/2)
@@ -335,6 +375,11 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_backoff_max", 64)
 	config.BindEnvAndSetDefault("forwarder_recovery_interval", DefaultForwarderRecoveryInterval)
 	config.BindEnvAndSetDefault("forwarder_recovery_reset", false)
+	// The number of requests sent concurrently to a domain adapts to its latency and errors, between 1 and
+	// forwarder_adaptive_concurrency_max_workers. The `Retry-After` headers are honored in any case.
+	config.BindEnvAndSetDefault("forwarder_adaptive_concurrency", false)
+	config.BindEnvAndSetDefault("forwarder_adaptive_concurrency_max_workers", 10)
+	config.BindEnvAndSetDefault("forwarder_adaptive_concurrency_latency_threshold", 5) // in seconds
 
 	// Forwarder storage on disk
 	defaultForwarderStoragePath := path.Join(config.GetString("run_path"), "transactions_to_retry")
@@ -342,11 +387,35 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_outdated_file_in_days", 10)
 	config.BindEnvAndSetDefault("forwarder_flush_to_disk_mem_ratio", 0.5)
 	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0) // 0 means disabled. This is a BETA feature.
+	// The retry files are encrypted with a key derived from the content of the key file or from the key itself,
+	// which can be resolved by the secrets backend. They are stored in clear text when none is set.
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key_file", "")
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key", "")
+
+	// Offline mode: the payloads are written as JSON lines to the files of offline_mode.output_dir,
+	// or to the standard output, instead of being sent to the intake
+	config.BindEnvAndSetDefault("offline_mode.enabled", false)
+	config.BindEnvAndSetDefault("offline_mode.output_dir", "")
+	config.BindEnvAndSetDefault("offline_mode.file_max_size", "10Mb")
+	config.BindEnvAndSetDefault("offline_mode.file_max_rolls", 1)
 
 	// Dogstatsd
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
 	config.BindEnvAndSetDefault("dogstatsd_pipe_name", "") // experimental and not officially supported for now.
+	// DogStatsD over TCP, 0 means TCP port closed
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_framing", "newline") // "newline" or "length_prefixed"
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_origin_detection", false)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_max_connections", 1024)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_idle_timeout", 300) // in seconds, 0 to disable
+	// Traffic capture, started from the agent CLI. An empty path means <run_path>/dsd_capture
+	config.BindEnvAndSetDefault("dogstatsd_capture_path", "")
+	config.BindEnvAndSetDefault("dogstatsd_capture_depth", 4096)
+	config.BindEnvAndSetDefault("dogstatsd_capture_max_file_size", 64*1024*1024)
+	config.BindEnvAndSetDefault("dogstatsd_capture_max_files", 4)
 
 	// The following options allow to configure how the dogstatsd intake buffers and queues incoming datagrams.
 	// When a datagram is received it is first added to a datagrams buffer. This buffer fills up until
@@ -374,6 +443,18 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_entity_id_precedence", false)
 	// Sends Dogstatsd parse errors to the Debug level instead of the Error level
 	config.BindEnvAndSetDefault("dogstatsd_disable_verbose_logs", false)
+	// Window around the current time in which the timestamps sent by the clients are accepted
+	config.BindEnvAndSetDefault("dogstatsd_timestamp_max_age_seconds", 600)
+	config.BindEnvAndSetDefault("dogstatsd_timestamp_max_future_seconds", 60)
+	// Rejects the metrics whose name or tags don't follow the naming rules instead of letting the intake normalize them
+	config.BindEnvAndSetDefault("dogstatsd_strict_validation", false)
+	// Limits on the number of contexts tracked by the aggregator, 0 means unlimited
+	config.BindEnvAndSetDefault("dogstatsd_context_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limit_per_metric", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limit_metric_overrides", map[string]int{})
+	config.BindEnvAndSetDefault("dogstatsd_context_limit_policy", "drop")
+	// Number of pipelines aggregating the DogStatsD samples in parallel
+	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 
 	_ = config.BindEnv("dogstatsd_mapper_profiles")
 	config.SetEnvKeyTransformer("dogstatsd_mapper_profiles", func(in string) interface{} {
@@ -384,6 +465,15 @@ func InitConfig(config Config) {
 		return mappings
 	})
 
+	_ = config.BindEnv("dogstatsd_tag_rules")
+	config.SetEnvKeyTransformer("dogstatsd_tag_rules", func(in string) interface{} {
+		var rules []TagRule
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_tag_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
@@ -608,6 +698,8 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.use_tcp", false)
 	config.BindEnvAndSetDefault("logs_config.use_compression", true)
 	config.BindEnvAndSetDefault("logs_config.compression_level", 6) // Default level for the gzip/deflate algorithm
+	// The logs fall back to gzip when the intake rejects the content encoding of the compression_kind
+	config.BindEnvAndSetDefault("logs_config.compression_kind", "gzip")
 	config.BindEnvAndSetDefault("logs_config.batch_wait", DefaultBatchWait)
 	config.BindEnvAndSetDefault("logs_config.connection_reset_interval", 0) // in seconds, 0 means disabled
 	config.BindEnvAndSetDefault("logs_config.dd_port", 10516)
@@ -616,12 +708,27 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.stop_grace_period", 30)
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
 	config.BindEnvAndSetDefault("logs_config.auditor_ttl", DefaultAuditorTTL) // in hours
+	// Identify tailed files by a fingerprint of their first bytes rather than by their path and inode,
+	// to detect rotations on filesystems reusing inodes and files rotated with copytruncate.
+	config.BindEnvAndSetDefault("logs_config.file_fingerprint_size", 0) // in bytes, 0 means disabled
+	// Store on disk the payloads that can't be sent while the intake is unreachable,
+	// the oldest payloads are evicted once the spool is full.
+	config.BindEnvAndSetDefault("logs_config.spool_path", path.Join(config.GetString("logs_config.run_path"), "logs_spool"))
+	config.BindEnvAndSetDefault("logs_config.spool_max_size_in_bytes", 0) // 0 means disabled
 	// Timeout in milliseonds used when performing agreggation operations,
 	// including multi-line log processing rules and chunked line reaggregation.
 	// It may be useful to increase it when logs writing is slowed down, that
 	// could happen while serializing large objects on log lines.
 	config.BindEnvAndSetDefault("logs_config.aggregation_timeout", 1000)
 	config.BindEnv("logs_config.additional_endpoints") //nolint:errcheck
+	// Automatically detect multi-line logs on file and container sources that don't declare
+	// a multi_line processing rule. The first lines of every source are matched against known
+	// start-of-record patterns (timestamps, levels) and, if one is common enough, the source
+	// is switched to multi-line aggregation with that pattern.
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_detection", false)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_sample_size", 500)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_timeout", 30) // Seconds
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_threshold", 0.48)
 
 	// The cardinality of tags to send for checks and dogstatsd respectively.
 	// Choices are: low, orchestrator, high.
@@ -1283,6 +1390,38 @@ func getDogstatsdMappingProfilesConfig(config Config) ([]MappingProfile, error)
 	return mappings, nil
 }
 
+// GetDogstatsdTagRules returns the rules used by the DogStatsD tag filter
+func GetDogstatsdTagRules() ([]TagRule, error) {
+	return getDogstatsdTagRulesConfig(Datadog)
+}
+
+func getDogstatsdTagRulesConfig(config Config) ([]TagRule, error) {
+	var rules []TagRule
+	if config.IsSet("dogstatsd_tag_rules") {
+		err := config.UnmarshalKey("dogstatsd_tag_rules", &rules)
+		if err != nil {
+			return []TagRule{}, log.Errorf("Could not parse dogstatsd_tag_rules: %v", err)
+		}
+	}
+	return rules, nil
+}
+
+// GetHistogramOverrides returns the per metric name histogram configurations
+func GetHistogramOverrides() ([]HistogramOverride, error) {
+	return getHistogramOverridesConfig(Datadog)
+}
+
+func getHistogramOverridesConfig(config Config) ([]HistogramOverride, error) {
+	var overrides []HistogramOverride
+	if config.IsSet("histogram_overrides") {
+		err := config.UnmarshalKey("histogram_overrides", &overrides)
+		if err != nil {
+			return []HistogramOverride{}, log.Errorf("Could not parse histogram_overrides: %v", err)
+		}
+	}
+	return overrides, nil
+}
+
 // IsCLCRunner returns whether the Agent is in cluster check runner mode
 func IsCLCRunner() bool {
 	if !Datadog.GetBool("clc_runner_enabled") {
@@ -1301,6 +1440,12 @@ func IsCLCRunner() bool {
 	return false
 }
 
+// IsOfflineModeToStdout returns true if the payloads are written to the standard output
+// by the offline mode, instead of being sent to the intake
+func IsOfflineModeToStdout() bool {
+	return Datadog.GetBool("offline_mode.enabled") && Datadog.GetString("offline_mode.output_dir") == ""
+}
+
 // GetBindHost returns `bind_host` variable or default value
 // Not using `config.BindEnvAndSetDefault` as some processes need to know
 // if value was default one or not (e.g. trace-agent)
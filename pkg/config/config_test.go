@@ -921,3 +921,86 @@ func TestDogstatsdMappingProfilesEnv(t *testing.T) {
 	mappings, _ := GetDogstatsdMappingProfiles()
 	assert.Equal(t, mappings, expected)
 }
+
+func TestDogstatsdTagRulesOk(t *testing.T) {
+	datadogYaml := `
+dogstatsd_tag_rules:
+  - match: "myapp.*"
+    drop_tags: ["request_id", "user_*"]
+    rewrite_tags:
+      - tag: "path"
+        match: '/users/\d+'
+        replace: "/users/:id"
+  - match: 'other\.(.*)'
+    match_type: "regex"
+    keep_tags: ["env", "service"]
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+
+	rules, err := getDogstatsdTagRulesConfig(testConfig)
+
+	expectedRules := []TagRule{
+		{
+			Match:    "myapp.*",
+			DropTags: []string{"request_id", "user_*"},
+			RewriteTags: []TagRewriteRule{
+				{Tag: "path", Match: "/users/\\d+", Replace: "/users/:id"},
+			},
+		},
+		{
+			Match:     "other\\.(.*)",
+			MatchType: "regex",
+			KeepTags:  []string{"env", "service"},
+		},
+	}
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedRules, rules)
+}
+
+func TestHistogramOverridesOk(t *testing.T) {
+	datadogYaml := `
+histogram_overrides:
+  - match: "myapp.latency.*"
+    aggregates: ["max", "count"]
+    percentiles: ["0.99"]
+  - match: "myapp.size"
+    aggregates: ["max"]
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+
+	overrides, err := getHistogramOverridesConfig(testConfig)
+
+	expectedOverrides := []HistogramOverride{
+		{Match: "myapp.latency.*", Aggregates: []string{"max", "count"}, Percentiles: []string{"0.99"}},
+		{Match: "myapp.size", Aggregates: []string{"max"}},
+	}
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedOverrides, overrides)
+}
+
+func TestDogstatsdTagRulesError(t *testing.T) {
+	datadogYaml := `
+dogstatsd_tag_rules:
+  - abc
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+	rules, err := getDogstatsdTagRulesConfig(testConfig)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not parse dogstatsd_tag_rules")
+	assert.Empty(t, rules)
+}
+
+func TestDogstatsdTagRulesEnv(t *testing.T) {
+	env := "DD_DOGSTATSD_TAG_RULES"
+	err := os.Setenv(env, `[{"match":"myapp.*","drop_tags":["request_id"],"rewrite_tags":[{"tag":"path","match":"\\d+","replace":"N"}]}]`)
+	assert.Nil(t, err)
+	defer os.Unsetenv(env)
+	expected := []TagRule{
+		{Match: "myapp.*", DropTags: []string{"request_id"}, RewriteTags: []TagRewriteRule{{Tag: "path", Match: "\\d+", Replace: "N"}}},
+	}
+	rules, _ := GetDogstatsdTagRules()
+	assert.Equal(t, expected, rules)
+}
@@ -124,6 +124,11 @@ func buildLoggerConfig(loggerName LoggerName, seelogLogLevel, logFile, syslogURI
 		formatID = "json"
 	}
 
+	// the logs would be mixed with the payloads written to the standard output by the offline mode
+	if IsOfflineModeToStdout() {
+		logToConsole = false
+	}
+
 	config := seelogCfg.NewSeelogConfig(string(loggerName), seelogLogLevel, formatID, buildJSONFormat(loggerName), buildCommonFormat(loggerName), syslogRFC)
 	config.EnableConsoleLog(logToConsole)
 	config.EnableFileLogging(logFile, Datadog.GetSizeInBytes("log_file_max_size"), uint(Datadog.GetInt("log_file_max_rolls")))
@@ -41,6 +41,27 @@ func TestSeelogConfig(t *testing.T) {
 	assert.NotNil(t, logger)
 }
 
+func TestConsoleLogDisabledInOfflineMode(t *testing.T) {
+	mockConfig := Mock()
+	mockConfig.Set("offline_mode.enabled", true)
+	defer mockConfig.Set("offline_mode.enabled", false)
+
+	// the payloads are written to the standard output
+	cfg, err := buildLoggerConfig("TEST", "info", "", "", false, true, false)
+	assert.Nil(t, err)
+	rendered, err := cfg.Render()
+	assert.Nil(t, err)
+	assert.NotContains(t, rendered, "<console />")
+
+	mockConfig.Set("offline_mode.output_dir", "/var/lib/payloads")
+	defer mockConfig.Set("offline_mode.output_dir", "")
+	cfg, err = buildLoggerConfig("TEST", "info", "", "", false, true, false)
+	assert.Nil(t, err)
+	rendered, err = cfg.Render()
+	assert.Nil(t, err)
+	assert.Contains(t, rendered, "<console />")
+}
+
 func benchmarkLogFormat(logFormat string, b *testing.B) {
 	var buff bytes.Buffer
 	w := bufio.NewWriter(&buff)
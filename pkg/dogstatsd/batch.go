@@ -8,37 +8,74 @@ import (
 // batcher batches multiple metrics before submission
 // this struct is not safe for concurrent use
 type batcher struct {
-	samples      []metrics.MetricSample
-	samplesCount int
+	// the samples are sharded by context between the pipelines of the aggregator,
+	// with one batch per pipeline
+	samples      [][]metrics.MetricSample
+	samplesCount []int
+	// samples carrying the timestamp provided by the client
+	samplesWithTs      [][]metrics.MetricSample
+	samplesWithTsCount []int
 
 	events        []*metrics.Event
 	serviceChecks []*metrics.ServiceCheck
 
 	// output channels
-	choutSamples       chan<- []metrics.MetricSample
+	choutSamples       []chan []metrics.MetricSample
+	choutSamplesWithTs []chan []metrics.MetricSample
 	choutEvents        chan<- []*metrics.Event
 	choutServiceChecks chan<- []*metrics.ServiceCheck
 
 	metricSamplePool *metrics.MetricSamplePool
+	pipelineCount    int
+	sharder          *aggregator.ContextSharder
 }
 
 func newBatcher(agg *aggregator.BufferedAggregator) *batcher {
-	s, e, sc := agg.GetBufferedChannels()
-	return &batcher{
-		samples:            agg.MetricSamplePool.GetBatch(),
+	_, e, sc := agg.GetBufferedChannels()
+	samplesIn, samplesWithTsIn := agg.GetDogstatsdPipelines()
+	pipelineCount := len(samplesIn)
+
+	b := &batcher{
+		samples:            make([][]metrics.MetricSample, pipelineCount),
+		samplesCount:       make([]int, pipelineCount),
+		samplesWithTs:      make([][]metrics.MetricSample, pipelineCount),
+		samplesWithTsCount: make([]int, pipelineCount),
 		metricSamplePool:   agg.MetricSamplePool,
-		choutSamples:       s,
+		choutSamples:       samplesIn,
+		choutSamplesWithTs: samplesWithTsIn,
 		choutEvents:        e,
 		choutServiceChecks: sc,
+		pipelineCount:      pipelineCount,
+		sharder:            aggregator.NewContextSharder(pipelineCount),
+	}
+	for i := 0; i < pipelineCount; i++ {
+		b.samples[i] = agg.MetricSamplePool.GetBatch()
+		b.samplesWithTs[i] = agg.MetricSamplePool.GetBatch()
 	}
+	return b
 }
 
 func (b *batcher) appendSample(sample metrics.MetricSample) {
-	if b.samplesCount == len(b.samples) {
-		b.flushSamples()
+	shard := b.sharder.Shard(&sample)
+	if sample.Timestamp > 0 {
+		b.appendSampleWithTs(shard, sample)
+		return
+	}
+	if b.samplesCount[shard] == len(b.samples[shard]) {
+		b.flushSamples(shard)
+	}
+	b.samples[shard][b.samplesCount[shard]] = sample
+	b.samplesCount[shard]++
+}
+
+// appendSampleWithTs batches the samples which have to be aggregated at
+// their own timestamp instead of their arrival time.
+func (b *batcher) appendSampleWithTs(shard int, sample metrics.MetricSample) {
+	if b.samplesWithTsCount[shard] == len(b.samplesWithTs[shard]) {
+		b.flushSamplesWithTs(shard)
 	}
-	b.samples[b.samplesCount] = sample
-	b.samplesCount++
+	b.samplesWithTs[shard][b.samplesWithTsCount[shard]] = sample
+	b.samplesWithTsCount[shard]++
 }
 
 func (b *batcher) appendEvent(event *metrics.Event) {
@@ -49,17 +86,28 @@ func (b *batcher) appendServiceCheck(serviceCheck *metrics.ServiceCheck) {
 	b.serviceChecks = append(b.serviceChecks, serviceCheck)
 }
 
-func (b *batcher) flushSamples() {
-	if b.samplesCount > 0 {
-		b.choutSamples <- b.samples[:b.samplesCount]
-		b.samplesCount = 0
-		b.samples = b.metricSamplePool.GetBatch()
+func (b *batcher) flushSamples(shard int) {
+	if b.samplesCount[shard] > 0 {
+		b.choutSamples[shard] <- b.samples[shard][:b.samplesCount[shard]]
+		b.samplesCount[shard] = 0
+		b.samples[shard] = b.metricSamplePool.GetBatch()
+	}
+}
+
+func (b *batcher) flushSamplesWithTs(shard int) {
+	if b.samplesWithTsCount[shard] > 0 {
+		b.choutSamplesWithTs[shard] <- b.samplesWithTs[shard][:b.samplesWithTsCount[shard]]
+		b.samplesWithTsCount[shard] = 0
+		b.samplesWithTs[shard] = b.metricSamplePool.GetBatch()
 	}
 }
 
 // flush pushes all batched metrics to the aggregator.
 func (b *batcher) flush() {
-	b.flushSamples()
+	for i := 0; i < b.pipelineCount; i++ {
+		b.flushSamples(i)
+		b.flushSamplesWithTs(i)
+	}
 	if len(b.events) > 0 {
 		b.choutEvents <- b.events
 		b.events = []*metrics.Event{}
@@ -2,7 +2,9 @@ package dogstatsd
 
 import (
 	"strings"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 )
@@ -65,6 +67,8 @@ func enrichMetricType(dogstatsdMetricType metricType) metrics.MetricType {
 		return metrics.SetType
 	case timingType:
 		return metrics.HistogramType
+	case sketchType:
+		return metrics.DistributionType
 	}
 	return metrics.GaugeType
 }
@@ -81,7 +85,7 @@ func isBlacklisted(metricName, namespace string, namespaceBlacklist []string) bo
 }
 
 func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsdMetricSample, namespace string, namespaceBlacklist []string,
-	defaultHostname string, origin string, entityIDPrecedenceEnabled bool, serverlessMode bool) []metrics.MetricSample {
+	tagFilter *mapper.TagFilter, defaultHostname string, origin string, entityIDPrecedenceEnabled bool, serverlessMode bool) []metrics.MetricSample {
 	metricName := ddSample.name
 	tags, hostnameFromTags, originID, k8sOriginID := extractTagsMetadata(ddSample.tags, defaultHostname, origin, entityIDPrecedenceEnabled)
 
@@ -89,12 +93,19 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 		metricName = namespace + metricName
 	}
 
+	if tagFilter != nil {
+		tags = tagFilter.Filter(metricName, tags)
+	}
+
 	if serverlessMode { // we don't want to set the host while running in serverless mode
 		hostnameFromTags = ""
 	}
 
 	mtype := enrichMetricType(ddSample.metricType)
 
+	// the aggregator expects the timestamps of the samples in nanoseconds
+	timestamp := float64(ddSample.ts) * float64(time.Second)
+
 	// if 'ddSample.values' contains values we're enriching a multi-value
 	// dogstatsd message and will create a MetricSample per value. If not
 	// we will use 'ddSample.value'and return a single MetricSample
@@ -111,6 +122,7 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 					RawValue:    ddSample.setValue,
 					OriginID:    originID,
 					K8sOriginID: k8sOriginID,
+					Timestamp:   timestamp,
 				})
 		}
 		return metricSamples
@@ -123,10 +135,12 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 		Tags:        tags,
 		Mtype:       mtype,
 		Value:       ddSample.value,
+		Bins:        ddSample.bins,
 		SampleRate:  ddSample.sampleRate,
 		RawValue:    ddSample.setValue,
 		OriginID:    originID,
 		K8sOriginID: k8sOriginID,
+		Timestamp:   timestamp,
 	})
 }
 
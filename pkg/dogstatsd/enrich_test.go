@@ -3,7 +3,10 @@ package dogstatsd
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,7 +33,7 @@ func parseAndEnrichSingleMetricMessage(message []byte, namespace string, namespa
 	}
 
 	samples := []metrics.MetricSample{}
-	samples = enrichMetricSample(samples, parsed, namespace, namespaceBlacklist, defaultHostname, "", true, false)
+	samples = enrichMetricSample(samples, parsed, namespace, namespaceBlacklist, nil, defaultHostname, "", true, false)
 	if len(samples) != 1 {
 		return metrics.MetricSample{}, fmt.Errorf("wrong number of metrics parsed")
 	}
@@ -45,7 +48,7 @@ func parseAndEnrichMultipleMetricMessage(message []byte, namespace string, names
 	}
 
 	samples := []metrics.MetricSample{}
-	return enrichMetricSample(samples, parsed, namespace, namespaceBlacklist, defaultHostname, "", true, false), nil
+	return enrichMetricSample(samples, parsed, namespace, namespaceBlacklist, nil, defaultHostname, "", true, false), nil
 }
 
 func parseAndEnrichServiceCheckMessage(message []byte, defaultHostname string) (*metrics.ServiceCheck, error) {
@@ -113,6 +116,21 @@ func TestConvertParseSingle(t *testing.T) {
 	}
 }
 
+func TestConvertParseSketch(t *testing.T) {
+	parsed, err := parseAndEnrichMultipleMetricMessage([]byte("daemon:666@3:777.5|ds|#sometag"), "", nil, "default-hostname")
+
+	assert.NoError(t, err)
+	// the pre-aggregated values are kept in a single sample
+	require.Len(t, parsed, 1)
+
+	assert.Equal(t, "daemon", parsed[0].Name)
+	assert.Equal(t, []metrics.DistributionBin{{Value: 666, Count: 3}, {Value: 777.5, Count: 1}}, parsed[0].Bins)
+	assert.Equal(t, metrics.DistributionType, parsed[0].Mtype)
+	assert.Equal(t, []string{"sometag"}, parsed[0].Tags)
+	assert.Equal(t, "default-hostname", parsed[0].Host)
+	assert.InEpsilon(t, 1.0, parsed[0].SampleRate, epsilon)
+}
+
 func TestConvertParseSingleWithTags(t *testing.T) {
 	for metricSymbol, metricType := range symbolToType {
 
@@ -195,6 +213,43 @@ func TestConvertParseSingleWithSampleRate(t *testing.T) {
 	}
 }
 
+func TestConvertParseWithTimestamp(t *testing.T) {
+	parsed, err := parseAndEnrichMultipleMetricMessage([]byte("daemon:666:667|g|#sometag:somevalue|T1617224400"), "", nil, "default-hostname")
+
+	assert.NoError(t, err)
+	require.Len(t, parsed, 2)
+	for _, sample := range parsed {
+		assert.Equal(t, "daemon", sample.Name)
+		assert.Equal(t, metrics.GaugeType, sample.Mtype)
+		assert.Equal(t, []string{"sometag:somevalue"}, sample.Tags)
+		assert.Equal(t, 1617224400*float64(time.Second), sample.Timestamp)
+	}
+
+	parsed, err = parseAndEnrichMultipleMetricMessage([]byte("daemon:666|c"), "", nil, "default-hostname")
+
+	assert.NoError(t, err)
+	require.Len(t, parsed, 1)
+	assert.Equal(t, 0.0, parsed[0].Timestamp)
+}
+
+func TestConvertParseWithTagFilter(t *testing.T) {
+	tagFilter, err := mapper.NewTagFilter([]config.TagRule{
+		{Match: "ns.daemon", DropTags: []string{"request_id"}},
+	}, 10)
+	require.NoError(t, err)
+
+	parser := newParser(newFloat64ListPool())
+	parsed, err := parser.parseMetricSample([]byte("daemon:666|g|#request_id:1234,host:custom-host,env:prod"))
+	require.NoError(t, err)
+
+	// the rules match the name of the metric once prefixed with the namespace
+	samples := enrichMetricSample([]metrics.MetricSample{}, parsed, "ns.", nil, tagFilter, "default-hostname", "", true, false)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "ns.daemon", samples[0].Name)
+	assert.Equal(t, "custom-host", samples[0].Host)
+	assert.Equal(t, []string{"env:prod"}, samples[0].Tags)
+}
+
 func TestConvertParseSet(t *testing.T) {
 	parsed, err := parseAndEnrichSingleMetricMessage([]byte("daemon:abc:def|s"), "", nil, "default-hostname")
 
@@ -0,0 +1,18 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+)
+
+// captureMessage records a message as it was received while a traffic capture is ongoing,
+// before it is merged with other messages into a packet, so that it can be replayed alone.
+func captureMessage(capture *replay.TrafficCapture, message []byte, origin string, ancillary []byte) {
+	if capture != nil && capture.IsOngoing() {
+		capture.Enqueue(replay.NewCaptureRecord(message, origin, ancillary))
+	}
+}
@@ -8,13 +8,15 @@ package listeners
 
 import (
 	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 )
 
 // NamedPipeListener implements the StatsdListener interface for named pipe protocol.
 type NamedPipeListener struct{}
 
 // NewNamedPipeListener returns an named pipe Statsd listener
-func NewNamedPipeListener(pipeName string, packetOut chan Packets, sharedPacketPool *PacketPool) (*NamedPipeListener, error) {
+func NewNamedPipeListener(pipeName string, packetOut chan Packets, sharedPacketPool *PacketPool, capture *replay.TrafficCapture) (*NamedPipeListener, error) {
 	return nil, errors.New("named pipe is only supported on Windows")
 }
 
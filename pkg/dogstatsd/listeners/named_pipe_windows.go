@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/Microsoft/go-winio"
@@ -27,26 +28,29 @@ const pipeNamePrefix = `\\.\pipe\`
 // It listens to a given pipe name and sends back packets ready to be processed.
 // Origin detection is not implemented for named pipe.
 type NamedPipeListener struct {
-	pipe          net.Listener
-	packetManager *packetManager
-	connections   *namedPipeConnections
+	pipe           net.Listener
+	packetManager  *packetManager
+	connections    *namedPipeConnections
+	trafficCapture *replay.TrafficCapture
 }
 
 // NewNamedPipeListener returns an named pipe Statsd listener
-func NewNamedPipeListener(pipeName string, packetOut chan Packets, sharedPacketPool *PacketPool) (*NamedPipeListener, error) {
+func NewNamedPipeListener(pipeName string, packetOut chan Packets, sharedPacketPool *PacketPool, capture *replay.TrafficCapture) (*NamedPipeListener, error) {
 	bufferSize := config.Datadog.GetInt("dogstatsd_buffer_size")
 	return newNamedPipeListener(
 		pipeName,
 		bufferSize,
 		newPacketManagerFromConfig(
 			packetOut,
-			sharedPacketPool))
+			sharedPacketPool),
+		capture)
 }
 
 func newNamedPipeListener(
 	pipeName string,
 	bufferSize int,
-	packetManager *packetManager) (*NamedPipeListener, error) {
+	packetManager *packetManager,
+	capture *replay.TrafficCapture) (*NamedPipeListener, error) {
 
 	config := winio.PipeConfig{
 		InputBufferSize:  int32(bufferSize),
@@ -60,8 +64,9 @@ func newNamedPipeListener(
 	}
 
 	listener := &NamedPipeListener{
-		pipe:          pipe,
-		packetManager: packetManager,
+		pipe:           pipe,
+		packetManager:  packetManager,
+		trafficCapture: capture,
 		connections: &namedPipeConnections{
 			newConn:         make(chan net.Conn),
 			connToClose:     make(chan net.Conn),
@@ -163,6 +168,7 @@ func (l *NamedPipeListener) listenConnection(conn net.Conn, buffer []byte) {
 			messageSize := bytes.LastIndexByte(buffer[:endIndex], '\n') + 1
 			if messageSize > 0 {
 				namedPipeTelemetry.onReadSuccess(messageSize)
+				captureMessage(l.trafficCapture, buffer[:messageSize], NoOrigin, nil)
 
 				// packetAssembler merges multiple packets together and sends them when its buffer is full
 				l.packetManager.packetAssembler.addMessage(buffer[:messageSize])
@@ -127,7 +127,8 @@ func newNamedPipeListenerTest(t *testing.T) namedPipeListenerTest {
 	listener, err := newNamedPipeListener(
 		pipeName,
 		namedPipeBufferSize,
-		packetManager)
+		packetManager,
+		nil)
 	assert.NoError(t, err)
 
 	listenerTest := namedPipeListenerTest{
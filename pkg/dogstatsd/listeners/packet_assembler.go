@@ -17,6 +17,8 @@ const messageSeparator = byte('\n')
 type packetAssembler struct {
 	packet       *Packet
 	packetLength int
+	// origin of the assembled packets, for the listeners assembling the messages of a single client
+	origin string
 	// assembled packets are pushed into this buffer
 	packetsBuffer    *packetsBuffer
 	sharedPacketPool *PacketPool
@@ -72,6 +74,7 @@ func (p *packetAssembler) flush() {
 		return
 	}
 	p.packet.Contents = p.packet.buffer[:p.packetLength]
+	p.packet.Origin = p.origin
 	p.packetsBuffer.append(p.packet)
 	// retrieve an available packet from the packet pool,
 	// which will be pushed back by the server when processed.
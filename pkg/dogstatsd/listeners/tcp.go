@@ -0,0 +1,392 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const (
+	// TCPFramingNewline frames the messages with a newline, as over UDP
+	TCPFramingNewline = "newline"
+	// TCPFramingLengthPrefixed prefixes each frame with its length as a little-endian uint32
+	TCPFramingLengthPrefixed = "length_prefixed"
+
+	tcpFrameHeaderSize = 4
+)
+
+var (
+	tcpExpvars               = expvar.NewMap("dogstatsd-tcp")
+	tcpOriginDetectionErrors = expvar.Int{}
+	tcpPacketReadingErrors   = expvar.Int{}
+	tcpPackets               = expvar.Int{}
+	tcpBytes                 = expvar.Int{}
+	tcpConnections           = expvar.Int{}
+	tcpRejectedConnections   = expvar.Int{}
+
+	tlmTCPPackets = telemetry.NewCounter("dogstatsd", "tcp_packets",
+		[]string{"state"}, "Dogstatsd TCP packets count")
+	tlmTCPPacketsBytes = telemetry.NewCounter("dogstatsd", "tcp_packets_bytes",
+		nil, "Dogstatsd TCP packets bytes count")
+	tlmTCPConnections = telemetry.NewGauge("dogstatsd", "tcp_connections",
+		nil, "Dogstatsd TCP open connections")
+	tlmTCPRejectedConnections = telemetry.NewCounter("dogstatsd", "tcp_rejected_connections",
+		nil, "Dogstatsd TCP connections closed because of the connections limit")
+	tlmTCPOriginDetectionError = telemetry.NewCounter("dogstatsd", "tcp_origin_detection_error",
+		nil, "Dogstatsd TCP origin detection error count")
+)
+
+func init() {
+	tcpExpvars.Set("OriginDetectionErrors", &tcpOriginDetectionErrors)
+	tcpExpvars.Set("PacketReadingErrors", &tcpPacketReadingErrors)
+	tcpExpvars.Set("Packets", &tcpPackets)
+	tcpExpvars.Set("Bytes", &tcpBytes)
+	tcpExpvars.Set("Connections", &tcpConnections)
+	tcpExpvars.Set("RejectedConnections", &tcpRejectedConnections)
+}
+
+// TCPListener implements the StatsdListener interface for TCP protocol.
+// It listens to a given TCP address, optionally over TLS, and sends back
+// packets ready to be processed.
+// The messages of each connection are assembled separately so that their
+// packets carry the origin of the connection when origin detection is enabled.
+type TCPListener struct {
+	listener         net.Listener
+	packetsBuffer    *packetsBuffer
+	sharedPacketPool *PacketPool
+	framing          string
+	bufferSize       int
+	flushTimeout     time.Duration
+	originDetection  bool
+	trafficCapture   *replay.TrafficCapture
+	// maxConnections bounds the number of open connections, 0 when unlimited
+	maxConnections int
+	// idleTimeout closes the connections without any data received for that long, 0 when disabled
+	idleTimeout time.Duration
+
+	connsMu  sync.Mutex
+	conns    map[net.Conn]struct{}
+	stopping bool
+	connsWg  sync.WaitGroup
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan Packets, sharedPacketPool *PacketPool, capture *replay.TrafficCapture) (*TCPListener, error) {
+	var url string
+	if config.Datadog.GetBool("dogstatsd_non_local_traffic") == true {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%d", config.Datadog.GetInt("dogstatsd_tcp_port"))
+	} else {
+		url = net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("dogstatsd_tcp_port"))
+	}
+
+	framing := config.Datadog.GetString("dogstatsd_tcp_framing")
+	if framing != TCPFramingNewline && framing != TCPFramingLengthPrefixed {
+		return nil, fmt.Errorf("dogstatsd-tcp: invalid framing %q, must be %q or %q", framing, TCPFramingNewline, TCPFramingLengthPrefixed)
+	}
+
+	tlsConfig, err := getTCPTLSConfig(
+		config.Datadog.GetString("dogstatsd_tcp_tls_cert_file"),
+		config.Datadog.GetString("dogstatsd_tcp_tls_key_file"))
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	packetsBufferSize := config.Datadog.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	l := &TCPListener{
+		listener:         listener,
+		packetsBuffer:    newPacketsBuffer(uint(packetsBufferSize), flushTimeout, packetOut),
+		sharedPacketPool: sharedPacketPool,
+		framing:          framing,
+		bufferSize:       config.Datadog.GetInt("dogstatsd_buffer_size"),
+		flushTimeout:     flushTimeout,
+		originDetection:  config.Datadog.GetBool("dogstatsd_tcp_origin_detection"),
+		trafficCapture:   capture,
+		maxConnections:   config.Datadog.GetInt("dogstatsd_tcp_max_connections"),
+		idleTimeout:      time.Duration(config.Datadog.GetInt("dogstatsd_tcp_idle_timeout")) * time.Second,
+		conns:            make(map[net.Conn]struct{}),
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized (framing: %s, tls: %t)", listener.Addr(), framing, tlsConfig != nil)
+	return l, nil
+}
+
+// getTCPTLSConfig returns the TLS configuration of the listener, nil when TLS is disabled
+func getTCPTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("dogstatsd-tcp: both dogstatsd_tcp_tls_cert_file and dogstatsd_tcp_tls_key_file are required to enable TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("dogstatsd-tcp: could not load the TLS certificate: %s", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// listener has been closed
+			if strings.HasSuffix(err.Error(), " use of closed network connection") {
+				return
+			}
+			log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			continue
+		}
+		tracked, stopping := l.trackConnection(conn)
+		if stopping {
+			conn.Close()
+			return
+		}
+		if !tracked {
+			log.Debugf("dogstatsd-tcp: closing the connection from %s, the limit of %d connections is reached", conn.RemoteAddr(), l.maxConnections)
+			tcpRejectedConnections.Add(1)
+			tlmTCPRejectedConnections.Inc()
+			conn.Close()
+			continue
+		}
+		go l.listenConnection(conn)
+	}
+}
+
+// trackConnection registers an accepted connection. The connection is not tracked when the
+// listener is stopping or when the limit of connections is reached.
+func (l *TCPListener) trackConnection(conn net.Conn) (tracked bool, stopping bool) {
+	l.connsMu.Lock()
+	defer l.connsMu.Unlock()
+	if l.stopping {
+		return false, true
+	}
+	if l.maxConnections > 0 && len(l.conns) >= l.maxConnections {
+		return false, false
+	}
+	l.conns[conn] = struct{}{}
+	l.connsWg.Add(1)
+	tcpConnections.Add(1)
+	tlmTCPConnections.Inc()
+	return true, false
+}
+
+func (l *TCPListener) untrackConnection(conn net.Conn) {
+	l.connsMu.Lock()
+	delete(l.conns, conn)
+	l.connsMu.Unlock()
+	tcpConnections.Add(-1)
+	tlmTCPConnections.Dec()
+	l.connsWg.Done()
+}
+
+func (l *TCPListener) listenConnection(conn net.Conn) {
+	defer l.untrackConnection(conn)
+	defer conn.Close()
+	log.Debugf("dogstatsd-tcp: new client connected from %s", conn.RemoteAddr())
+
+	// packetAssembler merges the messages of this connection together and sends them when its buffer is full
+	assembler := newPacketAssembler(l.flushTimeout, l.packetsBuffer, l.sharedPacketPool)
+	if l.originDetection {
+		origin, err := getTCPOrigin(conn)
+		if err != nil {
+			log.Warnf("dogstatsd-tcp: error processing origin of %s, data will not be tagged : %v", conn.RemoteAddr(), err)
+			tcpOriginDetectionErrors.Add(1)
+			tlmTCPOriginDetectionError.Inc()
+		} else {
+			assembler.origin = origin
+		}
+	}
+	defer func() {
+		assembler.close()
+		assembler.Lock()
+		assembler.flush()
+		// the packet not used anymore by the assembler goes back to the pool
+		l.sharedPacketPool.Put(assembler.packet)
+		assembler.Unlock()
+	}()
+
+	var err error
+	if l.framing == TCPFramingLengthPrefixed {
+		err = l.readLengthPrefixed(conn, assembler)
+	} else {
+		err = l.readNewlineDelimited(conn, assembler)
+	}
+
+	switch {
+	case err == io.EOF:
+		log.Debugf("dogstatsd-tcp: client disconnected from %s", conn.RemoteAddr())
+	case isTimeout(err):
+		log.Debugf("dogstatsd-tcp: closing the connection from %s, idle for %s", conn.RemoteAddr(), l.idleTimeout)
+	case strings.HasSuffix(err.Error(), " use of closed network connection"):
+		// the listener is stopping
+	default:
+		log.Errorf("dogstatsd-tcp: error reading from %s, closing the connection: %v", conn.RemoteAddr(), err)
+		tcpPacketReadingErrors.Add(1)
+		tlmTCPPackets.Inc("error")
+	}
+}
+
+// readNewlineDelimited reads the newline-delimited messages of the connection until it fails.
+// A message bigger than the buffer is dropped.
+func (l *TCPListener) readNewlineDelimited(conn net.Conn, assembler *packetAssembler) error {
+	buffer := make([]byte, l.bufferSize)
+	startWriteIndex := 0
+	// whether the bytes read belong to a message bigger than the buffer
+	discarding := false
+
+	for {
+		if err := l.setReadDeadline(conn); err != nil {
+			return err
+		}
+		bytesRead, err := conn.Read(buffer[startWriteIndex:])
+		data := buffer[:startWriteIndex+bytesRead]
+
+		if discarding {
+			if i := bytes.IndexByte(data, messageSeparator); i >= 0 {
+				data = data[i+1:]
+				discarding = false
+			} else {
+				data = data[len(data):]
+			}
+		}
+
+		// When there is no '\n', the message is partial. LastIndexByte returns -1 and messageSize is 0.
+		messageSize := bytes.LastIndexByte(data, messageSeparator) + 1
+		if messageSize > 0 {
+			onTCPReadSuccess(messageSize)
+			l.addMessage(assembler, data[:messageSize-1])
+		}
+
+		remaining := data[messageSize:]
+		if len(remaining) == len(buffer) {
+			log.Debugf("dogstatsd-tcp: dropping a message from %s bigger than the buffer of %d bytes", conn.RemoteAddr(), len(buffer))
+			tcpPacketReadingErrors.Add(1)
+			tlmTCPPackets.Inc("error")
+			discarding = true
+			startWriteIndex = 0
+		} else {
+			startWriteIndex = copy(buffer, remaining)
+		}
+
+		if err != nil {
+			// the last message of a connection doesn't need a trailing newline
+			if err == io.EOF && startWriteIndex > 0 && !discarding {
+				onTCPReadSuccess(startWriteIndex)
+				l.addMessage(assembler, buffer[:startWriteIndex])
+			}
+			return err
+		}
+	}
+}
+
+// readLengthPrefixed reads the length-prefixed frames of the connection until it fails.
+// The framing can't be recovered from a frame bigger than the buffer, the connection is
+// closed in that case.
+func (l *TCPListener) readLengthPrefixed(conn net.Conn, assembler *packetAssembler) error {
+	header := make([]byte, tcpFrameHeaderSize)
+	buffer := make([]byte, l.bufferSize)
+
+	for {
+		if err := l.setReadDeadline(conn); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("truncated frame header: %v", err)
+			}
+			return err
+		}
+		size := binary.LittleEndian.Uint32(header)
+		if uint64(size) > uint64(len(buffer)) {
+			return fmt.Errorf("frame of %d bytes is bigger than the buffer of %d bytes", size, len(buffer))
+		}
+		if size == 0 {
+			continue
+		}
+		if _, err := io.ReadFull(conn, buffer[:size]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("truncated frame: %v", io.ErrUnexpectedEOF)
+			}
+			return err
+		}
+		onTCPReadSuccess(int(size))
+		l.addMessage(assembler, bytes.TrimSuffix(buffer[:size], []byte{messageSeparator}))
+	}
+}
+
+// setReadDeadline bounds the time the next read of the connection waits for data
+func (l *TCPListener) setReadDeadline(conn net.Conn) error {
+	if l.idleTimeout <= 0 {
+		return nil
+	}
+	return conn.SetReadDeadline(time.Now().Add(l.idleTimeout))
+}
+
+// isTimeout returns whether err is a read deadline exceeded
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// addMessage records the message in the traffic capture before the assembler merges it
+// with the other messages of the connection.
+func (l *TCPListener) addMessage(assembler *packetAssembler, message []byte) {
+	captureMessage(l.trafficCapture, message, assembler.origin, nil)
+	assembler.addMessage(message)
+}
+
+func onTCPReadSuccess(n int) {
+	tcpPackets.Add(1)
+	tlmTCPPackets.Inc("ok")
+	tcpBytes.Add(int64(n))
+	tlmTCPPacketsBytes.Add(float64(n))
+}
+
+// Stop closes the TCP listener and its connections and stops listening
+func (l *TCPListener) Stop() {
+	l.listener.Close()
+
+	l.connsMu.Lock()
+	l.stopping = true
+	for conn := range l.conns {
+		// stops the current read of the connection
+		conn.Close()
+	}
+	l.connsMu.Unlock()
+	l.connsWg.Wait()
+
+	l.packetsBuffer.close()
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// getTCPOrigin returns the container entity of the process owning the peer socket of a
+// TCP connection. Only the peers sharing the network namespace of the agent can be found
+// in the socket tables, the connections of the other peers have no origin.
+func getTCPOrigin(conn net.Conn) (string, error) {
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return NoOrigin, fmt.Errorf("unexpected local address %s", conn.LocalAddr())
+	}
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return NoOrigin, fmt.Errorf("unexpected remote address %s", conn.RemoteAddr())
+	}
+
+	procRoot := config.Datadog.GetString("container_proc_root")
+	// the peer socket is bound to the remote address and connected to the local one
+	inode, err := findTCPSocketInode(procRoot, remote, local)
+	if err != nil || inode == 0 {
+		return NoOrigin, err
+	}
+	pid, err := findSocketPID(procRoot, inode)
+	if err != nil {
+		return NoOrigin, err
+	}
+	if pid == 0 {
+		return NoOrigin, fmt.Errorf("no process owns the socket %d of the peer, is the agent in host PID mode?", inode)
+	}
+	return getEntityForPID(int32(pid))
+}
+
+// findTCPSocketInode looks up the inode of the socket with the given addresses in the
+// socket tables of procRoot, it returns 0 if there is none.
+func findTCPSocketInode(procRoot string, localAddr, remoteAddr *net.TCPAddr) (uint64, error) {
+	for _, table := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(procRoot, "net", table))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		// skip the header
+		scanner.Scan()
+		for scanner.Scan() {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 {
+				continue
+			}
+			local, err := parseProcNetAddr(fields[1])
+			if err != nil || !sameTCPAddr(local, localAddr) {
+				continue
+			}
+			remote, err := parseProcNetAddr(fields[2])
+			if err != nil || !sameTCPAddr(remote, remoteAddr) {
+				continue
+			}
+			f.Close()
+			return strconv.ParseUint(fields[9], 10, 64)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// parseProcNetAddr parses an address of the socket tables, such as "0100007F:1F90": the
+// IP is made of 32 bits words in host byte order (little-endian), the port is big-endian.
+func parseProcNetAddr(s string) (*net.TCPAddr, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	ip, err := hex.DecodeString(s[:i])
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	for w := 0; w < len(ip); w += 4 {
+		ip[w], ip[w+1], ip[w+2], ip[w+3] = ip[w+3], ip[w+2], ip[w+1], ip[w]
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	return &net.TCPAddr{IP: net.IP(ip), Port: int(port)}, nil
+}
+
+func sameTCPAddr(a, b *net.TCPAddr) bool {
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
+
+// findSocketPID returns the PID of a process owning the socket inode, 0 if there is none
+func findSocketPID(procRoot string, inode uint64) (int, error) {
+	processes, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return 0, err
+	}
+	target := fmt.Sprintf("socket:[%d]", inode)
+	for _, process := range processes {
+		pid, err := strconv.Atoi(process.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, process.Name(), "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			// the process exited or can't be inspected
+			continue
+		}
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && link == target {
+				return pid, nil
+			}
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcNetAddr(t *testing.T) {
+	addr, err := parseProcNetAddr("0100007F:1F90")
+	require.NoError(t, err)
+	assert.True(t, addr.IP.Equal(net.ParseIP("127.0.0.1")))
+	assert.Equal(t, 8080, addr.Port)
+
+	addr, err = parseProcNetAddr("00000000000000000000000001000000:1FBD")
+	require.NoError(t, err)
+	assert.True(t, addr.IP.Equal(net.ParseIP("::1")))
+	assert.Equal(t, 8125, addr.Port)
+
+	for _, invalid := range []string{"", "0100007F", "0100007:1F90", "0100007F:1F90A", "zz00007F:1F90"} {
+		_, err = parseProcNetAddr(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFindTCPSocketInode(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "dogstatsd-proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(procRoot, "net"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, "net", "tcp"), []byte(
+		`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1FBD 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1000 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:D431 0100007F:1FBD 01 00000000:00000000 00:00000000 00000000  1000        0 1001 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:1FBD 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 20 4 30 10 -1
+`), 0644))
+
+	client := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0xD431}
+	server := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8125}
+
+	inode, err := findTCPSocketInode(procRoot, client, server)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), inode)
+
+	// a peer on another host or network namespace isn't found
+	inode, err = findTCPSocketInode(procRoot, &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 0xD431}, server)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), inode)
+}
+
+func TestFindSocketPID(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "dogstatsd-proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	for _, dir := range []string{"42/fd", "43/fd", "self/fd"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(procRoot, dir), 0755))
+	}
+	require.NoError(t, os.Symlink("socket:[1000]", filepath.Join(procRoot, "42", "fd", "3")))
+	require.NoError(t, os.Symlink("socket:[1001]", filepath.Join(procRoot, "43", "fd", "4")))
+	require.NoError(t, os.Symlink("socket:[1002]", filepath.Join(procRoot, "self", "fd", "5")))
+
+	pid, err := findSocketPID(procRoot, 1001)
+	require.NoError(t, err)
+	assert.Equal(t, 43, pid)
+
+	// the non-PID directories are skipped
+	pid, err = findSocketPID(procRoot, 1002)
+	require.NoError(t, err)
+	assert.Equal(t, 0, pid)
+}
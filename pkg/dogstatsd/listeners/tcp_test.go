@@ -0,0 +1,292 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+// +build !windows
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var packetPoolTCP = NewPacketPool(config.Datadog.GetInt("dogstatsd_buffer_size"))
+
+func TestStartStopTCPListener(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingNewline)
+	s, err := NewTCPListener(nil, packetPoolTCP, nil)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	go s.Listen()
+	// Local port should be unavailable
+	_, err = net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	assert.NotNil(t, err)
+
+	// the open connections are closed on stop
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer conn.Close()
+	s.Stop()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+}
+
+func TestNewTCPListenerInvalidFraming(t *testing.T) {
+	setupTCPListenerConfig(t, "invalid")
+	s, err := NewTCPListener(nil, packetPoolTCP, nil)
+	assert.Nil(t, s)
+	assert.Error(t, err)
+}
+
+func TestTCPReceiveNewlineDelimited(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingNewline)
+	packetChannel := make(chan Packets, 10)
+	s, err := NewTCPListener(packetChannel, packetPoolTCP, nil)
+	require.NoError(t, err)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	// messages split across writes are reassembled, the last one has no trailing newline
+	conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon:6"))
+	time.Sleep(10 * time.Millisecond)
+	conn.Write([]byte("67|g\ndaemon:668|g"))
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g|#sometag1:somevalue1\ndaemon:667|g\ndaemon:668|g", receiveTCPContents(t, packetChannel, 3))
+}
+
+func TestTCPReceiveNewlineDelimitedTooBig(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingNewline)
+	packetChannel := make(chan Packets, 10)
+	s, err := NewTCPListener(packetChannel, packetPoolTCP, nil)
+	require.NoError(t, err)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	tooBig := "daemon:" + strings.Repeat("6", config.Datadog.GetInt("dogstatsd_buffer_size")+10) + "|g\n"
+	conn.Write([]byte("daemon:1|g\n" + tooBig + "daemon:2|g\n"))
+	conn.Close()
+
+	// the message bigger than the buffer is dropped, the next ones are read
+	assert.Equal(t, "daemon:1|g\ndaemon:2|g", receiveTCPContents(t, packetChannel, 2))
+}
+
+func TestTCPReceiveLengthPrefixed(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingLengthPrefixed)
+	packetChannel := make(chan Packets, 10)
+	s, err := NewTCPListener(packetChannel, packetPoolTCP, nil)
+	require.NoError(t, err)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	conn.Write(lengthPrefixed("daemon:666|g\ndaemon:667|g"))
+	conn.Write(lengthPrefixed(""))
+	conn.Write(lengthPrefixed("daemon:668|g\n"))
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g\ndaemon:667|g\ndaemon:668|g", receiveTCPContents(t, packetChannel, 3))
+}
+
+func TestTCPReceiveTLS(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingNewline)
+	certFile, keyFile := writeTestCertificate(t)
+	config.Datadog.Set("dogstatsd_tcp_tls_cert_file", certFile)
+	config.Datadog.Set("dogstatsd_tcp_tls_key_file", keyFile)
+	defer config.Datadog.Set("dogstatsd_tcp_tls_cert_file", "")
+	defer config.Datadog.Set("dogstatsd_tcp_tls_key_file", "")
+
+	packetChannel := make(chan Packets, 10)
+	s, err := NewTCPListener(packetChannel, packetPoolTCP, nil)
+	require.NoError(t, err)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	conn.Write([]byte("daemon:666|g\n"))
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g", receiveTCPContents(t, packetChannel, 1))
+}
+
+func TestTCPMaxConnections(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingNewline)
+	config.Datadog.Set("dogstatsd_tcp_max_connections", 1)
+	defer config.Datadog.Set("dogstatsd_tcp_max_connections", 1024)
+
+	packetChannel := make(chan Packets, 10)
+	s, err := NewTCPListener(packetChannel, packetPoolTCP, nil)
+	require.NoError(t, err)
+
+	go s.Listen()
+	defer s.Stop()
+
+	first, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer first.Close()
+	first.Write([]byte("daemon:666|g\n"))
+	assert.Equal(t, "daemon:666|g", receiveTCPContents(t, packetChannel, 1))
+
+	// the connection over the limit is closed right away
+	second, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+
+	// a new connection is accepted once the first one is closed
+	first.Close()
+	require.Eventually(t, func() bool {
+		s.connsMu.Lock()
+		defer s.connsMu.Unlock()
+		return len(s.conns) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	third, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	third.Write([]byte("daemon:667|g\n"))
+	third.Close()
+	assert.Equal(t, "daemon:667|g", receiveTCPContents(t, packetChannel, 1))
+}
+
+func TestTCPIdleTimeout(t *testing.T) {
+	port := setupTCPListenerConfig(t, TCPFramingNewline)
+	packetChannel := make(chan Packets, 10)
+	s, err := NewTCPListener(packetChannel, packetPoolTCP, nil)
+	require.NoError(t, err)
+	s.idleTimeout = 100 * time.Millisecond
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.Write([]byte("daemon:666|g\n"))
+	assert.Equal(t, "daemon:666|g", receiveTCPContents(t, packetChannel, 1))
+
+	// the connection is closed once it stays idle
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestGetTCPTLSConfigError(t *testing.T) {
+	_, err := getTCPTLSConfig("cert.pem", "")
+	assert.Error(t, err)
+	_, err = getTCPTLSConfig("does-not-exist.pem", "does-not-exist.key")
+	assert.Error(t, err)
+
+	tlsConfig, err := getTCPTLSConfig("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func setupTCPListenerConfig(t *testing.T, framing string) int {
+	port, err := getAvailableTCPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_tcp_port", port)
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", framing)
+	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
+	return port
+}
+
+// receiveTCPContents returns the contents of the received packets, joined by a newline
+func receiveTCPContents(t *testing.T, packetChannel chan Packets, messages int) string {
+	var contents []string
+	for {
+		select {
+		case packets := <-packetChannel:
+			for _, packet := range packets {
+				assert.Equal(t, NoOrigin, packet.Origin)
+				contents = append(contents, string(packet.Contents))
+			}
+			received := strings.Join(contents, "\n")
+			if strings.Count(received, "\n")+1 >= messages {
+				return received
+			}
+		case <-time.After(2 * time.Second):
+			assert.FailNow(t, "Timeout on receive channel")
+		}
+	}
+}
+
+func lengthPrefixed(frame string) []byte {
+	b := make([]byte, tcpFrameHeaderSize+len(frame))
+	binary.LittleEndian.PutUint32(b, uint32(len(frame)))
+	copy(b[tcpFrameHeaderSize:], frame)
+	return b
+}
+
+// writeTestCertificate writes a self-signed certificate and its key, it returns their paths
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dogstatsd"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "dogstatsd-tcp")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+// getAvailableTCPPort requests a random port number and makes sure it is available
+func getAvailableTCPPort() (int, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return -1, fmt.Errorf("can't find an available tcp port: %s", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
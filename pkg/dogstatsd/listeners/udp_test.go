@@ -8,7 +8,10 @@ package listeners
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
 	"strconv"
 	"testing"
 	"time"
@@ -17,12 +20,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 )
 
 var packetPoolUDP = NewPacketPool(config.Datadog.GetInt("dogstatsd_buffer_size"))
 
 func TestNewUDPListener(t *testing.T) {
-	s, err := NewUDPListener(nil, packetPoolUDP)
+	s, err := NewUDPListener(nil, packetPoolUDP, nil)
 	assert.NotNil(t, s)
 	assert.Nil(t, err)
 
@@ -34,7 +38,7 @@ func TestStartStopUDPListener(t *testing.T) {
 	require.Nil(t, err)
 	config.Datadog.SetDefault("dogstatsd_port", port)
 	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
-	s, err := NewUDPListener(nil, packetPoolUDP)
+	s, err := NewUDPListener(nil, packetPoolUDP, nil)
 	require.NotNil(t, s)
 
 	assert.Nil(t, err)
@@ -65,7 +69,7 @@ func TestUDPNonLocal(t *testing.T) {
 	require.Nil(t, err)
 	config.Datadog.SetDefault("dogstatsd_port", port)
 	config.Datadog.SetDefault("dogstatsd_non_local_traffic", true)
-	s, err := NewUDPListener(nil, packetPoolUDP)
+	s, err := NewUDPListener(nil, packetPoolUDP, nil)
 	assert.Nil(t, err)
 	require.NotNil(t, s)
 
@@ -89,7 +93,7 @@ func TestUDPLocalOnly(t *testing.T) {
 	require.Nil(t, err)
 	config.Datadog.SetDefault("dogstatsd_port", port)
 	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
-	s, err := NewUDPListener(nil, packetPoolUDP)
+	s, err := NewUDPListener(nil, packetPoolUDP, nil)
 	assert.Nil(t, err)
 	require.NotNil(t, s)
 
@@ -117,7 +121,7 @@ func TestUDPReceive(t *testing.T) {
 	config.Datadog.SetDefault("dogstatsd_port", port)
 
 	packetChannel := make(chan Packets)
-	s, err := NewUDPListener(packetChannel, packetPoolUDP)
+	s, err := NewUDPListener(packetChannel, packetPoolUDP, nil)
 	require.NotNil(t, s)
 	assert.Nil(t, err)
 
@@ -141,6 +145,62 @@ func TestUDPReceive(t *testing.T) {
 	}
 }
 
+func TestUDPCapture(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.Nil(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+	dir, err := ioutil.TempDir("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config.Datadog.SetDefault("dogstatsd_capture_path", dir)
+	defer config.Datadog.SetDefault("dogstatsd_capture_path", "")
+
+	capture := replay.NewTrafficCapture()
+	path, err := capture.Start(time.Minute)
+	require.NoError(t, err)
+	defer capture.Stop()
+
+	packetChannel := make(chan Packets)
+	s, err := NewUDPListener(packetChannel, packetPoolUDP, capture)
+	require.NoError(t, err)
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.Write([]byte("daemon:666|g"))
+	conn.Write([]byte("daemon:667|g"))
+
+	var contents []byte
+	for len(contents) < len("daemon:666|g\ndaemon:667|g") {
+		select {
+		case packets := <-packetChannel:
+			for _, packet := range packets {
+				contents = append(contents, packet.Contents...)
+			}
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "Timeout on receive channel")
+		}
+	}
+	capture.Stop()
+
+	// the datagrams are captured as they were received, before they are merged into a packet
+	reader, err := replay.NewTrafficCaptureReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	var payloads []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		payloads = append(payloads, string(record.Payload))
+	}
+	assert.Equal(t, []string{"daemon:666|g", "daemon:667|g"}, payloads)
+}
+
 // Reproducer for https://github.com/DataDog/datadog-agent/issues/6803
 func TestNewUDPListenerWhenBusyWithSoRcvBufSet(t *testing.T) {
 	port, err := getAvailableUDPPort()
@@ -153,7 +213,7 @@ func TestNewUDPListenerWhenBusyWithSoRcvBufSet(t *testing.T) {
 	config.Datadog.SetDefault("dogstatsd_so_rcvbuf", 1)
 	config.Datadog.SetDefault("dogstatsd_port", port)
 	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
-	s, err := NewUDPListener(nil, packetPoolUDP)
+	s, err := NewUDPListener(nil, packetPoolUDP, nil)
 	assert.Nil(t, s)
 	assert.NotNil(t, err)
 }
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -53,10 +54,12 @@ type UDSListener struct {
 	sharedPacketPool *PacketPool
 	oobPool          *sync.Pool // For origin detection ancilary data
 	OriginDetection  bool
+	// trafficCapture records the datagrams received while a capture is ongoing
+	trafficCapture *replay.TrafficCapture
 }
 
 // NewUDSListener returns an idle UDS Statsd listener
-func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDSListener, error) {
+func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool, capture *replay.TrafficCapture) (*UDSListener, error) {
 	socketPath := config.Datadog.GetString("dogstatsd_socket")
 	originDetection := config.Datadog.GetBool("dogstatsd_origin_detection")
 
@@ -109,6 +112,7 @@ func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDSL
 		packetsBuffer: newPacketsBuffer(uint(config.Datadog.GetInt("dogstatsd_packet_buffer_size")),
 			config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout"), packetOut),
 		sharedPacketPool: sharedPacketPool,
+		trafficCapture:   capture,
 	}
 
 	// Init the oob buffer pool if origin detection is enabled
@@ -127,9 +131,12 @@ func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDSL
 // Listen runs the intake loop. Should be called in its own goroutine
 func (l *UDSListener) Listen() {
 	log.Infof("dogstatsd-uds: starting to listen on %s", l.conn.LocalAddr())
+	// ancillary keeps the credentials of the datagram for the traffic capture
+	var ancillary []byte
 	for {
 		var n int
 		var err error
+		ancillary = ancillary[:0]
 		// retrieve an available packet from the packet pool,
 		// which will be pushed back by the server when processed.
 		packet := l.sharedPacketPool.Get()
@@ -148,6 +155,9 @@ func (l *UDSListener) Listen() {
 			} else {
 				packet.Origin = container
 			}
+			if l.trafficCapture != nil && l.trafficCapture.IsOngoing() {
+				ancillary = append(ancillary, oob[:oobn]...)
+			}
 			// Return the buffer back to the pool for reuse
 			l.oobPool.Put(oob)
 		} else {
@@ -171,6 +181,7 @@ func (l *UDSListener) Listen() {
 		udsBytes.Add(int64(n))
 		tlmUDSPacketsBytes.Add(float64(n))
 		packet.Contents = packet.buffer[:n]
+		captureMessage(l.trafficCapture, packet.Contents, packet.Origin, ancillary)
 
 		// packetsBuffer handles the forwarding of the packets to the dogstatsd server intake channel
 		l.packetsBuffer.append(packet)
@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mapper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+// TagFilter drops, allowlists and rewrites the tags of the metrics matched by its rules
+type TagFilter struct {
+	rules []*tagRule
+	// matched rules by metric name
+	cache *lru.Cache
+}
+
+type tagRule struct {
+	// nil when the rule applies to all the metrics
+	regex    *regexp.Regexp
+	dropTags []*regexp.Regexp
+	keepTags []*regexp.Regexp
+	rewrites []*tagRewrite
+}
+
+type tagRewrite struct {
+	tag     *regexp.Regexp
+	value   *regexp.Regexp
+	replace string
+}
+
+// NewTagFilter creates, validates, prepares a new TagFilter
+func NewTagFilter(configRules []config.TagRule, cacheSize int) (*TagFilter, error) {
+	var rules []*tagRule
+	for i, configRule := range configRules {
+		rule := &tagRule{}
+		if configRule.Match != "" {
+			matchType := configRule.MatchType
+			if matchType == "" {
+				matchType = matchTypeWildcard
+			}
+			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+				return nil, fmt.Errorf("tag rule num %d: invalid match type, must be `wildcard` or `regex`", i)
+			}
+			regex, err := buildRegex(configRule.Match, matchType)
+			if err != nil {
+				return nil, fmt.Errorf("tag rule num %d: %v", i, err)
+			}
+			rule.regex = regex
+		}
+		if len(configRule.DropTags) == 0 && len(configRule.KeepTags) == 0 && len(configRule.RewriteTags) == 0 {
+			return nil, fmt.Errorf("tag rule num %d: one of drop_tags, keep_tags or rewrite_tags is required", i)
+		}
+		for _, pattern := range configRule.DropTags {
+			rule.dropTags = append(rule.dropTags, buildTagKeyRegex(pattern))
+		}
+		for _, pattern := range configRule.KeepTags {
+			rule.keepTags = append(rule.keepTags, buildTagKeyRegex(pattern))
+		}
+		for j, configRewrite := range configRule.RewriteTags {
+			if configRewrite.Tag == "" {
+				return nil, fmt.Errorf("tag rule num %d, rewrite num %d: tag is required", i, j)
+			}
+			if configRewrite.Match == "" {
+				return nil, fmt.Errorf("tag rule num %d, rewrite num %d: match is required", i, j)
+			}
+			value, err := regexp.Compile(configRewrite.Match)
+			if err != nil {
+				return nil, fmt.Errorf("tag rule num %d, rewrite num %d: invalid match `%s`. cannot compile regex: %v", i, j, configRewrite.Match, err)
+			}
+			rule.rewrites = append(rule.rewrites, &tagRewrite{
+				tag:     buildTagKeyRegex(configRewrite.Tag),
+				value:   value,
+				replace: configRewrite.Replace,
+			})
+		}
+		rules = append(rules, rule)
+	}
+	cache, err := lru.New(cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &TagFilter{rules: rules, cache: cache}, nil
+}
+
+// buildTagKeyRegex returns a regex matching the tag keys of a glob pattern, where `*`
+// matches any sequence of characters and `?` any single character
+func buildTagKeyRegex(pattern string) *regexp.Regexp {
+	pattern = regexp.QuoteMeta(pattern)
+	pattern = strings.Replace(pattern, `\*`, ".*", -1)
+	pattern = strings.Replace(pattern, `\?`, ".", -1)
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// Filter applies the rules matching the metric name to its tags. The tags are
+// filtered in place and the resulting slice is returned.
+func (f *TagFilter) Filter(metricName string, tags []string) []string {
+	for _, rule := range f.matchingRules(metricName) {
+		tags = rule.apply(tags)
+	}
+	return tags
+}
+
+func (f *TagFilter) matchingRules(metricName string) []*tagRule {
+	if rules, ok := f.cache.Get(metricName); ok {
+		return rules.([]*tagRule)
+	}
+	var rules []*tagRule
+	for _, rule := range f.rules {
+		if rule.regex == nil || rule.regex.MatchString(metricName) {
+			rules = append(rules, rule)
+		}
+	}
+	f.cache.Add(metricName, rules)
+	return rules
+}
+
+func (r *tagRule) apply(tags []string) []string {
+	n := 0
+	for _, tag := range tags {
+		key, value, hasValue := splitTag(tag)
+		if matchesAny(r.dropTags, key) {
+			continue
+		}
+		if len(r.keepTags) > 0 && !matchesAny(r.keepTags, key) {
+			continue
+		}
+		if hasValue && len(r.rewrites) > 0 {
+			rewritten := value
+			for _, rewrite := range r.rewrites {
+				if rewrite.tag.MatchString(key) {
+					rewritten = rewrite.value.ReplaceAllString(rewritten, rewrite.replace)
+				}
+			}
+			if rewritten != value {
+				// a rewrite to an empty value drops the tag
+				if rewritten == "" {
+					continue
+				}
+				tag = key + ":" + rewritten
+			}
+		}
+		tags[n] = tag
+		n++
+	}
+	return tags[:n]
+}
+
+// splitTag splits a "key:value" tag, a tag without value is its own key
+func splitTag(tag string) (string, string, bool) {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i], tag[i+1:], true
+	}
+	return tag, "", false
+}
+
+func matchesAny(regexes []*regexp.Regexp, s string) bool {
+	for _, regex := range regexes {
+		if regex.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mapper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagFilter(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		config       string
+		metricName   string
+		tags         []string
+		expectedTags []string
+	}{
+		{
+			name: "Drop tag keys by name and glob",
+			config: `
+dogstatsd_tag_rules:
+  - match: "test.*"
+    drop_tags: ["request_id", "user_*"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"env:prod", "request_id:1234", "user_id:42", "user_email:a@b.c", "user"},
+			expectedTags: []string{"env:prod", "user"},
+		},
+		{
+			name: "Keep allowlisted tag keys",
+			config: `
+dogstatsd_tag_rules:
+  - keep_tags: ["env", "service", "version?"]
+`,
+			metricName:   "any.metric",
+			tags:         []string{"env:prod", "service:web", "pod_name:web-1", "version2:1.0", "standalone"},
+			expectedTags: []string{"env:prod", "service:web", "version2:1.0"},
+		},
+		{
+			name: "Rewrite tag values",
+			config: `
+dogstatsd_tag_rules:
+  - match: 'test\.http\.(.*)'
+    match_type: regex
+    rewrite_tags:
+      - tag: "path"
+        match: '/users/\d+'
+        replace: "/users/:id"
+      - tag: "*email"
+        match: '.*'
+        replace: ""
+`,
+			metricName:   "test.http.requests",
+			tags:         []string{"path:/users/42/orders", "email:a@b.c", "user_email:a@b.c", "env:prod", "email"},
+			expectedTags: []string{"path:/users/:id/orders", "env:prod", "email"},
+		},
+		{
+			name: "Rules not matching the metric are ignored",
+			config: `
+dogstatsd_tag_rules:
+  - match: "test.*"
+    drop_tags: ["request_id"]
+`,
+			metricName:   "test.http.requests",
+			tags:         []string{"env:prod", "request_id:1234"},
+			expectedTags: []string{"env:prod", "request_id:1234"},
+		},
+		{
+			name: "Matching rules are applied in order",
+			config: `
+dogstatsd_tag_rules:
+  - rewrite_tags:
+      - tag: "team"
+        match: "^old-(.*)$"
+        replace: "$1"
+  - match: "test.*"
+    keep_tags: ["team"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"team:old-core", "env:prod"},
+			expectedTags: []string{"team:core"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			filter, err := getTagFilter(scenario.config)
+			require.NoError(t, err)
+
+			tags := filter.Filter(scenario.metricName, scenario.tags)
+			assert.Equal(t, scenario.expectedTags, tags)
+
+			// the rules matched by the metric are cached
+			_, cached := filter.cache.Get(scenario.metricName)
+			assert.True(t, cached)
+		})
+	}
+}
+
+func TestTagFilterError(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "Invalid match type",
+			config: `
+dogstatsd_tag_rules:
+  - match: "test.*"
+    match_type: invalid
+    drop_tags: ["request_id"]
+`,
+			expectedError: "invalid match type",
+		},
+		{
+			name: "Invalid wildcard match",
+			config: `
+dogstatsd_tag_rules:
+  - match: "test.**"
+    drop_tags: ["request_id"]
+`,
+			expectedError: "it should not contain consecutive `*`",
+		},
+		{
+			name: "Rule without action",
+			config: `
+dogstatsd_tag_rules:
+  - match: "test.*"
+`,
+			expectedError: "one of drop_tags, keep_tags or rewrite_tags is required",
+		},
+		{
+			name: "Rewrite without tag",
+			config: `
+dogstatsd_tag_rules:
+  - rewrite_tags:
+      - match: '\d+'
+`,
+			expectedError: "tag is required",
+		},
+		{
+			name: "Invalid rewrite regex",
+			config: `
+dogstatsd_tag_rules:
+  - rewrite_tags:
+      - tag: "path"
+        match: '(\d+'
+`,
+			expectedError: "cannot compile regex",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := getTagFilter(scenario.config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), scenario.expectedError)
+		})
+	}
+}
+
+func getTagFilter(configString string) (*TagFilter, error) {
+	var rules []config.TagRule
+	config.Datadog.SetConfigType("yaml")
+	err := config.Datadog.ReadConfig(strings.NewReader(configString))
+	if err != nil {
+		return nil, err
+	}
+	err = config.Datadog.UnmarshalKey("dogstatsd_tag_rules", &rules)
+	if err != nil {
+		return nil, err
+	}
+	return NewTagFilter(rules, 1000)
+}
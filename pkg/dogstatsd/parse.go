@@ -7,6 +7,7 @@ import (
 	"unsafe"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 type messageType int
@@ -90,7 +91,7 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 	// especially important here since all the unidentified garbage gets
 	// identified as metrics
 	if !hasMetricSampleFormat(message) {
-		return dogstatsdMetricSample{}, fmt.Errorf("invalid dogstatsd message format")
+		return dogstatsdMetricSample{}, newParseError(parseErrorInvalidFormat, "invalid dogstatsd message format")
 	}
 
 	rawNameAndValue, message := nextField(message)
@@ -108,9 +109,16 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 	var setValue []byte
 	var values []float64
 	var value float64
-	if metricType == setType {
+	var bins []metrics.DistributionBin
+	switch metricType {
+	case setType:
 		setValue = rawValue
-	} else {
+	case sketchType:
+		bins, err = parseMetricSampleBins(rawValue)
+		if err != nil {
+			return dogstatsdMetricSample{}, newParseError(parseErrorInvalidValue, "could not parse dogstatsd sketch values: %v", err)
+		}
+	default:
 		// In case the list contains only one value, dogstatsd 1.0
 		// protocol, we directly parse it as a float64. This avoids
 		// pulling a slice from the float64List and greatly improve
@@ -121,12 +129,13 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 			values, err = p.parseFloat64List(rawValue)
 		}
 		if err != nil {
-			return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd metric values: %v", err)
+			return dogstatsdMetricSample{}, newParseError(parseErrorInvalidValue, "could not parse dogstatsd metric values: %v", err)
 		}
 	}
 
 	sampleRate := 1.0
 	var tags []string
+	var ts int64
 	var optionalField []byte
 	for message != nil {
 		optionalField, message = nextField(message)
@@ -135,19 +144,32 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 		} else if bytes.HasPrefix(optionalField, sampleRateFieldPrefix) {
 			sampleRate, err = parseMetricSampleSampleRate(optionalField[1:])
 			if err != nil {
-				return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd sample rate %q", optionalField)
+				return dogstatsdMetricSample{}, newParseError(parseErrorInvalidSampleRate, "could not parse dogstatsd sample rate %q", optionalField)
+			}
+		} else if bytes.HasPrefix(optionalField, timestampFieldPrefix) {
+			ts, err = parseMetricSampleTimestamp(optionalField[1:])
+			if err != nil {
+				return dogstatsdMetricSample{}, newParseError(parseErrorInvalidTimestamp, "could not parse dogstatsd timestamp %q", optionalField)
 			}
 		}
 	}
 
+	// the timestamps are only honored for gauges and counts, the other types are
+	// aggregated by the agent and keep being bucketed at their arrival time.
+	if metricType != gaugeType && metricType != countType {
+		ts = 0
+	}
+
 	return dogstatsdMetricSample{
 		name:       p.interner.LoadOrStore(name),
 		value:      value,
 		values:     values,
+		bins:       bins,
 		setValue:   string(setValue),
 		metricType: metricType,
 		sampleRate: sampleRate,
 		tags:       tags,
+		ts:         ts,
 	}, nil
 }
 
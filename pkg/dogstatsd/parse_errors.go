@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// reasons of the parse errors
+const (
+	parseErrorInvalidFormat       = "invalid_format"
+	parseErrorInvalidType         = "invalid_type"
+	parseErrorInvalidValue        = "invalid_value"
+	parseErrorInvalidSampleRate   = "invalid_sample_rate"
+	parseErrorInvalidTimestamp    = "invalid_timestamp"
+	parseErrorInvalidName         = "invalid_name"
+	parseErrorInvalidTag          = "invalid_tag"
+	parseErrorInvalidEvent        = "invalid_event"
+	parseErrorInvalidServiceCheck = "invalid_service_check"
+
+	// origin reported for the messages received without origin detection
+	unknownOrigin = "unknown"
+	// number of origins listed in the parse errors report
+	parseErrorsTopOrigins = 10
+	// maximum number of origins accounted in the report, to keep it from
+	// growing with the number of containers sending invalid messages
+	parseErrorsMaxOrigins = 1000
+)
+
+var (
+	tlmParseErrors = telemetry.NewCounter("dogstatsd", "parse_errors",
+		[]string{"message_type", "reason"}, "Count of messages dropped by dogstatsd because they could not be parsed or validated")
+
+	parseErrorsStats = newParseErrorsStatsTracker()
+)
+
+// parseError is an error of the parser along with the reason reported in the parse errors stats
+type parseError struct {
+	reason string
+	err    error
+}
+
+func newParseError(reason string, format string, params ...interface{}) error {
+	return &parseError{reason: reason, err: fmt.Errorf(format, params...)}
+}
+
+func (e *parseError) Error() string {
+	return e.err.Error()
+}
+
+// parseErrorReason returns the reason of a parse error, defaultReason if it has none
+func parseErrorReason(err error, defaultReason string) string {
+	var pErr *parseError
+	if errors.As(err, &pErr) {
+		return pErr.reason
+	}
+	return defaultReason
+}
+
+// ParseErrorsStats holds the report of the messages dropped by dogstatsd because
+// they could not be parsed or validated
+type ParseErrorsStats struct {
+	Errors   uint64            `json:"errors"`
+	ByReason map[string]uint64 `json:"by_reason"`
+	// TopOrigins lists the origins which sent the most invalid messages
+	TopOrigins []ParseErrorsOrigin `json:"top_origins"`
+}
+
+// ParseErrorsOrigin is an entry of the parse errors report
+type ParseErrorsOrigin struct {
+	Origin string `json:"origin"`
+	// Tags are the low cardinality tags of the origin, to identify the container
+	Tags      []string          `json:"tags,omitempty"`
+	Errors    uint64            `json:"errors"`
+	ByReason  map[string]uint64 `json:"by_reason"`
+	LastError string            `json:"last_error"`
+}
+
+type originParseErrors struct {
+	errors    uint64
+	byReason  map[string]uint64
+	lastError string
+}
+
+type parseErrorsStatsTracker struct {
+	sync.Mutex
+	errors   uint64
+	byReason map[string]uint64
+	byOrigin map[string]*originParseErrors
+}
+
+func newParseErrorsStatsTracker() *parseErrorsStatsTracker {
+	return &parseErrorsStatsTracker{
+		byReason: make(map[string]uint64),
+		byOrigin: make(map[string]*originParseErrors),
+	}
+}
+
+// parseErrorFrom accounts an invalid message received from origin
+func (s *parseErrorsStatsTracker) parseErrorFrom(origin, messageType string, err error, defaultReason string) {
+	reason := parseErrorReason(err, defaultReason)
+	tlmParseErrors.Inc(messageType, reason)
+
+	if origin == listeners.NoOrigin {
+		origin = unknownOrigin
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.errors++
+	s.byReason[reason]++
+
+	originErrors, found := s.byOrigin[origin]
+	if !found {
+		if len(s.byOrigin) >= parseErrorsMaxOrigins {
+			return
+		}
+		originErrors = &originParseErrors{byReason: make(map[string]uint64)}
+		s.byOrigin[origin] = originErrors
+	}
+	originErrors.errors++
+	originErrors.byReason[reason]++
+	originErrors.lastError = err.Error()
+}
+
+func (s *parseErrorsStatsTracker) get() ParseErrorsStats {
+	s.Lock()
+	stats := ParseErrorsStats{
+		Errors:     s.errors,
+		ByReason:   copyCounts(s.byReason),
+		TopOrigins: make([]ParseErrorsOrigin, 0, len(s.byOrigin)),
+	}
+	for origin, originErrors := range s.byOrigin {
+		stats.TopOrigins = append(stats.TopOrigins, ParseErrorsOrigin{
+			Origin:    origin,
+			Errors:    originErrors.errors,
+			ByReason:  copyCounts(originErrors.byReason),
+			LastError: originErrors.lastError,
+		})
+	}
+	s.Unlock()
+
+	sort.Slice(stats.TopOrigins, func(i, j int) bool {
+		if stats.TopOrigins[i].Errors != stats.TopOrigins[j].Errors {
+			return stats.TopOrigins[i].Errors > stats.TopOrigins[j].Errors
+		}
+		return stats.TopOrigins[i].Origin < stats.TopOrigins[j].Origin
+	})
+	if len(stats.TopOrigins) > parseErrorsTopOrigins {
+		stats.TopOrigins = stats.TopOrigins[:parseErrorsTopOrigins]
+	}
+
+	// the origins are resolved out of the lock, only for the reported ones
+	for i := range stats.TopOrigins {
+		if stats.TopOrigins[i].Origin == unknownOrigin {
+			continue
+		}
+		if tags, err := tagger.Tag(stats.TopOrigins[i].Origin, collectors.LowCardinality); err == nil && len(tags) > 0 {
+			stats.TopOrigins[i].Tags = tags
+		}
+	}
+	return stats
+}
+
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	c := make(map[string]uint64, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+	return c
+}
+
+// GetParseErrorsStats returns the report of the parse errors
+func GetParseErrorsStats() ParseErrorsStats {
+	return parseErrorsStats.get()
+}
+
+// FormatParseErrorsStats returns a printable version of the parse errors report
+func FormatParseErrorsStats(stats []byte) (string, error) {
+	var report ParseErrorsStats
+	if err := json.Unmarshal(stats, &report); err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "Messages dropped because of a parse error: %d\n", report.Errors)
+	for _, reason := range sortedReasons(report.ByReason) {
+		fmt.Fprintf(buf, "  %s: %d\n", reason, report.ByReason[reason])
+	}
+
+	if len(report.TopOrigins) == 0 {
+		return buf.String(), nil
+	}
+	header := fmt.Sprintf("%-60s | %-10s | %-40s\n", "Origin", "Errors", "Reasons")
+	buf.WriteString("\n" + header)
+	buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+	for _, origin := range report.TopOrigins {
+		reasons := make([]string, 0, len(origin.ByReason))
+		for _, reason := range sortedReasons(origin.ByReason) {
+			reasons = append(reasons, fmt.Sprintf("%s:%d", reason, origin.ByReason[reason]))
+		}
+		fmt.Fprintf(buf, "%-60s | %-10d | %-40s\n", origin.Origin, origin.Errors, strings.Join(reasons, ", "))
+		if len(origin.Tags) > 0 {
+			fmt.Fprintf(buf, "  tags: %s\n", strings.Join(origin.Tags, ", "))
+		}
+		fmt.Fprintf(buf, "  last error: %s\n", origin.LastError)
+	}
+
+	return buf.String(), nil
+}
+
+func sortedReasons(counts map[string]uint64) []string {
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return reasons
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrorReason(t *testing.T) {
+	_, err := parseMetricSampleMetricType([]byte("z"))
+	assert.Equal(t, parseErrorInvalidType, parseErrorReason(err, parseErrorInvalidFormat))
+
+	p := newParser(newFloat64ListPool())
+	_, err = p.parseMetricSample([]byte("daemon:666|g|@abc"))
+	assert.Equal(t, parseErrorInvalidSampleRate, parseErrorReason(err, parseErrorInvalidFormat))
+	_, err = p.parseMetricSample([]byte("daemon:666|g|T-1"))
+	assert.Equal(t, parseErrorInvalidTimestamp, parseErrorReason(err, parseErrorInvalidFormat))
+	_, err = p.parseMetricSample([]byte("daemon"))
+	assert.Equal(t, parseErrorInvalidFormat, parseErrorReason(err, parseErrorInvalidValue))
+
+	assert.Equal(t, parseErrorInvalidEvent, parseErrorReason(fmt.Errorf("invalid event"), parseErrorInvalidEvent))
+}
+
+func TestParseErrorsStats(t *testing.T) {
+	s := newParseErrorsStatsTracker()
+	s.parseErrorFrom("", "metrics", newParseError(parseErrorInvalidValue, "invalid value"), parseErrorInvalidFormat)
+	for i := 0; i < 3; i++ {
+		s.parseErrorFrom("container_id://abc", "metrics", newParseError(parseErrorInvalidType, "invalid type %d", i), parseErrorInvalidFormat)
+	}
+	s.parseErrorFrom("container_id://abc", "events", fmt.Errorf("invalid event"), parseErrorInvalidEvent)
+
+	stats := s.get()
+	assert.Equal(t, uint64(5), stats.Errors)
+	assert.Equal(t, map[string]uint64{parseErrorInvalidValue: 1, parseErrorInvalidType: 3, parseErrorInvalidEvent: 1}, stats.ByReason)
+	require.Len(t, stats.TopOrigins, 2)
+	assert.Equal(t, ParseErrorsOrigin{
+		Origin:    "container_id://abc",
+		Errors:    4,
+		ByReason:  map[string]uint64{parseErrorInvalidType: 3, parseErrorInvalidEvent: 1},
+		LastError: "invalid event",
+	}, stats.TopOrigins[0])
+	assert.Equal(t, unknownOrigin, stats.TopOrigins[1].Origin)
+
+	// the number of origins accounted is bounded, the errors are still counted
+	for i := 0; i < parseErrorsMaxOrigins; i++ {
+		s.parseErrorFrom(fmt.Sprintf("container_id://%d", i), "metrics", fmt.Errorf("invalid"), parseErrorInvalidFormat)
+	}
+	assert.Len(t, s.byOrigin, parseErrorsMaxOrigins)
+	stats = s.get()
+	assert.Equal(t, uint64(5+parseErrorsMaxOrigins), stats.Errors)
+	assert.Len(t, stats.TopOrigins, parseErrorsTopOrigins)
+}
+
+func TestFormatParseErrorsStats(t *testing.T) {
+	s := newParseErrorsStatsTracker()
+	s.parseErrorFrom("container_id://abc", "metrics", newParseError(parseErrorInvalidName, "invalid metric name"), parseErrorInvalidFormat)
+	data, err := json.Marshal(s.get())
+	require.NoError(t, err)
+
+	formatted, err := FormatParseErrorsStats(data)
+	require.NoError(t, err)
+	assert.Contains(t, formatted, "Messages dropped because of a parse error: 1")
+	assert.Contains(t, formatted, "container_id://abc")
+	assert.Contains(t, formatted, "invalid_name:1")
+	assert.Contains(t, formatted, "last error: invalid metric name")
+
+	_, err = FormatParseErrorsStats([]byte("invalid"))
+	assert.Error(t, err)
+}
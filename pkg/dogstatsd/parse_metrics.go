@@ -3,6 +3,8 @@ package dogstatsd
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 type metricType int
@@ -14,6 +16,7 @@ const (
 	histogramType
 	setType
 	timingType
+	sketchType
 )
 
 var (
@@ -23,9 +26,14 @@ var (
 	distributionSymbol = []byte("d")
 	setSymbol          = []byte("s")
 	timingSymbol       = []byte("ms")
+	sketchSymbol       = []byte("ds")
 
 	tagsFieldPrefix       = []byte("#")
 	sampleRateFieldPrefix = []byte("@")
+	timestampFieldPrefix  = []byte("T")
+
+	// separates a value from its count in the value of a sketch message
+	countSeparator = []byte("@")
 )
 
 type dogstatsdMetricSample struct {
@@ -34,11 +42,15 @@ type dogstatsdMetricSample struct {
 	value float64
 	// use for multiple value messages
 	values []float64
+	// use to store the pre-aggregated values of sketch messages
+	bins []metrics.DistributionBin
 	// use to store set's values
 	setValue   string
 	metricType metricType
 	sampleRate float64
 	tags       []string
+	// timestamp provided by the client in seconds, 0 if none was sent
+	ts int64
 }
 
 // sanity checks a given message against the metric sample format
@@ -47,7 +59,7 @@ func hasMetricSampleFormat(message []byte) bool {
 		return false
 	}
 	separatorCount := bytes.Count(message, fieldSeparator)
-	if separatorCount < 1 || separatorCount > 3 {
+	if separatorCount < 1 || separatorCount > 4 {
 		return false
 	}
 	return true
@@ -56,12 +68,12 @@ func hasMetricSampleFormat(message []byte) bool {
 func parseMetricSampleNameAndRawValue(rawNameAndValue []byte) ([]byte, []byte, error) {
 	sepIndex := bytes.Index(rawNameAndValue, colonSeparator)
 	if sepIndex == -1 {
-		return nil, nil, fmt.Errorf("invalid name and value: %q", rawNameAndValue)
+		return nil, nil, newParseError(parseErrorInvalidFormat, "invalid name and value: %q", rawNameAndValue)
 	}
 	rawName := rawNameAndValue[:sepIndex]
 	rawValue := rawNameAndValue[sepIndex+1:]
 	if len(rawName) == 0 || len(rawValue) == 0 {
-		return nil, nil, fmt.Errorf("invalid name and value: %q", rawNameAndValue)
+		return nil, nil, newParseError(parseErrorInvalidFormat, "invalid name and value: %q", rawNameAndValue)
 	}
 	return rawName, rawValue, nil
 }
@@ -80,10 +92,68 @@ func parseMetricSampleMetricType(rawMetricType []byte) (metricType, error) {
 		return setType, nil
 	case bytes.Equal(rawMetricType, timingSymbol):
 		return timingType, nil
+	case bytes.Equal(rawMetricType, sketchSymbol):
+		return sketchType, nil
 	}
-	return 0, fmt.Errorf("invalid metric type: %q", rawMetricType)
+	return 0, newParseError(parseErrorInvalidType, "invalid metric type: %q", rawMetricType)
 }
 
 func parseMetricSampleSampleRate(rawSampleRate []byte) (float64, error) {
 	return parseFloat64(rawSampleRate)
 }
+
+func parseMetricSampleTimestamp(rawTimestamp []byte) (int64, error) {
+	ts, err := parseInt64(rawTimestamp)
+	if err != nil {
+		return 0, err
+	}
+	if ts <= 0 {
+		return 0, fmt.Errorf("invalid timestamp: %d", ts)
+	}
+	return ts, nil
+}
+
+// parseMetricSampleBins parses the value of a sketch message: a list of values
+// separated by colonSeparator, each one optionally followed by countSeparator
+// and the number of samples it stands for, e.g. "1.5@10:3@2:4".
+func parseMetricSampleBins(rawBins []byte) ([]metrics.DistributionBin, error) {
+	bins := make([]metrics.DistributionBin, 0, bytes.Count(rawBins, colonSeparator)+1)
+
+	var rawBin []byte
+	for len(rawBins) != 0 {
+		idx := bytes.Index(rawBins, colonSeparator)
+		if idx == -1 {
+			rawBin, rawBins = rawBins, nil
+		} else {
+			rawBin, rawBins = rawBins[:idx], rawBins[idx+len(colonSeparator):]
+		}
+		// skip empty value such as '21::22'
+		if len(rawBin) == 0 {
+			continue
+		}
+
+		rawValue, rawCount := rawBin, []byte(nil)
+		if idx := bytes.Index(rawBin, countSeparator); idx != -1 {
+			rawValue, rawCount = rawBin[:idx], rawBin[idx+len(countSeparator):]
+		}
+		value, err := parseFloat64(rawValue)
+		if err != nil {
+			return nil, err
+		}
+		count := int64(1)
+		if rawCount != nil {
+			count, err = parseInt64(rawCount)
+			if err != nil {
+				return nil, err
+			}
+			if count <= 0 {
+				return nil, fmt.Errorf("invalid count: %d", count)
+			}
+		}
+		bins = append(bins, metrics.DistributionBin{Value: value, Count: uint(count)})
+	}
+	if len(bins) == 0 {
+		return nil, fmt.Errorf("no value found")
+	}
+	return bins, nil
+}
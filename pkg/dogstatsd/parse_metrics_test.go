@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 func parseMetricSample(rawSample []byte) (dogstatsdMetricSample, error) {
@@ -189,6 +191,38 @@ func TestParseDistributionMultiple(t *testing.T) {
 	assert.Len(t, sample.tags, 0)
 }
 
+func TestParseSketch(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:3.5@10:4.5::5@1:6|ds|@0.5|#sometag"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.Equal(t, []metrics.DistributionBin{
+		{Value: 3.5, Count: 10},
+		{Value: 4.5, Count: 1},
+		{Value: 5, Count: 1},
+		{Value: 6, Count: 1},
+	}, sample.bins)
+	require.Nil(t, sample.values)
+	assert.Equal(t, sketchType, sample.metricType)
+	assert.Equal(t, []string{"sometag"}, sample.tags)
+	assert.InEpsilon(t, 0.5, sample.sampleRate, epsilon)
+}
+
+func TestParseSketchError(t *testing.T) {
+	for _, message := range []string{
+		"daemon:abc@1|ds",
+		"daemon:1@abc|ds",
+		"daemon:1@0|ds",
+		"daemon:1@-2|ds",
+		"daemon:1@|ds",
+		"daemon:::|ds",
+	} {
+		_, err := parseMetricSample([]byte(message))
+		assert.Error(t, err, message)
+	}
+}
+
 func TestParseSetUnicode(t *testing.T) {
 	sample, err := parseMetricSample([]byte("daemon:♬†øU†øU¥ºuT0♪|s"))
 
@@ -241,6 +275,38 @@ func TestParseGaugeWithSampleRate(t *testing.T) {
 	assert.InEpsilon(t, 0.21, sample.sampleRate, epsilon)
 }
 
+func TestParseGaugeWithTimestamp(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:666|g|@0.21|#sometag1:somevalue1|T1617224400"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 666.0, sample.value, epsilon)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, []string{"sometag1:somevalue1"}, sample.tags)
+	assert.InEpsilon(t, 0.21, sample.sampleRate, epsilon)
+	assert.Equal(t, int64(1617224400), sample.ts)
+}
+
+func TestParseCountWithTimestamp(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:21|c|T1617224400"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 21.0, sample.value, epsilon)
+	assert.Equal(t, countType, sample.metricType)
+	assert.Equal(t, int64(1617224400), sample.ts)
+}
+
+func TestParseTimestampIgnoredForAggregatedTypes(t *testing.T) {
+	for _, rawSample := range []string{"daemon:21|h|T1617224400", "daemon:21|d|T1617224400", "daemon:21|ms|T1617224400", "daemon:abc|s|T1617224400"} {
+		sample, err := parseMetricSample([]byte(rawSample))
+		assert.NoError(t, err, rawSample)
+		assert.Equal(t, int64(0), sample.ts, rawSample)
+	}
+}
+
 func TestParseGaugeWithPoundOnly(t *testing.T) {
 	sample, err := parseMetricSample([]byte("daemon:666|g|#"))
 
@@ -300,4 +366,14 @@ func TestParseMetricError(t *testing.T) {
 	// invalid sample rate
 	_, err = parseMetricSample([]byte("daemon:666|g|@abc"))
 	assert.Error(t, err)
+
+	// invalid timestamp
+	_, err = parseMetricSample([]byte("daemon:666|g|Tabc"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T-1617224400"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T0"))
+	assert.Error(t, err)
 }
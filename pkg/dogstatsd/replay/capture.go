@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MaxCaptureDuration bounds the duration of a capture
+const MaxCaptureDuration = time.Hour
+
+// TrafficCapture records the packets received by DogStatsD into rotating capture
+// files, for a bounded duration, so that they can be replayed later.
+type TrafficCapture struct {
+	location    string
+	maxFileSize int64
+	maxFiles    int
+	depth       int
+
+	// ongoing is an atomic int used as a boolean, checked for each packet
+	ongoing uint32
+	writer  *captureWriter
+	timer   *time.Timer
+	sync.RWMutex
+}
+
+// NewTrafficCapture returns an idle TrafficCapture configured from the agent configuration
+func NewTrafficCapture() *TrafficCapture {
+	location := config.Datadog.GetString("dogstatsd_capture_path")
+	if location == "" {
+		location = filepath.Join(config.Datadog.GetString("run_path"), "dsd_capture")
+	}
+	return &TrafficCapture{
+		location:    location,
+		maxFileSize: config.Datadog.GetInt64("dogstatsd_capture_max_file_size"),
+		maxFiles:    config.Datadog.GetInt("dogstatsd_capture_max_files"),
+		depth:       config.Datadog.GetInt("dogstatsd_capture_depth"),
+	}
+}
+
+// Start starts capturing the packets for the given duration. It returns the path of the
+// first capture file, the next ones are created next to it when it reaches its maximum size.
+func (tc *TrafficCapture) Start(d time.Duration) (string, error) {
+	if d <= 0 || d > MaxCaptureDuration {
+		return "", fmt.Errorf("the capture duration must be greater than 0 and lower than %s", MaxCaptureDuration)
+	}
+
+	tc.Lock()
+	defer tc.Unlock()
+	if tc.writer != nil {
+		return "", errors.New("a capture is already ongoing")
+	}
+
+	writer, err := newCaptureWriter(tc.location, tc.maxFileSize, tc.maxFiles, tc.depth)
+	if err != nil {
+		return "", err
+	}
+	tc.writer = writer
+	tc.timer = time.AfterFunc(d, tc.Stop)
+	atomic.StoreUint32(&tc.ongoing, 1)
+
+	log.Infof("Dogstatsd: capturing the traffic for %s in %s", d, writer.path)
+	return writer.path, nil
+}
+
+// Stop stops the ongoing capture, if any, and waits for its files to be written
+func (tc *TrafficCapture) Stop() {
+	tc.Lock()
+	defer tc.Unlock()
+	if tc.writer == nil {
+		return
+	}
+
+	atomic.StoreUint32(&tc.ongoing, 0)
+	tc.timer.Stop()
+	tc.writer.stop()
+	log.Infof("Dogstatsd: traffic capture stopped, %d packets captured, %d dropped", atomic.LoadUint64(&tc.writer.captured), atomic.LoadUint64(&tc.writer.dropped))
+	tc.writer = nil
+}
+
+// IsOngoing returns whether a capture is ongoing
+func (tc *TrafficCapture) IsOngoing() bool {
+	return atomic.LoadUint32(&tc.ongoing) == 1
+}
+
+// Enqueue queues a record to be written in the capture. The record is dropped when the
+// queue is full, so that the capture never slows the processing of the packets down.
+func (tc *TrafficCapture) Enqueue(record *CaptureRecord) {
+	tc.RLock()
+	defer tc.RUnlock()
+	if tc.writer != nil {
+		tc.writer.enqueue(record)
+	}
+}
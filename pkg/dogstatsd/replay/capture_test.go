@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordEncoding(t *testing.T) {
+	record := &CaptureRecord{
+		Timestamp: time.Unix(1600000000, 42),
+		Origin:    "container_id://abcdef",
+		Ancillary: []byte{1, 2, 3},
+		Payload:   []byte("daemon:666|g\ndaemon:667|g"),
+	}
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, record.encode(buf))
+	assert.Equal(t, record.encodedSize(), buf.Len())
+
+	decoded, err := decodeRecord(buf)
+	require.NoError(t, err)
+	assert.True(t, record.Timestamp.Equal(decoded.Timestamp))
+	assert.Equal(t, record.Origin, decoded.Origin)
+	assert.Equal(t, record.Ancillary, decoded.Ancillary)
+	assert.Equal(t, record.Payload, decoded.Payload)
+
+	_, err = decodeRecord(buf)
+	assert.Equal(t, io.EOF, err)
+
+	// truncated records are reported
+	buf.Reset()
+	require.NoError(t, record.encode(buf))
+	_, err = decodeRecord(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Error(t, err)
+}
+
+func TestTrafficCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := &TrafficCapture{location: dir, depth: 100}
+	assert.False(t, tc.IsOngoing())
+
+	_, err = tc.Start(2 * MaxCaptureDuration)
+	assert.Error(t, err)
+
+	path, err := tc.Start(time.Minute)
+	require.NoError(t, err)
+	assert.True(t, tc.IsOngoing())
+	assert.Equal(t, dir, filepath.Dir(path))
+
+	_, err = tc.Start(time.Minute)
+	assert.Error(t, err, "a single capture can be ongoing")
+
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:666|g"), "", nil))
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:667|g"), "container_id://abcdef", []byte{1, 2}))
+	tc.Stop()
+	assert.False(t, tc.IsOngoing())
+
+	// the records enqueued once the capture is stopped are ignored
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:668|g"), "", nil))
+
+	records := readCapture(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, "daemon:666|g", string(records[0].Payload))
+	assert.Equal(t, "daemon:667|g", string(records[1].Payload))
+	assert.Equal(t, "container_id://abcdef", records[1].Origin)
+	assert.Equal(t, []byte{1, 2}, records[1].Ancillary)
+}
+
+func TestTrafficCaptureDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := &TrafficCapture{location: dir, depth: 100}
+	_, err = tc.Start(10 * time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return !tc.IsOngoing() }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestTrafficCaptureRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	record := NewCaptureRecord([]byte("daemon:666|g"), "", nil)
+	// two records per file
+	maxFileSize := int64(len(fileHeader) + 2*record.encodedSize())
+	tc := &TrafficCapture{location: dir, depth: 100, maxFileSize: maxFileSize, maxFiles: 2}
+	_, err = tc.Start(time.Minute)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		tc.Enqueue(NewCaptureRecord([]byte("daemon:666|g"), "", nil))
+	}
+	tc.Stop()
+
+	// only the latest files are kept
+	files, err := filepath.Glob(filepath.Join(dir, "*"+FileExtension))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	sort.Strings(files)
+	assert.Len(t, readCapture(t, files[0]), 2)
+	assert.Len(t, readCapture(t, files[1]), 1)
+}
+
+func TestNewTrafficCaptureReaderError(t *testing.T) {
+	f, err := ioutil.TempFile("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("not a capture")
+	f.Close()
+
+	_, err = NewTrafficCaptureReader(f.Name())
+	assert.Error(t, err)
+}
+
+func readCapture(t *testing.T, path string) []*CaptureRecord {
+	reader, err := NewTrafficCaptureReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var records []*CaptureRecord
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
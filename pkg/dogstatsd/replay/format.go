@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A capture file starts with fileHeader, followed by the records. Each record is made of:
+//   - its size, without this field, as a little-endian uint32
+//   - its reception time, in nanoseconds since the epoch, as a little-endian int64
+//   - its origin, prefixed by its size as a little-endian uint16
+//   - its UDS ancillary data, prefixed by its size as a little-endian uint16
+//   - the packet contents
+var fileHeader = []byte("DSDCAPTURE\x01")
+
+const (
+	// FileExtension is the extension of the capture files
+	FileExtension = ".dsdcap"
+
+	recordSizeFieldSize = 4
+	recordHeaderSize    = 8 + 2 + 2
+	// maxRecordSize bounds the records read, to detect corrupted files
+	maxRecordSize = 16 * 1024 * 1024
+)
+
+var errInvalidRecord = errors.New("invalid capture record")
+
+// CaptureRecord is a packet received by DogStatsD
+type CaptureRecord struct {
+	Timestamp time.Time
+	// Origin is the origin container of the packet, if identified
+	Origin string
+	// Ancillary is the UDS ancillary data of the packet, holding the credentials of its sender
+	Ancillary []byte
+	Payload   []byte
+}
+
+// NewCaptureRecord returns a record holding a copy of the packet
+func NewCaptureRecord(payload []byte, origin string, ancillary []byte) *CaptureRecord {
+	record := &CaptureRecord{
+		Timestamp: time.Now(),
+		Origin:    origin,
+		Payload:   make([]byte, len(payload)),
+	}
+	copy(record.Payload, payload)
+	if len(ancillary) > 0 {
+		record.Ancillary = make([]byte, len(ancillary))
+		copy(record.Ancillary, ancillary)
+	}
+	return record
+}
+
+// encodedSize returns the number of bytes written by encode
+func (r *CaptureRecord) encodedSize() int {
+	return recordSizeFieldSize + recordHeaderSize + len(r.Origin) + len(r.Ancillary) + len(r.Payload)
+}
+
+func (r *CaptureRecord) encode(w io.Writer) error {
+	if len(r.Origin) > 0xffff || len(r.Ancillary) > 0xffff {
+		return fmt.Errorf("%v: origin or ancillary data too big", errInvalidRecord)
+	}
+	header := make([]byte, recordSizeFieldSize+recordHeaderSize)
+	binary.LittleEndian.PutUint32(header, uint32(r.encodedSize()-recordSizeFieldSize))
+	binary.LittleEndian.PutUint64(header[4:], uint64(r.Timestamp.UnixNano()))
+	binary.LittleEndian.PutUint16(header[12:], uint16(len(r.Origin)))
+	if _, err := w.Write(header[:14]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, r.Origin); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint16(header[14:], uint16(len(r.Ancillary)))
+	if _, err := w.Write(header[14:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(r.Ancillary); err != nil {
+		return err
+	}
+	_, err := w.Write(r.Payload)
+	return err
+}
+
+// decodeRecord reads the next record, it returns io.EOF when there is none
+func decodeRecord(r io.Reader) (*CaptureRecord, error) {
+	sizeField := make([]byte, recordSizeFieldSize)
+	if _, err := io.ReadFull(r, sizeField); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%v: truncated size", errInvalidRecord)
+		}
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(sizeField)
+	if size < recordHeaderSize || size > maxRecordSize {
+		return nil, fmt.Errorf("%v: unexpected size %d", errInvalidRecord, size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("%v: truncated record", errInvalidRecord)
+	}
+
+	record := &CaptureRecord{
+		Timestamp: time.Unix(0, int64(binary.LittleEndian.Uint64(data))),
+	}
+	data = data[8:]
+	originSize := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < originSize+2 {
+		return nil, fmt.Errorf("%v: truncated origin", errInvalidRecord)
+	}
+	record.Origin = string(data[:originSize])
+	data = data[originSize:]
+	ancillarySize := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < ancillarySize {
+		return nil, fmt.Errorf("%v: truncated ancillary data", errInvalidRecord)
+	}
+	if ancillarySize > 0 {
+		record.Ancillary = data[:ancillarySize]
+	}
+	record.Payload = data[ancillarySize:]
+	return record, nil
+}
@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// TrafficCaptureReader reads the records of a capture file
+type TrafficCaptureReader struct {
+	file *os.File
+	r    *bufio.Reader
+}
+
+// NewTrafficCaptureReader opens a capture file
+func NewTrafficCaptureReader(path string) (*TrafficCaptureReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(file)
+
+	header := make([]byte, len(fileHeader))
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header, fileHeader) {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a DogStatsD capture file", path)
+	}
+	return &TrafficCaptureReader{file: file, r: r}, nil
+}
+
+// Read returns the next record of the capture, io.EOF when all the records have been read
+func (tc *TrafficCaptureReader) Read() (*CaptureRecord, error) {
+	return decodeRecord(tc.r)
+}
+
+// Close closes the capture file
+func (tc *TrafficCaptureReader) Close() error {
+	return tc.file.Close()
+}
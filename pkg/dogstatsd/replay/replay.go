@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Sender sends captured packets to a DogStatsD listener
+type Sender struct {
+	conn net.Conn
+	// unixConn is an unconnected socket sending the packets and their credentials to unixAddr,
+	// set when sending to the UDS listener
+	unixConn *net.UnixConn
+	unixAddr *net.UnixAddr
+	// credentialsErr is set once the kernel refused to send the credentials of the packets
+	credentialsErr error
+	// ignoreOrigin allows sending the packets whose origin can't be reproduced,
+	// otherwise they interrupt the replay
+	ignoreOrigin bool
+}
+
+// NewUDSSender returns a Sender writing to the DogStatsD UDS socket. The credentials
+// of the captured packets are sent along with them, so that their origin is detected
+// again, as long as the kernel allows it: sending the credentials of another process
+// requires the CAP_SYS_ADMIN capability and the process must still be running.
+// Unless ignoreOrigin is set, a packet whose origin can't be reproduced is an error.
+func NewUDSSender(socketPath string, ignoreOrigin bool) (*Sender, error) {
+	addr, err := net.ResolveUnixAddr("unixgram", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	// the ancillary data can't be written on a connected datagram socket
+	if conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"}); err == nil {
+		return &Sender{conn: conn, unixConn: conn, unixAddr: addr, ignoreOrigin: ignoreOrigin}, nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %v", socketPath, err)
+	}
+	return &Sender{conn: conn, ignoreOrigin: ignoreOrigin}, nil
+}
+
+// NewUDPSender returns a Sender writing to the DogStatsD UDP port. The origin of the
+// packets is lost, unless ignoreOrigin is set a packet with an origin is an error.
+func NewUDPSender(address string, ignoreOrigin bool) (*Sender, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %v", address, err)
+	}
+	return &Sender{conn: conn, ignoreOrigin: ignoreOrigin}, nil
+}
+
+// Send sends the packet of a record, along with its credentials when they are needed
+// to detect its origin again
+func (s *Sender) Send(record *CaptureRecord) error {
+	if s.unixConn == nil {
+		if err := s.checkOriginLost(record, errors.New("the credentials can only be sent to the UDS socket")); err != nil {
+			return err
+		}
+		_, err := s.conn.Write(record.Payload)
+		return err
+	}
+	if s.credentialsErr == nil && len(record.Ancillary) > 0 {
+		_, _, err := s.unixConn.WriteMsgUnix(record.Payload, record.Ancillary, s.unixAddr)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, syscall.EPERM):
+			s.credentialsErr = fmt.Errorf("sending the credentials of another process requires the CAP_SYS_ADMIN capability: %v", err)
+			if err := s.checkOriginLost(record, s.credentialsErr); err != nil {
+				return err
+			}
+			log.Warnf("The credentials of the captured packets can't be sent, their origin won't be detected: %v", err)
+		case errors.Is(err, syscall.ESRCH):
+			if err := s.checkOriginLost(record, fmt.Errorf("the process which sent the packet is gone: %v", err)); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	} else {
+		reason := s.credentialsErr
+		if reason == nil {
+			reason = errors.New("its credentials were not captured")
+		}
+		if err := s.checkOriginLost(record, reason); err != nil {
+			return err
+		}
+	}
+	_, err := s.unixConn.WriteToUnix(record.Payload, s.unixAddr)
+	return err
+}
+
+// checkOriginLost returns an error when the record has an origin that won't be detected
+// again for the given reason, unless the origins are ignored
+func (s *Sender) checkOriginLost(record *CaptureRecord, reason error) error {
+	if record.Origin == "" || s.ignoreOrigin {
+		return nil
+	}
+	return fmt.Errorf("the origin %q of the packet can't be reproduced, %v", record.Origin, reason)
+}
+
+// Close closes the connection of the Sender
+func (s *Sender) Close() error {
+	return s.conn.Close()
+}
+
+// Replay sends the records of the capture files, in order, and returns the number of
+// packets sent. When realTime is set, the delays between the packets are reproduced.
+func Replay(paths []string, sender *Sender, realTime bool) (int, error) {
+	sent := 0
+	var previous time.Time
+	for _, path := range paths {
+		reader, err := NewTrafficCaptureReader(path)
+		if err != nil {
+			return sent, err
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				reader.Close()
+				return sent, fmt.Errorf("could not read %s: %v", path, err)
+			}
+
+			if realTime && !previous.IsZero() {
+				if delay := record.Timestamp.Sub(previous); delay > 0 {
+					time.Sleep(delay)
+				}
+			}
+			previous = record.Timestamp
+
+			if err := sender.Send(record); err != nil {
+				reader.Close()
+				return sent, fmt.Errorf("could not send a packet: %v", err)
+			}
+			sent++
+		}
+		reader.Close()
+	}
+	return sent, nil
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := &TrafficCapture{location: dir, depth: 100}
+	path, err := tc.Start(time.Minute)
+	require.NoError(t, err)
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:666|g"), "", nil))
+	time.Sleep(50 * time.Millisecond)
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:667|g\ndaemon:668|g"), "", nil))
+	tc.Stop()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sender, err := NewUDPSender(conn.LocalAddr().String(), false)
+	require.NoError(t, err)
+	defer sender.Close()
+
+	start := time.Now()
+	sent, err := Replay([]string{path}, sender, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	// the delay between the packets is reproduced
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	buffer := make([]byte, 1024)
+	for _, expected := range []string{"daemon:666|g", "daemon:667|g\ndaemon:668|g"} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buffer)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(buffer[:n]))
+	}
+}
+
+func TestReplayInvalidFile(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sender, err := NewUDPSender(conn.LocalAddr().String(), false)
+	require.NoError(t, err)
+	defer sender.Close()
+
+	_, err = Replay([]string{"does-not-exist" + FileExtension}, sender, false)
+	assert.Error(t, err)
+}
+
+func TestReplayOrigin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := &TrafficCapture{location: dir, depth: 100}
+	path, err := tc.Start(time.Minute)
+	require.NoError(t, err)
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:666|g"), "", nil))
+	tc.Enqueue(NewCaptureRecord([]byte("daemon:667|g"), "container_id://abcdef", []byte{1, 2, 3}))
+	tc.Stop()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the origin of the second packet can't be sent over UDP
+	sender, err := NewUDPSender(conn.LocalAddr().String(), false)
+	require.NoError(t, err)
+	defer sender.Close()
+	sent, err := Replay([]string{path}, sender, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `origin "container_id://abcdef"`)
+	assert.Equal(t, 1, sent)
+
+	sender, err = NewUDPSender(conn.LocalAddr().String(), true)
+	require.NoError(t, err)
+	defer sender.Close()
+	sent, err = Replay([]string{path}, sender, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+}
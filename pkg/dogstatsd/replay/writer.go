@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var tlmCaptureRecords = telemetry.NewCounter("dogstatsd", "capture_records",
+	[]string{"state"}, "Count of the packets captured or dropped by the traffic capture")
+
+// captureWriter writes the queued records into capture files of a bounded size, only the
+// latest maxFiles files are kept.
+type captureWriter struct {
+	// path is the path of the first capture file
+	path        string
+	base        string
+	maxFileSize int64
+	maxFiles    int
+
+	records chan *CaptureRecord
+	done    chan struct{}
+
+	file     *os.File
+	buf      *bufio.Writer
+	fileSize int64
+	// files are the capture files kept, from the oldest to the current one
+	files     []string
+	nextIndex int
+
+	// atomically updated by the enqueuing goroutines
+	captured uint64
+	dropped  uint64
+}
+
+func newCaptureWriter(location string, maxFileSize int64, maxFiles int, depth int) (*captureWriter, error) {
+	if err := os.MkdirAll(location, 0700); err != nil {
+		return nil, fmt.Errorf("could not create the capture directory: %v", err)
+	}
+	if maxFiles < 1 {
+		maxFiles = 1
+	}
+
+	w := &captureWriter{
+		base:        filepath.Join(location, "datadog-capture-"+time.Now().UTC().Format("20060102T150405Z")),
+		maxFileSize: maxFileSize,
+		maxFiles:    maxFiles,
+		records:     make(chan *CaptureRecord, depth),
+		done:        make(chan struct{}),
+	}
+	if err := w.openNextFile(); err != nil {
+		return nil, err
+	}
+	w.path = w.files[0]
+
+	go w.run()
+	return w, nil
+}
+
+func (w *captureWriter) enqueue(record *CaptureRecord) {
+	select {
+	case w.records <- record:
+		atomic.AddUint64(&w.captured, 1)
+		tlmCaptureRecords.Inc("ok")
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+		tlmCaptureRecords.Inc("dropped")
+	}
+}
+
+func (w *captureWriter) run() {
+	defer close(w.done)
+	failed := false
+	for record := range w.records {
+		// the records are consumed until the writer is stopped, even after a failure
+		if failed {
+			continue
+		}
+		if err := w.write(record); err != nil {
+			log.Errorf("Dogstatsd: could not write the traffic capture, the next packets won't be captured: %v", err)
+			failed = true
+		}
+	}
+	if err := w.closeFile(); err != nil {
+		log.Errorf("Dogstatsd: could not close the traffic capture file: %v", err)
+	}
+}
+
+func (w *captureWriter) write(record *CaptureRecord) error {
+	size := int64(record.encodedSize())
+	if w.maxFileSize > 0 && w.fileSize > int64(len(fileHeader)) && w.fileSize+size > w.maxFileSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	if err := record.encode(w.buf); err != nil {
+		return err
+	}
+	w.fileSize += size
+	return nil
+}
+
+// rotate closes the current file and opens the next one, removing the oldest file
+// when there are more than maxFiles
+func (w *captureWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if err := w.openNextFile(); err != nil {
+		return err
+	}
+	if len(w.files) > w.maxFiles {
+		if err := os.Remove(w.files[0]); err != nil {
+			log.Warnf("Dogstatsd: could not remove the capture file %s: %v", w.files[0], err)
+		}
+		w.files = w.files[1:]
+	}
+	return nil
+}
+
+func (w *captureWriter) openNextFile() error {
+	// the index is padded so that the files are sorted by name
+	path := fmt.Sprintf("%s-%04d%s", w.base, w.nextIndex, FileExtension)
+	w.nextIndex++
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create the capture file: %v", err)
+	}
+	w.file = file
+	w.buf = bufio.NewWriter(file)
+	w.files = append(w.files, path)
+	w.fileSize = 0
+
+	if _, err := w.buf.Write(fileHeader); err != nil {
+		return err
+	}
+	w.fileSize = int64(len(fileHeader))
+	return nil
+}
+
+func (w *captureWriter) closeFile() error {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// stop writes the queued records and closes the current file
+func (w *captureWriter) stop() {
+	close(w.records)
+	<-w.done
+}
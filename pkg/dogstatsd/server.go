@@ -23,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd/listeners"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
@@ -41,11 +42,16 @@ var (
 	dogstatsdMetricPackets            = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
+	dogstatsdMetricLateTimestamps     = expvar.Int{}
+	dogstatsdMetricFutureTimestamps   = expvar.Int{}
 
 	tlmProcessed = telemetry.NewCounter("dogstatsd", "processed",
 		[]string{"message_type", "state"}, "Count of service checks/events/metrics processed by dogstatsd")
 	tlmProcessedErrorTags = map[string]string{"message_type": "metrics", "state": "error"}
 	tlmProcessedOkTags    = map[string]string{"message_type": "metrics", "state": "ok"}
+
+	tlmRejectedTimestamps = telemetry.NewCounter("dogstatsd", "rejected_timestamps",
+		[]string{"reason"}, "Count of metric samples dropped because their timestamp was outside of the accepted window")
 )
 
 func init() {
@@ -56,6 +62,11 @@ func init() {
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
+	dogstatsdExpvars.Set("MetricLateTimestamps", &dogstatsdMetricLateTimestamps)
+	dogstatsdExpvars.Set("MetricFutureTimestamps", &dogstatsdMetricFutureTimestamps)
+	dogstatsdExpvars.Set("ParseErrors", expvar.Func(func() interface{} {
+		return GetParseErrorsStats()
+	}))
 }
 
 // Server represent a Dogstatsd server
@@ -84,6 +95,7 @@ type Server struct {
 	extraTags                 []string
 	Debug                     *dsdServerDebug
 	mapper                    *mapper.MetricMapper
+	tagFilter                 *mapper.TagFilter
 	eolTerminationEnabled     bool
 	telemetryEnabled          bool
 	entityIDPrecedenceEnabled bool
@@ -92,10 +104,19 @@ type Server struct {
 	// NOTE(remy): this should probably be dropped and use a throttler logger, see
 	// package (pkg/trace/logutils) for a possible throttler implemetation.
 	disableVerboseLogs bool
+	// timestampMaxAge and timestampMaxFuture define the window, in seconds around
+	// the current time, in which the timestamps sent by the clients are accepted.
+	timestampMaxAge    int64
+	timestampMaxFuture int64
+	// strictValidation rejects the metrics whose name or tags would be normalized by the intake
+	strictValidation bool
 
 	// ServerlessMode is set to true if we're running in a serverless environment.
 	ServerlessMode     bool
 	UdsListenerRunning bool
+
+	// TCapture records the messages received by the listeners while a capture is ongoing
+	TCapture *replay.TrafficCapture
 }
 
 // metricStat holds how many times a metric has been
@@ -157,9 +178,12 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 
 	udsListenerRunning := false
 
+	// the traffic capture is idle until it is started from the agent CLI
+	capture := replay.NewTrafficCapture()
+
 	socketPath := config.Datadog.GetString("dogstatsd_socket")
 	if len(socketPath) > 0 {
-		unixListener, err := listeners.NewUDSListener(packetsChannel, sharedPacketPool)
+		unixListener, err := listeners.NewUDSListener(packetsChannel, sharedPacketPool, capture)
 		if err != nil {
 			log.Errorf(err.Error())
 		} else {
@@ -168,7 +192,7 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 		}
 	}
 	if config.Datadog.GetInt("dogstatsd_port") > 0 {
-		udpListener, err := listeners.NewUDPListener(packetsChannel, sharedPacketPool)
+		udpListener, err := listeners.NewUDPListener(packetsChannel, sharedPacketPool, capture)
 		if err != nil {
 			log.Errorf(err.Error())
 		} else {
@@ -176,9 +200,18 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 		}
 	}
 
+	if config.Datadog.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPool, capture)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
 	pipeName := config.Datadog.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
-		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPool)
+		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPool, capture)
 		if err != nil {
 			log.Errorf("named pipe error: %v", err.Error())
 		} else {
@@ -187,7 +220,7 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 	}
 
 	if len(tmpListeners) == 0 {
-		return nil, fmt.Errorf("listening on neither udp nor socket, please check your configuration")
+		return nil, fmt.Errorf("listening on neither udp, tcp nor socket, please check your configuration")
 	}
 
 	// check configuration for custom namespace
@@ -231,6 +264,9 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 		telemetryEnabled:          telemetry_utils.IsEnabled(),
 		entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
 		disableVerboseLogs:        config.Datadog.GetBool("dogstatsd_disable_verbose_logs"),
+		timestampMaxAge:           config.Datadog.GetInt64("dogstatsd_timestamp_max_age_seconds"),
+		timestampMaxFuture:        config.Datadog.GetInt64("dogstatsd_timestamp_max_future_seconds"),
+		strictValidation:          config.Datadog.GetBool("dogstatsd_strict_validation"),
 		Debug: &dsdServerDebug{
 			Stats: make(map[ckey.ContextKey]metricStat),
 			metricsCounts: metricsCountBuckets{
@@ -241,6 +277,7 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 			keyGen: ckey.NewKeyGenerator(),
 		},
 		UdsListenerRunning: udsListenerRunning,
+		TCapture:           capture,
 	}
 
 	// packets forwarding
@@ -287,6 +324,21 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 			s.mapper = mapperInstance
 		}
 	}
+
+	// filter the tags of some metrics
+	// ----------------------
+
+	tagRules, err := config.GetDogstatsdTagRules()
+	if err != nil {
+		log.Warnf("Could not parse tag rules: %v", err)
+	} else if len(tagRules) != 0 {
+		tagFilter, err := mapper.NewTagFilter(tagRules, cacheSize)
+		if err != nil {
+			log.Warnf("Could not create tag filter: %v", err)
+		} else {
+			s.tagFilter = tagFilter
+		}
+	}
 	return s, nil
 }
 
@@ -463,8 +515,15 @@ func (s *Server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 	if err != nil {
 		dogstatsdMetricParseErrors.Add(1)
 		tlmProcessed.IncWithTags(tlmProcessedErrorTags)
+		parseErrorsStats.parseErrorFrom(origin, "metrics", err, parseErrorInvalidFormat)
 		return metricSamples, err
 	}
+	if sample.ts != 0 && !s.isTimestampAccepted(sample) {
+		if len(sample.values) > 0 {
+			s.sharedFloat64List.put(sample.values)
+		}
+		return metricSamples, nil
+	}
 	if s.mapper != nil {
 		mapResult := s.mapper.Map(sample.name)
 		if mapResult != nil {
@@ -473,7 +532,19 @@ func (s *Server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 			sample.tags = append(sample.tags, mapResult.Tags...)
 		}
 	}
-	metricSamples = enrichMetricSample(metricSamples, sample, s.metricPrefix, s.metricPrefixBlacklist, s.defaultHostname, origin, s.entityIDPrecedenceEnabled, s.ServerlessMode)
+	if s.strictValidation {
+		if err := validateMetricName(sample.name); err != nil {
+			return s.rejectMetricSample(metricSamples, sample, origin, err)
+		}
+	}
+	enriched := len(metricSamples)
+	metricSamples = enrichMetricSample(metricSamples, sample, s.metricPrefix, s.metricPrefixBlacklist, s.tagFilter, s.defaultHostname, origin, s.entityIDPrecedenceEnabled, s.ServerlessMode)
+	// the tags are validated once filtered by the tag rules, all the samples of the message share them
+	if s.strictValidation && len(metricSamples) > enriched {
+		if err := validateMetricTags(sample.name, metricSamples[enriched].Tags); err != nil {
+			return s.rejectMetricSample(metricSamples[:enriched], sample, origin, err)
+		}
+	}
 
 	if len(sample.values) > 0 {
 		s.sharedFloat64List.put(sample.values)
@@ -493,11 +564,42 @@ func (s *Server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 	return metricSamples, nil
 }
 
+// rejectMetricSample counts a metric sample failing the strict validation as a parse error
+func (s *Server) rejectMetricSample(metricSamples []metrics.MetricSample, sample dogstatsdMetricSample, origin string, err error) ([]metrics.MetricSample, error) {
+	if len(sample.values) > 0 {
+		s.sharedFloat64List.put(sample.values)
+	}
+	dogstatsdMetricParseErrors.Add(1)
+	tlmProcessed.IncWithTags(tlmProcessedErrorTags)
+	parseErrorsStats.parseErrorFrom(origin, "metrics", err, parseErrorInvalidFormat)
+	return metricSamples, err
+}
+
+// isTimestampAccepted returns true if the timestamp of the sample is in the accepted window,
+// the samples which are too late or too far in the future are counted and should be dropped.
+func (s *Server) isTimestampAccepted(sample dogstatsdMetricSample) bool {
+	now := time.Now().Unix()
+	switch {
+	case sample.ts < now-s.timestampMaxAge:
+		log.Debugf("Dogstatsd: dropping sample of %s, its timestamp %d is older than %d seconds", sample.name, sample.ts, s.timestampMaxAge)
+		dogstatsdMetricLateTimestamps.Add(1)
+		tlmRejectedTimestamps.Inc("late")
+		return false
+	case sample.ts > now+s.timestampMaxFuture:
+		log.Debugf("Dogstatsd: dropping sample of %s, its timestamp %d is more than %d seconds in the future", sample.name, sample.ts, s.timestampMaxFuture)
+		dogstatsdMetricFutureTimestamps.Add(1)
+		tlmRejectedTimestamps.Inc("future")
+		return false
+	}
+	return true
+}
+
 func (s *Server) parseEventMessage(parser *parser, message []byte, origin string) (*metrics.Event, error) {
 	sample, err := parser.parseEvent(message)
 	if err != nil {
 		dogstatsdEventParseErrors.Add(1)
 		tlmProcessed.Inc("events", "error")
+		parseErrorsStats.parseErrorFrom(origin, "events", err, parseErrorInvalidEvent)
 		return nil, err
 	}
 	event := enrichEvent(sample, s.defaultHostname, origin, s.entityIDPrecedenceEnabled)
@@ -512,6 +614,7 @@ func (s *Server) parseServiceCheckMessage(parser *parser, message []byte, origin
 	if err != nil {
 		dogstatsdServiceCheckParseErrors.Add(1)
 		tlmProcessed.Inc("service_checks", "error")
+		parseErrorsStats.parseErrorFrom(origin, "service_checks", err, parseErrorInvalidServiceCheck)
 		return nil, err
 	}
 	serviceCheck := enrichServiceCheck(sample, s.defaultHostname, origin, s.entityIDPrecedenceEnabled)
@@ -527,6 +630,9 @@ func (s *Server) Stop() {
 	for _, l := range s.listeners {
 		l.Stop()
 	}
+	if s.TCapture != nil {
+		s.TCapture.Stop()
+	}
 	if s.Statistics != nil {
 		s.Statistics.Stop()
 	}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
@@ -414,6 +415,52 @@ func TestHistToDist(t *testing.T) {
 	}
 }
 
+func TestUDPReceiveWithTimestamp(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+
+	agg := mockAggregator()
+	metricOut, _, _ := agg.GetBufferedChannels()
+	metricWithTsOut := agg.GetBufferedMetricsWithTsChannel()
+	s, err := NewServer(agg, nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	url := fmt.Sprintf("127.0.0.1:%d", config.Datadog.GetInt("dogstatsd_port"))
+	conn, err := net.Dial("udp", url)
+	require.NoError(t, err, "cannot connect to DSD socket")
+	defer conn.Close()
+
+	ts := time.Now().Unix() - 30
+	conn.Write([]byte(fmt.Sprintf("daemon:666|g|#sometag1:somevalue1|T%d", ts)))
+	select {
+	case res := <-metricWithTsOut:
+		require.Len(t, res, 1)
+		assert.Equal(t, "daemon", res[0].Name)
+		assert.EqualValues(t, 666.0, res[0].Value)
+		assert.Equal(t, metrics.GaugeType, res[0].Mtype)
+		assert.Equal(t, float64(ts)*float64(time.Second), res[0].Timestamp)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+
+	// the samples out of the accepted window are dropped
+	lateTimestamps := dogstatsdMetricLateTimestamps.Value()
+	futureTimestamps := dogstatsdMetricFutureTimestamps.Value()
+	conn.Write([]byte(fmt.Sprintf("daemon:666|c|T%d\ndaemon:666|c|T%d\ndaemon:666|c", time.Now().Unix()-3600, time.Now().Unix()+3600)))
+	select {
+	case res := <-metricOut:
+		require.Len(t, res, 1)
+		assert.Equal(t, 0.0, res[0].Timestamp)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+	assert.Equal(t, lateTimestamps+1, dogstatsdMetricLateTimestamps.Value())
+	assert.Equal(t, futureTimestamps+1, dogstatsdMetricFutureTimestamps.Value())
+	assert.Len(t, metricWithTsOut, 0)
+}
+
 func TestScanLines(t *testing.T) {
 
 	messages := []string{"foo", "bar", "baz", "quz", "hax", ""}
@@ -851,3 +898,73 @@ func TestNewServerExtraTags(t *testing.T) {
 	require.Equal(s.extraTags[1], "new:constructor", "the tag new:constructor should be set")
 	s.Stop()
 }
+
+func TestStrictValidation(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+	config.Datadog.Set("dogstatsd_strict_validation", true)
+	defer config.Datadog.Set("dogstatsd_strict_validation", false)
+	parseErrorsStats = newParseErrorsStatsTracker()
+
+	s, err := NewServer(mockAggregator(), nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	parser := newParser(newFloat64ListPool())
+	samples, err := s.parseMetricMessage(nil, parser, []byte("test.metric:666|g|#env:prod,service:web"), "container_id://abc")
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1)
+
+	_, err = s.parseMetricMessage(nil, parser, []byte("test-metric:666|g"), "container_id://abc")
+	assert.Error(t, err)
+	_, err = s.parseMetricMessage(nil, parser, []byte("test.metric:666:667|g|#env:prod,with space"), "container_id://abc")
+	assert.Error(t, err)
+	_, err = s.parseMetricMessage(nil, parser, []byte("test.metric:abc|g"), "container_id://def")
+	assert.Error(t, err)
+	_, err = s.parseServiceCheckMessage(parser, []byte("_sc|agent.up|5"), "")
+	assert.Error(t, err)
+
+	stats := GetParseErrorsStats()
+	assert.Equal(t, uint64(4), stats.Errors)
+	assert.Equal(t, map[string]uint64{
+		parseErrorInvalidName:         1,
+		parseErrorInvalidTag:          1,
+		parseErrorInvalidValue:        1,
+		parseErrorInvalidServiceCheck: 1,
+	}, stats.ByReason)
+	require.Len(t, stats.TopOrigins, 3)
+	assert.Equal(t, "container_id://abc", stats.TopOrigins[0].Origin)
+	assert.Equal(t, uint64(2), stats.TopOrigins[0].Errors)
+	assert.Equal(t, map[string]uint64{parseErrorInvalidName: 1, parseErrorInvalidTag: 1}, stats.TopOrigins[0].ByReason)
+	assert.Contains(t, stats.TopOrigins[0].LastError, "with space")
+}
+
+func TestStrictValidationAfterTagFilter(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+	config.Datadog.Set("dogstatsd_strict_validation", true)
+	defer config.Datadog.Set("dogstatsd_strict_validation", false)
+	parseErrorsStats = newParseErrorsStatsTracker()
+
+	s, err := NewServer(mockAggregator(), nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+	s.tagFilter, err = mapper.NewTagFilter([]config.TagRule{
+		{Match: "test.metric", DropTags: []string{"request id"}},
+	}, 10)
+	require.NoError(t, err)
+
+	// the invalid tag is dropped by the tag rules before the validation
+	parser := newParser(newFloat64ListPool())
+	samples, err := s.parseMetricMessage(nil, parser, []byte("test.metric:666:667|g|#env:prod,request id:1234"), "")
+	assert.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, []string{"env:prod"}, samples[0].Tags)
+
+	samples, err = s.parseMetricMessage(samples, parser, []byte("other.metric:666:667|g|#env:prod,request id:1234"), "")
+	assert.Error(t, err)
+	assert.Len(t, samples, 2)
+	assert.Equal(t, map[string]uint64{parseErrorInvalidTag: 1}, GetParseErrorsStats().ByReason)
+}
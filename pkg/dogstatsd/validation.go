@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maximum length of the metric names and of the tags accepted by the strict validation
+	maxMetricNameLength = 200
+	maxTagLength        = 200
+)
+
+// validateMetricName returns an error if the name of the metric doesn't follow the Datadog
+// naming rules, it would otherwise be normalized by the intake.
+func validateMetricName(name string) error {
+	if !isValidMetricName(name) {
+		return newParseError(parseErrorInvalidName, "invalid metric name %q: it must start with a letter, contain only ASCII alphanumerics, underscores and periods and be at most %d characters long", name, maxMetricNameLength)
+	}
+	return nil
+}
+
+// validateMetricTags returns an error if a tag of the metric doesn't follow the Datadog
+// naming rules. The tags are validated once filtered, the ones dropped by the tag rules
+// never reach the intake.
+func validateMetricTags(name string, tags []string) error {
+	for _, tag := range tags {
+		if !isValidTag(tag) {
+			return newParseError(parseErrorInvalidTag, "invalid tag %q of %s: it must start with a letter, contain only letters, digits, underscores, minuses, colons, periods and slashes and be at most %d characters long", tag, name, maxTagLength)
+		}
+	}
+	return nil
+}
+
+// isValidMetricName returns true if the name starts with an ASCII letter and only contains
+// ASCII alphanumerics, underscores and periods
+func isValidMetricName(name string) bool {
+	if len(name) == 0 || len(name) > maxMetricNameLength || !isASCIILetter(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if !isASCIILetter(c) && !('0' <= c && c <= '9') && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidTag returns true if the tag starts with a letter and only contains letters, digits,
+// underscores, minuses, colons, periods and slashes
+func isValidTag(tag string) bool {
+	if len(tag) == 0 || utf8.RuneCountInString(tag) > maxTagLength {
+		return false
+	}
+	for i, r := range tag {
+		switch {
+		case unicode.IsLetter(r):
+		case i == 0:
+			return false
+		case unicode.IsDigit(r), r == '_', r == '-', r == ':', r == '.', r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidMetricName(t *testing.T) {
+	for _, name := range []string{"a", "my.metric_name", "My.Metric2", strings.Repeat("a", maxMetricNameLength)} {
+		assert.True(t, isValidMetricName(name), name)
+	}
+	for _, name := range []string{"", "2metric", "_metric", "my-metric", "my metric", "my.métric", strings.Repeat("a", maxMetricNameLength+1)} {
+		assert.False(t, isValidMetricName(name), name)
+	}
+}
+
+func TestIsValidTag(t *testing.T) {
+	for _, tag := range []string{"a", "env:prod", "image:datadog/agent:7.25", "région:île-de-france", "path:/var/log", strings.Repeat("é", maxTagLength)} {
+		assert.True(t, isValidTag(tag), tag)
+	}
+	for _, tag := range []string{"", "1tag", ":value", "env:prod!", "with space", "env:a,b", strings.Repeat("a", maxTagLength+1)} {
+		assert.False(t, isValidTag(tag), tag)
+	}
+}
+
+func TestValidateMetric(t *testing.T) {
+	assert.NoError(t, validateMetricName("my.metric"))
+	assert.NoError(t, validateMetricTags("my.metric", []string{"env:prod"}))
+
+	err := validateMetricName("my-metric")
+	assert.Equal(t, parseErrorInvalidName, parseErrorReason(err, ""))
+	err = validateMetricTags("my.metric", []string{"env:prod", "env prod"})
+	assert.Equal(t, parseErrorInvalidTag, parseErrorReason(err, ""))
+}
@@ -0,0 +1,346 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"errors"
+	"expvar"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+
+	// concurrencyDecreaseFactor is applied to the limit on every congestion signal
+	concurrencyDecreaseFactor = 0.5
+	// concurrencyDecreaseCooldown avoids collapsing the limit when the requests in flight
+	// fail together, a single decrease is applied for a congestion event
+	concurrencyDecreaseCooldown = time.Second
+	// latencyEWMAWeight is the weight of the last request in the average latency
+	latencyEWMAWeight = 0.2
+)
+
+var concurrencyLimiters = struct {
+	sync.Mutex
+	byDomain map[string]*concurrencyLimiter
+}{byDomain: make(map[string]*concurrencyLimiter)}
+
+func init() {
+	forwarderExpvars.Set("DomainConcurrency", expvar.Func(func() interface{} {
+		return getConcurrencyLimitersStats()
+	}))
+}
+
+// httpError is returned by a transaction when the intake answered with an error
+// status code, the worker uses it to adapt the concurrency of the domain.
+type httpError struct {
+	statusCode int
+	// retryAfter is the delay requested by the `Retry-After` header, 0 if it is missing
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// parseRetryAfter returns the delay of a `Retry-After` header, given either in seconds
+// or as an HTTP date, 0 if it is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// concurrencyLimiter limits the number of transactions sent concurrently to a domain.
+// When adaptive, the limit follows an AIMD policy: it grows by one for every window of
+// successful requests and is halved when the intake is slow or answers with a 429 or
+// a 5xx. It also acts as a circuit breaker honoring the `Retry-After` headers: no request
+// is sent to the domain until the delay expires, then a single one is sent to probe it.
+type concurrencyLimiter struct {
+	domain           string
+	adaptive         bool
+	minLimit         float64
+	maxLimit         float64
+	latencyThreshold time.Duration
+	maxRetryAfter    time.Duration
+
+	m            sync.Mutex
+	limit        float64
+	inFlight     int
+	openUntil    time.Time
+	halfOpen     bool
+	probing      bool
+	lastDecrease time.Time
+	latencyEWMA  time.Duration
+	decreases    int64
+	retryAfters  int64
+	// wake is closed to wake up the workers waiting for a slot
+	wake chan struct{}
+}
+
+func newConcurrencyLimiter(domain string, numberOfWorkers int) *concurrencyLimiter {
+	initialLimit := math.Max(1, float64(numberOfWorkers))
+	maxLimit := initialLimit
+
+	adaptive := config.Datadog.GetBool("forwarder_adaptive_concurrency")
+	if adaptive {
+		maxWorkers := config.Datadog.GetInt("forwarder_adaptive_concurrency_max_workers")
+		if maxWorkers < numberOfWorkers {
+			log.Warnf("Configured forwarder_adaptive_concurrency_max_workers (%v) is less than forwarder_num_workers; %v will be used", maxWorkers, numberOfWorkers)
+			maxWorkers = numberOfWorkers
+		}
+		maxLimit = math.Max(1, float64(maxWorkers))
+	}
+
+	latencyThreshold := config.Datadog.GetDuration("forwarder_adaptive_concurrency_latency_threshold") * time.Second
+	if latencyThreshold <= 0 {
+		log.Warnf("Configured forwarder_adaptive_concurrency_latency_threshold (%v) is not positive; 5 seconds will be used", latencyThreshold)
+		latencyThreshold = 5 * time.Second
+	}
+
+	return &concurrencyLimiter{
+		domain:           domain,
+		adaptive:         adaptive,
+		minLimit:         1,
+		maxLimit:         maxLimit,
+		latencyThreshold: latencyThreshold,
+		maxRetryAfter:    time.Duration(config.Datadog.GetFloat64("forwarder_backoff_max") * secondsFloat),
+		limit:            initialLimit,
+		wake:             make(chan struct{}),
+	}
+}
+
+// maxConcurrency returns the number of workers needed to reach the maximum limit
+func (l *concurrencyLimiter) maxConcurrency() int {
+	return int(l.maxLimit)
+}
+
+// acquire waits for a slot to send a transaction, ok is false if stop was signaled
+// meanwhile. probe is true for the single request sent to the domain once the delay
+// requested by the intake expired, no other slot is acquired until the probe is released.
+func (l *concurrencyLimiter) acquire(stop <-chan struct{}) (probe bool, ok bool) {
+	for {
+		l.m.Lock()
+		wait := time.Until(l.openUntil)
+		if wait <= 0 && !l.probing && l.inFlight < l.currentLimit() {
+			l.inFlight++
+			l.probing = l.halfOpen
+			probe = l.probing
+			l.m.Unlock()
+			return probe, true
+		}
+		wake := l.wake
+		l.m.Unlock()
+
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-wake:
+		case <-timeout:
+		case <-stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return false, false
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// release frees a slot acquired with acquire
+func (l *concurrencyLimiter) release() {
+	l.m.Lock()
+	l.inFlight--
+	if l.inFlight == 0 {
+		// the probe is the only request in flight
+		l.probing = false
+	}
+	l.wakeUp()
+	l.m.Unlock()
+}
+
+// observe adapts the limit to the outcome of a request sent to the domain
+func (l *concurrencyLimiter) observe(latency time.Duration, err error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.latencyEWMA == 0 {
+		l.latencyEWMA = latency
+	} else {
+		l.latencyEWMA = time.Duration(latencyEWMAWeight*float64(latency) + (1-latencyEWMAWeight)*float64(l.latencyEWMA))
+	}
+
+	var httpErr *httpError
+	isHTTPError := errors.As(err, &httpErr)
+	if isHTTPError && httpErr.retryAfter > 0 && (httpErr.statusCode == http.StatusTooManyRequests || httpErr.statusCode == http.StatusServiceUnavailable) {
+		l.open(httpErr.retryAfter)
+		return
+	}
+
+	congested := err != nil || latency > l.latencyThreshold
+	if isHTTPError && httpErr.statusCode != http.StatusTooManyRequests && httpErr.statusCode < 500 {
+		// the other errors are not related to the load of the intake
+		congested = false
+	}
+
+	if err == nil {
+		// the domain answered, it can be sent more than a probe
+		l.halfOpen = false
+	}
+	if congested {
+		l.decrease()
+	} else if err == nil && l.adaptive {
+		// grow by one once every `limit` successful requests
+		l.limit = math.Min(l.maxLimit, l.limit+1/l.limit)
+	}
+	l.wakeUp()
+}
+
+// open stops sending requests to the domain for the given delay, capped by `forwarder_backoff_max`
+func (l *concurrencyLimiter) open(retryAfter time.Duration) {
+	if retryAfter > l.maxRetryAfter {
+		retryAfter = l.maxRetryAfter
+	}
+	until := time.Now().Add(retryAfter)
+	if until.After(l.openUntil) {
+		log.Warnf("The intake of %s requested to retry in %s, pausing the requests to this domain", l.domain, retryAfter)
+		l.openUntil = until
+	}
+	l.retryAfters++
+	l.halfOpen = true
+}
+
+func (l *concurrencyLimiter) decrease() {
+	if !l.adaptive {
+		return
+	}
+	now := time.Now()
+	if now.Sub(l.lastDecrease) < concurrencyDecreaseCooldown {
+		return
+	}
+	l.lastDecrease = now
+	l.limit = math.Max(l.minLimit, l.limit*concurrencyDecreaseFactor)
+	l.decreases++
+	log.Debugf("Decreasing the concurrency limit of %s to %d", l.domain, int(l.limit))
+}
+
+// currentLimit returns the number of requests which can be in flight, a single one
+// is sent while the circuit is half-open.
+func (l *concurrencyLimiter) currentLimit() int {
+	if l.halfOpen {
+		return int(l.minLimit)
+	}
+	return int(l.limit)
+}
+
+// canSend returns false while the requests to the domain are paused, and while the
+// circuit is half-open for the requests other than the probe.
+func (l *concurrencyLimiter) canSend(probe bool) bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if time.Now().Before(l.openUntil) {
+		return false
+	}
+	return probe || !l.halfOpen
+}
+
+// isOpen returns true while the requests to the domain are paused
+func (l *concurrencyLimiter) isOpen() bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return time.Now().Before(l.openUntil)
+}
+
+func (l *concurrencyLimiter) wakeUp() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// concurrencyLimiterStats is the state of a concurrency limiter reported in the forwarder expvars
+type concurrencyLimiterStats struct {
+	State          string
+	Adaptive       bool
+	Limit          int
+	MaxLimit       int
+	InFlight       int
+	LatencyMs      int64
+	Decreases      int64
+	RetryAfters    int64
+	RetryAfterLeft string `json:",omitempty"`
+}
+
+func (l *concurrencyLimiter) stats() concurrencyLimiterStats {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	stats := concurrencyLimiterStats{
+		State:       circuitClosed,
+		Adaptive:    l.adaptive,
+		Limit:       l.currentLimit(),
+		MaxLimit:    int(l.maxLimit),
+		InFlight:    l.inFlight,
+		LatencyMs:   int64(l.latencyEWMA / time.Millisecond),
+		Decreases:   l.decreases,
+		RetryAfters: l.retryAfters,
+	}
+	if wait := time.Until(l.openUntil); wait > 0 {
+		stats.State = circuitOpen
+		stats.RetryAfterLeft = wait.Round(time.Second).String()
+	} else if l.halfOpen {
+		stats.State = circuitHalfOpen
+	}
+	return stats
+}
+
+func registerConcurrencyLimiter(l *concurrencyLimiter) {
+	concurrencyLimiters.Lock()
+	defer concurrencyLimiters.Unlock()
+	concurrencyLimiters.byDomain[l.domain] = l
+}
+
+func unregisterConcurrencyLimiter(l *concurrencyLimiter) {
+	concurrencyLimiters.Lock()
+	defer concurrencyLimiters.Unlock()
+	if concurrencyLimiters.byDomain[l.domain] == l {
+		delete(concurrencyLimiters.byDomain, l.domain)
+	}
+}
+
+func getConcurrencyLimitersStats() map[string]concurrencyLimiterStats {
+	concurrencyLimiters.Lock()
+	defer concurrencyLimiters.Unlock()
+	stats := make(map[string]concurrencyLimiterStats, len(concurrencyLimiters.byDomain))
+	for domain, l := range concurrencyLimiters.byDomain {
+		stats[domain] = l.stats()
+	}
+	return stats
+}
@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func newAdaptiveConcurrencyLimiterForTest(t *testing.T, numberOfWorkers, maxWorkers int) *concurrencyLimiter {
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_adaptive_concurrency", true)
+	mockConfig.Set("forwarder_adaptive_concurrency_max_workers", maxWorkers)
+	t.Cleanup(func() {
+		mockConfig.Set("forwarder_adaptive_concurrency", false)
+		mockConfig.Set("forwarder_adaptive_concurrency_max_workers", 10)
+	})
+	return newConcurrencyLimiter("test", numberOfWorkers)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+
+	date := now.Add(time.Minute).UTC().Format(http.TimeFormat)
+	assert.InDelta(t, float64(time.Minute), float64(parseRetryAfter(date, now)), float64(time.Second))
+	date = now.Add(-time.Minute).UTC().Format(http.TimeFormat)
+	assert.Equal(t, time.Duration(0), parseRetryAfter(date, now))
+}
+
+func TestConcurrencyLimiterDefault(t *testing.T) {
+	l := newConcurrencyLimiter("test", 2)
+	assert.False(t, l.adaptive)
+	assert.Equal(t, 2, l.maxConcurrency())
+
+	// the limit is fixed when the concurrency is not adaptive
+	l.observe(10*time.Millisecond, nil)
+	l.observe(time.Minute, errors.New("timeout"))
+	assert.Equal(t, 2, l.currentLimit())
+}
+
+func TestConcurrencyLimiterAIMD(t *testing.T) {
+	l := newAdaptiveConcurrencyLimiterForTest(t, 1, 4)
+	assert.Equal(t, 4, l.maxConcurrency())
+	assert.Equal(t, 1, l.currentLimit())
+
+	for i := 0; i < 20; i++ {
+		l.observe(10*time.Millisecond, nil)
+	}
+	assert.Equal(t, 4, l.currentLimit())
+
+	// a single decrease is applied for concurrent errors
+	l.observe(10*time.Millisecond, &httpError{statusCode: http.StatusInternalServerError, err: errors.New("500")})
+	l.observe(10*time.Millisecond, &httpError{statusCode: http.StatusTooManyRequests, err: errors.New("429")})
+	assert.Equal(t, 2, l.currentLimit())
+	assert.Equal(t, int64(1), l.stats().Decreases)
+
+	// slow requests are a congestion signal
+	l.lastDecrease = time.Time{}
+	l.observe(time.Minute, nil)
+	assert.Equal(t, 1, l.currentLimit())
+
+	// the errors unrelated to the load are ignored
+	l.lastDecrease = time.Time{}
+	l.observe(10*time.Millisecond, &httpError{statusCode: http.StatusUnauthorized, err: errors.New("401")})
+	assert.Equal(t, 1, l.currentLimit())
+}
+
+func TestConcurrencyLimiterRetryAfter(t *testing.T) {
+	l := newAdaptiveConcurrencyLimiterForTest(t, 2, 4)
+	stop := make(chan struct{})
+
+	l.observe(10*time.Millisecond, &httpError{statusCode: http.StatusTooManyRequests, retryAfter: 200 * time.Millisecond, err: errors.New("429")})
+	assert.True(t, l.isOpen())
+	assert.Equal(t, circuitOpen, l.stats().State)
+	assert.Equal(t, int64(1), l.stats().RetryAfters)
+
+	start := time.Now()
+	probe, ok := l.acquire(stop)
+	require.True(t, ok)
+	assert.True(t, probe)
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+	assert.False(t, l.isOpen())
+
+	// a single request probes the domain
+	assert.Equal(t, circuitHalfOpen, l.stats().State)
+	assert.Equal(t, 1, l.currentLimit())
+	acquired := make(chan bool)
+	go func() {
+		probe, ok := l.acquire(stop)
+		acquired <- ok && !probe
+	}()
+
+	l.observe(10*time.Millisecond, nil)
+	l.release()
+	require.True(t, <-acquired)
+	assert.Equal(t, circuitClosed, l.stats().State)
+	assert.Equal(t, 2, l.currentLimit())
+	l.release()
+}
+
+func TestConcurrencyLimiterSingleProbe(t *testing.T) {
+	l := newConcurrencyLimiter("test", 2)
+	stop := make(chan struct{})
+
+	// a slot acquired before the domain asked to retry later
+	probe, ok := l.acquire(stop)
+	require.True(t, ok)
+	require.False(t, probe)
+
+	l.observe(10*time.Millisecond, &httpError{statusCode: http.StatusServiceUnavailable, retryAfter: 100 * time.Millisecond, err: errors.New("503")})
+	assert.False(t, l.canSend(probe))
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, circuitHalfOpen, l.stats().State)
+	// only the probe can be sent once the delay expired
+	assert.False(t, l.canSend(probe))
+
+	acquired := make(chan bool)
+	go func() {
+		probe, ok := l.acquire(stop)
+		acquired <- ok && probe
+	}()
+	select {
+	case <-acquired:
+		assert.Fail(t, "the probe must wait for the requests in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	l.release()
+	require.True(t, <-acquired)
+	assert.True(t, l.canSend(true))
+
+	// no other request is sent while the probe is in flight
+	go func() {
+		_, ok := l.acquire(stop)
+		acquired <- ok
+	}()
+	select {
+	case <-acquired:
+		assert.Fail(t, "a single probe can be in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	stop <- struct{}{}
+	assert.False(t, <-acquired)
+	assert.Equal(t, 1, l.stats().InFlight)
+}
+
+func TestConcurrencyLimiterRetryAfterCapped(t *testing.T) {
+	l := newConcurrencyLimiter("test", 1)
+	l.observe(10*time.Millisecond, &httpError{statusCode: http.StatusServiceUnavailable, retryAfter: 24 * time.Hour, err: errors.New("503")})
+	assert.True(t, l.openUntil.Before(time.Now().Add(l.maxRetryAfter+time.Second)))
+}
+
+func TestConcurrencyLimiterAcquireStop(t *testing.T) {
+	l := newConcurrencyLimiter("test", 1)
+	stop := make(chan struct{})
+
+	_, ok := l.acquire(stop)
+	require.True(t, ok)
+	acquired := make(chan bool)
+	go func() {
+		_, ok := l.acquire(stop)
+		acquired <- ok
+	}()
+
+	stop <- struct{}{}
+	assert.False(t, <-acquired)
+	assert.Equal(t, 1, l.stats().InFlight)
+}
+
+func TestConcurrencyLimitersStats(t *testing.T) {
+	l := newConcurrencyLimiter("stats_domain", 3)
+	registerConcurrencyLimiter(l)
+	stats, found := getConcurrencyLimitersStats()["stats_domain"]
+	require.True(t, found)
+	assert.Equal(t, concurrencyLimiterStats{State: circuitClosed, Limit: 3, MaxLimit: 3}, stats)
+
+	unregisterConcurrencyLimiter(l)
+	_, found = getConcurrencyLimitersStats()["stats_domain"]
+	assert.False(t, found)
+}
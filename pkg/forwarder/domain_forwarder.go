@@ -38,6 +38,7 @@ type domainForwarder struct {
 	m                         sync.Mutex // To control Start/Stop races
 	transactionPrioritySorter transactionPrioritySorter
 	blockedList               *blockedEndpoints
+	limiter                   *concurrencyLimiter
 }
 
 func newDomainForwarder(
@@ -53,6 +54,7 @@ func newDomainForwarder(
 		connectionResetInterval:   connectionResetInterval,
 		internalState:             Stopped,
 		blockedList:               newBlockedEndpoints(),
+		limiter:                   newConcurrencyLimiter(domain, numberOfWorkers),
 		transactionPrioritySorter: transactionPrioritySorter,
 	}
 }
@@ -79,9 +81,12 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 
 	f.transactionPrioritySorter.Sort(transactions)
 
+	// The transactions are kept in the retry queue while the domain asked to retry later
+	paused := f.limiter.isOpen()
+
 	for _, t := range transactions {
 		transactionEndpointName := t.GetEndpointName()
-		if !f.blockedList.isBlock(t.GetTarget()) {
+		if !paused && !f.blockedList.isBlock(t.GetTarget()) {
 			select {
 			case f.lowPrio <- t:
 				transactionsRetriedByEndpoint.Add(transactionEndpointName, 1)
@@ -189,11 +194,18 @@ func (f *domainForwarder) Start() error {
 	// reset internal state to purge transactions from past starts
 	f.init()
 
-	for i := 0; i < f.numberOfWorkers; i++ {
-		w := NewWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList)
+	// When the concurrency is adaptive, the workers above the current limit of
+	// the domain wait for it to increase.
+	workerCount := f.numberOfWorkers
+	if f.numberOfWorkers > 0 && f.limiter.maxConcurrency() > workerCount {
+		workerCount = f.limiter.maxConcurrency()
+	}
+	for i := 0; i < workerCount; i++ {
+		w := NewWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList, f.limiter)
 		w.Start()
 		f.workers = append(f.workers, w)
 	}
+	registerConcurrencyLimiter(f.limiter)
 	go f.handleFailedTransactions()
 	if f.connectionResetInterval != 0 {
 		go f.scheduleConnectionResets()
@@ -225,6 +237,7 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 	close(f.highPrio)
 	close(f.lowPrio)
 	close(f.requeuedTransaction)
+	unregisterConcurrencyLimiter(f.limiter)
 	log.Info("domainForwarder stopped")
 	f.internalState = Stopped
 }
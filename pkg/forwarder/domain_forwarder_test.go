@@ -143,6 +143,29 @@ func TestRetryTransactions(t *testing.T) {
 	assert.Equal(t, int64(1), transactionsDropped.Value())
 }
 
+func TestRetryTransactionsRetryAfter(t *testing.T) {
+	forwarder := newDomainForwarderForTest(0)
+	forwarder.init()
+
+	payload := []byte{1}
+	tr := NewHTTPTransaction()
+	tr.Domain = "domain/"
+	tr.Endpoint.route = "test"
+	tr.Payload = &payload
+	forwarder.requeueTransaction(tr)
+
+	// the transactions are kept in the retry queue while the domain asked to retry later
+	forwarder.limiter.openUntil = time.Now().Add(1 * time.Hour)
+	forwarder.retryTransactions(time.Now())
+	requireLenForwarderRetryQueue(t, forwarder, 1)
+	assert.Len(t, forwarder.lowPrio, 0)
+
+	forwarder.limiter.openUntil = time.Now().Add(-1 * time.Hour)
+	forwarder.retryTransactions(time.Now())
+	requireLenForwarderRetryQueue(t, forwarder, 0)
+	assert.Len(t, forwarder.lowPrio, 1)
+}
+
 func TestForwarderRetry(t *testing.T) {
 	forwarder := newDomainForwarderForTest(0)
 	forwarder.Start()
@@ -154,7 +154,9 @@ func (p *failedTransactionRemovalPolicy) getRetryFiles(folder string) ([]string,
 	}
 	var files []string
 	for _, entry := range entries {
-		if entry.Mode().IsRegular() && filepath.Ext(entry.Name()) == retryTransactionsExtension {
+		// The quarantined files are also removed once outdated
+		ext := filepath.Ext(entry.Name())
+		if entry.Mode().IsRegular() && (ext == retryTransactionsExtension || ext == quarantinedTransactionsExtension) {
 			files = append(files, path.Join(folder, entry.Name()))
 		}
 	}
@@ -0,0 +1,405 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	agentpayload "github.com/DataDog/agent-payload/gogen"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// PayloadDecoder decodes a payload to a value which can be marshaled to JSON
+type PayloadDecoder func(payload []byte) (interface{}, error)
+
+// payloadLine is a payload written by the FileForwarder, one per line
+type payloadLine struct {
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Route     string `json:"route"`
+	// Payload is the decoded payload, it is missing if it cannot be decoded
+	Payload interface{} `json:"payload,omitempty"`
+	// Raw is the payload encoded in base64 when it cannot be decoded
+	Raw   []byte `json:"raw,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// FileForwarder is a Forwarder writing the payloads as JSON lines to a local file or
+// to the standard output instead of sending them to the intake, for the environments
+// without access to the intake and for the tests.
+type FileForwarder struct {
+	m      sync.Mutex
+	output io.Writer
+	// closer is nil when the payloads are written to the standard output
+	closer io.Closer
+
+	processPayloadDecoder PayloadDecoder
+}
+
+// Compile-time check to ensure that FileForwarder implements the Forwarder interface
+var _ Forwarder = &FileForwarder{}
+
+// stdout is shared by the file forwarders writing to the standard output, so that
+// the payloads of the different forwarders of a process are not interleaved
+var stdout io.Writer = &lockedWriter{w: os.Stdout}
+
+// lockedWriter serializes the writes to w
+type lockedWriter struct {
+	m sync.Mutex
+	w io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.w.Write(p)
+}
+
+// NewForwarder returns a FileForwarder writing the payloads locally when `offline_mode.enabled`
+// is set, a DefaultForwarder sending them to the intake otherwise. name identifies the file
+// the payloads of the forwarder are written to in offline mode.
+func NewForwarder(name string, options *Options) Forwarder {
+	if !config.Datadog.GetBool("offline_mode.enabled") {
+		return NewDefaultForwarder(options)
+	}
+
+	f, err := NewFileForwarderFromConfig(name)
+	if err != nil {
+		// the payloads are never sent to the intake in offline mode, and they can't be written
+		// to the standard output where they would be mixed with the logs
+		log.Errorf("Cannot write the payloads to a file, they are dropped: %v", err)
+		return NewFileForwarder(ioutil.Discard, nil)
+	}
+	return f
+}
+
+// NewFileForwarderFromConfig returns a FileForwarder writing the payloads to the file `<name>.json`
+// of `offline_mode.output_dir`, or to the standard output if it is not set, in which case the
+// logs are not written to the console.
+func NewFileForwarderFromConfig(name string) (*FileForwarder, error) {
+	outputDir := config.Datadog.GetString("offline_mode.output_dir")
+	if outputDir == "" {
+		log.Infof("Offline mode is enabled, the payloads are written to the standard output")
+		return NewFileForwarder(stdout, nil), nil
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(outputDir, name+".json")
+	file, err := newRotatingFile(path, int64(config.Datadog.GetSizeInBytes("offline_mode.file_max_size")), config.Datadog.GetInt("offline_mode.file_max_rolls"))
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Offline mode is enabled, the payloads are written to %s", path)
+	return NewFileForwarder(file, file), nil
+}
+
+// NewFileForwarder returns a FileForwarder writing the payloads to output, closer is
+// closed when the forwarder is stopped if it is not nil.
+func NewFileForwarder(output io.Writer, closer io.Closer) *FileForwarder {
+	return &FileForwarder{
+		output: output,
+		closer: closer,
+	}
+}
+
+// SetProcessPayloadDecoder sets the decoder of the payloads of the process and orchestrator
+// checks, they are written encoded in base64 otherwise.
+func (f *FileForwarder) SetProcessPayloadDecoder(decoder PayloadDecoder) {
+	f.processPayloadDecoder = decoder
+}
+
+// Start starts the file forwarder: nothing to do.
+func (f *FileForwarder) Start() error {
+	return nil
+}
+
+// Stop stops the file forwarder and closes its file.
+func (f *FileForwarder) Stop() {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.closer != nil {
+		if err := f.closer.Close(); err != nil {
+			log.Errorf("Error closing the file of the payloads: %v", err)
+		}
+		f.closer = nil
+		f.output = nil
+	}
+}
+
+func (f *FileForwarder) writePayloads(ep endpoint, payloadType string, payloads Payloads, extra http.Header, decoder PayloadDecoder) error {
+	lines := make([]byte, 0, 4096)
+	for _, payload := range payloads {
+		line := payloadLine{
+			Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+			Type:      payloadType,
+			Route:     ep.route,
+		}
+
+		decoded, err := decompressPayload(*payload, extra)
+		if err == nil {
+			line.Payload, err = decoder(decoded)
+		}
+		if err != nil {
+			line.Raw = *payload
+			line.Error = err.Error()
+		}
+
+		data, err := json.Marshal(line)
+		if err != nil {
+			return fmt.Errorf("cannot marshal a %s payload: %v", payloadType, err)
+		}
+		lines = append(append(lines, data...), '\n')
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.output == nil {
+		return errors.New("the file forwarder is stopped")
+	}
+	_, err := f.output.Write(lines)
+	return err
+}
+
+// decompressPayload decompresses a payload according to its `Content-Encoding` header
+func decompressPayload(payload []byte, extra http.Header) ([]byte, error) {
+	encoding := extra.Get("Content-Encoding")
+	strategy, ok := compression.StrategyForContentEncoding(encoding)
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	return strategy.Decompress(nil, payload)
+}
+
+func decodeJSONPayload(payload []byte) (interface{}, error) {
+	if !json.Valid(payload) {
+		return nil, errors.New("invalid JSON payload")
+	}
+	return json.RawMessage(payload), nil
+}
+
+func protobufPayloadDecoder(newMessage func() interface{ Unmarshal([]byte) error }) PayloadDecoder {
+	return func(payload []byte) (interface{}, error) {
+		message := newMessage()
+		if err := message.Unmarshal(payload); err != nil {
+			return nil, err
+		}
+		return message, nil
+	}
+}
+
+func (f *FileForwarder) submitProcessLikePayload(ep endpoint, payloadType string, payload Payloads, extra http.Header) (chan Response, error) {
+	decoder := f.processPayloadDecoder
+	if decoder == nil {
+		decoder = func([]byte) (interface{}, error) { return nil, errors.New("no decoder for the process payloads") }
+	}
+	if err := f.writePayloads(ep, payloadType, payload, extra, decoder); err != nil {
+		return nil, err
+	}
+	// there is no response from the intake to report
+	results := make(chan Response)
+	close(results)
+	return results, nil
+}
+
+// SubmitV1Series writes timeseries payloads.
+func (f *FileForwarder) SubmitV1Series(payload Payloads, extra http.Header) error {
+	return f.writePayloads(v1SeriesEndpoint, "series_v1", payload, extra, decodeJSONPayload)
+}
+
+// SubmitV1Intake writes payloads of the universal `/intake/` endpoint.
+func (f *FileForwarder) SubmitV1Intake(payload Payloads, extra http.Header) error {
+	return f.writePayloads(v1IntakeEndpoint, "intake", payload, extra, decodeJSONPayload)
+}
+
+// SubmitV1CheckRuns writes service checks payloads.
+func (f *FileForwarder) SubmitV1CheckRuns(payload Payloads, extra http.Header) error {
+	return f.writePayloads(v1CheckRunsEndpoint, "check_run_v1", payload, extra, decodeJSONPayload)
+}
+
+// SubmitSeries writes series protobuf payloads.
+func (f *FileForwarder) SubmitSeries(payload Payloads, extra http.Header) error {
+	return f.writePayloads(seriesEndpoint, "series_v2", payload, extra, protobufPayloadDecoder(func() interface{ Unmarshal([]byte) error } {
+		return &agentpayload.MetricsPayload{}
+	}))
+}
+
+// SubmitEvents writes events protobuf payloads.
+func (f *FileForwarder) SubmitEvents(payload Payloads, extra http.Header) error {
+	return f.writePayloads(eventsEndpoint, "events_v2", payload, extra, protobufPayloadDecoder(func() interface{ Unmarshal([]byte) error } {
+		return &agentpayload.EventsPayload{}
+	}))
+}
+
+// SubmitServiceChecks writes service checks protobuf payloads.
+func (f *FileForwarder) SubmitServiceChecks(payload Payloads, extra http.Header) error {
+	return f.writePayloads(serviceChecksEndpoint, "services_checks_v2", payload, extra, protobufPayloadDecoder(func() interface{ Unmarshal([]byte) error } {
+		return &agentpayload.ServiceChecksPayload{}
+	}))
+}
+
+// SubmitSketchSeries writes sketches protobuf payloads.
+func (f *FileForwarder) SubmitSketchSeries(payload Payloads, extra http.Header) error {
+	return f.writePayloads(sketchSeriesEndpoint, "sketches_v2", payload, extra, protobufPayloadDecoder(func() interface{ Unmarshal([]byte) error } {
+		return &agentpayload.SketchPayload{}
+	}))
+}
+
+// SubmitHostMetadata writes host metadata payloads.
+func (f *FileForwarder) SubmitHostMetadata(payload Payloads, extra http.Header) error {
+	return f.writePayloads(v1IntakeEndpoint, "host_metadata", payload, extra, decodeJSONPayload)
+}
+
+// SubmitAgentChecksMetadata writes agent checks metadata payloads.
+func (f *FileForwarder) SubmitAgentChecksMetadata(payload Payloads, extra http.Header) error {
+	return f.writePayloads(v1IntakeEndpoint, "agentchecks_metadata", payload, extra, decodeJSONPayload)
+}
+
+// SubmitMetadata writes metadata payloads.
+func (f *FileForwarder) SubmitMetadata(payload Payloads, extra http.Header) error {
+	return f.writePayloads(v1IntakeEndpoint, "metadata", payload, extra, decodeJSONPayload)
+}
+
+// SubmitProcessChecks writes process checks payloads.
+func (f *FileForwarder) SubmitProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(processesEndpoint, processesEndpoint.name, payload, extra)
+}
+
+// SubmitRTProcessChecks writes real time process checks payloads.
+func (f *FileForwarder) SubmitRTProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(rtProcessesEndpoint, rtProcessesEndpoint.name, payload, extra)
+}
+
+// SubmitContainerChecks writes container checks payloads.
+func (f *FileForwarder) SubmitContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(containerEndpoint, containerEndpoint.name, payload, extra)
+}
+
+// SubmitRTContainerChecks writes real time container checks payloads.
+func (f *FileForwarder) SubmitRTContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(rtContainerEndpoint, rtContainerEndpoint.name, payload, extra)
+}
+
+// SubmitConnectionChecks writes connection checks payloads.
+func (f *FileForwarder) SubmitConnectionChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(connectionsEndpoint, connectionsEndpoint.name, payload, extra)
+}
+
+// SubmitOrchestratorChecks writes orchestrator checks payloads.
+func (f *FileForwarder) SubmitOrchestratorChecks(payload Payloads, extra http.Header, payloadType string) (chan Response, error) {
+	return f.submitProcessLikePayload(orchestratorEndpoint, orchestratorEndpoint.name+"_"+payloadType, payload, extra)
+}
+
+// rotatingFile is a file rolled over when it reaches its maximum size, the previous files
+// are kept as `<path>.1` to `<path>.<maxRolls>`, from the newest to the oldest.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxRolls int
+	file     *os.File
+	size     int64
+}
+
+// newRotatingFile opens the file at path, it is never rolled over if maxSize is 0
+func newRotatingFile(path string, maxSize int64, maxRolls int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:     path,
+		maxSize:  maxSize,
+		maxRolls: maxRolls,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write writes p to the file, rolling it over first if p doesn't fit in it. p is written
+// to the current file when it cannot be rolled over.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			log.Errorf("Cannot roll over %s: %v", r.path, err)
+		}
+	}
+	if r.file == nil {
+		// the file couldn't be reopened by the last rotation
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate rolls the file over and reopens it. If the files cannot be renamed, the current
+// file is reopened to keep appending to it. r.file is nil if the file cannot be reopened.
+func (r *rotatingFile) rotate() error {
+	// the file is closed before being renamed, which fails on Windows for an open file
+	closeErr := r.file.Close()
+	r.file = nil
+	renameErr := r.renameFiles()
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	return closeErr
+}
+
+// renameFiles shifts the previous files and renames the file to `<path>.1`, or removes
+// it if no previous file is kept
+func (r *rotatingFile) renameFiles() error {
+	if r.maxRolls <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	for i := r.maxRolls - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(r.path, r.path+".1")
+}
+
+// Close closes the file
+func (r *rotatingFile) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
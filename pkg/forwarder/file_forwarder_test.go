@@ -0,0 +1,253 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agentpayload "github.com/DataDog/agent-payload/gogen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func readPayloadLines(t *testing.T, output *bytes.Buffer) []payloadLine {
+	var lines []payloadLine
+	for _, data := range strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n") {
+		var line payloadLine
+		require.NoError(t, json.Unmarshal([]byte(data), &line))
+		lines = append(lines, line)
+	}
+	output.Reset()
+	return lines
+}
+
+func compressedPayloads(t *testing.T, payloads ...[]byte) (Payloads, http.Header) {
+	extra := make(http.Header)
+	if compression.ContentEncoding != "" {
+		extra.Set("Content-Encoding", compression.ContentEncoding)
+	}
+	var compressed Payloads
+	for _, payload := range payloads {
+		c, err := compression.Compress(nil, payload)
+		require.NoError(t, err)
+		compressed = append(compressed, &c)
+	}
+	return compressed, extra
+}
+
+func TestFileForwarderJSONPayloads(t *testing.T) {
+	output := &bytes.Buffer{}
+	f := NewFileForwarder(output, nil)
+
+	payloads, extra := compressedPayloads(t, []byte(`{"series": [{"metric": "test.metrics"}]}`), []byte(`{"series": []}`))
+	require.NoError(t, f.SubmitV1Series(payloads, extra))
+
+	lines := readPayloadLines(t, output)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "series_v1", lines[0].Type)
+	assert.Equal(t, "/api/v1/series", lines[0].Route)
+	assert.Empty(t, lines[0].Error)
+	payload, err := json.Marshal(lines[0].Payload)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"series": [{"metric": "test.metrics"}]}`, string(payload))
+
+	payloads, extra = compressedPayloads(t, []byte(`{"host": "localhost"`))
+	require.NoError(t, f.SubmitHostMetadata(payloads, extra))
+
+	lines = readPayloadLines(t, output)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "host_metadata", lines[0].Type)
+	assert.Nil(t, lines[0].Payload)
+	assert.Equal(t, *payloads[0], lines[0].Raw)
+	assert.NotEmpty(t, lines[0].Error)
+}
+
+func TestFileForwarderProtobufPayloads(t *testing.T) {
+	output := &bytes.Buffer{}
+	f := NewFileForwarder(output, nil)
+
+	series, err := (&agentpayload.MetricsPayload{
+		Samples: []*agentpayload.MetricsPayload_Sample{{
+			Metric: "test.metrics",
+			Points: []*agentpayload.MetricsPayload_Sample_Point{{Ts: 12345, Value: 1.5}},
+		}},
+	}).Marshal()
+	require.NoError(t, err)
+	payloads, extra := compressedPayloads(t, series)
+	require.NoError(t, f.SubmitSeries(payloads, extra))
+
+	lines := readPayloadLines(t, output)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "series_v2", lines[0].Type)
+	assert.Empty(t, lines[0].Error)
+	payload, err := json.Marshal(lines[0].Payload)
+	require.NoError(t, err)
+	var decoded agentpayload.MetricsPayload
+	require.NoError(t, json.Unmarshal(payload, &decoded))
+	require.Len(t, decoded.Samples, 1)
+	assert.Equal(t, "test.metrics", decoded.Samples[0].Metric)
+	assert.Equal(t, 1.5, decoded.Samples[0].Points[0].Value)
+
+	// the payloads with an unknown encoding are written as is
+	extra = http.Header{"Content-Encoding": []string{"unknown"}}
+	require.NoError(t, f.SubmitEvents(Payloads{&series}, extra))
+	lines = readPayloadLines(t, output)
+	require.Len(t, lines, 1)
+	assert.Equal(t, series, lines[0].Raw)
+	assert.Contains(t, lines[0].Error, "unsupported content encoding")
+}
+
+func TestFileForwarderProcessPayloads(t *testing.T) {
+	output := &bytes.Buffer{}
+	f := NewFileForwarder(output, nil)
+	payload := []byte("process payload")
+
+	responses, err := f.SubmitProcessChecks(Payloads{&payload}, http.Header{})
+	require.NoError(t, err)
+	_, ok := <-responses
+	assert.False(t, ok)
+	lines := readPayloadLines(t, output)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "process", lines[0].Type)
+	assert.Equal(t, payload, lines[0].Raw)
+
+	f.SetProcessPayloadDecoder(func(payload []byte) (interface{}, error) {
+		if string(payload) != "pod payload" {
+			return nil, errors.New("unexpected payload")
+		}
+		return map[string]string{"kind": "pod"}, nil
+	})
+	payload = []byte("pod payload")
+	_, err = f.SubmitOrchestratorChecks(Payloads{&payload}, http.Header{}, PayloadTypePod)
+	require.NoError(t, err)
+	lines = readPayloadLines(t, output)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "orchestrator_pod", lines[0].Type)
+	assert.Equal(t, map[string]interface{}{"kind": "pod"}, lines[0].Payload)
+	assert.Empty(t, lines[0].Error)
+}
+
+func TestNewForwarderOfflineMode(t *testing.T) {
+	mockConfig := config.Mock()
+	dir, err := ioutil.TempDir("", "offline_mode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, isDefault := NewForwarder("test", NewOptions(nil)).(*DefaultForwarder)
+	assert.True(t, isDefault)
+
+	mockConfig.Set("offline_mode.enabled", true)
+	defer mockConfig.Set("offline_mode.enabled", false)
+	mockConfig.Set("offline_mode.output_dir", filepath.Join(dir, "payloads"))
+	defer mockConfig.Set("offline_mode.output_dir", "")
+
+	f, isFile := NewForwarder("test", NewOptions(nil)).(*FileForwarder)
+	require.True(t, isFile)
+	payload := []byte(`{"metadata": {}}`)
+	require.NoError(t, f.SubmitMetadata(Payloads{&payload}, http.Header{}))
+	f.Stop()
+	assert.Error(t, f.SubmitMetadata(Payloads{&payload}, http.Header{}))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "payloads", "test.json"))
+	require.NoError(t, err)
+	lines := readPayloadLines(t, bytes.NewBuffer(content))
+	require.Len(t, lines, 1)
+	assert.Equal(t, "metadata", lines[0].Type)
+}
+
+func TestNewForwarderOfflineModeOutput(t *testing.T) {
+	mockConfig := config.Mock()
+	dir, err := ioutil.TempDir("", "offline_mode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	mockConfig.Set("offline_mode.enabled", true)
+	defer mockConfig.Set("offline_mode.enabled", false)
+
+	// the forwarders writing to the standard output share the same writer
+	core, isFile := NewForwarder("core", NewOptions(nil)).(*FileForwarder)
+	require.True(t, isFile)
+	process, isFile := NewForwarder("process", NewOptions(nil)).(*FileForwarder)
+	require.True(t, isFile)
+	assert.True(t, core.output == stdout)
+	assert.True(t, process.output == stdout)
+
+	// the payloads are dropped rather than mixed with the logs when the file can't be created
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	mockConfig.Set("offline_mode.output_dir", filepath.Join(file, "payloads"))
+	defer mockConfig.Set("offline_mode.output_dir", "")
+	f, isFile := NewForwarder("test", NewOptions(nil)).(*FileForwarder)
+	require.True(t, isFile)
+	assert.True(t, f.output == ioutil.Discard)
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline_mode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+
+	r, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	for file, expected := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+		content, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// the file is appended to when it is reopened
+	r, err = newRotatingFile(path, 0, 0)
+	require.NoError(t, err)
+	_, err = r.Write([]byte("fifth\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\nfifth\n", string(content))
+}
+
+func TestRotatingFileRenameError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline_mode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+
+	// the file cannot be renamed over a non-empty directory
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "dir"), 0755))
+
+	r, err := newRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	// the lines keep being written to the current file
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\nthird\n", string(content))
+}
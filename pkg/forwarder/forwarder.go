@@ -304,6 +304,7 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 		completionHandler: options.CompletionHandler,
 	}
 	var optionalRemovalPolicy *failedTransactionRemovalPolicy
+	var storageEncoder *transactionsFileEncoder
 	storageMaxSize := config.Datadog.GetInt64("forwarder_storage_max_size_in_bytes")
 
 	// Disk Persistence is a core-only feature for now.
@@ -314,8 +315,12 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 		outdatedFileInDays := config.Datadog.GetInt("forwarder_outdated_file_in_days")
 		var err error
 
-		optionalRemovalPolicy, err = newFailedTransactionRemovalPolicy(storagePath, outdatedFileInDays, failedTransactionRemovalPolicyTelemetry{})
+		// The transactions are never written in clear text when the encryption is configured
+		// but its key cannot be loaded.
+		storageEncoder, err = newTransactionsFileEncoderFromConfig()
 		if err != nil {
+			log.Errorf("Retry queue storage on disk is disabled. Cannot load the encryption key: %v", err)
+		} else if optionalRemovalPolicy, err = newFailedTransactionRemovalPolicy(storagePath, outdatedFileInDays, failedTransactionRemovalPolicyTelemetry{}); err != nil {
 			log.Errorf("Error when initializing the removal policy: %v", err)
 		} else {
 			filesRemoved, err := optionalRemovalPolicy.removeOutdatedFiles()
@@ -351,6 +356,7 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				flushToDiskMemRatio,
 				domainFolderPath,
 				storageMaxSize,
+				storageEncoder,
 				transactionContainerSort,
 				domain,
 				keys)
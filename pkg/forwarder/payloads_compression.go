@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	payloadsCompression     *compression.FallbackStrategy
+	payloadsCompressionOnce sync.Once
+)
+
+// PayloadsCompression returns the compression strategy shared by the serializer, compressing the
+// payloads, and the forwarder, falling back to the compression the agent is built with when the
+// intake rejects the configured one.
+func PayloadsCompression() *compression.FallbackStrategy {
+	payloadsCompressionOnce.Do(func() {
+		payloadsCompression = newPayloadsCompression()
+	})
+	return payloadsCompression
+}
+
+func newPayloadsCompression() *compression.FallbackStrategy {
+	kind := config.Datadog.GetString("serializer_compressor_kind")
+	level := config.Datadog.GetInt("serializer_compressor_level")
+
+	fallback := compression.DefaultStrategy()
+	preferred, err := compression.NewStrategy(kind, level)
+	if err != nil {
+		// zlib, the default kind, is not available in the builds sending uncompressed payloads
+		if kind != compression.ZlibKind {
+			log.Warnf("Using the %q compression for the payloads: %s", compression.DefaultKind, err)
+		}
+		preferred = fallback
+	}
+	return compression.NewFallbackStrategy(preferred, fallback)
+}
@@ -30,6 +30,7 @@ var (
 	filesRemovedCountExpvar            = expvar.Int{}
 	deserializeErrorsCountExpvar       = expvar.Int{}
 	deserializeTransactionsCountExpvar = expvar.Int{}
+	quarantinedFilesCountExpvar        = expvar.Int{}
 )
 
 func init() {
@@ -55,6 +56,7 @@ func init() {
 	fileStorageExpvar.Set("FilesRemovedCount", &filesRemovedCountExpvar)
 	fileStorageExpvar.Set("DeserializeErrorsCount", &deserializeErrorsCountExpvar)
 	fileStorageExpvar.Set("DeserializeTransactionsCount", &deserializeTransactionsCountExpvar)
+	fileStorageExpvar.Set("QuarantinedFilesCount", &quarantinedFilesCountExpvar)
 }
 
 type failedTransactionRemovalPolicyTelemetry struct{}
@@ -128,3 +130,7 @@ func (transactionsFileStorageTelemetry) addDeserializeErrorsCount(count int) {
 func (transactionsFileStorageTelemetry) addDeserializeTransactionsCount(count int) {
 	deserializeTransactionsCountExpvar.Add(int64(count))
 }
+
+func (transactionsFileStorageTelemetry) addQuarantinedFilesCount() {
+	quarantinedFilesCountExpvar.Add(1)
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -355,11 +356,22 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, client *http.Clie
 		transactionsDropped.Add(1)
 		tlmTxDropped.Inc(t.Domain, transactionEndpointName)
 		return resp.StatusCode, body, nil
+	} else if resp.StatusCode == http.StatusUnsupportedMediaType && t.fallbackCompression(PayloadsCompression()) {
+		transactionsErrors.Add(1)
+		tlmTxErrors.Inc(t.Domain, transactionEndpointName, "unsupported_content_encoding")
+		return resp.StatusCode, body, &httpError{
+			statusCode: resp.StatusCode,
+			err:        fmt.Errorf("content encoding rejected by %q, rescheduling the transaction with the %q content encoding", logURL, t.Headers.Get("Content-Encoding")),
+		}
 	} else if resp.StatusCode > 400 {
 		t.ErrorCount++
 		transactionsErrors.Add(1)
 		tlmTxErrors.Inc(t.Domain, transactionEndpointName, "gt_400")
-		return resp.StatusCode, body, fmt.Errorf("error %q while sending transaction to %q, rescheduling it", resp.Status, logURL)
+		return resp.StatusCode, body, &httpError{
+			statusCode: resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			err:        fmt.Errorf("error %q while sending transaction to %q, rescheduling it", resp.Status, logURL),
+		}
 	}
 
 	tlmTxSuccessCount.Inc(t.Domain, transactionEndpointName)
@@ -384,6 +396,27 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, client *http.Clie
 	return resp.StatusCode, body, nil
 }
 
+// fallbackCompression compresses the payload again with the fallback strategy when it was compressed
+// with the preferred one, it returns false when the payload cannot fall back to another compression.
+func (t *HTTPTransaction) fallbackCompression(payloadsCompression *compression.FallbackStrategy) bool {
+	if !payloadsCompression.Reject(t.Headers.Get("Content-Encoding")) {
+		return false
+	}
+	payload, err := payloadsCompression.Recompress(*t.Payload)
+	if err != nil {
+		log.Errorf("Could not compress the transaction payload again: %s", err)
+		return false
+	}
+	// the payload is shared with the transactions of the other domains, it is replaced and not modified
+	t.Payload = &payload
+	if contentEncoding := payloadsCompression.Fallback().ContentEncoding(); contentEncoding != "" {
+		t.Headers.Set("Content-Encoding", contentEncoding)
+	} else {
+		t.Headers.Del("Content-Encoding")
+	}
+	return true
+}
+
 // SerializeTo serializes the transaction using TransactionsSerializer
 func (t *HTTPTransaction) SerializeTo(serializer *TransactionsSerializer) error {
 	if t.storableOnDisk {
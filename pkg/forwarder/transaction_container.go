@@ -40,6 +40,7 @@ func buildTransactionContainer(
 	flushToStorageRatio float64,
 	optionalDomainFolderPath string,
 	storageMaxSize int64,
+	storageEncoder *transactionsFileEncoder,
 	dropPrioritySorter transactionPrioritySorter,
 	domain string,
 	apiKeys []string) *transactionContainer {
@@ -48,7 +49,7 @@ func buildTransactionContainer(
 
 	if optionalDomainFolderPath != "" && storageMaxSize > 0 {
 		serializer := NewTransactionsSerializer(domain, apiKeys)
-		storage, err = newTransactionsFileStorage(serializer, optionalDomainFolderPath, storageMaxSize, transactionsFileStorageTelemetry{})
+		storage, err = newTransactionsFileStorage(serializer, storageEncoder, optionalDomainFolderPath, storageMaxSize, transactionsFileStorageTelemetry{})
 
 		// If the storage on disk cannot be used, log the error and continue.
 		// Returning `nil, err` would mean not using `TransactionContainer` and so not using `forwarder_retry_queue_payloads_max_size` config.
@@ -6,12 +6,17 @@ package forwarder
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func TestNewHTTPTransaction(t *testing.T) {
@@ -111,6 +116,26 @@ func TestProcessHTTPError(t *testing.T) {
 	assert.Equal(t, transaction.ErrorCount, 1)
 }
 
+func TestProcessRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	transaction := NewHTTPTransaction()
+	transaction.Domain = ts.URL
+	transaction.Endpoint.route = "/endpoint/test"
+	payload := []byte("test payload")
+	transaction.Payload = &payload
+
+	err := transaction.Process(context.Background(), &http.Client{})
+	var httpErr *httpError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.statusCode)
+	assert.Equal(t, 30*time.Second, httpErr.retryAfter)
+}
+
 func TestProcessCancel(t *testing.T) {
 	transaction := NewHTTPTransaction()
 	transaction.Domain = "example.com"
@@ -125,3 +150,49 @@ func TestProcessCancel(t *testing.T) {
 	err := transaction.Process(ctx, client)
 	assert.Nil(t, err)
 }
+
+func TestProcessUnsupportedContentEncoding(t *testing.T) {
+	preferred, err := compression.NewStrategy(compression.GzipKind, compression.DefaultLevel)
+	require.NoError(t, err)
+	fallback, err := compression.NewStrategy(compression.NoneKind, compression.DefaultLevel)
+	require.NoError(t, err)
+	payloadsCompressionOnce.Do(func() {})
+	defer func(initial *compression.FallbackStrategy) { payloadsCompression = initial }(payloadsCompression)
+	payloadsCompression = compression.NewFallbackStrategy(preferred, fallback)
+
+	var receivedPayload []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		receivedPayload, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	transaction := NewHTTPTransaction()
+	transaction.Domain = ts.URL
+	transaction.Endpoint.route = "/endpoint/test"
+	payload, err := preferred.Compress(nil, []byte("test payload"))
+	require.NoError(t, err)
+	transaction.Payload = &payload
+	transaction.Headers.Set("Content-Encoding", "gzip")
+
+	// the transaction is compressed again and rescheduled
+	err = transaction.Process(context.Background(), &http.Client{})
+	var httpErr *httpError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusUnsupportedMediaType, httpErr.statusCode)
+	assert.Empty(t, transaction.Headers.Get("Content-Encoding"))
+	assert.Equal(t, fallback, payloadsCompression.Current())
+
+	err = transaction.Process(context.Background(), &http.Client{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("test payload"), receivedPayload)
+
+	// the payloads that can't fall back are retried as is
+	transaction.Headers.Set("Content-Encoding", "deflate")
+	err = transaction.Process(context.Background(), &http.Client{})
+	require.True(t, errors.As(err, &httpErr))
+	assert.Contains(t, err.Error(), "error \"415 Unsupported Media Type\" while sending transaction")
+}
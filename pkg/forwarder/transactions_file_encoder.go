@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The retry files start with a header made of a magic number, flags and the CRC32 checksum
+// of the flags and of the body. The body is either the serialized transactions or, when the files are encrypted,
+// a random nonce followed by the transactions encrypted with AES-256-GCM.
+// The files written by the previous versions of the agent have no header, they start with the
+// protobuf tag of the version of the transactions collection. When an encryption key is configured,
+// the files which are not encrypted are rejected so that they cannot be substituted to the encrypted ones.
+const (
+	transactionsFileMagic      = "DDRQ"
+	transactionsFileHeaderSize = len(transactionsFileMagic) + 1 + crc32.Size
+
+	transactionsFileEncrypted byte = 1 << 0
+
+	// minimum length of the secret the encryption key is derived from
+	transactionsFileMinSecretLength = 16
+	// salt and context of the derivation of the encryption key, changing them makes
+	// the existing files undecipherable
+	transactionsFileKeySalt = "datadog-agent-forwarder-retry-storage"
+	transactionsFileKeyInfo = "aes-256-gcm-v1"
+)
+
+// errCorruptedTransactionsFile is returned for the retry files which cannot be trusted or deciphered
+var errCorruptedTransactionsFile = errors.New("corrupted retry file")
+
+// transactionsFileEncoder adds an integrity checksum to the retry files and optionally
+// encrypts them.
+type transactionsFileEncoder struct {
+	// aead is nil when the files are not encrypted
+	aead cipher.AEAD
+}
+
+// newTransactionsFileEncoder returns an encoder encrypting the files with a key derived
+// from secret, or only checksumming them if secret is empty.
+func newTransactionsFileEncoder(secret []byte) (*transactionsFileEncoder, error) {
+	if len(secret) == 0 {
+		return &transactionsFileEncoder{}, nil
+	}
+	if len(secret) < transactionsFileMinSecretLength {
+		return nil, fmt.Errorf("the encryption secret must be at least %d bytes long", transactionsFileMinSecretLength)
+	}
+
+	block, err := aes.NewCipher(deriveTransactionsFileKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionsFileEncoder{aead: aead}, nil
+}
+
+// newTransactionsFileEncoderFromConfig returns the encoder configured by
+// `forwarder_storage_encryption_key_file` or `forwarder_storage_encryption_key`,
+// the latter can be resolved by the secrets backend.
+func newTransactionsFileEncoderFromConfig() (*transactionsFileEncoder, error) {
+	keyFile := config.Datadog.GetString("forwarder_storage_encryption_key_file")
+	key := config.Datadog.GetString("forwarder_storage_encryption_key")
+
+	switch {
+	case keyFile != "" && key != "":
+		return nil, errors.New("forwarder_storage_encryption_key_file and forwarder_storage_encryption_key cannot be both set")
+	case keyFile != "":
+		secret, err := readTransactionsFileSecret(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return newTransactionsFileEncoder(secret)
+	default:
+		return newTransactionsFileEncoder([]byte(key))
+	}
+}
+
+func readTransactionsFileSecret(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the encryption key file: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		log.Warnf("The encryption key file %s of the retry files is accessible by other users than its owner", path)
+	}
+	secret, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the encryption key file: %v", err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("the encryption key file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// deriveTransactionsFileKey derives a 256 bits key from secret with HKDF-SHA256
+func deriveTransactionsFileKey(secret []byte) []byte {
+	extract := hmac.New(sha256.New, []byte(transactionsFileKeySalt))
+	extract.Write(secret)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(transactionsFileKeyInfo))
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
+
+func (e *transactionsFileEncoder) isEncrypting() bool {
+	return e.aead != nil
+}
+
+// encode returns the content of the retry file storing the serialized transactions
+func (e *transactionsFileEncoder) encode(transactions []byte) ([]byte, error) {
+	var flags byte
+	body := transactions
+	if e.isEncrypting() {
+		flags |= transactionsFileEncrypted
+		nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(transactions)+e.aead.Overhead())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		body = e.aead.Seal(nonce, nonce, transactions, transactionsFileAdditionalData(flags))
+	}
+
+	content := make([]byte, transactionsFileHeaderSize, transactionsFileHeaderSize+len(body))
+	copy(content, transactionsFileMagic)
+	content[len(transactionsFileMagic)] = flags
+	binary.LittleEndian.PutUint32(content[len(transactionsFileMagic)+1:], transactionsFileChecksum(flags, body))
+	return append(content, body...), nil
+}
+
+// decode returns the serialized transactions stored in a retry file, the error wraps
+// errCorruptedTransactionsFile if the file cannot be trusted or deciphered, or if it is
+// not encrypted while the encoder is.
+func (e *transactionsFileEncoder) decode(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte(transactionsFileMagic)) {
+		// file written by a previous version of the agent
+		if e.isEncrypting() {
+			return nil, fmt.Errorf("%w: the file written by a previous version of the agent is not encrypted while an encryption key is configured", errCorruptedTransactionsFile)
+		}
+		return content, nil
+	}
+	if len(content) < transactionsFileHeaderSize {
+		return nil, fmt.Errorf("%w: truncated header", errCorruptedTransactionsFile)
+	}
+
+	flags := content[len(transactionsFileMagic)]
+	checksum := binary.LittleEndian.Uint32(content[len(transactionsFileMagic)+1:])
+	body := content[transactionsFileHeaderSize:]
+	if transactionsFileChecksum(flags, body) != checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", errCorruptedTransactionsFile)
+	}
+
+	if flags&transactionsFileEncrypted == 0 {
+		if e.isEncrypting() {
+			return nil, fmt.Errorf("%w: the file is not encrypted while an encryption key is configured", errCorruptedTransactionsFile)
+		}
+		return body, nil
+	}
+	if !e.isEncrypting() {
+		return nil, fmt.Errorf("%w: the file is encrypted but no encryption key is configured", errCorruptedTransactionsFile)
+	}
+	if len(body) < e.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated nonce", errCorruptedTransactionsFile)
+	}
+	nonce, ciphertext := body[:e.aead.NonceSize()], body[e.aead.NonceSize():]
+	transactions, err := e.aead.Open(nil, nonce, ciphertext, transactionsFileAdditionalData(flags))
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decipher the file, was the encryption key changed?", errCorruptedTransactionsFile)
+	}
+	return transactions, nil
+}
+
+func transactionsFileChecksum(flags byte, body []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE([]byte{flags}), crc32.IEEETable, body)
+}
+
+// transactionsFileAdditionalData returns the header fields authenticated along with the encrypted body
+func transactionsFileAdditionalData(flags byte) []byte {
+	return append([]byte(transactionsFileMagic), flags)
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestTransactionsFileEncoderRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	payload := []byte("serialized transactions")
+
+	for _, secret := range []string{"", "a secret of the retry files"} {
+		encoder, err := newTransactionsFileEncoder([]byte(secret))
+		a.NoError(err)
+		a.Equal(secret != "", encoder.isEncrypting())
+
+		content, err := encoder.encode(payload)
+		a.NoError(err)
+		a.Equal(secret == "", len(content) == transactionsFileHeaderSize+len(payload))
+
+		decoded, err := encoder.decode(content)
+		a.NoError(err)
+		a.Equal(payload, decoded)
+	}
+}
+
+func TestTransactionsFileEncoderCorrupted(t *testing.T) {
+	a := assert.New(t)
+	encoder, err := newTransactionsFileEncoder([]byte("a secret of the retry files"))
+	a.NoError(err)
+	content, err := encoder.encode([]byte("serialized transactions"))
+	a.NoError(err)
+
+	corruptedFlags := append([]byte{}, content...)
+	corruptedFlags[len(transactionsFileMagic)] = 0
+	corruptedBody := append([]byte{}, content...)
+	corruptedBody[transactionsFileHeaderSize] ^= 0xff
+
+	for _, corrupted := range [][]byte{
+		content[:transactionsFileHeaderSize-1],
+		content[:len(content)-1],
+		corruptedFlags,
+		corruptedBody,
+	} {
+		_, err := encoder.decode(corrupted)
+		a.True(errors.Is(err, errCorruptedTransactionsFile))
+	}
+}
+
+func TestTransactionsFileEncoderFromConfig(t *testing.T) {
+	a := assert.New(t)
+	mockConfig := config.Mock()
+	defer mockConfig.Set("forwarder_storage_encryption_key_file", "")
+	defer mockConfig.Set("forwarder_storage_encryption_key", "")
+
+	encoder, err := newTransactionsFileEncoderFromConfig()
+	a.NoError(err)
+	a.False(encoder.isEncrypting())
+
+	mockConfig.Set("forwarder_storage_encryption_key", "too short")
+	_, err = newTransactionsFileEncoderFromConfig()
+	a.Error(err)
+
+	dir, clean := createTmpFolder(a)
+	defer clean()
+	keyFile := filepath.Join(dir, "retry.key")
+	a.NoError(ioutil.WriteFile(keyFile, []byte("a secret of the retry files\n"), 0600))
+	mockConfig.Set("forwarder_storage_encryption_key_file", keyFile)
+
+	// the key and the key file cannot be both set
+	_, err = newTransactionsFileEncoderFromConfig()
+	a.Error(err)
+
+	mockConfig.Set("forwarder_storage_encryption_key", "")
+	fileEncoder, err := newTransactionsFileEncoderFromConfig()
+	a.NoError(err)
+	a.True(fileEncoder.isEncrypting())
+
+	// the trailing new line of the key file is ignored
+	mockConfig.Set("forwarder_storage_encryption_key_file", "")
+	mockConfig.Set("forwarder_storage_encryption_key", "a secret of the retry files")
+	keyEncoder, err := newTransactionsFileEncoderFromConfig()
+	a.NoError(err)
+	content, err := fileEncoder.encode([]byte("serialized transactions"))
+	a.NoError(err)
+	decoded, err := keyEncoder.decode(content)
+	a.NoError(err)
+	a.Equal([]byte("serialized transactions"), decoded)
+
+	mockConfig.Set("forwarder_storage_encryption_key", "")
+	mockConfig.Set("forwarder_storage_encryption_key_file", filepath.Join(dir, "missing.key"))
+	_, err = newTransactionsFileEncoderFromConfig()
+	a.Error(err)
+}
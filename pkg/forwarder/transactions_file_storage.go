@@ -21,8 +21,13 @@ import (
 const retryTransactionsExtension = ".retry"
 const retryFileFormat = "2006_01_02__15_04_05_"
 
+// quarantinedTransactionsExtension is appended to the retry files which are corrupted or
+// cannot be deciphered. They are kept for investigation until the removal policy removes them.
+const quarantinedTransactionsExtension = ".quarantine"
+
 type transactionsFileStorage struct {
 	serializer         *TransactionsSerializer
+	encoder            *transactionsFileEncoder
 	storagePath        string
 	maxSizeInBytes     int64
 	filenames          []string
@@ -32,6 +37,7 @@ type transactionsFileStorage struct {
 
 func newTransactionsFileStorage(
 	serializer *TransactionsSerializer,
+	encoder *transactionsFileEncoder,
 	storagePath string,
 	maxSizeInBytes int64,
 	telemetry transactionsFileStorageTelemetry) (*transactionsFileStorage, error) {
@@ -42,6 +48,7 @@ func newTransactionsFileStorage(
 
 	storage := &transactionsFileStorage{
 		serializer:     serializer,
+		encoder:        encoder,
 		storagePath:    storagePath,
 		maxSizeInBytes: maxSizeInBytes,
 		telemetry:      telemetry,
@@ -71,6 +78,10 @@ func (s *transactionsFileStorage) Serialize(transactions []Transaction) error {
 	if err != nil {
 		return err
 	}
+	bytes, err = s.encoder.encode(bytes)
+	if err != nil {
+		return err
+	}
 	bufferSize := int64(len(bytes))
 
 	if err := s.makeRoomFor(bufferSize); err != nil {
@@ -106,6 +117,11 @@ func (s *transactionsFileStorage) Deserialize() ([]Transaction, error) {
 	index := len(s.filenames) - 1
 	path := s.filenames[index]
 	bytes, err := ioutil.ReadFile(path)
+	if err == nil {
+		if bytes, err = s.encoder.decode(bytes); err != nil {
+			return nil, s.quarantineFileAt(index, err)
+		}
+	}
 
 	// Remove the file even in case of a read failure.
 	if errRemoveFile := s.removeFileAt(index); errRemoveFile != nil {
@@ -175,6 +191,30 @@ func (s *transactionsFileStorage) removeFileAt(index int) error {
 	return nil
 }
 
+// quarantineFileAt renames a retry file which cannot be decoded so it is no longer reloaded
+// and returns the decoding error.
+func (s *transactionsFileStorage) quarantineFileAt(index int, decodeErr error) error {
+	filename := s.filenames[index]
+	size, err := util.GetFileSize(filename)
+	if err != nil {
+		return err
+	}
+
+	// Remove the file from s.filenames also in case of error to not
+	// fail on the next call.
+	s.filenames = append(s.filenames[:index], s.filenames[index+1:]...)
+	s.currentSizeInBytes -= size
+	if err := os.Rename(filename, filename+quarantinedTransactionsExtension); err != nil {
+		return err
+	}
+
+	log.Errorf("The retry file %s is quarantined: %v", filename, decodeErr)
+	s.telemetry.addQuarantinedFilesCount()
+	s.telemetry.setCurrentSizeInBytes(s.getCurrentSizeInBytes())
+	s.telemetry.setFilesCount(s.getFilesCount())
+	return decodeErr
+}
+
 func (s *transactionsFileStorage) reloadExistingRetryFiles() error {
 	files, sizeInBytes, err := s.getExistingRetryFiles()
 	if err != nil {
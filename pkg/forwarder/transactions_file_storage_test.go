@@ -1,6 +1,7 @@
 package forwarder
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -83,6 +84,131 @@ func TestTransactionsFileStorageReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestTransactionsFileStorageEncrypted(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	storage := newTestEncryptedTransactionsFileStorage(a, path, "a secret of the retry files")
+	err := storage.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2"))
+	a.NoError(err)
+
+	content, err := ioutil.ReadFile(storage.filenames[0])
+	a.NoError(err)
+	a.NotContains(string(content), "endpoint1")
+
+	newStorage := newTestEncryptedTransactionsFileStorage(a, path, "a secret of the retry files")
+	transactions, err := newStorage.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestTransactionsFileStorageQuarantineCorruptedFile(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	s := newTestTransactionsFileStorage(a, path, 1000)
+	err := s.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2"))
+	a.NoError(err)
+	filename := s.filenames[0]
+
+	content, err := ioutil.ReadFile(filename)
+	a.NoError(err)
+	content[len(content)-1] ^= 0xff
+	a.NoError(ioutil.WriteFile(filename, content, 0600))
+
+	quarantinedFilesCount := quarantinedFilesCountExpvar.Value()
+	transactions, err := s.Deserialize()
+	a.True(errors.Is(err, errCorruptedTransactionsFile))
+	a.Nil(transactions)
+	a.Equal(0, s.getFilesCount())
+	a.Equal(int64(0), s.getCurrentSizeInBytes())
+	a.Equal(quarantinedFilesCount+1, quarantinedFilesCountExpvar.Value())
+	a.FileExists(filename + quarantinedTransactionsExtension)
+
+	// the quarantined files are not reloaded
+	newStorage := newTestTransactionsFileStorage(a, path, 1000)
+	a.Equal(0, newStorage.getFilesCount())
+}
+
+func TestTransactionsFileStorageQuarantineWrongKey(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	storage := newTestEncryptedTransactionsFileStorage(a, path, "a secret of the retry files")
+	err := storage.Serialize(createHTTPTransactionCollectionTests("endpoint1"))
+	a.NoError(err)
+	filename := storage.filenames[0]
+
+	for _, newStorage := range []*transactionsFileStorage{
+		newTestEncryptedTransactionsFileStorage(a, path, "another secret of the retry files"),
+		newTestTransactionsFileStorage(a, path, 1000),
+	} {
+		a.Equal(1, newStorage.getFilesCount())
+		_, err = newStorage.Deserialize()
+		a.True(errors.Is(err, errCorruptedTransactionsFile))
+		a.FileExists(filename + quarantinedTransactionsExtension)
+
+		// restore the file for the next storage
+		a.NoError(os.Rename(filename+quarantinedTransactionsExtension, filename))
+	}
+}
+
+func TestTransactionsFileStorageLegacyFile(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	// retry file written without header by the previous versions of the agent
+	serializer := NewTransactionsSerializer(domainName, nil)
+	for _, t := range createHTTPTransactionCollectionTests("endpoint1") {
+		a.NoError(t.SerializeTo(serializer))
+	}
+	content, err := serializer.GetBytesAndReset()
+	a.NoError(err)
+	a.NoError(ioutil.WriteFile(path+"/legacy"+retryTransactionsExtension, content, 0600))
+
+	storage := newTestTransactionsFileStorage(a, path, 1000)
+	transactions, err := storage.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestTransactionsFileStorageEncryptedRejectsPlaintextFiles(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	// retry file written without header by the previous versions of the agent
+	serializer := NewTransactionsSerializer(domainName, nil)
+	for _, t := range createHTTPTransactionCollectionTests("endpoint1") {
+		a.NoError(t.SerializeTo(serializer))
+	}
+	content, err := serializer.GetBytesAndReset()
+	a.NoError(err)
+	legacyFilename := path + "/legacy" + retryTransactionsExtension
+	a.NoError(ioutil.WriteFile(legacyFilename, content, 0600))
+
+	// retry file written without encryption key
+	plaintextStorage := newTestTransactionsFileStorage(a, path, 1000)
+	a.NoError(plaintextStorage.Serialize(createHTTPTransactionCollectionTests("endpoint2")))
+	plaintextFilename := plaintextStorage.filenames[len(plaintextStorage.filenames)-1]
+
+	storage := newTestEncryptedTransactionsFileStorage(a, path, "a secret of the retry files")
+	a.Equal(2, storage.getFilesCount())
+	for i := 0; i < 2; i++ {
+		transactions, err := storage.Deserialize()
+		a.True(errors.Is(err, errCorruptedTransactionsFile))
+		a.Nil(transactions)
+	}
+	a.Equal(0, storage.getFilesCount())
+	a.Equal(int64(0), storage.getCurrentSizeInBytes())
+	a.FileExists(legacyFilename + quarantinedTransactionsExtension)
+	a.FileExists(plaintextFilename + quarantinedTransactionsExtension)
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []Transaction {
 	var transactions []Transaction
 
@@ -112,7 +238,15 @@ func getEndpointsFromTransactions(transactions []Transaction) []string {
 
 func newTestTransactionsFileStorage(a *assert.Assertions, path string, maxSizeInBytes int64) *transactionsFileStorage {
 	telemetry := transactionsFileStorageTelemetry{}
-	storage, err := newTransactionsFileStorage(NewTransactionsSerializer(domainName, nil), path, maxSizeInBytes, telemetry)
+	storage, err := newTransactionsFileStorage(NewTransactionsSerializer(domainName, nil), &transactionsFileEncoder{}, path, maxSizeInBytes, telemetry)
+	a.NoError(err)
+	return storage
+}
+
+func newTestEncryptedTransactionsFileStorage(a *assert.Assertions, path string, secret string) *transactionsFileStorage {
+	encoder, err := newTransactionsFileEncoder([]byte(secret))
+	a.NoError(err)
+	storage, err := newTransactionsFileStorage(NewTransactionsSerializer(domainName, nil), encoder, path, 1000, transactionsFileStorageTelemetry{})
 	a.NoError(err)
 	return storage
 }
@@ -35,11 +35,13 @@ type Worker struct {
 	stopChan            chan struct{}
 	stopped             chan struct{}
 	blockedList         *blockedEndpoints
+	limiter             *concurrencyLimiter
 }
 
 // NewWorker returns a new worker to consume Transaction from inputChan
-// and push back erroneous ones into requeueChan.
-func NewWorker(highPrioChan <-chan Transaction, lowPrioChan <-chan Transaction, requeueChan chan<- Transaction, blocked *blockedEndpoints) *Worker {
+// and push back erroneous ones into requeueChan. The transactions are processed
+// only when the limiter of the domain allows it.
+func NewWorker(highPrioChan <-chan Transaction, lowPrioChan <-chan Transaction, requeueChan chan<- Transaction, blocked *blockedEndpoints, limiter *concurrencyLimiter) *Worker {
 	return &Worker{
 		HighPrio:            highPrioChan,
 		LowPrio:             lowPrioChan,
@@ -49,6 +51,7 @@ func NewWorker(highPrioChan <-chan Transaction, lowPrioChan <-chan Transaction,
 		stopped:             make(chan struct{}),
 		Client:              newHTTPClient(),
 		blockedList:         blocked,
+		limiter:             limiter,
 	}
 }
 
@@ -73,7 +76,7 @@ func (w *Worker) Stop(purgeHighPrio bool) {
 			select {
 			case t := <-w.HighPrio:
 				log.Debugf("Flushing one new transaction before stopping Worker")
-				w.callProcess(t) //nolint:errcheck
+				w.callProcess(t, false) //nolint:errcheck
 			default:
 				break L
 			}
@@ -89,34 +92,47 @@ func (w *Worker) Start() {
 		defer close(w.stopped)
 
 		for {
-			// handling high priority transactions first
-			select {
-			case t := <-w.HighPrio:
-				if w.callProcess(t) == nil {
-					continue
-				}
+			t, stop := w.next()
+			if stop {
 				return
-			case <-w.stopChan:
+			}
+			// wait for the domain to accept one more request
+			probe, ok := w.limiter.acquire(w.stopChan)
+			if !ok {
+				// the worker was stopped before the transaction could be sent
+				w.requeue(t)
 				return
-			default:
 			}
-
-			select {
-			case t := <-w.HighPrio:
-				if w.callProcess(t) != nil {
-					return
-				}
-			case t := <-w.LowPrio:
-				if w.callProcess(t) != nil {
-					return
-				}
-			case <-w.stopChan:
+			err := w.callProcess(t, probe)
+			w.limiter.release()
+			if err != nil {
 				return
 			}
 		}
 	}()
 }
 
+// next returns the next transaction to process, stop is true if the worker must stop.
+func (w *Worker) next() (t Transaction, stop bool) {
+	// handling high priority transactions first
+	select {
+	case t = <-w.HighPrio:
+		return t, false
+	case <-w.stopChan:
+		return nil, true
+	default:
+	}
+
+	select {
+	case t = <-w.HighPrio:
+		return t, false
+	case t = <-w.LowPrio:
+		return t, false
+	case <-w.stopChan:
+		return nil, true
+	}
+}
+
 // ScheduleConnectionReset allows signaling the worker that all connections should
 // be recreated before sending the next transaction. Returns immediately.
 func (w *Worker) ScheduleConnectionReset() {
@@ -128,8 +144,8 @@ func (w *Worker) ScheduleConnectionReset() {
 }
 
 // callProcess will process a transaction and cancel it if we need to stop the
-// worker.
-func (w *Worker) callProcess(t Transaction) error {
+// worker. probe is true when the transaction probes a domain which asked to retry later.
+func (w *Worker) callProcess(t Transaction, probe bool) error {
 	// poll for connection reset events first
 	select {
 	case <-w.resetConnectionChan:
@@ -141,7 +157,7 @@ func (w *Worker) callProcess(t Transaction) error {
 	ctx = httptrace.WithClientTrace(ctx, trace)
 	done := make(chan interface{})
 	go func() {
-		w.process(ctx, t)
+		w.process(ctx, t, probe)
 		done <- nil
 	}()
 
@@ -158,26 +174,35 @@ func (w *Worker) callProcess(t Transaction) error {
 	return nil
 }
 
-func (w *Worker) process(ctx context.Context, t Transaction) {
-	requeue := func() {
-		select {
-		case w.RequeueChan <- t:
-		default:
-			log.Errorf("dropping transaction because the retry goroutine is too busy to handle another one")
-		}
+func (w *Worker) requeue(t Transaction) {
+	select {
+	case w.RequeueChan <- t:
+	default:
+		log.Errorf("dropping transaction because the retry goroutine is too busy to handle another one")
 	}
+}
 
+func (w *Worker) process(ctx context.Context, t Transaction, probe bool) {
 	// Run the endpoint through our blockedEndpoints circuit breaker
 	target := t.GetTarget()
 	if w.blockedList.isBlock(target) {
-		requeue()
+		w.requeue(t)
 		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
-	} else if err := t.Process(ctx, w.Client); err != nil {
-		w.blockedList.close(target)
-		requeue()
-		log.Errorf("Error while processing transaction: %v", err)
+	} else if !w.limiter.canSend(probe) {
+		// the slot was acquired before the domain asked to retry later
+		w.requeue(t)
+		log.Debugf("The domain of endpoint '%s' asked to retry later", target)
 	} else {
-		w.blockedList.recover(target)
+		start := time.Now()
+		err := t.Process(ctx, w.Client)
+		w.limiter.observe(time.Since(start), err)
+		if err != nil {
+			w.blockedList.close(target)
+			w.requeue(t)
+			log.Errorf("Error while processing transaction: %v", err)
+		} else {
+			w.blockedList.recover(target)
+		}
 	}
 }
 
@@ -22,7 +22,7 @@ func TestNewWorker(t *testing.T) {
 	lowPrio := make(chan Transaction)
 	requeue := make(chan Transaction)
 
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 	assert.NotNil(t, w)
 	assert.Equal(t, w.Client.Timeout, config.Datadog.GetDuration("forwarder_timeout")*time.Second)
 }
@@ -36,7 +36,7 @@ func TestNewNoSSLWorker(t *testing.T) {
 	mockConfig.Set("skip_ssl_validation", true)
 	defer mockConfig.Set("skip_ssl_validation", false)
 
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 	assert.True(t, w.Client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
 }
 
@@ -44,7 +44,7 @@ func TestWorkerStart(t *testing.T) {
 	highPrio := make(chan Transaction)
 	lowPrio := make(chan Transaction)
 	requeue := make(chan Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 
 	mock := newTestTransaction()
 	mock.On("Process", w.Client).Return(nil).Times(1)
@@ -74,7 +74,7 @@ func TestWorkerRetry(t *testing.T) {
 	highPrio := make(chan Transaction)
 	lowPrio := make(chan Transaction)
 	requeue := make(chan Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 
 	mock := newTestTransaction()
 	mock.On("Process", w.Client).Return(fmt.Errorf("some kind of error")).Times(1)
@@ -95,7 +95,7 @@ func TestWorkerRetryBlockedTransaction(t *testing.T) {
 	highPrio := make(chan Transaction)
 	lowPrio := make(chan Transaction)
 	requeue := make(chan Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 
 	mock := newTestTransaction()
 	mock.On("GetTarget").Return("error_url").Times(1)
@@ -112,11 +112,59 @@ func TestWorkerRetryBlockedTransaction(t *testing.T) {
 	assert.True(t, w.blockedList.isBlock("error_url"))
 }
 
+func TestWorkerRetryAfter(t *testing.T) {
+	highPrio := make(chan Transaction)
+	lowPrio := make(chan Transaction)
+	requeue := make(chan Transaction, 1)
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
+
+	mock := newTestTransaction()
+	mock.On("Process", w.Client).Return(&httpError{statusCode: http.StatusTooManyRequests, retryAfter: time.Minute, err: fmt.Errorf("429")}).Times(1)
+	mock.On("GetTarget").Return("error_url").Times(1)
+
+	w.Start()
+	highPrio <- mock
+	retryTransaction := <-requeue
+	w.Stop(false)
+	mock.AssertExpectations(t)
+	assert.Equal(t, mock, retryTransaction)
+	assert.True(t, w.limiter.isOpen())
+	assert.Equal(t, circuitOpen, w.limiter.stats().State)
+}
+
+func TestWorkerIdleDoesNotHoldSlot(t *testing.T) {
+	highPrio := make(chan Transaction)
+	lowPrio := make(chan Transaction)
+	requeue := make(chan Transaction, 1)
+	limiter := newConcurrencyLimiter("test", 1)
+	w1 := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), limiter)
+	w2 := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), limiter)
+
+	w1.Start()
+	w2.Start()
+	// the slot is acquired once a transaction is received
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, limiter.stats().InFlight)
+
+	for i := 0; i < 2; i++ {
+		mock := newTestTransactionWithoutClientAssert()
+		mock.On("Process").Return(nil).Times(1)
+		mock.On("GetTarget").Return("").Times(1)
+		highPrio <- mock
+		<-mock.processed
+		mock.AssertExpectations(t)
+	}
+
+	w1.Stop(false)
+	w2.Stop(false)
+	assert.Equal(t, 0, limiter.stats().InFlight)
+}
+
 func TestWorkerResetConnections(t *testing.T) {
 	highPrio := make(chan Transaction)
 	lowPrio := make(chan Transaction)
 	requeue := make(chan Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 
 	mock := newTestTransaction()
 	mock.On("Process", w.Client).Return(nil).Times(1)
@@ -159,7 +207,7 @@ func TestWorkerPurgeOnStop(t *testing.T) {
 	highPrio := make(chan Transaction, 1)
 	lowPrio := make(chan Transaction, 1)
 	requeue := make(chan Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newConcurrencyLimiter("test", 1))
 	// making stopChan non blocking on insert and closing stopped channel
 	// to avoid blocking in the Stop method since we don't actually start
 	// the workder
@@ -8,6 +8,8 @@ package logs
 import (
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -25,10 +27,20 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/input/traps"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/windowsevent"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 )
 
+const (
+	// logMetricsSenderID is the ID of the aggregator sender submitting the metrics generated from the logs
+	logMetricsSenderID = check.ID("logs-agent:log_metrics")
+	// logMetricsCommitInterval is the interval at which the metrics generated from the logs are committed,
+	// it matches the default interval of the checks.
+	logMetricsCommitInterval = 15 * time.Second
+)
+
 // Agent represents the data pipeline that collects, decodes,
 // processes and sends logs to the backend
 // + ------------------------------------------------------ +
@@ -37,12 +49,14 @@ import (
 // |                                                        |
 // + ------------------------------------------------------ +
 type Agent struct {
+	sources                   *config.LogSources
 	auditor                   auditor.Auditor
 	destinationsCtx           *client.DestinationsContext
 	pipelineProvider          pipeline.Provider
 	inputs                    []restart.Restartable
 	health                    *health.Handle
 	diagnosticMessageReceiver *diagnostic.BufferedMessageReceiver
+	metricCommitter           *processor.MetricCommitter
 }
 
 // NewAgent returns a new Logs Agent
@@ -57,8 +71,29 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	destinationsCtx := client.NewDestinationsContext()
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver()
 
+	// setup the spool storing the payloads on disk while the intake is unreachable
+	var spool *sender.Spool
+	if spoolMaxSize := config.SpoolMaxSizeInBytes(); spoolMaxSize > 0 {
+		var err error
+		spool, err = sender.NewSpool(config.SpoolPath(), spoolMaxSize)
+		if err != nil {
+			log.Errorf("Could not create the logs spool, payloads won't be stored on disk: %v", err)
+			spool = nil
+		}
+	}
+
+	// setup the sender submitting the metrics generated from the logs
+	var metricSender processor.MetricSender
+	var metricCommitter *processor.MetricCommitter
+	if sender, err := aggregator.GetSender(logMetricsSenderID); err == nil {
+		metricSender = sender
+		metricCommitter = processor.NewMetricCommitter(sender, logMetricsCommitInterval)
+	} else {
+		log.Warnf("Log metrics are disabled: %v", err)
+	}
+
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx)
+	pipelineProvider := pipeline.NewAgentProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx, spool, metricSender)
 
 	// setup the inputs
 	inputs := []restart.Restartable{
@@ -77,12 +112,14 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	}
 
 	return &Agent{
+		sources:                   sources,
 		auditor:                   auditor,
 		destinationsCtx:           destinationsCtx,
 		pipelineProvider:          pipelineProvider,
 		inputs:                    inputs,
 		health:                    health,
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		metricCommitter:           metricCommitter,
 	}
 }
 
@@ -105,6 +142,7 @@ func NewServerless(sources *config.LogSources, services *service.Services, proce
 	}
 
 	return &Agent{
+		sources:          sources,
 		auditor:          auditor,
 		destinationsCtx:  destinationsCtx,
 		pipelineProvider: pipelineProvider,
@@ -117,6 +155,9 @@ func NewServerless(sources *config.LogSources, services *service.Services, proce
 // in the right order to prevent data loss
 func (a *Agent) Start() {
 	starter := restart.NewStarter(a.destinationsCtx, a.auditor, a.pipelineProvider, a.diagnosticMessageReceiver)
+	if a.metricCommitter != nil {
+		starter.Add(a.metricCommitter)
+	}
 	for _, input := range a.inputs {
 		starter.Add(input)
 	}
@@ -128,6 +169,11 @@ func (a *Agent) Flush() {
 	a.pipelineProvider.Flush()
 }
 
+// SetProcessingRules replaces the global processing rules applied by the pipelines.
+func (a *Agent) SetProcessingRules(processingRules []*config.ProcessingRule) {
+	a.pipelineProvider.SetProcessingRules(processingRules)
+}
+
 // Stop stops all the elements of the data pipeline
 // in the right order to prevent data loss
 func (a *Agent) Stop() {
@@ -142,6 +188,10 @@ func (a *Agent) Stop() {
 		a.destinationsCtx,
 		a.diagnosticMessageReceiver,
 	)
+	if a.metricCommitter != nil {
+		// commit the metrics generated from the logs flushed by the pipelines
+		stopper.Add(a.metricCommitter)
+	}
 
 	// This will try to stop everything in order, including the potentially blocking
 	// parts like the sender. After StopTimeout it will just stop the last part of the
@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditorUnmarshalRegistryV3(t *testing.T) {
+	input := `{
+	    "Registry": {
+	        "file:/var/log/app.log": {
+	            "Offset": "1",
+	            "LastUpdated": "2006-01-12T01:01:01.000000001Z",
+	            "TailingMode": "beginning",
+	            "Fingerprint": "3f2a8c1d5e6b7a90"
+	        },
+	        "docker:123456789": {
+	            "Offset": "2006-01-12T01:01:03.000000001Z",
+	            "LastUpdated": "2006-01-12T01:01:02.000000001Z"
+	        }
+	    },
+	    "Version": 3
+	}`
+	r, err := unmarshalRegistryV3([]byte(input))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1", r["file:/var/log/app.log"].Offset)
+	assert.Equal(t, 1, r["file:/var/log/app.log"].LastUpdated.Second())
+	assert.Equal(t, "beginning", r["file:/var/log/app.log"].TailingMode)
+	assert.Equal(t, "3f2a8c1d5e6b7a90", r["file:/var/log/app.log"].Fingerprint)
+
+	assert.Equal(t, "2006-01-12T01:01:03.000000001Z", r["docker:123456789"].Offset)
+	assert.Equal(t, "", r["docker:123456789"].Fingerprint)
+}
@@ -28,12 +28,13 @@ const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
 // latest version of the API used by the auditor to retrieve the registry from disk.
-const registryAPIVersion = 2
+const registryAPIVersion = 3
 
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetFingerprint(identifier string) string
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -42,6 +43,8 @@ type RegistryEntry struct {
 	LastUpdated time.Time
 	Offset      string
 	TailingMode string
+	// Fingerprint identifies the content the offset belongs to, it is empty when the origin is not fingerprinted.
+	Fingerprint string `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -145,6 +148,17 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the fingerprint committed with the offset for a given identifier,
+// returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetFingerprint(identifier string) string {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return ""
+	}
+	return entry.Fingerprint
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -166,7 +180,7 @@ func (a *RegistryAuditor) run() {
 				return
 			}
 			// update the registry with new entry
-			a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode)
+			a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint)
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
 			a.cleanupRegistry()
@@ -218,7 +232,7 @@ func (a *RegistryAuditor) cleanupRegistry() {
 }
 
 // updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string) {
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint string) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -231,6 +245,7 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		LastUpdated: time.Now().UTC(),
 		Offset:      offset,
 		TailingMode: tailingMode,
+		Fingerprint: fingerprint,
 	}
 }
 
@@ -277,6 +292,8 @@ func (a *RegistryAuditor) unmarshalRegistry(b []byte) (map[string]*RegistryEntry
 	}
 	// ensure backward compatibility
 	switch int(version) {
+	case 3:
+		return unmarshalRegistryV3(b)
 	case 2:
 		return unmarshalRegistryV2(b)
 	case 1:
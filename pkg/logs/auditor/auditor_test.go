@@ -62,14 +62,15 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end")
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "")
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning")
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", "3f2a8c1d5e6b7a90")
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
+	suite.Equal("3f2a8c1d5e6b7a90", suite.a.GetFingerprint(suite.source.Config.Path))
 }
 
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
@@ -78,15 +79,33 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
 		Offset:      "42",
 		TailingMode: "end",
+		Fingerprint: "3f2a8c1d5e6b7a90",
 	}
 	suite.a.flushRegistry()
 	r, err := ioutil.ReadFile(suite.testPath)
 	suite.Nil(err)
-	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\"}}}", string(r))
+	suite.Equal("{\"Version\":3,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"Fingerprint\":\"3f2a8c1d5e6b7a90\"}}}", string(r))
 
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry = suite.a.recoverRegistry()
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+	suite.Equal("3f2a8c1d5e6b7a90", suite.a.registry[suite.source.Config.Path].Fingerprint)
+}
+
+func (suite *AuditorTestSuite) TestAuditorMigratesRegistryV2() {
+	err := ioutil.WriteFile(suite.testPath, []byte(`{"Version":2,"Registry":{"testpath":{"LastUpdated":"2006-01-12T01:01:01.000000001Z","Offset":"42","TailingMode":"end"}}}`), 0644)
+	suite.Nil(err)
+
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal("42", suite.a.GetOffset(suite.source.Config.Path))
+	suite.Equal("end", suite.a.GetTailingMode(suite.source.Config.Path))
+	suite.Equal("", suite.a.GetFingerprint(suite.source.Config.Path))
+
+	// the registry is written back in the latest version
+	suite.a.flushRegistry()
+	r, err := ioutil.ReadFile(suite.testPath)
+	suite.Nil(err)
+	suite.Equal("{\"Version\":3,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\"}}}", string(r))
 }
 
 func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
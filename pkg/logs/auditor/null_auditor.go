@@ -27,6 +27,9 @@ func (a *NullAuditor) GetOffset(identifier string) string { return "" }
 // GetTailingMode returns an empty string.
 func (a *NullAuditor) GetTailingMode(identifier string) string { return "" }
 
+// GetFingerprint returns an empty string.
+func (a *NullAuditor) GetFingerprint(identifier string) string { return "" }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
@@ -8,6 +8,8 @@ package http
 import (
 	"bytes"
 	"compress/gzip"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // ContentEncoding encodes the payload
@@ -71,3 +73,23 @@ func (c *GzipContentEncoding) encode(payload []byte) ([]byte, error) {
 	}
 	return compressedPayload.Bytes(), nil
 }
+
+// StrategyContentEncoding encodes the payload with a compression strategy
+type StrategyContentEncoding struct {
+	strategy compression.Strategy
+}
+
+// NewStrategyContentEncoding creates a new content encoding compressing the payloads with the strategy
+func NewStrategyContentEncoding(strategy compression.Strategy) *StrategyContentEncoding {
+	return &StrategyContentEncoding{
+		strategy,
+	}
+}
+
+func (c *StrategyContentEncoding) name() string {
+	return c.strategy.ContentEncoding()
+}
+
+func (c *StrategyContentEncoding) encode(payload []byte) ([]byte, error) {
+	return c.strategy.Compress(nil, payload)
+}
@@ -11,6 +11,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func TestIdentityContentType(t *testing.T) {
@@ -42,6 +44,22 @@ func TestGzipContentEncodingName(t *testing.T) {
 	assert.Equal(t, NewGzipContentEncoding(gzip.BestCompression).name(), "gzip")
 }
 
+func TestStrategyContentEncoding(t *testing.T) {
+	payload := []byte("my payload")
+	strategy, err := compression.NewStrategy(compression.GzipKind, gzip.BestCompression)
+	assert.Nil(t, err)
+	contentEncoding := NewStrategyContentEncoding(strategy)
+	assert.Equal(t, "gzip", contentEncoding.name())
+
+	encodedPayload, err := contentEncoding.encode(payload)
+	assert.Nil(t, err)
+
+	decompressedPayload, err := decompress(encodedPayload)
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+}
+
 func decompress(payload []byte) ([]byte, error) {
 	reader, err := gzip.NewReader(bytes.NewReader(payload))
 	if err != nil {
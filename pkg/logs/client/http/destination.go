@@ -8,8 +8,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -42,6 +44,10 @@ type Destination struct {
 	destinationsContext *client.DestinationsContext
 	once                sync.Once
 	payloadChan         chan []byte
+
+	// fallbackContentEncoding is used once the intake rejected contentEncoding, nil without compression
+	fallbackContentEncoding ContentEncoding
+	contentEncodingRejected int32
 }
 
 // NewDestination returns a new Destination.
@@ -51,12 +57,17 @@ func NewDestination(endpoint config.Endpoint, contentType string, destinationsCo
 }
 
 func newDestination(endpoint config.Endpoint, contentType string, destinationsContext *client.DestinationsContext, timeout time.Duration) *Destination {
+	var fallbackContentEncoding ContentEncoding
+	if endpoint.UseCompression {
+		fallbackContentEncoding = NewGzipContentEncoding(endpoint.CompressionLevel)
+	}
 	return &Destination{
-		url:                 buildURL(endpoint),
-		contentType:         contentType,
-		contentEncoding:     buildContentEncoding(endpoint),
-		client:              httputils.NewResetClient(endpoint.ConnectionResetInterval, httpClientFactory(timeout)),
-		destinationsContext: destinationsContext,
+		url:                     buildURL(endpoint),
+		contentType:             contentType,
+		contentEncoding:         buildContentEncoding(endpoint),
+		fallbackContentEncoding: fallbackContentEncoding,
+		client:                  httputils.NewResetClient(endpoint.ConnectionResetInterval, httpClientFactory(timeout)),
+		destinationsContext:     destinationsContext,
 	}
 }
 
@@ -65,7 +76,8 @@ func newDestination(endpoint config.Endpoint, contentType string, destinationsCo
 func (d *Destination) Send(payload []byte) error {
 	ctx := d.destinationsContext.Context()
 
-	encodedPayload, err := d.contentEncoding.encode(payload)
+	contentEncoding := d.currentContentEncoding()
+	encodedPayload, err := contentEncoding.encode(payload)
 	if err != nil {
 		return err
 	}
@@ -79,7 +91,7 @@ func (d *Destination) Send(payload []byte) error {
 		return err
 	}
 	req.Header.Set("Content-Type", d.contentType)
-	req.Header.Set("Content-Encoding", d.contentEncoding.name())
+	req.Header.Set("Content-Encoding", contentEncoding.name())
 	req = req.WithContext(ctx)
 
 	resp, err := d.client.Do(req)
@@ -99,7 +111,10 @@ func (d *Destination) Send(payload []byte) error {
 		return err
 	}
 
-	if resp.StatusCode >= 500 {
+	if resp.StatusCode == http.StatusUnsupportedMediaType && d.rejectContentEncoding(contentEncoding) {
+		// the payload is sent again with the fallback content encoding
+		return d.Send(payload)
+	} else if resp.StatusCode >= 500 {
 		// the server could not serve the request,
 		// most likely because of an internal error
 		return client.NewRetryableError(errServer)
@@ -112,6 +127,26 @@ func (d *Destination) Send(payload []byte) error {
 	}
 }
 
+// currentContentEncoding returns the content encoding to send the payloads with
+func (d *Destination) currentContentEncoding() ContentEncoding {
+	if atomic.LoadInt32(&d.contentEncodingRejected) == 1 {
+		return d.fallbackContentEncoding
+	}
+	return d.contentEncoding
+}
+
+// rejectContentEncoding switches to the fallback content encoding when the intake rejected the
+// preferred one, it returns false when there is no other content encoding to fall back to.
+func (d *Destination) rejectContentEncoding(contentEncoding ContentEncoding) bool {
+	if d.fallbackContentEncoding == nil || contentEncoding != d.contentEncoding || contentEncoding.name() == d.fallbackContentEncoding.name() {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&d.contentEncodingRejected, 0, 1) {
+		log.Warnf("The logs intake does not support the %q content encoding, falling back to %q", contentEncoding.name(), d.fallbackContentEncoding.name())
+	}
+	return true
+}
+
 // SendAsync sends a payload in background.
 func (d *Destination) SendAsync(payload []byte) {
 	d.once.Do(func() {
@@ -165,10 +200,21 @@ func buildURL(endpoint config.Endpoint) string {
 }
 
 func buildContentEncoding(endpoint config.Endpoint) ContentEncoding {
-	if endpoint.UseCompression {
+	if !endpoint.UseCompression {
+		return IdentityContentType
+	}
+	switch endpoint.CompressionKind {
+	case "", compression.GzipKind:
+		return NewGzipContentEncoding(endpoint.CompressionLevel)
+	case compression.NoneKind:
+		return IdentityContentType
+	}
+	strategy, err := compression.NewStrategy(endpoint.CompressionKind, endpoint.CompressionLevel)
+	if err != nil {
+		log.Warnf("Compressing the logs with gzip: %s", err)
 		return NewGzipContentEncoding(endpoint.CompressionLevel)
 	}
-	return IdentityContentType
+	return NewStrategyContentEncoding(strategy)
 }
 
 // CheckConnectivity check if sending logs through HTTP works
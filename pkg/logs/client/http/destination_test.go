@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 type HTTPServerTest struct {
@@ -108,6 +109,70 @@ func TestDestinationSend400(t *testing.T) {
 	server.stop()
 }
 
+func TestDestinationSendUnsupportedContentEncoding(t *testing.T) {
+	var contentEncodings []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentEncodings = append(contentEncodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer ts.Close()
+	url := strings.Split(ts.URL, ":")
+	port, _ := strconv.Atoi(url[2])
+	destCtx := client.NewDestinationsContext()
+	destCtx.Start()
+	defer destCtx.Stop()
+
+	endpoint := config.Endpoint{
+		APIKey:          "test",
+		Host:            strings.Replace(url[1], "/", "", -1),
+		Port:            port,
+		UseCompression:  true,
+		CompressionKind: compression.NoneKind,
+	}
+	destination := NewDestination(endpoint, JSONContentType, destCtx)
+
+	// the payload is sent again with gzip, which is used for the next payloads
+	assert.Nil(t, destination.Send([]byte("yo")))
+	assert.Nil(t, destination.Send([]byte("yo")))
+	assert.Equal(t, []string{"identity", "gzip", "gzip"}, contentEncodings)
+
+	// without compression, there is nothing to fall back to
+	contentEncodings = nil
+	endpoint.UseCompression = false
+	destination = NewDestination(endpoint, JSONContentType, destCtx)
+	assert.Equal(t, errClient, destination.Send([]byte("yo")))
+	assert.Equal(t, []string{"identity"}, contentEncodings)
+}
+
+func TestBuildContentEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		useCompression  bool
+		compressionKind string
+		expected        string
+	}{
+		{false, "", "identity"},
+		{false, compression.GzipKind, "identity"},
+		{true, "", "gzip"},
+		{true, compression.GzipKind, "gzip"},
+		{true, compression.NoneKind, "identity"},
+		{true, "unknown", "gzip"},
+	} {
+		contentEncoding := buildContentEncoding(config.Endpoint{
+			UseCompression:   tc.useCompression,
+			CompressionKind:  tc.compressionKind,
+			CompressionLevel: 6,
+		})
+		assert.Equal(t, tc.expected, contentEncoding.name())
+	}
+
+	if compression.IsAvailable(compression.ZlibKind) {
+		contentEncoding := buildContentEncoding(config.Endpoint{UseCompression: true, CompressionKind: compression.ZlibKind})
+		assert.Equal(t, "deflate", contentEncoding.name())
+	}
+}
+
 func TestConnectivityCheck(t *testing.T) {
 	// Connectivity is ok when server return 200
 	server := NewHTTPServerTest(200)
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"expvar"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	warningPeriod = 1000
+)
+
+// Destination sends RFC5424 syslog messages to a collector over TCP or TLS,
+// messages are framed with octet counting as described in RFC6587.
+type Destination struct {
+	name                string
+	connManager         *tcp.ConnectionManager
+	destinationsContext *client.DestinationsContext
+	conn                net.Conn
+	connCreationTime    time.Time
+	inputChan           chan []byte
+	once                sync.Once
+}
+
+// NewDestination returns a new syslog destination.
+func NewDestination(endpoint config.Endpoint, destinationsContext *client.DestinationsContext) *Destination {
+	return &Destination{
+		name:                endpoint.Name,
+		connManager:         tcp.NewConnectionManager(endpoint),
+		destinationsContext: destinationsContext,
+	}
+}
+
+// Send frames a syslog message and sends it to the collector,
+// returns an error if the operation failed.
+func (d *Destination) Send(payload []byte) error {
+	if d.conn == nil {
+		var err error
+		ctx := d.destinationsContext.Context()
+		if d.conn, err = d.connManager.NewConnection(ctx); err != nil {
+			// the connection manager is not meant to fail,
+			// this can happen only when the context is cancelled.
+			return err
+		}
+		d.connCreationTime = time.Now()
+	}
+
+	_, err := d.conn.Write(frame(payload))
+	if err != nil {
+		d.connManager.CloseConnection(d.conn)
+		d.conn = nil
+		return client.NewRetryableError(err)
+	}
+
+	if d.connManager.ShouldReset(d.connCreationTime) {
+		log.Debug("Resetting syslog connection")
+		d.connManager.CloseConnection(d.conn)
+		d.conn = nil
+	}
+	return nil
+}
+
+// SendAsync sends a message to the destination without blocking. If the channel is full, the incoming messages will be
+// dropped
+func (d *Destination) SendAsync(payload []byte) {
+	d.once.Do(func() {
+		d.inputChan = make(chan []byte, config.ChanSize)
+		// the counter is shared with the previous instances of the destination
+		metrics.DestinationLogsDropped.Add(d.name, 0)
+		go d.runAsync()
+	})
+
+	select {
+	case d.inputChan <- payload:
+	default:
+		if metrics.DestinationLogsDropped.Get(d.name).(*expvar.Int).Value()%warningPeriod == 0 {
+			log.Warnf("Some logs sent to syslog destination %v were dropped", d.name)
+		}
+		metrics.DestinationLogsDropped.Add(d.name, 1)
+		metrics.TlmLogsDropped.Inc(d.name)
+	}
+}
+
+// runAsync reads the messages from the channel and sends them
+func (d *Destination) runAsync() {
+	ctx := d.destinationsContext.Context()
+	for {
+		select {
+		case payload := <-d.inputChan:
+			d.Send(payload) //nolint:errcheck
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// frame prefixes the message with its length in bytes.
+func frame(payload []byte) []byte {
+	framed := make([]byte, 0, len(payload)+11)
+	framed = strconv.AppendInt(framed, int64(len(payload)), 10)
+	framed = append(framed, ' ')
+	return append(framed, payload...)
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
+)
+
+func TestFrame(t *testing.T) {
+	assert.Equal(t, "5 hello", string(frame([]byte("hello"))))
+	assert.Equal(t, "0 ", string(frame(nil)))
+}
+
+func TestDestinationSendsOctetCountedMessages(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		frames := make([]byte, len("28 <14>0 - host app - - - hello28 <14>0 - host app - - - world"))
+		if _, err := io.ReadFull(reader, frames); err == nil {
+			received <- string(frames)
+		}
+	}()
+
+	ctx := client.NewDestinationsContext()
+	ctx.Start()
+	defer ctx.Stop()
+
+	endpoint := tcp.AddrToEndPoint(listener.Addr())
+	endpoint.Name = "compliance"
+	destination := NewDestination(endpoint, ctx)
+	require.NoError(t, destination.Send([]byte("<14>0 - host app - - - hello")))
+	destination.SendAsync([]byte("<14>0 - host app - - - world"))
+
+	assert.Equal(t, "28 <14>0 - host app - - - hello28 <14>0 - host app - - - world", <-received)
+}
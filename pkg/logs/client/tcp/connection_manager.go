@@ -35,6 +35,9 @@ type ConnectionManager struct {
 	endpoint  config.Endpoint
 	mutex     sync.Mutex
 	firstConn sync.Once
+	// retries and nextAttempt space the attempts of TryNewConnection
+	retries     uint
+	nextAttempt time.Time
 }
 
 // NewConnectionManager returns an initialized ConnectionManager
@@ -56,13 +59,7 @@ func (cm *ConnectionManager) NewConnection(ctx context.Context) (net.Conn, error
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
-	cm.firstConn.Do(func() {
-		if cm.endpoint.ProxyAddress != "" {
-			log.Infof("Connecting to the backend: %v, via socks5: %v, with SSL: %v", cm.address(), cm.endpoint.ProxyAddress, cm.endpoint.UseSSL)
-		} else {
-			log.Infof("Connecting to the backend: %v, with SSL: %v", cm.address(), cm.endpoint.UseSSL)
-		}
-	})
+	cm.logFirstConnection()
 
 	var retries uint
 	var err error
@@ -86,44 +83,97 @@ func (cm *ConnectionManager) NewConnection(ctx context.Context) (net.Conn, error
 		}
 
 		var conn net.Conn
+		conn, err = cm.connect(ctx)
+		if err != nil {
+			continue
+		}
+		status.RemoveGlobalWarning(statusConnectionError)
+		return conn, nil
+	}
+}
+
+// TryNewConnection returns an initialized connection to the intake, or an error when it can't be
+// established in a single attempt. The failed attempts are spaced with the same backoff as the
+// ones of NewConnection: until the next attempt is due, it returns an error without connecting.
+func (cm *ConnectionManager) TryNewConnection(ctx context.Context) (net.Conn, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.logFirstConnection()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	if wait := time.Until(cm.nextAttempt); wait > 0 {
+		return nil, fmt.Errorf("connection to %v failed, next attempt in %v", cm.address(), wait.Round(time.Second))
+	}
+
+	conn, err := cm.connect(ctx)
+	if err != nil {
+		cm.retries++
+		cm.nextAttempt = time.Now().Add(cm.backoffDuration(cm.retries))
+		status.AddGlobalWarning(statusConnectionError, fmt.Sprintf("Connection to the log intake cannot be established: %v", err))
+		return nil, err
+	}
+	cm.retries = 0
+	cm.nextAttempt = time.Time{}
+	status.RemoveGlobalWarning(statusConnectionError)
+	return conn, nil
+}
+
+func (cm *ConnectionManager) logFirstConnection() {
+	cm.firstConn.Do(func() {
 		if cm.endpoint.ProxyAddress != "" {
-			var dialer proxy.Dialer
-			dialer, err = proxy.SOCKS5("tcp", cm.endpoint.ProxyAddress, nil, proxy.Direct)
-			if err != nil {
-				log.Warn(err)
-				continue
-			}
-			// TODO: handle timeouts with ctx.
-			conn, err = dialer.Dial("tcp", cm.address())
+			log.Infof("Connecting to the backend: %v, via socks5: %v, with SSL: %v", cm.address(), cm.endpoint.ProxyAddress, cm.endpoint.UseSSL)
 		} else {
-			var dialer net.Dialer
-			dctx, cancel := context.WithTimeout(ctx, connectionTimeout)
-			defer cancel()
-			conn, err = dialer.DialContext(dctx, "tcp", cm.address())
+			log.Infof("Connecting to the backend: %v, with SSL: %v", cm.address(), cm.endpoint.UseSSL)
 		}
+	})
+}
+
+// connect makes a single attempt to connect to the intake.
+func (cm *ConnectionManager) connect(ctx context.Context) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if cm.endpoint.ProxyAddress != "" {
+		var dialer proxy.Dialer
+		dialer, err = proxy.SOCKS5("tcp", cm.endpoint.ProxyAddress, nil, proxy.Direct)
 		if err != nil {
 			log.Warn(err)
-			continue
-		}
-		log.Debugf("connected to %v", cm.address())
-
-		if cm.endpoint.UseSSL {
-			sslConn := tls.Client(conn, &tls.Config{
-				ServerName: cm.endpoint.Host,
-			})
-			err = cm.handshakeWithTimeout(sslConn, connectionTimeout)
-			if err != nil {
-				log.Warn(err)
-				continue
-			}
-			log.Debug("SSL handshake successful")
-			conn = sslConn
+			return nil, err
 		}
+		// TODO: handle timeouts with ctx.
+		conn, err = dialer.Dial("tcp", cm.address())
+	} else {
+		var dialer net.Dialer
+		dctx, cancel := context.WithTimeout(ctx, connectionTimeout)
+		defer cancel()
+		conn, err = dialer.DialContext(dctx, "tcp", cm.address())
+	}
+	if err != nil {
+		log.Warn(err)
+		return nil, err
+	}
+	log.Debugf("connected to %v", cm.address())
 
-		go cm.handleServerClose(conn)
-		status.RemoveGlobalWarning(statusConnectionError)
-		return conn, nil
+	if cm.endpoint.UseSSL {
+		sslConn := tls.Client(conn, &tls.Config{
+			ServerName: cm.endpoint.Host,
+		})
+		err = cm.handshakeWithTimeout(sslConn, connectionTimeout)
+		if err != nil {
+			log.Warn(err)
+			return nil, err
+		}
+		log.Debug("SSL handshake successful")
+		conn = sslConn
 	}
+
+	go cm.handleServerClose(conn)
+	return conn, nil
 }
 
 func (cm *ConnectionManager) handshakeWithTimeout(conn *tls.Conn, timeout time.Duration) error {
@@ -184,15 +234,18 @@ func (cm *ConnectionManager) handleServerClose(conn net.Conn) {
 // each invocation will trigger a sleep between [2^(retries-1), 2^retries) second
 // the exponent is capped at 7, which translates to max sleep between ~1min and ~2min
 func (cm *ConnectionManager) backoff(ctx context.Context, retries uint) {
+	ctx, cancel := context.WithTimeout(ctx, cm.backoffDuration(retries))
+	defer cancel()
+	<-ctx.Done()
+}
+
+// backoffDuration returns a random duration between [2^(retries-1), 2^retries) second
+func (cm *ConnectionManager) backoffDuration(retries uint) time.Duration {
 	if retries > maxExpBackoffCount {
 		retries = maxExpBackoffCount
 	}
 
 	backoffMax := 1 << retries
 	backoffMin := 1 << (retries - 1)
-	backoffDuration := time.Duration(backoffMin+rand.Intn(backoffMax-backoffMin)) * time.Second
-
-	ctx, cancel := context.WithTimeout(ctx, backoffDuration)
-	defer cancel()
-	<-ctx.Done()
+	return time.Duration(backoffMin+rand.Intn(backoffMax-backoffMin)) * time.Second
 }
@@ -71,6 +71,39 @@ func TestNewConnectionReturnsWhenContextCancelled(t *testing.T) {
 	wg.Wait()
 }
 
+func TestTryNewConnectionFailsWithoutBlocking(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	addr := l.Addr()
+	// nothing listens on the address anymore
+	l.Close()
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	connManager := newConnectionManagerForAddr(addr)
+
+	conn, err := connManager.TryNewConnection(destinationsCtx.Context())
+	assert.Nil(t, conn)
+	assert.Error(t, err)
+
+	// the next attempt is delayed by the backoff
+	start := time.Now()
+	conn, err = connManager.TryNewConnection(destinationsCtx.Context())
+	assert.Nil(t, conn)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "next attempt")
+	assert.True(t, time.Since(start) < time.Second)
+
+	// a single attempt is enough when the intake is reachable
+	l = mock.NewMockLogsIntake(t)
+	defer l.Close()
+	connManager = newConnectionManagerForAddr(l.Addr())
+	conn, err = connManager.TryNewConnection(destinationsCtx.Context())
+	assert.NotNil(t, conn)
+	assert.NoError(t, err)
+}
+
 func TestShouldReset(t *testing.T) {
 	endpoint := config.Endpoint{ConnectionResetInterval: time.Duration(10) * time.Second}
 	connManager := NewConnectionManager(endpoint)
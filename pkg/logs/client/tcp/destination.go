@@ -31,6 +31,8 @@ type Destination struct {
 	connCreationTime    time.Time
 	inputChan           chan []byte
 	once                sync.Once
+	// nonBlocking destinations fail instead of waiting for the connection to be established
+	nonBlocking bool
 }
 
 // NewDestination returns a new destination.
@@ -44,6 +46,15 @@ func NewDestination(endpoint config.Endpoint, useProto bool, destinationsContext
 	}
 }
 
+// NewNonBlockingDestination returns a new destination failing with a retryable error
+// instead of blocking while the connection to the server can't be established,
+// the payloads can then be stored until the server is reachable again.
+func NewNonBlockingDestination(endpoint config.Endpoint, useProto bool, destinationsContext *client.DestinationsContext) *Destination {
+	d := NewDestination(endpoint, useProto, destinationsContext)
+	d.nonBlocking = true
+	return d
+}
+
 // Send transforms a message into a frame and sends it to a remote server,
 // returns an error if the operation failed.
 func (d *Destination) Send(payload []byte) error {
@@ -52,7 +63,14 @@ func (d *Destination) Send(payload []byte) error {
 
 		// We work only if we have a started destination context
 		ctx := d.destinationsContext.Context()
-		if d.conn, err = d.connManager.NewConnection(ctx); err != nil {
+		if d.nonBlocking {
+			if d.conn, err = d.connManager.TryNewConnection(ctx); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				return client.NewRetryableError(err)
+			}
+		} else if d.conn, err = d.connManager.NewConnection(ctx); err != nil {
 			// the connection manager is not meant to fail,
 			// this can happen only when the context is cancelled.
 			return err
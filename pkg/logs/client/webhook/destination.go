@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+)
+
+const (
+	warningPeriod = 1000
+	timeout       = 10 * time.Second
+
+	// the delay between two attempts to post a payload doubles up to maxRetryBackoff
+	minRetryBackoff = time.Second
+	maxRetryBackoff = 2 * time.Minute
+)
+
+// Destination posts JSON messages to a generic HTTP endpoint,
+// the messages sent asynchronously are batched in JSON arrays.
+type Destination struct {
+	name                string
+	url                 string
+	client              *httputils.ResetClient
+	destinationsContext *client.DestinationsContext
+	batchWait           time.Duration
+	inputChan           chan *message.Message
+	once                sync.Once
+	mu                  sync.Mutex
+}
+
+// NewDestination returns a new webhook destination, the messages sent asynchronously
+// are posted at least every batchWait.
+func NewDestination(endpoint config.Endpoint, batchWait time.Duration, destinationsContext *client.DestinationsContext) *Destination {
+	return &Destination{
+		name:                endpoint.Name,
+		url:                 endpoint.URL,
+		client:              httputils.NewResetClient(endpoint.ConnectionResetInterval, httpClientFactory(timeout)),
+		destinationsContext: destinationsContext,
+		batchWait:           batchWait,
+	}
+}
+
+// Send posts a payload to the webhook,
+// the error returned can be retryable and it is the responsibility of the callee to retry.
+func (d *Destination) Send(payload []byte) error {
+	ctx := d.destinationsContext.Context()
+
+	req, err := http.NewRequest("POST", d.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(ctx)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return ctx.Err()
+		}
+		return client.NewRetryableError(err)
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 500 {
+		return client.NewRetryableError(fmt.Errorf("webhook %s returned %s", d.name, resp.Status))
+	} else if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook %s returned %s", d.name, resp.Status)
+	}
+	return nil
+}
+
+// SendAsync adds a JSON message to the next batch posted to the webhook without blocking.
+// If the channel is full, the incoming messages will be dropped
+func (d *Destination) SendAsync(payload []byte) {
+	d.once.Do(func() {
+		d.inputChan = make(chan *message.Message, config.ChanSize)
+		// the counter is shared with the previous instances of the destination
+		metrics.DestinationLogsDropped.Add(d.name, 0)
+		go d.runAsync()
+	})
+
+	select {
+	case d.inputChan <- message.NewMessage(payload, nil, "", 0):
+	default:
+		if metrics.DestinationLogsDropped.Get(d.name).(*expvar.Int).Value()%warningPeriod == 0 {
+			log.Warnf("Some logs sent to webhook destination %v were dropped", d.name)
+		}
+		metrics.DestinationLogsDropped.Add(d.name, 1)
+		metrics.TlmLogsDropped.Inc(d.name)
+	}
+}
+
+// runAsync batches the messages in JSON arrays and posts them
+func (d *Destination) runAsync() {
+	outputChan := make(chan *message.Message, config.ChanSize)
+	go func() {
+		// the messages have already been audited by the main destination
+		for range outputChan {
+		}
+	}()
+	strategy := sender.NewBatchStrategy(sender.ArraySerializer, d.batchWait)
+	strategy.Send(d.inputChan, outputChan, d.sendWithRetry, &d.mu)
+}
+
+// sendWithRetry posts a payload to the webhook and retries while the error is retryable,
+// until the destinations context is cancelled.
+func (d *Destination) sendWithRetry(payload []byte) error {
+	ctx := d.destinationsContext.Context()
+	backoff := minRetryBackoff
+	for {
+		err := d.Send(payload)
+		if _, ok := err.(*client.RetryableError); !ok {
+			return err
+		}
+		log.Warnf("Could not send payload to webhook destination %v, retrying in %v: %v", d.name, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+func httpClientFactory(timeout time.Duration) func() *http.Client {
+	return func() *http.Client {
+		return &http.Client{
+			Timeout: timeout,
+			// reusing core agent HTTP transport to benefit from proxy settings.
+			Transport: httputils.CreateHTTPTransport(),
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func newTestDestination(url string) (*Destination, *client.DestinationsContext) {
+	ctx := client.NewDestinationsContext()
+	ctx.Start()
+	return NewDestination(config.Endpoint{Name: "hook", Type: config.EndpointTypeWebhook, URL: url}, 100*time.Millisecond, ctx), ctx
+}
+
+func TestDestinationPostsJSON(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	destination, ctx := newTestDestination(server.URL)
+	defer ctx.Stop()
+
+	assert.NoError(t, destination.Send([]byte(`{"message":"hello"}`)))
+	assert.Equal(t, `{"message":"hello"}`, <-received)
+
+	destination.SendAsync([]byte(`{"message":"world"}`))
+	destination.SendAsync([]byte(`{"message":"again"}`))
+	assert.Equal(t, `[{"message":"world"},{"message":"again"}]`, <-received)
+}
+
+func TestDestinationRetriesAsync(t *testing.T) {
+	var attempts int32
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	destination, ctx := newTestDestination(server.URL)
+	defer ctx.Stop()
+
+	destination.SendAsync([]byte(`{"message":"hello"}`))
+	select {
+	case payload := <-received:
+		assert.Equal(t, `[{"message":"hello"}]`, payload)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the payload was not retried")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestDestinationErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/client_error" {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	destination, ctx := newTestDestination(server.URL + "/server_error")
+	defer ctx.Stop()
+	err := destination.Send([]byte(`{}`))
+	assert.IsType(t, &client.RetryableError{}, err)
+
+	destination, ctx = newTestDestination(server.URL + "/client_error")
+	defer ctx.Stop()
+	err = destination.Send([]byte(`{}`))
+	assert.NotNil(t, err)
+	_, retryable := err.(*client.RetryableError)
+	assert.False(t, retryable)
+}
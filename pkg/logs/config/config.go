@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -75,16 +76,35 @@ func SNMPTrapsSource() *LogSource {
 
 // GlobalProcessingRules returns the global processing rules to apply to all logs.
 func GlobalProcessingRules() ([]*ProcessingRule, error) {
+	return processingRulesFromConfig(coreConfig.Datadog)
+}
+
+// ReadGlobalProcessingRules reads again the configuration file used by the agent
+// and returns the global processing rules it defines, it does not alter the agent configuration.
+func ReadGlobalProcessingRules() ([]*ProcessingRule, error) {
+	cfg := coreConfig.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	cfg.BindEnv("logs_config.processing_rules") //nolint:errcheck
+	if configFile := coreConfig.Datadog.ConfigFileUsed(); configFile != "" {
+		cfg.SetConfigFile(configFile)
+		if err := cfg.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("unable to read the configuration file %s: %v", configFile, err)
+		}
+	}
+	return processingRulesFromConfig(cfg)
+}
+
+// processingRulesFromConfig returns the validated and compiled global processing rules defined in cfg.
+func processingRulesFromConfig(cfg coreConfig.Config) ([]*ProcessingRule, error) {
 	var rules []*ProcessingRule
 	var err error
-	raw := coreConfig.Datadog.Get("logs_config.processing_rules")
+	raw := cfg.Get("logs_config.processing_rules")
 	if raw == nil {
 		return rules, nil
 	}
 	if s, ok := raw.(string); ok && s != "" {
 		err = json.Unmarshal([]byte(s), &rules)
 	} else {
-		err = coreConfig.Datadog.UnmarshalKey("logs_config.processing_rules", &rules)
+		err = cfg.UnmarshalKey("logs_config.processing_rules", &rules)
 	}
 	if err != nil {
 		return nil, err
@@ -137,8 +157,15 @@ func isForceHTTPUse() bool {
 	return coreConfig.Datadog.GetBool("logs_config.use_http")
 }
 
+// hasAdditionalEndpoints returns true if logs are dualshipped to other Datadog intakes,
+// generic endpoints don't depend on the protocol used to send logs to Datadog.
 func hasAdditionalEndpoints() bool {
-	return len(getAdditionalEndpoints()) > 0
+	for _, endpoint := range getAdditionalEndpoints() {
+		if !endpoint.IsGeneric() {
+			return true
+		}
+	}
+	return false
 }
 
 func buildTCPEndpoints() (*Endpoints, error) {
@@ -179,6 +206,10 @@ func buildTCPEndpoints() (*Endpoints, error) {
 
 	additionals := getAdditionalEndpoints()
 	for i := 0; i < len(additionals); i++ {
+		if additionals[i].IsGeneric() {
+			// generic endpoints have their own connection settings
+			continue
+		}
 		additionals[i].UseSSL = main.UseSSL
 		additionals[i].ProxyAddress = proxyAddress
 		additionals[i].APIKey = coreConfig.SanitizeAPIKey(additionals[i].APIKey)
@@ -190,6 +221,7 @@ func buildTCPEndpoints() (*Endpoints, error) {
 type LogsConfigKeys struct {
 	UseCompression          string
 	CompressionLevel        string
+	CompressionKind         string
 	ConnectionResetInterval string
 	LogsDDURL               string
 	LogsNoSSL               string
@@ -203,6 +235,7 @@ type LogsConfigKeys struct {
 var logsConfigDefaultKeys = LogsConfigKeys{
 	UseCompression:          "logs_config.use_compression",
 	CompressionLevel:        "logs_config.compression_level",
+	CompressionKind:         "logs_config.compression_kind",
 	ConnectionResetInterval: "logs_config.connection_reset_interval",
 	LogsDDURL:               "logs_config.logs_dd_url",
 	LogsNoSSL:               "logs_config.logs_no_ssl",
@@ -230,10 +263,17 @@ func BuildHTTPEndpointsWithConfig(logsConfig LogsConfigKeys, endpointPrefix stri
 		defaultUseCompression = coreConfig.Datadog.GetBool(logsConfig.UseCompression)
 	}
 
+	// an empty kind compresses the payloads with gzip
+	defaultCompressionKind := ""
+	if len(logsConfig.CompressionKind) != 0 {
+		defaultCompressionKind = coreConfig.Datadog.GetString(logsConfig.CompressionKind)
+	}
+
 	main := Endpoint{
 		APIKey:                  getLogsAPIKey(coreConfig.Datadog),
 		UseCompression:          defaultUseCompression,
 		CompressionLevel:        coreConfig.Datadog.GetInt(logsConfig.CompressionLevel),
+		CompressionKind:         defaultCompressionKind,
 		ConnectionResetInterval: time.Duration(coreConfig.Datadog.GetInt(logsConfig.ConnectionResetInterval)) * time.Second,
 	}
 
@@ -253,6 +293,10 @@ func BuildHTTPEndpointsWithConfig(logsConfig LogsConfigKeys, endpointPrefix stri
 
 	additionals := getAdditionalEndpointsFromKey(logsConfig.AdditionalEndpoints)
 	for i := 0; i < len(additionals); i++ {
+		if additionals[i].IsGeneric() {
+			// generic endpoints have their own connection settings
+			continue
+		}
 		additionals[i].UseSSL = main.UseSSL
 		additionals[i].APIKey = coreConfig.SanitizeAPIKey(additionals[i].APIKey)
 	}
@@ -327,3 +371,37 @@ func TaggerWarmupDuration() time.Duration {
 func AggregationTimeout() time.Duration {
 	return coreConfig.Datadog.GetDuration("logs_config.aggregation_timeout") * time.Millisecond
 }
+
+// AutoMultiLineDetection returns true if multi-line detection should be attempted on sources
+// which don't set auto_multi_line_detection explicitly.
+func AutoMultiLineDetection() bool {
+	return coreConfig.Datadog.GetBool("logs_config.auto_multi_line_detection")
+}
+
+// AutoMultiLineSampleSize returns the default number of lines used to detect a multi-line pattern.
+func AutoMultiLineSampleSize() int {
+	return coreConfig.Datadog.GetInt("logs_config.auto_multi_line_default_sample_size")
+}
+
+// AutoMultiLineMatchTimeout returns the maximum time spent sampling a source before deciding
+// whether it should be handled as multi-line.
+func AutoMultiLineMatchTimeout() time.Duration {
+	return coreConfig.Datadog.GetDuration("logs_config.auto_multi_line_default_match_timeout") * time.Second
+}
+
+// AutoMultiLineMatchThreshold returns the default ratio of sampled lines a pattern must match
+// to be selected as the start-of-record pattern of a source.
+func AutoMultiLineMatchThreshold() float64 {
+	return coreConfig.Datadog.GetFloat64("logs_config.auto_multi_line_default_match_threshold")
+}
+
+// SpoolPath returns the directory where the payloads that can't be sent are stored.
+func SpoolPath() string {
+	return coreConfig.Datadog.GetString("logs_config.spool_path")
+}
+
+// SpoolMaxSizeInBytes returns the maximum disk space used to store the payloads
+// that can't be sent, 0 means that the spool is disabled.
+func SpoolMaxSizeInBytes() int64 {
+	return coreConfig.Datadog.GetInt64("logs_config.spool_max_size_in_bytes")
+}
@@ -6,6 +6,7 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -128,6 +129,56 @@ func (suite *ConfigTestSuite) TestGlobalProcessingRulesShouldReturnRulesWithVali
 	suite.NotNil(rule.Regex)
 }
 
+func (suite *ConfigTestSuite) TestReadGlobalProcessingRulesShouldReadTheConfigurationFile() {
+	configFile, err := ioutil.TempFile("", "datadog.*.yaml")
+	suite.Nil(err)
+	defer os.Remove(configFile.Name())
+	_, err = configFile.WriteString(`
+logs_config:
+  processing_rules:
+    - type: exclude_at_match
+      name: exclude_bar
+      pattern: bar
+`)
+	suite.Nil(err)
+	suite.Nil(configFile.Close())
+
+	suite.config.Set("logs_config.processing_rules", `[{"type":"exclude_at_match","name":"exclude_foo","pattern":"foo"}]`)
+	suite.config.SetConfigFile(configFile.Name())
+
+	rules, err := ReadGlobalProcessingRules()
+	suite.Nil(err)
+	suite.Equal(1, len(rules))
+	suite.Equal("exclude_bar", rules[0].Name)
+	suite.NotNil(rules[0].Regex)
+
+	// the agent configuration is left untouched
+	rules, err = GlobalProcessingRules()
+	suite.Nil(err)
+	suite.Equal(1, len(rules))
+	suite.Equal("exclude_foo", rules[0].Name)
+}
+
+func (suite *ConfigTestSuite) TestReadGlobalProcessingRulesShouldFailWithInvalidRules() {
+	configFile, err := ioutil.TempFile("", "datadog.*.yaml")
+	suite.Nil(err)
+	defer os.Remove(configFile.Name())
+	_, err = configFile.WriteString(`
+logs_config:
+  processing_rules:
+    - type: exclude_at_match
+      name: exclude_bar
+`)
+	suite.Nil(err)
+	suite.Nil(configFile.Close())
+
+	suite.config.SetConfigFile(configFile.Name())
+
+	rules, err := ReadGlobalProcessingRules()
+	suite.NotNil(err)
+	suite.Nil(rules)
+}
+
 func (suite *ConfigTestSuite) TestTaggerWarmupDuration() {
 	// assert TaggerWarmupDuration is disabled by default
 	taggerWarmupDuration := TaggerWarmupDuration()
@@ -162,7 +213,8 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
-		CompressionLevel: 6}
+		CompressionLevel: 6,
+		CompressionKind:  "gzip"}
 	expectedAdditionalEndpoint1 := Endpoint{
 		APIKey:           "456",
 		Host:             "additional.endpoint.1",
@@ -248,7 +300,8 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
-		CompressionLevel: 6}
+		CompressionLevel: 6,
+		CompressionKind:  "gzip"}
 	expectedAdditionalEndpoint1 := Endpoint{
 		APIKey:           "456",
 		Host:             "additional.endpoint.1",
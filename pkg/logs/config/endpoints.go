@@ -6,37 +6,105 @@
 package config
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Endpoint types
+const (
+	// EndpointTypeDatadog is the type of the endpoints of the Datadog intake, it is the default type.
+	EndpointTypeDatadog = "datadog"
+	// EndpointTypeSyslog is the type of the endpoints receiving RFC5424 syslog messages over TCP.
+	EndpointTypeSyslog = "syslog"
+	// EndpointTypeWebhook is the type of the endpoints receiving JSON messages over HTTP.
+	EndpointTypeWebhook = "webhook"
 )
 
-// Endpoint holds all the organization and network parameters to send logs to Datadog.
+// Endpoint holds all the organization and network parameters to send logs to Datadog
+// or to a generic destination.
 type Endpoint struct {
 	APIKey                  string `mapstructure:"api_key" json:"api_key"`
 	Host                    string
 	Port                    int
-	UseSSL                  bool
-	UseCompression          bool `mapstructure:"use_compression" json:"use_compression"`
-	CompressionLevel        int  `mapstructure:"compression_level" json:"compression_level"`
+	UseSSL                  bool   `mapstructure:"use_ssl" json:"use_ssl"`
+	UseCompression          bool   `mapstructure:"use_compression" json:"use_compression"`
+	CompressionLevel        int    `mapstructure:"compression_level" json:"compression_level"`
+	CompressionKind         string `mapstructure:"compression_kind" json:"compression_kind"`
 	ProxyAddress            string
 	ConnectionResetInterval time.Duration
+
+	// Generic destinations only, they receive the logs of the sources selecting them by name.
+	Name string `mapstructure:"name" json:"name"`
+	Type string `mapstructure:"type" json:"type"`
+	URL  string `mapstructure:"url" json:"url"` // Webhook
 }
 
-// Endpoints holds the main endpoint and additional ones to dualship logs.
+// IsGeneric returns true if the endpoint is not a Datadog intake.
+func (e *Endpoint) IsGeneric() bool {
+	return e.Type != "" && e.Type != EndpointTypeDatadog
+}
+
+// validateGeneric returns an error if the generic endpoint is misconfigured.
+func (e *Endpoint) validateGeneric() error {
+	if e.Name == "" {
+		return fmt.Errorf("a %s endpoint must have a name", e.Type)
+	}
+	switch e.Type {
+	case EndpointTypeSyslog:
+		if e.Host == "" || e.Port == 0 {
+			return fmt.Errorf("syslog endpoint %s must have a host and a port", e.Name)
+		}
+	case EndpointTypeWebhook:
+		if e.URL == "" {
+			return fmt.Errorf("webhook endpoint %s must have a url", e.Name)
+		}
+	default:
+		return fmt.Errorf("endpoint %s has an unknown type: %s", e.Name, e.Type)
+	}
+	return nil
+}
+
+// Endpoints holds the main endpoint and additional ones to dualship logs,
+// and the generic endpoints receiving the logs of the sources selecting them.
 type Endpoints struct {
 	Main        Endpoint
 	Additionals []Endpoint
+	Generics    []Endpoint
 	UseProto    bool
 	UseHTTP     bool
 	BatchWait   time.Duration
 }
 
 // NewEndpoints returns a new endpoints composite.
+// Generic endpoints are split from the additional ones.
 func NewEndpoints(main Endpoint, additionals []Endpoint, useProto bool, useHTTP bool, batchWait time.Duration) *Endpoints {
+	additionals, generics := splitGenericEndpoints(additionals)
 	return &Endpoints{
 		Main:        main,
 		Additionals: additionals,
+		Generics:    generics,
 		UseProto:    useProto,
 		UseHTTP:     useHTTP,
 		BatchWait:   batchWait,
 	}
 }
+
+// splitGenericEndpoints separates the Datadog endpoints from the generic ones,
+// misconfigured generic endpoints are discarded.
+func splitGenericEndpoints(endpoints []Endpoint) ([]Endpoint, []Endpoint) {
+	var datadog, generics []Endpoint
+	for _, endpoint := range endpoints {
+		if !endpoint.IsGeneric() {
+			datadog = append(datadog, endpoint)
+			continue
+		}
+		if err := endpoint.validateGeneric(); err != nil {
+			log.Warnf("Invalid logs endpoint: %v", err)
+			continue
+		}
+		generics = append(generics, endpoint)
+	}
+	return datadog, generics
+}
@@ -371,6 +371,68 @@ func (suite *EndpointsTestSuite) TestAdditionalEndpoints() {
 	suite.True(endpoint.UseSSL)
 }
 
+func (suite *EndpointsTestSuite) TestGenericEndpoints() {
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"host":    "foo",
+			"api_key": "1234",
+		},
+		{
+			"name":    "compliance",
+			"type":    "syslog",
+			"host":    "syslog.example.com",
+			"port":    6514,
+			"use_ssl": true,
+		},
+		{
+			"name": "hook",
+			"type": "webhook",
+			"url":  "http://hooks.example.com/logs",
+		},
+		{
+			"name": "invalid",
+			"type": "syslog",
+		},
+	})
+
+	endpoints, err := BuildEndpoints(HTTPConnectivityFailure)
+	suite.Nil(err)
+	suite.Len(endpoints.Additionals, 1)
+	suite.Equal("foo", endpoints.Additionals[0].Host)
+	suite.Len(endpoints.Generics, 2)
+
+	syslog := endpoints.Generics[0]
+	suite.Equal("compliance", syslog.Name)
+	suite.Equal(EndpointTypeSyslog, syslog.Type)
+	suite.Equal("syslog.example.com", syslog.Host)
+	suite.Equal(6514, syslog.Port)
+	suite.True(syslog.UseSSL)
+
+	webhook := endpoints.Generics[1]
+	suite.Equal("hook", webhook.Name)
+	suite.Equal(EndpointTypeWebhook, webhook.Type)
+	suite.Equal("http://hooks.example.com/logs", webhook.URL)
+}
+
+func (suite *EndpointsTestSuite) TestGenericEndpointsDoNotForceTCP() {
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"name": "compliance",
+			"type": "syslog",
+			"host": "localhost",
+			"port": 514,
+		},
+	})
+
+	endpoints, err := BuildEndpoints(HTTPConnectivitySuccess)
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.Len(endpoints.Additionals, 0)
+	suite.Len(endpoints.Generics, 1)
+	suite.False(endpoints.Generics[0].UseSSL)
+}
+
 func TestEndpointsTestSuite(t *testing.T) {
 	suite.Run(t, new(EndpointsTestSuite))
 }
@@ -16,6 +16,7 @@ import (
 const (
 	TCPType           = "tcp"
 	UDPType           = "udp"
+	SyslogType        = "syslog"
 	FileType          = "file"
 	DockerType        = "docker"
 	JournaldType      = "journald"
@@ -27,6 +28,9 @@ const (
 	UTF16BE string = "utf-16-be"
 	// UTF16LE for UTF-16 Little Endian encoding
 	UTF16LE string = "utf-16-le"
+
+	// GzipCompression for gzip compressed files
+	GzipCompression string = "gzip"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -34,12 +38,16 @@ const (
 type LogsConfig struct {
 	Type string
 
-	Port int    // Network
-	Path string // File, Journald
+	Port     int    // Network
+	Path     string // File, Journald
+	Protocol string `mapstructure:"protocol" json:"protocol"` // Syslog
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
+	Compression  string   `mapstructure:"compression" json:"compression"`       // File
+	// ReadRotatedArchives lets the tailer read the end of the archive a file was rotated to while it was not tailed.
+	ReadRotatedArchives bool `mapstructure:"read_rotated_archives" json:"read_rotated_archives"` // File
 
 	IncludeUnits  []string `mapstructure:"include_units" json:"include_units"`   // Journald
 	ExcludeUnits  []string `mapstructure:"exclude_units" json:"exclude_units"`   // Journald
@@ -66,6 +74,20 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	LogMetrics      []*LogMetric      `mapstructure:"log_metrics" json:"log_metrics"`
+
+	AutoMultiLine               *bool   `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
+	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size"`
+	AutoMultiLineMatchThreshold float64 `mapstructure:"auto_multi_line_match_threshold" json:"auto_multi_line_match_threshold"`
+
+	// Rate limiting and sampling, the sources of a service share their limits when the scope is "service".
+	MaxLinesPerSecond float64            `mapstructure:"max_lines_per_second" json:"max_lines_per_second"`
+	MaxBytesPerSecond float64            `mapstructure:"max_bytes_per_second" json:"max_bytes_per_second"`
+	RateLimitScope    string             `mapstructure:"rate_limit_scope" json:"rate_limit_scope"`
+	SamplingRates     map[string]float64 `mapstructure:"sampling_rates" json:"sampling_rates"` // by status
+
+	// Destinations contains the names of the generic endpoints which receive a copy of the logs of the source.
+	Destinations []string `mapstructure:"destinations" json:"destinations"`
 }
 
 // TailingMode type
@@ -125,18 +147,70 @@ func (c *LogsConfig) Validate() error {
 		if err != nil {
 			return err
 		}
+		if c.Compression != "" && c.Compression != GzipCompression {
+			return fmt.Errorf("compression is not supported: %s", c.Compression)
+		}
 	case c.Type == TCPType && c.Port == 0:
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType && c.Port == 0:
+		return fmt.Errorf("syslog source must have a port")
+	case c.Type == SyslogType && c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType:
+		return fmt.Errorf("syslog source protocol must be tcp or udp: %s", c.Protocol)
+	}
+	if c.MaxLinesPerSecond < 0 || c.MaxBytesPerSecond < 0 {
+		return fmt.Errorf("max_lines_per_second and max_bytes_per_second must be positive")
+	}
+	switch c.RateLimitScope {
+	case "", RateLimitScopeSource:
+	case RateLimitScopeService:
+		if c.Service == "" {
+			return fmt.Errorf("a service must be set to use the service rate limit scope")
+		}
+	default:
+		return fmt.Errorf("rate_limit_scope must be source or service: %s", c.RateLimitScope)
+	}
+	for status, rate := range c.SamplingRates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sampling rate of status %s must be between 0 and 1", status)
+		}
+	}
+	if c.AutoMultiLineSampleSize < 0 {
+		return fmt.Errorf("auto_multi_line_sample_size must be positive")
+	}
+	if c.AutoMultiLineMatchThreshold < 0 || c.AutoMultiLineMatchThreshold > 1 {
+		return fmt.Errorf("auto_multi_line_match_threshold must be between 0 and 1")
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
 		return err
 	}
+	err = ValidateLogMetrics(c.LogMetrics)
+	if err != nil {
+		return err
+	}
+	err = CompileLogMetrics(c.LogMetrics)
+	if err != nil {
+		return err
+	}
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+// IsRateLimited returns true if the lines or the bytes per second of the source are limited.
+func (c *LogsConfig) IsRateLimited() bool {
+	return c.MaxLinesPerSecond > 0 || c.MaxBytesPerSecond > 0
+}
+
+// IsAutoMultiLineEnabled returns true if multi-line detection should be attempted for this config,
+// the source setting takes precedence over the global one.
+func (c *LogsConfig) IsAutoMultiLineEnabled() bool {
+	if c.AutoMultiLine != nil {
+		return *c.AutoMultiLine
+	}
+	return AutoMultiLineDetection()
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
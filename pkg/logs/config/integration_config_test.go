@@ -18,9 +18,15 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: FileType, Path: "/var/log/foo.log"},
 		{Type: TCPType, Port: 1234},
 		{Type: UDPType, Port: 5678},
+		{Type: SyslogType, Port: 514},
+		{Type: SyslogType, Port: 514, Protocol: UDPType},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: SnmpTrapsType},
+		{Type: FileType, Path: "/var/log/foo.log", MaxLinesPerSecond: 100, MaxBytesPerSecond: 1024},
+		{Type: FileType, Path: "/var/log/foo.log", Service: "foo", MaxLinesPerSecond: 100, RateLimitScope: RateLimitScopeService},
+		{Type: FileType, Path: "/var/log/foo.log", SamplingRates: map[string]float64{"debug": 0, "info": 0.5}},
+		{Type: FileType, Path: "/var/log/foo.log.gz", Compression: GzipCompression},
 	}
 
 	for _, config := range validConfigs {
@@ -35,6 +41,8 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: SyslogType},
+		{Type: SyslogType, Port: 514, Protocol: "http"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -42,6 +50,14 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Pattern: ".*"}}},
+		{Type: FileType, Path: "/var/log/foo.log", AutoMultiLineMatchThreshold: 1.5},
+		{Type: FileType, Path: "/var/log/foo.log", AutoMultiLineSampleSize: -1},
+		{Type: FileType, Path: "/var/log/foo.log", MaxLinesPerSecond: -1},
+		{Type: FileType, Path: "/var/log/foo.log", MaxBytesPerSecond: -1},
+		{Type: FileType, Path: "/var/log/foo.log", MaxLinesPerSecond: 100, RateLimitScope: RateLimitScopeService},
+		{Type: FileType, Path: "/var/log/foo.log", MaxLinesPerSecond: 100, RateLimitScope: "host"},
+		{Type: FileType, Path: "/var/log/foo.log", SamplingRates: map[string]float64{"info": 1.5}},
+		{Type: FileType, Path: "/var/log/foo.log.zst", Compression: "zstd"},
 	}
 
 	for _, config := range invalidConfigs {
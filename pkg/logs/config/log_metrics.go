@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Log metric types
+const (
+	LogMetricCount     = "count"
+	LogMetricGauge     = "gauge"
+	LogMetricHistogram = "histogram"
+)
+
+// LogMetric defines a metric generated from the log lines matching a pattern,
+// its value is either 1 per matching line or the number captured by a named group.
+type LogMetric struct {
+	Name       string
+	Type       string
+	Pattern    string
+	ValueGroup string   `mapstructure:"value_group" json:"value_group"`
+	TagGroups  []string `mapstructure:"tag_groups" json:"tag_groups"`
+	Tags       []string
+	// TODO: should be moved out
+	Regex *regexp.Regexp
+}
+
+// ValidateLogMetrics validates the log metrics and raises an error if one is misconfigured.
+// Each log metric must have:
+// - a valid name
+// - a valid type, a value group is required by gauges and histograms
+// - a valid pattern that compiles
+// - named capture groups matching its value and tag groups
+func ValidateLogMetrics(logMetrics []*LogMetric) error {
+	for _, metric := range logMetrics {
+		if metric.Name == "" {
+			return fmt.Errorf("all log metrics must have a name")
+		}
+
+		switch metric.Type {
+		case LogMetricCount:
+			break
+		case LogMetricGauge, LogMetricHistogram:
+			if metric.ValueGroup == "" {
+				return fmt.Errorf("value_group must be set for log metric `%s` of type %s", metric.Name, metric.Type)
+			}
+		case "":
+			return fmt.Errorf("type must be set for log metric `%s`", metric.Name)
+		default:
+			return fmt.Errorf("type %s is not supported for log metric `%s`", metric.Type, metric.Name)
+		}
+
+		if metric.Pattern == "" {
+			return fmt.Errorf("no pattern provided for log metric: %s", metric.Name)
+		}
+		re, err := regexp.Compile(metric.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s for log metric: %s", metric.Pattern, metric.Name)
+		}
+		groups := append([]string{}, metric.TagGroups...)
+		if metric.ValueGroup != "" {
+			groups = append(groups, metric.ValueGroup)
+		}
+		for _, group := range groups {
+			if !hasCaptureGroup(re, group) {
+				return fmt.Errorf("pattern %s of log metric `%s` has no capture group named %s", metric.Pattern, metric.Name, group)
+			}
+		}
+	}
+	return nil
+}
+
+// hasCaptureGroup returns true if the regular expression defines a capture group with this name.
+func hasCaptureGroup(re *regexp.Regexp, name string) bool {
+	for _, subexpName := range re.SubexpNames() {
+		if subexpName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileLogMetrics compiles all log metric regular expressions.
+func CompileLogMetrics(logMetrics []*LogMetric) error {
+	for _, metric := range logMetrics {
+		re, err := regexp.Compile(metric.Pattern)
+		if err != nil {
+			return err
+		}
+		metric.Regex = re
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLogMetrics(t *testing.T) {
+	validLogMetrics := []*LogMetric{
+		{Name: "app.errors", Type: LogMetricCount, Pattern: "ERROR"},
+		{Name: "app.errors", Type: LogMetricCount, Pattern: "ERROR (?P<code>\\d+)", TagGroups: []string{"code"}},
+		{Name: "app.bytes", Type: LogMetricCount, Pattern: "sent (?P<bytes>\\d+) bytes", ValueGroup: "bytes"},
+		{Name: "app.latency", Type: LogMetricGauge, Pattern: "took (?P<ms>[\\d.]+)ms", ValueGroup: "ms"},
+		{Name: "app.latency", Type: LogMetricHistogram, Pattern: "took (?P<ms>[\\d.]+)ms", ValueGroup: "ms"},
+	}
+	for _, metric := range validLogMetrics {
+		assert.Nil(t, ValidateLogMetrics([]*LogMetric{metric}))
+	}
+
+	invalidLogMetrics := []*LogMetric{
+		{Type: LogMetricCount, Pattern: "ERROR"},
+		{Name: "app.errors", Pattern: "ERROR"},
+		{Name: "app.errors", Type: "rate", Pattern: "ERROR"},
+		{Name: "app.errors", Type: LogMetricCount},
+		{Name: "app.errors", Type: LogMetricCount, Pattern: "("},
+		{Name: "app.errors", Type: LogMetricCount, Pattern: "ERROR (\\d+)", TagGroups: []string{"code"}},
+		{Name: "app.latency", Type: LogMetricGauge, Pattern: "took (?P<ms>[\\d.]+)ms"},
+		{Name: "app.latency", Type: LogMetricHistogram, Pattern: "took ([\\d.]+)ms", ValueGroup: "ms"},
+	}
+	for _, metric := range invalidLogMetrics {
+		assert.NotNil(t, ValidateLogMetrics([]*LogMetric{metric}))
+	}
+}
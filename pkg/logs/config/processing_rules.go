@@ -16,25 +16,43 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	ParseJSON      = "parse_json"
+	ParseKeyValue  = "parse_key_value"
+	ParseRegex     = "parse_regex"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
+// ProcessingRule defines an exclusion, a masking or a parsing rule to
 // be applied on log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Parsing rules only
+	StatusAttribute   string   `mapstructure:"status_attribute" json:"status_attribute"`
+	TagAttributes     []string `mapstructure:"tag_attributes" json:"tag_attributes"`
+	ExcludeAttributes []string `mapstructure:"exclude_attributes" json:"exclude_attributes"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
 }
 
+// IsParsingRule returns true if the rule extracts attributes from log lines.
+func (r *ProcessingRule) IsParsingRule() bool {
+	switch r.Type {
+	case ParseJSON, ParseKeyValue, ParseRegex:
+		return true
+	default:
+		return false
+	}
+}
+
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, json and key/value parsing rules don't need a pattern
+// - at least one named capture group for regex parsing rules
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,8 +60,13 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, ParseRegex:
 			break
+		case ParseJSON, ParseKeyValue:
+			if rule.Pattern != "" {
+				return fmt.Errorf("pattern is not supported for processing rule `%s` of type %s", rule.Name, rule.Type)
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,23 +76,39 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+		if rule.Type == ParseRegex && !hasNamedGroup(re) {
+			return fmt.Errorf("pattern %s of processing rule `%s` must contain at least one named capture group", rule.Pattern, rule.Name)
+		}
 	}
 	return nil
 }
 
+// hasNamedGroup returns true if the regular expression defines at least one named capture group.
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == ParseJSON || rule.Type == ParseKeyValue {
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, ParseRegex:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -33,3 +33,25 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateParsingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "json", Type: ParseJSON, StatusAttribute: "level"},
+		{Name: "kv", Type: ParseKeyValue, TagAttributes: []string{"user"}},
+		{Name: "regex", Type: ParseRegex, Pattern: `user=(?P<user>\w+)`},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.NotNil(t, validRules[2].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "json", Type: ParseJSON, Pattern: ".*"},
+		{Name: "regex", Type: ParseRegex},
+		{Name: "regex", Type: ParseRegex, Pattern: `user=(\w+)`},
+		{Name: "regex", Type: ParseRegex, Pattern: `user=(?P<user>\w+`},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}))
+	}
+}
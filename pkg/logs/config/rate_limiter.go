@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate limit scopes
+const (
+	RateLimitScopeSource  = "source"
+	RateLimitScopeService = "service"
+)
+
+var (
+	serviceRateLimiters     = make(map[serviceRateLimiterKey]*RateLimiter)
+	serviceRateLimitersLock sync.Mutex
+)
+
+// serviceRateLimiterKey identifies the limiter shared by the sources of a service with the same limits.
+type serviceRateLimiterKey struct {
+	service        string
+	linesPerSecond float64
+	bytesPerSecond float64
+}
+
+// tokenBucket holds up to one second worth of tokens, refilled continuously at a fixed rate.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		tokens: rate,
+		last:   now,
+	}
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+}
+
+// hasTokens returns true if n tokens can be taken, a request bigger than the bucket
+// only requires a full bucket.
+func (b *tokenBucket) hasTokens(n float64) bool {
+	if n > b.rate {
+		n = b.rate
+	}
+	return b.tokens >= n
+}
+
+// RateLimiter limits the number of lines and bytes per second of one or several sources.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	mu    sync.Mutex
+	lines *tokenBucket
+	bytes *tokenBucket
+	now   func() time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter, a rate of 0 means unlimited.
+func NewRateLimiter(linesPerSecond, bytesPerSecond float64) *RateLimiter {
+	return newRateLimiter(linesPerSecond, bytesPerSecond, time.Now)
+}
+
+func newRateLimiter(linesPerSecond, bytesPerSecond float64, now func() time.Time) *RateLimiter {
+	r := &RateLimiter{now: now}
+	if linesPerSecond > 0 {
+		r.lines = newTokenBucket(linesPerSecond, now())
+	}
+	if bytesPerSecond > 0 {
+		r.bytes = newTokenBucket(bytesPerSecond, now())
+	}
+	return r
+}
+
+// Allow returns true if a line of size bytes can be sent and consumes the matching tokens.
+// The bytes of a line bigger than the byte rate are borrowed from the following seconds.
+func (r *RateLimiter) Allow(size int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if r.lines != nil {
+		r.lines.refill(now)
+		if !r.lines.hasTokens(1) {
+			return false
+		}
+	}
+	if r.bytes != nil {
+		r.bytes.refill(now)
+		if !r.bytes.hasTokens(float64(size)) {
+			return false
+		}
+		r.bytes.tokens -= float64(size)
+	}
+	if r.lines != nil {
+		r.lines.tokens--
+	}
+	return true
+}
+
+// getServiceRateLimiter returns the rate limiter shared by the sources of a service with the same limits,
+// a source configured with other limits gets its own limiter instead of the stale one of the service.
+func getServiceRateLimiter(service string, linesPerSecond, bytesPerSecond float64) *RateLimiter {
+	serviceRateLimitersLock.Lock()
+	defer serviceRateLimitersLock.Unlock()
+	key := serviceRateLimiterKey{service: service, linesPerSecond: linesPerSecond, bytesPerSecond: bytesPerSecond}
+	limiter, exists := serviceRateLimiters[key]
+	if !exists {
+		limiter = NewRateLimiter(linesPerSecond, bytesPerSecond)
+		serviceRateLimiters[key] = limiter
+	}
+	return limiter
+}
+
+// RemoveStaleServiceRateLimiters removes the shared rate limiters no source uses anymore, once the
+// sources of a service are removed or reloaded with other limits.
+func RemoveStaleServiceRateLimiters(sources []*LogSource) {
+	used := make(map[serviceRateLimiterKey]bool)
+	for _, source := range sources {
+		if source.Config == nil || !source.Config.IsRateLimited() || source.Config.RateLimitScope != RateLimitScopeService {
+			continue
+		}
+		used[serviceRateLimiterKey{service: source.Config.Service, linesPerSecond: source.Config.MaxLinesPerSecond, bytesPerSecond: source.Config.MaxBytesPerSecond}] = true
+	}
+	serviceRateLimitersLock.Lock()
+	defer serviceRateLimitersLock.Unlock()
+	for key := range serviceRateLimiters {
+		if !used[key] {
+			delete(serviceRateLimiters, key)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestRateLimiterLimitsLines(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newRateLimiter(2, 0, clock.Now)
+
+	assert.True(t, limiter.Allow(10))
+	assert.True(t, limiter.Allow(10))
+	assert.False(t, limiter.Allow(10))
+
+	clock.Add(500 * time.Millisecond)
+	assert.True(t, limiter.Allow(10))
+	assert.False(t, limiter.Allow(10))
+
+	// the bucket never holds more than one second of tokens
+	clock.Add(time.Minute)
+	assert.True(t, limiter.Allow(10))
+	assert.True(t, limiter.Allow(10))
+	assert.False(t, limiter.Allow(10))
+}
+
+func TestRateLimiterLimitsBytes(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newRateLimiter(0, 100, clock.Now)
+
+	assert.True(t, limiter.Allow(60))
+	assert.False(t, limiter.Allow(60))
+	assert.True(t, limiter.Allow(40))
+	assert.False(t, limiter.Allow(1))
+
+	clock.Add(time.Second)
+	// a line bigger than the rate is accepted with a full bucket and delays the following ones
+	assert.True(t, limiter.Allow(250))
+	clock.Add(time.Second)
+	assert.False(t, limiter.Allow(1))
+	clock.Add(2 * time.Second)
+	assert.True(t, limiter.Allow(100))
+}
+
+func TestRateLimiterLimitsLinesAndBytes(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newRateLimiter(2, 100, clock.Now)
+
+	assert.True(t, limiter.Allow(90))
+	// a line rejected on bytes does not consume a line token
+	assert.False(t, limiter.Allow(20))
+	assert.True(t, limiter.Allow(10))
+	assert.False(t, limiter.Allow(0))
+}
+
+func TestGetRateLimiter(t *testing.T) {
+	source := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/foo.log"})
+	assert.Nil(t, source.GetRateLimiter())
+
+	source = NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/foo.log", MaxLinesPerSecond: 1})
+	assert.NotNil(t, source.GetRateLimiter())
+	assert.True(t, source.GetRateLimiter() == source.GetRateLimiter())
+
+	// the sources of a service share the same limiter
+	first := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/foo.log", Service: "rate-limited", MaxLinesPerSecond: 1, RateLimitScope: RateLimitScopeService})
+	second := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/bar.log", Service: "rate-limited", MaxLinesPerSecond: 1, RateLimitScope: RateLimitScopeService})
+	assert.True(t, first.GetRateLimiter() == second.GetRateLimiter())
+	assert.True(t, first.GetRateLimiter().Allow(1))
+	assert.False(t, second.GetRateLimiter().Allow(1))
+
+	// a source of the service with other limits, for instance after a config reload, doesn't get the stale limiter
+	third := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/foo.log", Service: "rate-limited", MaxLinesPerSecond: 10, RateLimitScope: RateLimitScopeService})
+	assert.False(t, first.GetRateLimiter() == third.GetRateLimiter())
+	assert.True(t, third.GetRateLimiter().Allow(1))
+}
+
+func TestRemoveStaleServiceRateLimiters(t *testing.T) {
+	kept := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/foo.log", Service: "reloaded", MaxLinesPerSecond: 1, RateLimitScope: RateLimitScopeService})
+	removed := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/bar.log", Service: "reloaded", MaxLinesPerSecond: 2, RateLimitScope: RateLimitScopeService})
+	limiter := kept.GetRateLimiter()
+	removed.GetRateLimiter()
+
+	RemoveStaleServiceRateLimiters([]*LogSource{kept})
+
+	// the sources still in use keep sharing their limiter
+	added := NewLogSource("", &LogsConfig{Type: FileType, Path: "/var/log/baz.log", Service: "reloaded", MaxLinesPerSecond: 1, RateLimitScope: RateLimitScopeService})
+	assert.True(t, limiter == added.GetRateLimiter())
+
+	serviceRateLimitersLock.Lock()
+	defer serviceRateLimitersLock.Unlock()
+	assert.NotContains(t, serviceRateLimiters, serviceRateLimiterKey{service: "reloaded", linesPerSecond: 2})
+}
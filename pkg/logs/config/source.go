@@ -31,6 +31,9 @@ type LogSource struct {
 	// Put expvar Int first because it's modified with sync/atomic, so it needs to
 	// be 64-bit aligned on 32-bit systems. See https://golang.org/pkg/sync/atomic/#pkg-note-BUG
 	BytesRead expvar.Int
+	// RateLimited and SampledOut count the logs dropped by the rate limiter and the sampling of the source.
+	RateLimited expvar.Int
+	SampledOut  expvar.Int
 
 	Name     string
 	Config   *LogsConfig
@@ -48,6 +51,7 @@ type LogSource struct {
 	// LatencyStats tracks internal stats on the time spent by messages from this source in a processing pipeline, i.e.
 	// the duration between when a message is decoded by the tailer/listener/decoder and when the message is handled by a sender
 	LatencyStats *util.StatsTracker
+	rateLimiter  *RateLimiter
 }
 
 // NewLogSource creates a new log source.
@@ -128,3 +132,20 @@ func (s *LogSource) GetInfo() []string {
 	}
 	return info
 }
+
+// GetRateLimiter returns the rate limiter of the source, nil if the source is not rate limited.
+func (s *LogSource) GetRateLimiter() *RateLimiter {
+	if !s.Config.IsRateLimited() {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.rateLimiter == nil {
+		if s.Config.RateLimitScope == RateLimitScopeService {
+			s.rateLimiter = getServiceRateLimiter(s.Config.Service, s.Config.MaxLinesPerSecond, s.Config.MaxBytesPerSecond)
+		} else {
+			s.rateLimiter = NewRateLimiter(s.Config.MaxLinesPerSecond, s.Config.MaxBytesPerSecond)
+		}
+	}
+	return s.rateLimiter
+}
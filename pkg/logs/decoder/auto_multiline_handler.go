@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// autoMultiLineMessageKey is the key of the message reporting the detection
+// result in the status of the source.
+const autoMultiLineMessageKey = "auto_multi_line"
+
+// startOfRecordPatterns contains the patterns commonly found at the beginning of a log record,
+// they are tested in order so that the most specific ones win when several patterns get the same score.
+var startOfRecordPatterns = []*regexp.Regexp{
+	// time.RFC3339, time.RFC3339Nano: 2006-01-02T15:04:05.999999999Z07:00
+	regexp.MustCompile(`^\d+-\d+-\d+T\d+:\d+:\d+(\.\d+)?(Z|[+-]\d+:?\d*)?`),
+	// 2006-01-02 15:04:05,000 (python logging, log4j ISO8601)
+	regexp.MustCompile(`^\d+-\d+-\d+ \d+:\d+:\d+([.,]\d+)?`),
+	// [2006-01-02 15:04:05] or [2006-01-02T15:04:05]
+	regexp.MustCompile(`^\[\d+-\d+-\d+[ T]\d+:\d+:\d+`),
+	// 2006/01/02 15:04:05 (go log package)
+	regexp.MustCompile(`^\d+/\d+/\d+ \d+:\d+:\d+`),
+	// time.ANSIC: Mon Jan _2 15:04:05 2006
+	regexp.MustCompile(`^[A-Za-z_]+ [A-Za-z_]+ +\d+ \d+:\d+:\d+ \d+`),
+	// time.UnixDate: Mon Jan _2 15:04:05 MST 2006
+	regexp.MustCompile(`^[A-Za-z_]+ [A-Za-z_]+ +\d+ \d+:\d+:\d+( [A-Za-z_]+ \d+)?`),
+	// time.RubyDate: Mon Jan 02 15:04:05 -0700 2006
+	regexp.MustCompile(`^[A-Za-z_]+ [A-Za-z_]+ \d+ \d+:\d+:\d+ [\-\+]\d+ \d+`),
+	// time.RFC822: 02 Jan 06 15:04 MST
+	regexp.MustCompile(`^\d+ [A-Za-z_]+ \d+ \d+:\d+ [A-Za-z_]+`),
+	// time.RFC822Z: 02 Jan 06 15:04 -0700
+	regexp.MustCompile(`^\d+ [A-Za-z_]+ \d+ \d+:\d+ -\d+`),
+	// time.RFC850: Monday, 02-Jan-06 15:04:05 MST
+	regexp.MustCompile(`^[A-Za-z_]+, \d+-[A-Za-z_]+-\d+ \d+:\d+:\d+ [A-Za-z_]+`),
+	// time.RFC1123, time.RFC1123Z: Mon, 02 Jan 2006 15:04:05 MST
+	regexp.MustCompile(`^[A-Za-z_]+, \d+ [A-Za-z_]+ \d+ \d+:\d+:\d+ ([A-Za-z_]+|[\-\+]\d+)`),
+	// time.Stamp (syslog): Jan _2 15:04:05
+	regexp.MustCompile(`^[A-Za-z_]{3} +\d+ \d+:\d+:\d+`),
+	// Default java.util.logging SimpleFormatter: Jan 2, 2006 3:04:05 PM
+	regexp.MustCompile(`^[A-Za-z_]+ \d+, \d+ \d+:\d+:\d+ (AM|PM)`),
+	// Records starting with a level: ERROR:root:..., [WARN] ..., INFO  main ...
+	regexp.MustCompile(`^\[?(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|SEVERE|CRITICAL|FATAL)\]?[\s:]`),
+}
+
+// scoredPattern tracks how many sampled lines matched a pattern.
+type scoredPattern struct {
+	score  int
+	regexp *regexp.Regexp
+}
+
+// AutoMultilineHandler samples the first lines of a source to detect whether it
+// emits multi-line records. Lines are handled as single lines while sampling,
+// then the handler switches to multi-line aggregation if a start-of-record
+// pattern matched enough of the sampled lines.
+type AutoMultilineHandler struct {
+	inputChan         chan *Message
+	outputChan        chan *Message
+	singleLineHandler *SingleLineHandler
+	multiLineHandler  *MultiLineHandler
+	source            *config.LogSource
+	scoredMatches     []*scoredPattern
+	linesToAssess     int
+	linesTested       int
+	matchThreshold    float64
+	detectionTimeout  time.Duration
+	detectionStart    time.Time
+	flushTimeout      time.Duration
+	lineLimit         int
+	processFunc       func(message *Message)
+}
+
+// NewAutoMultilineHandler returns a new AutoMultilineHandler.
+func NewAutoMultilineHandler(outputChan chan *Message, lineLimit, linesToAssess int, matchThreshold float64, detectionTimeout time.Duration, flushTimeout time.Duration, source *config.LogSource) *AutoMultilineHandler {
+	scoredMatches := make([]*scoredPattern, len(startOfRecordPatterns))
+	for i, re := range startOfRecordPatterns {
+		scoredMatches[i] = &scoredPattern{regexp: re}
+	}
+	h := &AutoMultilineHandler{
+		inputChan:         make(chan *Message),
+		outputChan:        outputChan,
+		singleLineHandler: NewSingleLineHandler(outputChan, lineLimit),
+		source:            source,
+		scoredMatches:     scoredMatches,
+		linesToAssess:     linesToAssess,
+		matchThreshold:    matchThreshold,
+		detectionTimeout:  detectionTimeout,
+		flushTimeout:      flushTimeout,
+		lineLimit:         lineLimit,
+	}
+	h.processFunc = h.processAndTry
+	return h
+}
+
+// Handle forward lines to inputChan to process them.
+func (h *AutoMultilineHandler) Handle(input *Message) {
+	h.inputChan <- input
+}
+
+// Stop stops the handler.
+func (h *AutoMultilineHandler) Stop() {
+	close(h.inputChan)
+}
+
+// Start starts the handler.
+func (h *AutoMultilineHandler) Start() {
+	go h.run()
+}
+
+// run processes new lines from the channel and makes sure the content aggregated
+// by the multi-line handler is sent when it stayed for too long in the buffer.
+func (h *AutoMultilineHandler) run() {
+	flushTimer := time.NewTimer(h.flushTimeout)
+	defer func() {
+		flushTimer.Stop()
+		// make sure the content stored in the buffer gets sent,
+		// this can happen when the stop is called in between two timer ticks.
+		if h.multiLineHandler != nil {
+			h.multiLineHandler.sendBuffer()
+		}
+		close(h.outputChan)
+	}()
+	for {
+		select {
+		case message, isOpen := <-h.inputChan:
+			if !isOpen {
+				// inputChan has been closed, no more lines are expected
+				return
+			}
+			// process the new line and restart the timeout
+			if !flushTimer.Stop() {
+				select {
+				case <-flushTimer.C:
+				default:
+				}
+			}
+			h.processFunc(message)
+			flushTimer.Reset(h.flushTimeout)
+		case <-flushTimer.C:
+			if h.multiLineHandler != nil {
+				// no line has been collected since a while,
+				// the content is supposed to be complete.
+				h.multiLineHandler.sendBuffer()
+			}
+		}
+	}
+}
+
+// processAndTry sends the line as a single line and scores it against
+// all start-of-record patterns until enough lines have been sampled.
+func (h *AutoMultilineHandler) processAndTry(message *Message) {
+	if h.linesTested == 0 {
+		h.detectionStart = time.Now()
+	}
+
+	content := bytes.TrimSpace(message.Content)
+	for _, scored := range h.scoredMatches {
+		if scored.regexp.Match(content) {
+			scored.score++
+		}
+	}
+	h.linesTested++
+
+	h.singleLineHandler.process(message)
+
+	if h.linesTested >= h.linesToAssess || time.Since(h.detectionStart) >= h.detectionTimeout {
+		h.detect()
+	}
+}
+
+// detect selects the pattern with the highest score and switches to multi-line
+// aggregation if it matched at least matchThreshold of the sampled lines.
+func (h *AutoMultilineHandler) detect() {
+	sort.SliceStable(h.scoredMatches, func(i, j int) bool {
+		return h.scoredMatches[i].score > h.scoredMatches[j].score
+	})
+	top := h.scoredMatches[0]
+	matchRatio := float64(top.score) / float64(h.linesTested)
+
+	if top.score > 0 && matchRatio >= h.matchThreshold {
+		log.Debugf("Pattern %v matched %d lines out of %d for source %v, switching to multi-line aggregation", top.regexp, top.score, h.linesTested, h.source.Name)
+		h.source.Messages.AddMessage(autoMultiLineMessageKey, fmt.Sprintf("Auto multi-line detection: pattern %v matched %.0f%% of the first %d lines, multi-line aggregation is enabled", top.regexp, matchRatio*100, h.linesTested))
+		h.multiLineHandler = NewMultiLineHandler(h.outputChan, top.regexp, h.flushTimeout, h.lineLimit)
+		h.processFunc = h.multiLineHandler.process
+	} else {
+		log.Debugf("No pattern matched enough lines out of %d for source %v, keeping single-line handling", h.linesTested, h.source.Name)
+		h.source.Messages.AddMessage(autoMultiLineMessageKey, fmt.Sprintf("Auto multi-line detection: no pattern matched enough of the first %d lines, multi-line aggregation is disabled", h.linesTested))
+		h.processFunc = h.singleLineHandler.process
+	}
+	h.scoredMatches = nil
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func newTestAutoMultilineHandler(outputChan chan *Message, linesToAssess int) (*AutoMultilineHandler, *config.LogSource) {
+	source := config.NewLogSource("config", &config.LogsConfig{Type: config.FileType, Path: "/var/log/app.log"})
+	h := NewAutoMultilineHandler(outputChan, 500, linesToAssess, 0.75, time.Minute, 10*time.Millisecond, source)
+	return h, source
+}
+
+func TestAutoMultilineHandlerDetectsPattern(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	h, source := newTestAutoMultilineHandler(outputChan, 2)
+	h.Start()
+
+	// sampled lines are sent as single lines
+	h.Handle(getDummyMessageWithLF("2020-01-01 10:00:00,000 INFO starting"))
+	output := <-outputChan
+	assert.Equal(t, "2020-01-01 10:00:00,000 INFO starting", string(output.Content))
+	h.Handle(getDummyMessageWithLF("2020-01-01 10:00:01,000 INFO started"))
+	output = <-outputChan
+	assert.Equal(t, "2020-01-01 10:00:01,000 INFO started", string(output.Content))
+
+	assert.Len(t, source.Messages.GetMessages(), 1)
+	assert.True(t, strings.Contains(source.Messages.GetMessages()[0], "multi-line aggregation is enabled"))
+
+	// following lines are aggregated
+	h.Handle(getDummyMessageWithLF("2020-01-01 10:00:02,000 ERROR failure"))
+	h.Handle(getDummyMessageWithLF("Traceback (most recent call last):"))
+	h.Handle(getDummyMessageWithLF("  File \"app.py\", line 1"))
+	h.Handle(getDummyMessageWithLF("2020-01-01 10:00:03,000 INFO recovered"))
+
+	output = <-outputChan
+	assert.Equal(t, "2020-01-01 10:00:02,000 ERROR failure\\nTraceback (most recent call last):\\n  File \"app.py\", line 1", string(output.Content))
+
+	// the last message is flushed on timeout
+	output = <-outputChan
+	assert.Equal(t, "2020-01-01 10:00:03,000 INFO recovered", string(output.Content))
+
+	h.Stop()
+}
+
+func TestAutoMultilineHandlerKeepsSingleLine(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	h, source := newTestAutoMultilineHandler(outputChan, 2)
+	h.Start()
+
+	h.Handle(getDummyMessageWithLF("hello world"))
+	<-outputChan
+	h.Handle(getDummyMessageWithLF("2020-01-01 10:00:00,000 INFO starting"))
+	<-outputChan
+
+	assert.Len(t, source.Messages.GetMessages(), 1)
+	assert.True(t, strings.Contains(source.Messages.GetMessages()[0], "multi-line aggregation is disabled"))
+
+	h.Handle(getDummyMessageWithLF("2020-01-01 10:00:02,000 ERROR failure"))
+	output := <-outputChan
+	assert.Equal(t, "2020-01-01 10:00:02,000 ERROR failure", string(output.Content))
+	h.Handle(getDummyMessageWithLF("  at Foo.bar(Foo.java:12)"))
+	output = <-outputChan
+	assert.Equal(t, "at Foo.bar(Foo.java:12)", string(output.Content))
+
+	h.Stop()
+}
+
+func TestStartOfRecordPatterns(t *testing.T) {
+	lines := []string{
+		"2021-03-28T13:45:30.123456789Z message",
+		"2021-03-28 13:45:30,123 message",
+		"[2021-03-28 13:45:30] message",
+		"2021/03/28 13:45:30 message",
+		"Sun Mar 28 13:45:30 2021 message",
+		"Sun Mar 28 13:45:30 UTC 2021 message",
+		"28 Mar 21 13:45 UTC message",
+		"Sunday, 28-Mar-21 13:45:30 UTC message",
+		"Sun, 28 Mar 2021 13:45:30 -0700 message",
+		"Mar 28 13:45:30 message",
+		"Mar 28, 2021 1:45:30 PM message",
+		"ERROR:root:message",
+		"[WARN] message",
+	}
+	for _, line := range lines {
+		matched := false
+		for _, re := range startOfRecordPatterns {
+			if re.MatchString(line) {
+				matched = true
+				break
+			}
+		}
+		assert.True(t, matched, line)
+	}
+
+	for _, line := range []string{"  at Foo.bar(Foo.java:12)", "Traceback (most recent call last):", "}"} {
+		for _, re := range startOfRecordPatterns {
+			assert.False(t, re.MatchString(line), line)
+		}
+	}
+}
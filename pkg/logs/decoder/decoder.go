@@ -94,6 +94,9 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 			lineHandler = NewMultiLineHandler(outputChan, rule.Regex, config.AggregationTimeout(), lineLimit)
 		}
 	}
+	if lineHandler == nil && shouldDetectMultiLine(source) {
+		lineHandler = newAutoMultilineHandlerForSource(outputChan, lineLimit, source)
+	}
 	if lineHandler == nil {
 		lineHandler = NewSingleLineHandler(outputChan, lineLimit)
 	}
@@ -107,6 +110,31 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 	return New(inputChan, outputChan, lineParser, lineLimit, matcher)
 }
 
+// shouldDetectMultiLine returns true if multi-line detection is enabled
+// for the source and the source reads from files or containers.
+func shouldDetectMultiLine(source *config.LogSource) bool {
+	switch source.Config.Type {
+	case config.FileType, config.DockerType:
+		return source.Config.IsAutoMultiLineEnabled()
+	default:
+		return false
+	}
+}
+
+// newAutoMultilineHandlerForSource returns an AutoMultilineHandler using the
+// settings of the source, falling back to the global defaults.
+func newAutoMultilineHandlerForSource(outputChan chan *Message, lineLimit int, source *config.LogSource) *AutoMultilineHandler {
+	linesToAssess := source.Config.AutoMultiLineSampleSize
+	if linesToAssess <= 0 {
+		linesToAssess = config.AutoMultiLineSampleSize()
+	}
+	matchThreshold := source.Config.AutoMultiLineMatchThreshold
+	if matchThreshold <= 0 {
+		matchThreshold = config.AutoMultiLineMatchThreshold()
+	}
+	return NewAutoMultilineHandler(outputChan, lineLimit, linesToAssess, matchThreshold, config.AutoMultiLineMatchTimeout(), config.AggregationTimeout(), source)
+}
+
 // New returns an initialized Decoder
 func New(InputChan chan *Input, OutputChan chan *Message, lineParser LineParser, contentLenLimit int, matcher EndLineMatcher) *Decoder {
 	var lineBuffer bytes.Buffer
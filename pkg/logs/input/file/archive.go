@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// rotatedArchivePatterns are the suffixes of the files a file is usually rotated to,
+// for instance app.log.1, app.log.1.gz or app.log-20210101.gz.
+var rotatedArchivePatterns = []string{".*", "-*"}
+
+// rotatedArchiveSuffix matches the suffixes of the rotated files: a number or a date,
+// optionally followed by the extension of the compression.
+var rotatedArchiveSuffix = regexp.MustCompile(`^(\.[0-9]+|-[0-9][0-9T_-]*)(\.gz)?$`)
+
+// compressionFromPath returns the compression of a file from its extension.
+func compressionFromPath(path string) string {
+	if filepath.Ext(path) == ".gz" {
+		return config.GzipCompression
+	}
+	return ""
+}
+
+// findRotatedArchive returns the most recently modified file the file at path has been rotated to,
+// or an empty string if there is none.
+func findRotatedArchive(path string) string {
+	var archive string
+	var modTime time.Time
+	for _, pattern := range rotatedArchivePatterns {
+		matches, err := filepath.Glob(path + pattern)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if !rotatedArchiveSuffix.MatchString(strings.TrimPrefix(match, path)) {
+				continue
+			}
+			fi, err := os.Stat(match)
+			if err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			if archive == "" || fi.ModTime().After(modTime) {
+				archive, modTime = match, fi.ModTime()
+			}
+		}
+	}
+	return archive
+}
+
+// newDecompressor returns a reader of the decompressed content read from r.
+func newDecompressor(r io.Reader, compression string) (io.Reader, error) {
+	switch compression {
+	case config.GzipCompression:
+		return gzip.NewReader(r)
+	default:
+		return r, nil
+	}
+}
+
+// decompressedPosition returns the position in the decompressed content of the file reached at offset
+// relative to whence. Offsets of compressed files can't be seeked to, the content is decompressed from
+// the beginning of the file instead, and the position can't go past the end of the content written so far.
+func decompressedPosition(f *os.File, compression string, offset int64, whence int) (int64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	reader, err := newDecompressor(f, compression)
+	if err != nil {
+		return 0, err
+	}
+	var position int64
+	if whence == io.SeekEnd {
+		position, err = io.Copy(ioutil.Discard, reader)
+	} else {
+		position, err = io.CopyN(ioutil.Discard, reader, offset)
+	}
+	if isEndOfContent(err) {
+		err = nil
+	}
+	return position, err
+}
+
+// decompressingReader reads the decompressed content of a gzip file being appended to, after its first
+// skip bytes. A decompressor can't resume once it reached the end of its input, so the compressed content
+// is read with a followingReader: the decompression is started once and goes on as content is appended
+// to the file, instead of starting over from the beginning of the file.
+type decompressingReader struct {
+	source      *followingReader
+	buffered    *bufio.Reader
+	skip        int64
+	gzip        *gzip.Reader
+	endOfStream bool
+}
+
+// newDecompressingReader returns a reader of the decompressed content of the gzip content read from source,
+// which starts at the beginning of the file. The reads wait for more content at the end of the content
+// written so far until stopped returns true.
+func newDecompressingReader(source io.Reader, skip int64, sleepDuration time.Duration, stopped func() bool) *decompressingReader {
+	following := &followingReader{reader: source, sleepDuration: sleepDuration, stopped: stopped}
+	return &decompressingReader{
+		source: following,
+		// the content buffered by the decompressor must be kept from a stream to the next
+		buffered: bufio.NewReader(following),
+		skip:     skip,
+	}
+}
+
+// compressedOffset returns the number of bytes of the compressed content read so far.
+func (r *decompressingReader) compressedOffset() int64 {
+	return r.source.offset
+}
+
+// Read implements io.Reader. A gzip file holds a stream for each time content was appended to it, the
+// streams are decompressed one at a time so that the content of a stream is returned without waiting
+// for the header of the next one, which may not be written yet.
+func (r *decompressingReader) Read(p []byte) (int, error) {
+	for {
+		if r.gzip == nil || r.endOfStream {
+			if err := r.nextStream(); err != nil {
+				return 0, err
+			}
+		}
+		var n int
+		var err error
+		if r.skip > 0 {
+			var skipped int64
+			skipped, err = io.CopyN(ioutil.Discard, r.gzip, r.skip)
+			r.skip -= skipped
+		} else {
+			n, err = r.gzip.Read(p)
+		}
+		if err == io.EOF {
+			r.endOfStream = true
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// nextStream starts the decompression of the next stream of the file.
+func (r *decompressingReader) nextStream() error {
+	var err error
+	if r.gzip == nil {
+		r.gzip, err = gzip.NewReader(r.buffered)
+	} else {
+		err = r.gzip.Reset(r.buffered)
+	}
+	if err != nil {
+		return err
+	}
+	r.gzip.Multistream(false)
+	r.endOfStream = false
+	return nil
+}
+
+// followingReader reads a file being appended to: at the end of its content it waits for
+// more content instead of returning io.EOF, until stopped returns true.
+type followingReader struct {
+	reader        io.Reader
+	sleepDuration time.Duration
+	stopped       func() bool
+	offset        int64
+}
+
+// Read implements io.Reader
+func (r *followingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.reader.Read(p)
+		r.offset += int64(n)
+		if n > 0 || err != io.EOF || r.stopped() {
+			return n, err
+		}
+		time.Sleep(r.sleepDuration)
+	}
+}
+
+// isEndOfContent returns true if the error reports that the content written so far has been read,
+// a compressed file being written ends with an incomplete stream.
+func isEndOfContent(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
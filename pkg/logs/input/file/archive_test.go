@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build !windows
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+// appendGzipMember appends a gzip stream holding content to the file.
+func appendGzipMember(t *testing.T, path string, content string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func readMessage(t *testing.T, outputChan chan *message.Message) *message.Message {
+	select {
+	case msg := <-outputChan:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout")
+	}
+	return nil
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	assert.Equal(t, config.GzipCompression, compressionFromPath("/var/log/app.log.1.gz"))
+	assert.Equal(t, "", compressionFromPath("/var/log/app.log.1"))
+	assert.Equal(t, "", compressionFromPath("/var/log/app.log"))
+}
+
+func TestFindRotatedArchive(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	assert.Equal(t, "", findRotatedArchive(path))
+
+	now := time.Now()
+	// the files not named like a rotated archive are ignored even when they are more recent
+	for i, name := range []string{"app.log.2.gz", "app.log-20210101.gz", "app.log.1", "other.log.1", "app.log.pos", "app.log-old", "app.log.1.tmp"} {
+		archive := filepath.Join(testDir, name)
+		require.NoError(t, ioutil.WriteFile(archive, []byte("content"), 0644))
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(archive, modTime, modTime))
+	}
+	assert.Equal(t, filepath.Join(testDir, "app.log.1"), findRotatedArchive(path))
+}
+
+func TestDecompressedPosition(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.gz")
+	appendGzipMember(t, path, "hello\n")
+	appendGzipMember(t, path, "world\n")
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	position, err := decompressedPosition(f, config.GzipCompression, 6, io.SeekStart)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), position)
+
+	position, err = decompressedPosition(f, config.GzipCompression, 0, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), position)
+
+	// the position can't go past the end of the content
+	position, err = decompressedPosition(f, config.GzipCompression, 100, io.SeekStart)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), position)
+}
+
+func TestDecompressingReader(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.gz")
+	appendGzipMember(t, path, "hello\n")
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var stopped int32
+	reader := newDecompressingReader(f, 2, time.Millisecond, func() bool { return atomic.LoadInt32(&stopped) == 1 })
+	buf := make([]byte, 64)
+	n, err := reader.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "llo\n", string(buf[:n]))
+
+	// the reads wait at the end of the content and the decompression resumes once the file has grown
+	member := bytes.NewBuffer(nil)
+	w := gzip.NewWriter(member)
+	_, err = w.Write([]byte("world\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	appended := make(chan error, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err == nil {
+			_, err = f.Write(member.Bytes())
+			f.Close()
+		}
+		appended <- err
+	}()
+	content := ""
+	for len(content) < len("world\n") {
+		n, err = reader.Read(buf)
+		require.NoError(t, err)
+		content += string(buf[:n])
+	}
+	require.NoError(t, <-appended)
+	assert.Equal(t, "world\n", content)
+
+	// the reads don't wait for more content once stopped
+	atomic.StoreInt32(&stopped, 1)
+	n, err = reader.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.True(t, isEndOfContent(err))
+
+	info, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), reader.compressedOffset())
+}
+
+func TestTailCompressedFile(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.gz")
+	appendGzipMember(t, path, "hello\nworld\n")
+
+	outputChan := make(chan *message.Message, chanSize)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, Compression: config.GzipCompression})
+	tailer := NewTailer(outputChan, NewFile(path, source, false), 10*time.Millisecond)
+	require.NoError(t, tailer.Start(6, io.SeekStart))
+
+	msg := readMessage(t, outputChan)
+	assert.Equal(t, "world", string(msg.Content))
+	// offsets are positions in the decompressed content
+	assert.Equal(t, "12", msg.Origin.Offset)
+
+	// the content appended to the file is read once the file has grown
+	appendGzipMember(t, path, "again\n")
+	msg = readMessage(t, outputChan)
+	assert.Equal(t, "again", string(msg.Content))
+	assert.Equal(t, "18", msg.Origin.Offset)
+
+	tailer.Stop()
+}
+
+func TestScannerReadsRotatedArchive(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	archiveContent := "before\nmissed 1\nmissed 2\n"
+	appendGzipMember(t, path+".1.gz", archiveContent)
+	require.NoError(t, ioutil.WriteFile(path, []byte("new\n"), 0644))
+
+	// the offset committed before the rotation is bigger than the new file
+	registry := auditor.NewRegistry()
+	registry.SetOffset(strconv.Itoa(len("before\n")))
+
+	pipelineProvider := mock.NewMockProvider()
+	scanner := NewScanner(config.NewLogSources(), 3, pipelineProvider, registry, 10*time.Millisecond)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, ReadRotatedArchives: true})
+	scanner.addSource(source)
+	defer scanner.cleanup()
+
+	require.Len(t, scanner.archiveTailers, 1)
+	outputChan := pipelineProvider.NextPipelineChan()
+
+	var contents []string
+	for i := 0; i < 3; i++ {
+		msg := readMessage(t, outputChan)
+		contents = append(contents, string(msg.Content))
+		if string(msg.Content) != "new" {
+			// the offsets of the archive are not tracked
+			assert.Equal(t, "", msg.Origin.Identifier)
+		}
+	}
+	assert.ElementsMatch(t, []string{"missed 1", "missed 2", "new"}, contents)
+
+	// the archive tailer stops once the archive is read
+	assert.Eventually(t, func() bool {
+		scanner.removeFinishedArchiveTailers()
+		return len(scanner.archiveTailers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"hash/crc64"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var fingerprintTable = crc64.MakeTable(crc64.ECMA)
+
+// computeFingerprint returns a hash of the first size bytes of the file, or an empty string
+// if fingerprinting is disabled or the file does not have enough content yet.
+func computeFingerprint(f *os.File, size int) (string, error) {
+	if size <= 0 {
+		return "", nil
+	}
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, 0)
+	if n < size {
+		if err == io.EOF {
+			return "", nil
+		}
+		return "", err
+	}
+	return strconv.FormatUint(crc64.Checksum(buf, fingerprintTable), 16), nil
+}
+
+// getFingerprint returns the fingerprint of the file being tailed.
+func (t *Tailer) getFingerprint() string {
+	fingerprint, _ := t.fingerprint.Load().(string)
+	return fingerprint
+}
+
+// updateFingerprint fingerprints the file being tailed if it is not fingerprinted yet
+// and has enough content.
+func (t *Tailer) updateFingerprint(f *os.File) {
+	if t.fingerprintSize <= 0 || t.getFingerprint() != "" {
+		return
+	}
+	fingerprint, err := computeFingerprint(f, t.fingerprintSize)
+	if err != nil {
+		log.Debugf("Could not fingerprint %s: %v", t.file.Path, err)
+		return
+	}
+	t.fingerprint.Store(fingerprint)
+}
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build !windows
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+const testFingerprintSize = 16
+
+func fingerprintOf(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	fingerprint, err := computeFingerprint(f, testFingerprintSize)
+	require.NoError(t, err)
+	return fingerprint
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("too short\n"), 0644))
+	assert.Equal(t, "", fingerprintOf(t, path))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("2021-01-01 first line\n"), 0644))
+	fingerprint := fingerprintOf(t, path)
+	assert.NotEqual(t, "", fingerprint)
+
+	// only the first bytes are fingerprinted
+	require.NoError(t, ioutil.WriteFile(path, []byte("2021-01-01 first line\nsecond line\n"), 0644))
+	assert.Equal(t, fingerprint, fingerprintOf(t, path))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("2021-01-02 first line\n"), 0644))
+	assert.NotEqual(t, fingerprint, fingerprintOf(t, path))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	fingerprint, err = computeFingerprint(f, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "", fingerprint)
+}
+
+func TestDidRotateUsingFingerprintWithCopyTruncate(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("2021-01-01 first line\n"), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	fingerprint := fingerprintOf(t, path)
+	lastReadOffset := int64(len("2021-01-01 first line\n"))
+
+	didRotate, err := DidRotateUsingFingerprint(f, fingerprint, testFingerprintSize)
+	assert.NoError(t, err)
+	assert.False(t, didRotate)
+
+	// the file is copied then truncated, and new lines are written past the last offset before the next check
+	require.NoError(t, os.Truncate(path, 0))
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = w.WriteString("2021-01-02 new line after the rotation\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	didRotate, err = DidRotate(f, lastReadOffset)
+	assert.NoError(t, err)
+	assert.False(t, didRotate)
+
+	didRotate, err = DidRotateUsingFingerprint(f, fingerprint, testFingerprintSize)
+	assert.NoError(t, err)
+	assert.True(t, didRotate)
+}
+
+func TestDidRotateUsingFingerprintWithInodeReuse(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	content := "2021-01-01 first line\n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	fingerprint := fingerprintOf(t, path)
+
+	// a new file reusing the same inode looks like the same file with the same size
+	w, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = w.WriteAt([]byte("2021-01-02 other line\n"), 0)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	didRotate, err := DidRotate(f, int64(len(content)))
+	assert.NoError(t, err)
+	assert.False(t, didRotate)
+
+	didRotate, err = DidRotateUsingFingerprint(f, fingerprint, testFingerprintSize)
+	assert.NoError(t, err)
+	assert.True(t, didRotate)
+
+	// a file not fingerprinted yet is never considered rotated
+	didRotate, err = DidRotateUsingFingerprint(f, "", testFingerprintSize)
+	assert.NoError(t, err)
+	assert.False(t, didRotate)
+}
+
+func TestTailerFingerprintsFile(t *testing.T) {
+	coreConfig.Datadog.Set("logs_config.file_fingerprint_size", testFingerprintSize)
+	defer coreConfig.Datadog.Set("logs_config.file_fingerprint_size", 0)
+
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("short\n"), 0644))
+
+	outputChan := make(chan *message.Message, chanSize)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	tailer := NewTailer(outputChan, NewFile(path, source, false), 10*time.Millisecond)
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	// the file does not have enough content to be fingerprinted yet
+	msg := readMessage(t, outputChan)
+	assert.Equal(t, "", msg.Origin.Fingerprint)
+
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = w.WriteString("long enough line\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	msg = readMessage(t, outputChan)
+	assert.Equal(t, fingerprintOf(t, path), msg.Origin.Fingerprint)
+}
+
+func TestScannerChecksFingerprintOfCommittedOffset(t *testing.T) {
+	coreConfig.Datadog.Set("logs_config.file_fingerprint_size", testFingerprintSize)
+	defer coreConfig.Datadog.Set("logs_config.file_fingerprint_size", 0)
+
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("2021-01-01 first line\n2021-01-01 second line\n"), 0644))
+
+	registry := auditor.NewRegistry()
+	registry.SetOffset(strconv.Itoa(len("2021-01-01 first line\n")))
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+
+	// same fingerprint, the file is tailed from the committed offset
+	registry.SetFingerprint(fingerprintOf(t, path))
+	pipelineProvider := mock.NewMockProvider()
+	scanner := NewScanner(config.NewLogSources(), 3, pipelineProvider, registry, 10*time.Millisecond)
+	scanner.addSource(source)
+	msg := readMessage(t, pipelineProvider.NextPipelineChan())
+	assert.Equal(t, "2021-01-01 second line", string(msg.Content))
+	scanner.cleanup()
+
+	// the file was replaced by another one of the same size, it is tailed from the beginning
+	registry.SetFingerprint("0123456789abcdef")
+	pipelineProvider = mock.NewMockProvider()
+	scanner = NewScanner(config.NewLogSources(), 3, pipelineProvider, registry, 10*time.Millisecond)
+	scanner.addSource(source)
+	msg = readMessage(t, pipelineProvider.NextPipelineChan())
+	assert.Equal(t, "2021-01-01 first line", string(msg.Content))
+	msg = readMessage(t, pipelineProvider.NextPipelineChan())
+	assert.Equal(t, "2021-01-01 second line", string(msg.Content))
+	scanner.cleanup()
+}
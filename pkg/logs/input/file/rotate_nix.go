@@ -38,3 +38,24 @@ func DidRotate(file *os.File, lastReadOffset int64) (bool, error) {
 
 	return recreated || truncated, nil
 }
+
+// DidRotateUsingFingerprint returns true if the file does not start with the content it had
+// when it was fingerprinted anymore. It detects the rotations DidRotate misses when the inode
+// of the file is reused, or when the file is truncated and written past the last offset read
+// before the next check. A file that is not fingerprinted yet is never considered rotated.
+func DidRotateUsingFingerprint(file *os.File, fingerprint string, fingerprintSize int) (bool, error) {
+	if fingerprint == "" {
+		return false, nil
+	}
+	f, err := openFile(file.Name())
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	current, err := computeFingerprint(f, fingerprintSize)
+	if err != nil {
+		return false, err
+	}
+	return current != fingerprint, nil
+}
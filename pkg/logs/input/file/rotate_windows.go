@@ -16,3 +16,9 @@ import (
 func DidRotate(file *os.File, lastReadOffset int64) (bool, error) {
 	return false, nil
 }
+
+// DidRotateUsingFingerprint is not implemented on windows, the tailer compares
+// the fingerprint of the file each time it opens it instead.
+func DidRotateUsingFingerprint(file *os.File, fingerprint string, fingerprintSize int) (bool, error) {
+	return false, nil
+}
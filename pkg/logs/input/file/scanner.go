@@ -6,6 +6,7 @@
 package file
 
 import (
+	"io"
 	"sync/atomic"
 	"time"
 
@@ -31,6 +32,7 @@ type Scanner struct {
 	tailingLimit        int
 	fileProvider        *Provider
 	tailers             map[string]*Tailer
+	archiveTailers      []*Tailer
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
 	stop                chan struct{}
@@ -90,6 +92,10 @@ func (s *Scanner) cleanup() {
 		stopper.Add(tailer)
 		delete(s.tailers, tailer.file.GetScanKey())
 	}
+	for _, tailer := range s.archiveTailers {
+		stopper.Add(tailer)
+	}
+	s.archiveTailers = nil
 	stopper.Stop()
 }
 
@@ -138,7 +144,21 @@ func (s *Scanner) scan() {
 			continue
 		}
 
-		didRotate, err := DidRotate(tailer.osFile, tailer.GetReadOffset())
+		if !s.isActiveSource(tailer.file.Source) {
+			// the source of the tailer has been replaced, by a configuration reload for instance
+			succeeded := s.restartTailerWithNewSource(tailer, file)
+			if !succeeded {
+				// the setup failed, let's try to tail this file in the next scan
+				continue
+			}
+			filesTailed[tailerKey] = true
+			continue
+		}
+
+		didRotate, err := DidRotate(tailer.osFile, tailer.getRotationOffset())
+		if err == nil && !didRotate {
+			didRotate, err = DidRotateUsingFingerprint(tailer.osFile, tailer.getFingerprint(), tailer.fingerprintSize)
+		}
 		if err != nil {
 			continue
 		}
@@ -154,6 +174,8 @@ func (s *Scanner) scan() {
 		filesTailed[tailerKey] = true
 	}
 
+	s.removeFinishedArchiveTailers()
+
 	for _, tailer := range s.tailers {
 		// stop all tailers which have not been selected
 		_, shouldTail := filesTailed[tailer.file.GetScanKey()]
@@ -180,6 +202,16 @@ func (s *Scanner) removeSource(source *config.LogSource) {
 	}
 }
 
+// isActiveSource returns true if the source has not been removed.
+func (s *Scanner) isActiveSource(source *config.LogSource) bool {
+	for _, src := range s.activeSources {
+		if src == source {
+			return true
+		}
+	}
+	return false
+}
+
 // launch launches new tailers for a new source.
 func (s *Scanner) launchTailers(source *config.LogSource) {
 	files, err := s.fileProvider.CollectFiles(source)
@@ -192,7 +224,10 @@ func (s *Scanner) launchTailers(source *config.LogSource) {
 		if len(s.tailers) >= s.tailingLimit {
 			return
 		}
-		if _, isTailed := s.tailers[file.GetScanKey()]; isTailed {
+		if tailer, isTailed := s.tailers[file.GetScanKey()]; isTailed {
+			if !s.isActiveSource(tailer.file.Source) {
+				s.restartTailerWithNewSource(tailer, file)
+			}
 			continue
 		}
 
@@ -221,6 +256,13 @@ func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
+	if whence == io.SeekStart && offset > 0 && s.didRotateSinceCommit(tailer, offset) {
+		log.Infof("%s has been rotated since its offset was committed, tailing it from the beginning", file.Path)
+		if file.Source.Config.ReadRotatedArchives {
+			s.readRotatedArchive(file, tailer.outputChan, offset)
+		}
+		offset = 0
+	}
 
 	log.Infof("Starting a new tailer for: %s (offset: %d, whence: %d) for tailer key %s", file.Path, offset, whence, file.GetScanKey())
 
@@ -234,6 +276,55 @@ func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 	return true
 }
 
+// didRotateSinceCommit returns true if the file of the tailer has been rotated since offset was committed,
+// which is the case when it does not match the fingerprint committed with the offset or, when it was
+// not fingerprinted, when it is smaller than the offset.
+func (s *Scanner) didRotateSinceCommit(tailer *Tailer, offset int64) bool {
+	f, err := openFile(tailer.file.Path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if fingerprint := s.registry.GetFingerprint(tailer.Identifier()); fingerprint != "" && tailer.fingerprintSize > 0 {
+		current, err := computeFingerprint(f, tailer.fingerprintSize)
+		return err == nil && current != fingerprint
+	}
+	if tailer.compression != "" {
+		// the offsets of compressed files are positions in their decompressed content
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Size() < offset
+}
+
+// readRotatedArchive starts reading from offset the end of the archive the file was rotated to.
+func (s *Scanner) readRotatedArchive(file *File, outputChan chan *message.Message, offset int64) {
+	archivePath := findRotatedArchive(file.Path)
+	if archivePath == "" {
+		log.Infof("No archive found for %s, some logs may be missing", file.Path)
+		return
+	}
+	log.Infof("Reading the end of %s from offset %d", archivePath, offset)
+	tailer := newArchiveTailer(outputChan, NewFile(archivePath, file.Source, file.IsWildcardPath), s.tailerSleepDuration)
+	if err := tailer.Start(offset, io.SeekStart); err != nil {
+		log.Warnf("Could not read the archive %s: %v", archivePath, err)
+		return
+	}
+	s.archiveTailers = append(s.archiveTailers, tailer)
+}
+
+// removeFinishedArchiveTailers forgets the archive tailers which have read their archive.
+func (s *Scanner) removeFinishedArchiveTailers() {
+	var archiveTailers []*Tailer
+	for _, tailer := range s.archiveTailers {
+		if atomic.LoadInt32(&tailer.shouldStop) == 0 {
+			archiveTailers = append(archiveTailers, tailer)
+		}
+	}
+	s.archiveTailers = archiveTailers
+}
+
 // handleTailingModeChange determines the tailing behaviour when the tailing mode for a given file has its
 // configuration change. Two case may happen we can switch from "end" to "beginning" (1) and from "beginning" to
 // "end" (2). If the tailing mode is set to forceEnd or forceBeginning it will remain unchanged.
@@ -278,6 +369,24 @@ func (s *Scanner) restartTailerAfterFileRotation(tailer *Tailer, file *File) boo
 	return true
 }
 
+// restartTailerWithNewSource stops the tailer of a file whose source has been replaced and starts
+// a new one for the new source from the position the previous tailer stopped at, so that no log is lost or duplicated.
+// returns true if the new tailer is up and running, false if an error occurred
+func (s *Scanner) restartTailerWithNewSource(tailer *Tailer, file *File) bool {
+	log.Infof("The configuration of %s has changed, restarting its tailer", file.Path)
+	tailer.Stop()
+	delete(s.tailers, tailer.file.GetScanKey())
+	offset := tailer.decodedOffset
+	tailer = s.createTailer(file, tailer.outputChan)
+	err := tailer.Start(offset, io.SeekStart)
+	if err != nil {
+		log.Warn(err)
+		return false
+	}
+	s.tailers[file.GetScanKey()] = tailer
+	return true
+}
+
 // createTailer returns a new initialized tailer
 func (s *Scanner) createTailer(file *File, outputChan chan *message.Message) *Tailer {
 	return NewTailer(outputChan, file, s.tailerSleepDuration)
@@ -181,6 +181,35 @@ func (suite *ScannerTestSuite) TestScannerScanWithFileRemovedAndCreated() {
 	suite.Equal(tailerLen, len(s.tailers))
 }
 
+func (suite *ScannerTestSuite) TestScannerRestartsTailerWhenSourceIsReplaced() {
+	s := suite.s
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+
+	tailer := s.tailers[getScanKey(suite.testPath, suite.source)]
+	newSource := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Identifier: suite.configID, Path: suite.testPath, Service: "foo"})
+	s.removeSource(suite.source)
+	s.addSource(newSource)
+
+	newTailer := s.tailers[getScanKey(suite.testPath, newSource)]
+	suite.True(tailer != newTailer)
+	suite.Equal(newSource, newTailer.file.Source)
+
+	// the new tailer starts where the previous one stopped
+	_, err = suite.testFile.WriteString("hello again\n")
+	suite.Nil(err)
+	msg = <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
+	suite.Equal(newSource, msg.Origin.LogSource)
+
+	// the tailer is kept on the next scan
+	s.scan()
+	suite.True(newTailer == s.tailers[getScanKey(suite.testPath, newSource)])
+}
+
 func (suite *ScannerTestSuite) TestLifeCycle() {
 	s := suite.s
 	suite.Equal(1, len(s.tailers))
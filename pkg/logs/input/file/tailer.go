@@ -44,6 +44,18 @@ type Tailer struct {
 	osFile   *os.File
 	tags     []string
 
+	// reader reads osFile, decompressing its content when the file is compressed,
+	// the offsets of a compressed file are positions in its decompressed content.
+	reader         io.Reader
+	compression    string
+	compressedSize int64
+	// stopAtEOF is set when the file is a rotated archive read once up to its end.
+	stopAtEOF bool
+
+	// fingerprint identifies the file by its first fingerprintSize bytes once it has enough content.
+	fingerprint     atomic.Value
+	fingerprintSize int
+
 	outputChan  chan *message.Message
 	decoder     *decoder.Decoder
 	tagProvider tag.Provider
@@ -55,6 +67,9 @@ type Tailer struct {
 	didFileRotate int32
 	stop          chan struct{}
 	done          chan struct{}
+	// isStopping is set once the tailer is asked to stop, the reads of a compressed
+	// file don't wait for more content anymore.
+	isStopping int32
 
 	forwardContext context.Context
 	stopForward    context.CancelFunc
@@ -95,22 +110,34 @@ func NewTailer(outputChan chan *message.Message, file *File, sleepDuration time.
 
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := coreConfig.Datadog.GetDuration("logs_config.close_timeout") * time.Second
+	fingerprintSize := coreConfig.Datadog.GetInt("logs_config.file_fingerprint_size")
 
 	return &Tailer{
-		file:           file,
-		outputChan:     outputChan,
-		decoder:        decoder.NewDecoderWithEndLineMatcher(file.Source, parser, matcher),
-		tagProvider:    tagProvider,
-		readOffset:     0,
-		sleepDuration:  sleepDuration,
-		closeTimeout:   closeTimeout,
-		stop:           make(chan struct{}, 1),
-		done:           make(chan struct{}, 1),
-		forwardContext: forwardContext,
-		stopForward:    stopForward,
+		file:            file,
+		outputChan:      outputChan,
+		decoder:         decoder.NewDecoderWithEndLineMatcher(file.Source, parser, matcher),
+		tagProvider:     tagProvider,
+		compression:     file.Source.Config.Compression,
+		readOffset:      0,
+		sleepDuration:   sleepDuration,
+		closeTimeout:    closeTimeout,
+		fingerprintSize: fingerprintSize,
+		stop:            make(chan struct{}, 1),
+		done:            make(chan struct{}, 1),
+		forwardContext:  forwardContext,
+		stopForward:     stopForward,
 	}
 }
 
+// newArchiveTailer returns a tailer reading once, up to its end, the archive a file
+// has been rotated to. The offsets of an archive are not tracked.
+func newArchiveTailer(outputChan chan *message.Message, archive *File, sleepDuration time.Duration) *Tailer {
+	tailer := NewTailer(outputChan, archive, sleepDuration)
+	tailer.compression = compressionFromPath(archive.Path)
+	tailer.stopAtEOF = true
+	return tailer
+}
+
 // Identifier returns a string that uniquely identifies a source.
 // This is the identifier used in the registry.
 // FIXME(remy): during container rotation, this Identifier() method could return
@@ -186,6 +213,7 @@ func (t *Tailer) StartFromBeginning() error {
 // Stop stops the tailer and returns only when the decoder is flushed
 func (t *Tailer) Stop() {
 	atomic.StoreInt32(&t.didFileRotate, 0)
+	atomic.StoreInt32(&t.isStopping, 1)
 	t.stop <- struct{}{}
 	t.file.Source.RemoveInput(t.file.Path)
 	// wait for the decoder to be flushed
@@ -205,13 +233,23 @@ func (t *Tailer) startStopTimer() {
 	stopTimer := time.NewTimer(t.closeTimeout)
 	<-stopTimer.C
 	t.stopForward()
+	atomic.StoreInt32(&t.isStopping, 1)
 	t.stop <- struct{}{}
 }
 
+// stopped returns true when the reads of a compressed file should not wait for more content,
+// once the tailer is asked to stop or when it reads an archive
+func (t *Tailer) stopped() bool {
+	return t.stopAtEOF || atomic.LoadInt32(&t.isStopping) == 1
+}
+
 // onStop finishes to stop the tailer
 func (t *Tailer) onStop() {
 	log.Info("Closing", t.file.Path, "for tailer key", t.file.GetScanKey())
 	t.osFile.Close()
+	if t.stopAtEOF {
+		t.file.Source.RemoveInput(t.file.Path)
+	}
 	t.decoder.Stop()
 }
 
@@ -233,6 +271,7 @@ func (t *Tailer) forwardMessages() {
 		origin := message.NewOrigin(t.file.Source)
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		origin.Fingerprint = t.getFingerprint()
 		origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
 		// Ignore empty lines once the registry offset is updated
 		if len(output.Content) == 0 {
@@ -272,12 +311,27 @@ func (t *Tailer) SetDecodedOffset(off int64) {
 
 // shouldTrackOffset returns whether the tailer should track the file offset or not
 func (t *Tailer) shouldTrackOffset() bool {
-	if atomic.LoadInt32(&t.didFileRotate) != 0 {
+	if t.stopAtEOF || atomic.LoadInt32(&t.didFileRotate) != 0 {
 		return false
 	}
 	return true
 }
 
+// updateCompressedSize records the number of bytes of the compressed file read so far.
+func (t *Tailer) updateCompressedSize() {
+	if reader, ok := t.reader.(*decompressingReader); ok {
+		atomic.StoreInt64(&t.compressedSize, reader.compressedOffset())
+	}
+}
+
+// getRotationOffset returns the offset below which the size of the file means it has been truncated.
+func (t *Tailer) getRotationOffset() int64 {
+	if t.compression != "" {
+		return atomic.LoadInt64(&t.compressedSize)
+	}
+	return t.GetReadOffset()
+}
+
 // wait lets the tailer sleep for a bit
 func (t *Tailer) wait() {
 	time.Sleep(t.sleepDuration)
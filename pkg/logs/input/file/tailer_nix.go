@@ -33,27 +33,57 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	}
 
 	t.osFile = f
+	t.updateFingerprint(f)
+	if t.compression != "" {
+		return t.setupDecompression(offset, whence)
+	}
 	ret, _ := f.Seek(offset, whence)
+	t.reader = f
 	t.readOffset = ret
 	t.decodedOffset = ret
 
 	return nil
 }
 
+// setupDecompression positions the tailer at offset in the decompressed content of the file.
+func (t *Tailer) setupDecompression(offset int64, whence int) error {
+	position, err := decompressedPosition(t.osFile, t.compression, offset, whence)
+	if err == nil {
+		_, err = t.osFile.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		t.osFile.Close()
+		return err
+	}
+	t.reader = newDecompressingReader(t.osFile, position, t.sleepDuration, t.stopped)
+	t.readOffset = position
+	t.decodedOffset = position
+
+	return nil
+}
+
 // read lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) read() (int, error) {
 	// keep reading data from file
 	inBuf := make([]byte, 4096)
-	n, err := t.osFile.Read(inBuf)
-	if err != nil && err != io.EOF {
+	n, err := t.reader.Read(inBuf)
+	t.updateCompressedSize()
+	if err != nil && !isEndOfContent(err) {
 		// an unexpected error occurred, stop the tailor
 		t.file.Source.Status.Error(err)
 		return 0, log.Error("Unexpected error occurred while reading file: ", err)
 	}
 	if n == 0 {
+		if err != nil && t.stopAtEOF {
+			return 0, io.EOF
+		}
 		return 0, nil
 	}
+	if t.GetReadOffset()+int64(n) >= int64(t.fingerprintSize) {
+		// fingerprint the file before its content is decoded so that the offsets are committed with it
+		t.updateFingerprint(t.osFile)
+	}
 	t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
 	t.incrementReadOffset(n)
 	return n, nil
@@ -32,8 +32,17 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	if err != nil {
 		return err
 	}
-	filePos, _ := f.Seek(offset, whence)
+	t.updateFingerprint(f)
+	var filePos int64
+	if t.compression != "" {
+		filePos, err = decompressedPosition(f, t.compression, offset, whence)
+	} else {
+		filePos, _ = f.Seek(offset, whence)
+	}
 	f.Close()
+	if err != nil {
+		return err
+	}
 
 	t.readOffset = filePos
 	t.decodedOffset = filePos
@@ -54,6 +63,21 @@ func (t *Tailer) readAvailable() (err error) {
 		return err
 	}
 
+	if fingerprint := t.getFingerprint(); fingerprint != "" {
+		if current, err := computeFingerprint(f, t.fingerprintSize); err == nil && current != fingerprint {
+			log.Debug("File fingerprint changed, resetting offset")
+			t.fingerprint.Store(current)
+			t.SetReadOffset(0)
+			t.SetDecodedOffset(0)
+			t.reader = nil
+		}
+	}
+	t.updateFingerprint(f)
+
+	if t.compression != "" {
+		return t.readAvailableDecompressed()
+	}
+
 	sz := st.Size()
 	offset := t.GetReadOffset()
 	log.Debugf("Size is %d, offset is %d", sz, offset)
@@ -81,11 +105,61 @@ func (t *Tailer) readAvailable() (err error) {
 	}
 }
 
+// readAvailableDecompressed reads the decompressed content of the file after the read offset. The
+// decompressor is kept between the calls, the file is reopened for each read of its compressed content.
+func (t *Tailer) readAvailableDecompressed() error {
+	if t.reader == nil {
+		t.reader = newDecompressingReader(&reopeningReader{path: t.fullpath}, t.GetReadOffset(), t.sleepDuration, t.stopped)
+	}
+	for {
+		inBuf := make([]byte, 4096)
+		n, err := t.reader.Read(inBuf)
+		t.updateCompressedSize()
+		if n > 0 {
+			t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+			t.incrementReadOffset(n)
+		}
+		if isEndOfContent(err) {
+			return io.EOF
+		}
+		if err != nil {
+			// the decompression restarts from the read offset on the next call
+			t.reader = nil
+			return err
+		}
+	}
+}
+
+// reopeningReader reads a file from an offset, opening and closing the file on each read
+// in order not to block the file and prevent the user from renaming it.
+type reopeningReader struct {
+	path   string
+	offset int64
+}
+
+// Read implements io.Reader
+func (r *reopeningReader) Read(p []byte) (int, error) {
+	f, err := openFile(r.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := f.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
 // read lets the tailer tail the content of a file until it is closed. The
 // windows version open and close the file between each call to 'read'. This is
 // needed in order not to block the file and prevent the user from renaming it.
 func (t *Tailer) read() (int, error) {
 	err := t.readAvailable()
+	if err == io.EOF && t.stopAtEOF {
+		return 0, err
+	}
 	if err == io.EOF || os.IsNotExist(err) {
 		return 0, nil
 	} else if err != nil {
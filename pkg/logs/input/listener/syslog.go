@@ -0,0 +1,382 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// syslogFrameLimit represents the max size of a syslog message,
+// if a message is bigger than this limit, it will be truncated.
+const syslogFrameLimit = 256 * 1000
+
+// maxOctetCountDigits is the max number of digits of the length of an octet-counted frame.
+const maxOctetCountDigits = 10
+
+// rfc3164TimestampLayout is the layout of the timestamps of RFC3164 messages, they don't have a year.
+const rfc3164TimestampLayout = time.Stamp
+
+// syslogSeverities maps the syslog severities to message statuses.
+var syslogSeverities = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// utf8BOM is the byte order mark which can prefix the content of RFC5424 messages.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// syslogFramer splits a stream into syslog messages, it supports both the octet-counting
+// and the non-transparent (newline delimited) framings described in RFC6587.
+type syslogFramer struct {
+	buffer []byte
+	limit  int
+	// skip is the number of bytes of a truncated octet-counted frame which remain to be discarded.
+	skip int
+}
+
+// newSyslogFramer returns a new syslogFramer.
+func newSyslogFramer(limit int) *syslogFramer {
+	return &syslogFramer{
+		limit: limit,
+	}
+}
+
+// frames returns the complete frames found after appending data to the pending bytes.
+func (f *syslogFramer) frames(data []byte) [][]byte {
+	if f.skip > 0 {
+		n := f.skip
+		if n > len(data) {
+			n = len(data)
+		}
+		f.skip -= n
+		data = data[n:]
+	}
+	f.buffer = append(f.buffer, data...)
+
+	var frames [][]byte
+	for {
+		f.buffer = bytes.TrimLeft(f.buffer, "\r\n ")
+		if len(f.buffer) == 0 {
+			break
+		}
+		frame, ok := f.next()
+		if !ok {
+			break
+		}
+		if len(frame) > 0 {
+			frames = append(frames, frame)
+		}
+	}
+	// release the memory used by the consumed frames
+	if len(f.buffer) == 0 {
+		f.buffer = nil
+	}
+	return frames
+}
+
+// flush returns the pending bytes as a last frame.
+func (f *syslogFramer) flush() []byte {
+	frame := bytes.TrimSpace(f.buffer)
+	f.buffer = nil
+	return frame
+}
+
+// next returns the first frame of the buffer, returns false if more data is needed.
+func (f *syslogFramer) next() ([]byte, bool) {
+	if isDigit(f.buffer[0]) {
+		if frame, ok, isOctetCounted := f.nextOctetCounted(); isOctetCounted {
+			return frame, ok
+		}
+	}
+	return f.nextDelimited()
+}
+
+// nextOctetCounted returns the first frame of the buffer when it is prefixed with its length,
+// isOctetCounted is false if the buffer does not start with a valid length.
+func (f *syslogFramer) nextOctetCounted() (frame []byte, ok bool, isOctetCounted bool) {
+	space := bytes.IndexByte(f.buffer, ' ')
+	if space < 0 {
+		// wait for the end of the length unless it is too long to be one
+		return nil, false, len(f.buffer) <= maxOctetCountDigits
+	}
+	if space > maxOctetCountDigits {
+		return nil, false, false
+	}
+	length, err := strconv.Atoi(string(f.buffer[:space]))
+	if err != nil || length <= 0 {
+		return nil, false, false
+	}
+	start := space + 1
+	if length > f.limit {
+		// keep the beginning of the message and discard the rest as it arrives
+		if len(f.buffer) < start+f.limit {
+			return nil, false, true
+		}
+		frame = f.buffer[start : start+f.limit]
+		available := len(f.buffer) - start
+		if available >= length {
+			f.buffer = f.buffer[start+length:]
+		} else {
+			f.skip = length - available
+			f.buffer = f.buffer[len(f.buffer):]
+		}
+		return copyBytes(frame), true, true
+	}
+	if len(f.buffer) < start+length {
+		return nil, false, true
+	}
+	frame = f.buffer[start : start+length]
+	f.buffer = f.buffer[start+length:]
+	return copyBytes(bytes.TrimRight(frame, "\r\n")), true, true
+}
+
+// nextDelimited returns the first line of the buffer, lines longer than the limit are truncated.
+func (f *syslogFramer) nextDelimited() ([]byte, bool) {
+	end := bytes.IndexByte(f.buffer, '\n')
+	switch {
+	case end >= 0 && end <= f.limit:
+		frame := f.buffer[:end]
+		f.buffer = f.buffer[end+1:]
+		return copyBytes(bytes.TrimRight(frame, "\r")), true
+	case len(f.buffer) >= f.limit:
+		frame := f.buffer[:f.limit]
+		f.buffer = f.buffer[f.limit:]
+		return copyBytes(frame), true
+	default:
+		return nil, false
+	}
+}
+
+// syslogMessage holds the fields of a syslog message.
+type syslogMessage struct {
+	severity  int // -1 when the message has no priority
+	timestamp time.Time
+	hostname  string
+	appName   string
+	procID    string
+	msgID     string
+	tags      []string // structured data
+	content   []byte
+}
+
+// status returns the status matching the severity of the message.
+func (m *syslogMessage) status() string {
+	if m.severity < 0 || m.severity >= len(syslogSeverities) {
+		return message.StatusInfo
+	}
+	return syslogSeverities[m.severity]
+}
+
+// parseSyslog parses a RFC5424 or RFC3164 message, the content of the messages
+// which can't be parsed is the whole frame.
+func parseSyslog(frame []byte) *syslogMessage {
+	msg := &syslogMessage{severity: -1, content: frame}
+	priority, rest, ok := parsePriority(frame)
+	if !ok {
+		return msg
+	}
+	msg.severity = priority % 8
+	msg.content = rest
+	if len(rest) >= 2 && rest[0] == '1' && rest[1] == ' ' {
+		parseRFC5424(msg, rest[2:])
+	} else {
+		parseRFC3164(msg, rest)
+	}
+	return msg
+}
+
+// parsePriority returns the priority of the message and the bytes following it.
+func parsePriority(frame []byte) (int, []byte, bool) {
+	if len(frame) < 3 || frame[0] != '<' {
+		return 0, nil, false
+	}
+	end := bytes.IndexByte(frame[:min(len(frame), 5)], '>')
+	if end < 2 {
+		return 0, nil, false
+	}
+	priority, err := strconv.Atoi(string(frame[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, nil, false
+	}
+	return priority, frame[end+1:], true
+}
+
+// parseRFC5424 parses the header fields following the version of a RFC5424 message:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func parseRFC5424(msg *syslogMessage, rest []byte) {
+	var fields [5]string
+	for i := range fields {
+		var field []byte
+		field, rest = nextField(rest)
+		if string(field) != "-" {
+			fields[i] = string(field)
+		}
+	}
+	if fields[0] != "" {
+		if timestamp, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+			msg.timestamp = timestamp.UTC()
+		}
+	}
+	msg.hostname, msg.appName, msg.procID, msg.msgID = fields[1], fields[2], fields[3], fields[4]
+
+	if len(rest) > 0 && rest[0] == '[' {
+		msg.tags, rest = parseStructuredData(rest)
+	} else {
+		// nil structured data
+		_, rest = nextField(rest)
+	}
+	msg.content = bytes.TrimPrefix(rest, utf8BOM)
+}
+
+// parseStructuredData returns the parameters of the structured data elements
+// as tags formatted as <SD-ID>.<PARAM-NAME>:<PARAM-VALUE>, and the bytes following them.
+func parseStructuredData(data []byte) ([]string, []byte) {
+	var tags []string
+	i, n := 0, len(data)
+	for i < n && data[i] == '[' {
+		i++
+		start := i
+		for i < n && data[i] != ' ' && data[i] != ']' {
+			i++
+		}
+		id := string(data[start:i])
+		for i < n && data[i] != ']' {
+			// skip spaces
+			for i < n && data[i] == ' ' {
+				i++
+			}
+			start = i
+			for i < n && data[i] != '=' && data[i] != ']' {
+				i++
+			}
+			name := string(data[start:i])
+			if i+1 >= n || data[i] != '=' || data[i+1] != '"' {
+				break
+			}
+			i += 2
+			var value []byte
+			for i < n && data[i] != '"' {
+				if data[i] == '\\' && i+1 < n {
+					i++
+				}
+				value = append(value, data[i])
+				i++
+			}
+			i++ // skip the closing quote
+			tags = append(tags, id+"."+name+":"+string(value))
+		}
+		i++ // skip ']'
+	}
+	if i > n {
+		i = n
+	}
+	return tags, bytes.TrimPrefix(data[i:], []byte(" "))
+}
+
+// parseRFC3164 parses the header of a RFC3164 message: TIMESTAMP HOSTNAME TAG: MSG,
+// the content is left untouched if the message does not start with a timestamp.
+func parseRFC3164(msg *syslogMessage, rest []byte) {
+	if len(rest) < len(rfc3164TimestampLayout) {
+		return
+	}
+	timestamp, err := time.ParseInLocation(rfc3164TimestampLayout, string(rest[:len(rfc3164TimestampLayout)]), time.Local)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	timestamp = timestamp.AddDate(now.Year(), 0, 0)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		// the message was sent at the end of the previous year
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	msg.timestamp = timestamp.UTC()
+	rest = bytes.TrimPrefix(rest[len(rfc3164TimestampLayout):], []byte(" "))
+
+	var hostname []byte
+	hostname, rest = nextField(rest)
+	msg.hostname = string(hostname)
+	msg.content = rest
+
+	// the tag is made of alphanumeric characters and ends with a '[', a ':' or a space.
+	end := 0
+	for end < len(rest) && end < 32 && rest[end] != '[' && rest[end] != ':' && rest[end] != ' ' {
+		end++
+	}
+	if end == 0 || end == len(rest) || rest[end] == ' ' {
+		return
+	}
+	msg.appName = string(rest[:end])
+	rest = rest[end:]
+	if rest[0] == '[' {
+		if closing := bytes.IndexByte(rest, ']'); closing > 0 {
+			msg.procID = string(rest[1:closing])
+			rest = rest[closing+1:]
+		}
+	}
+	rest = bytes.TrimPrefix(rest, []byte(":"))
+	msg.content = bytes.TrimPrefix(rest, []byte(" "))
+}
+
+// newSyslogMessage returns a message built from a syslog frame, the hostname,
+// the app name and the structured data of the frame are added to the tags of the message.
+func newSyslogMessage(frame []byte, source *config.LogSource) *message.Message {
+	parsed := parseSyslog(frame)
+	msg := message.NewMessageWithSource(parsed.content, parsed.status(), source, time.Now().UnixNano())
+	msg.Timestamp = parsed.timestamp
+
+	var tags []string
+	if parsed.hostname != "" {
+		tags = append(tags, "syslog_hostname:"+parsed.hostname)
+	}
+	if parsed.appName != "" {
+		tags = append(tags, "syslog_appname:"+parsed.appName)
+	}
+	if parsed.procID != "" {
+		tags = append(tags, "syslog_procid:"+parsed.procID)
+	}
+	if parsed.msgID != "" {
+		tags = append(tags, "syslog_msgid:"+parsed.msgID)
+	}
+	tags = append(tags, parsed.tags...)
+	if len(tags) > 0 {
+		msg.Origin.SetTags(tags)
+	}
+	return msg
+}
+
+// nextField returns the bytes before the next space and the bytes following it.
+func nextField(data []byte) ([]byte, []byte) {
+	if i := bytes.IndexByte(data, ' '); i >= 0 {
+		return data[:i], data[i+1:]
+	}
+	return data, nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+func toStrings(frames [][]byte) []string {
+	var result []string
+	for _, frame := range frames {
+		result = append(result, string(frame))
+	}
+	return result
+}
+
+func TestSyslogFramerNonTransparentFraming(t *testing.T) {
+	f := newSyslogFramer(100)
+	assert.Equal(t, []string{"<13>hello"}, toStrings(f.frames([]byte("<13>hello\r\n<13>wor"))))
+	assert.Equal(t, []string{"<13>world"}, toStrings(f.frames([]byte("ld\n"))))
+	assert.Nil(t, f.frames([]byte("\n")))
+	assert.Nil(t, f.frames([]byte("<13>pending")))
+	assert.Equal(t, "<13>pending", string(f.flush()))
+}
+
+func TestSyslogFramerOctetCounting(t *testing.T) {
+	f := newSyslogFramer(100)
+	assert.Equal(t, []string{"<13>hello"}, toStrings(f.frames([]byte("9 <13>hello14 <13>multi"))))
+	assert.Equal(t, []string{"<13>multi\nline"}, toStrings(f.frames([]byte("\nline"))))
+	assert.Nil(t, f.frames([]byte("1")))
+	assert.Equal(t, []string{"<13>world"}, toStrings(f.frames([]byte("0 <13>world\n"))))
+}
+
+func TestSyslogFramerTruncatesFrames(t *testing.T) {
+	f := newSyslogFramer(5)
+	assert.Equal(t, []string{"aaaaa", "aaaaa", "a"}, toStrings(f.frames([]byte("aaaaaaaaaaa\n"))))
+
+	assert.Equal(t, []string{"bbbbb"}, toStrings(f.frames([]byte("8 bbbbbb"))))
+	assert.Equal(t, []string{"ccc"}, toStrings(f.frames([]byte("bb3 ccc"))))
+}
+
+func TestParseRFC5424(t *testing.T) {
+	msg := parseSyslog([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Appli\"cation"][origin ip="192.0.2.1"] ` + "\xEF\xBB\xBF" + `An application event`))
+	assert.Equal(t, message.StatusNotice, msg.status())
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), msg.timestamp)
+	assert.Equal(t, "mymachine.example.com", msg.hostname)
+	assert.Equal(t, "evntslog", msg.appName)
+	assert.Equal(t, "1234", msg.procID)
+	assert.Equal(t, "ID47", msg.msgID)
+	assert.Equal(t, []string{"exampleSDID@32473.iut:3", `exampleSDID@32473.eventSource:Appli"cation`, "origin.ip:192.0.2.1"}, msg.tags)
+	assert.Equal(t, "An application event", string(msg.content))
+
+	msg = parseSyslog([]byte(`<34>1 - - su - - - 'su root' failed`))
+	assert.Equal(t, message.StatusCritical, msg.status())
+	assert.True(t, msg.timestamp.IsZero())
+	assert.Equal(t, "", msg.hostname)
+	assert.Equal(t, "su", msg.appName)
+	assert.Nil(t, msg.tags)
+	assert.Equal(t, "'su root' failed", string(msg.content))
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg := parseSyslog([]byte("<34>Oct 11 22:14:15 mymachine su[42]: 'su root' failed for lonvick on /dev/pts/8"))
+	assert.Equal(t, message.StatusCritical, msg.status())
+	assert.Equal(t, time.October, msg.timestamp.Local().Month())
+	assert.Equal(t, 11, msg.timestamp.Local().Day())
+	assert.Equal(t, "mymachine", msg.hostname)
+	assert.Equal(t, "su", msg.appName)
+	assert.Equal(t, "42", msg.procID)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.content))
+
+	msg = parseSyslog([]byte("<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!"))
+	assert.Equal(t, message.StatusNotice, msg.status())
+	assert.Equal(t, "10.0.0.99", msg.hostname)
+	assert.Equal(t, "", msg.appName)
+	assert.Equal(t, "Use the BFG!", string(msg.content))
+
+	msg = parseSyslog([]byte("<11>no timestamp"))
+	assert.Equal(t, message.StatusError, msg.status())
+	assert.True(t, msg.timestamp.IsZero())
+	assert.Equal(t, "no timestamp", string(msg.content))
+}
+
+func TestParseInvalidSyslog(t *testing.T) {
+	for _, frame := range []string{"hello world", "<>hello", "<999>hello", "<abc>hello"} {
+		msg := parseSyslog([]byte(frame))
+		assert.Equal(t, message.StatusInfo, msg.status())
+		assert.Equal(t, frame, string(msg.content))
+	}
+}
+
+func TestSyslogOverTCPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()))
+	assert.Nil(t, err)
+
+	content := "<11>1 2021-03-28T13:45:30Z host app - - [meta env=\"prod\"] first\nline"
+	fmt.Fprintf(conn, "%d %s", len(content), content)
+	fmt.Fprintf(conn, "<14>Mar 28 13:45:31 host app: second\n")
+
+	msg := <-msgChan
+	assert.Equal(t, "first\nline", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, time.Date(2021, 3, 28, 13, 45, 30, 0, time.UTC), msg.Timestamp)
+	assert.Equal(t, []string{"syslog_hostname:host", "syslog_appname:app", "meta.env:prod"}, msg.Origin.Tags())
+
+	msg = <-msgChan
+	assert.Equal(t, "second", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	listener.Stop()
+}
+
+func TestSyslogOverUDPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewUDPListener(pp, config.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.UDPType, Port: udpTestPort}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("udp", fmt.Sprintf("%s", listener.tailer.conn.LocalAddr()))
+	assert.Nil(t, err)
+
+	fmt.Fprintf(conn, "<12>1 - host app 42 - - warning")
+	msg := <-msgChan
+	assert.Equal(t, "warning", string(msg.Content))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.True(t, strings.Contains(msg.Origin.TagsToString(), "syslog_procid:42"))
+
+	listener.Stop()
+}
@@ -19,32 +19,41 @@ import (
 
 // Tailer reads data from a connection
 type Tailer struct {
-	source     *config.LogSource
-	conn       net.Conn
-	outputChan chan *message.Message
-	read       func(*Tailer) ([]byte, error)
-	decoder    *decoder.Decoder
-	stop       chan struct{}
-	done       chan struct{}
+	source       *config.LogSource
+	conn         net.Conn
+	outputChan   chan *message.Message
+	read         func(*Tailer) ([]byte, error)
+	decoder      *decoder.Decoder
+	syslogFramer *syslogFramer
+	stop         chan struct{}
+	done         chan struct{}
 }
 
-// NewTailer returns a new Tailer
+// NewTailer returns a new Tailer, the data of syslog sources is split
+// in syslog messages instead of lines.
 func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, error)) *Tailer {
-	return &Tailer{
+	t := &Tailer{
 		source:     source,
 		conn:       conn,
 		outputChan: outputChan,
 		read:       read,
-		decoder:    decoder.InitializeDecoder(source, parser.NoopParser),
 		stop:       make(chan struct{}, 1),
 		done:       make(chan struct{}, 1),
 	}
+	if source.Config.Type == config.SyslogType {
+		t.syslogFramer = newSyslogFramer(syslogFrameLimit)
+	} else {
+		t.decoder = decoder.InitializeDecoder(source, parser.NoopParser)
+	}
+	return t
 }
 
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
-	go t.forwardMessages()
-	t.decoder.Start()
+	if t.decoder != nil {
+		go t.forwardMessages()
+		t.decoder.Start()
+	}
 	go t.readForever()
 }
 
@@ -72,7 +81,12 @@ func (t *Tailer) forwardMessages() {
 func (t *Tailer) readForever() {
 	defer func() {
 		t.conn.Close()
-		t.decoder.Stop()
+		if t.decoder != nil {
+			t.decoder.Stop()
+		} else {
+			t.forwardSyslogMessages(t.syslogFramer.flush())
+			t.done <- struct{}{}
+		}
 	}()
 	for {
 		select {
@@ -91,7 +105,20 @@ func (t *Tailer) readForever() {
 				return
 			}
 			t.source.BytesRead.Add(int64(len(data)))
+			if t.syslogFramer != nil {
+				t.forwardSyslogMessages(t.syslogFramer.frames(data)...)
+				continue
+			}
 			t.decoder.InputChan <- decoder.NewInput(data)
 		}
 	}
 }
+
+// forwardSyslogMessages parses syslog frames and forwards them to the output channel
+func (t *Tailer) forwardSyslogMessages(frames ...[]byte) {
+	for _, frame := range frames {
+		if len(frame) > 0 {
+			t.outputChan <- newSyslogMessage(frame, t.source)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serverless/aws"
@@ -183,6 +184,39 @@ func Flush() {
 	}
 }
 
+// ReloadResult reports the changes applied by a reload of the logs configuration.
+type ReloadResult struct {
+	ProcessingRules int `json:"processing_rules"`
+	AddedSources    int `json:"added_sources"`
+	RemovedSources  int `json:"removed_sources"`
+}
+
+// Reload applies the global processing rules read again from the configuration file and
+// updates the sources defined in the configuration files from fileConfigs without restarting
+// the logs-agent: the pipelines keep running and only the inputs of the sources which changed
+// are restarted, from their last committed offset.
+func Reload(fileConfigs []integration.Config) (ReloadResult, error) {
+	var result ReloadResult
+	if !IsAgentRunning() || agent == nil {
+		return result, errors.New("the logs-agent is not running")
+	}
+	log.Info("Reloading the logs-agent configuration")
+
+	processingRules, err := config.ReadGlobalProcessingRules()
+	if err != nil {
+		return result, fmt.Errorf("invalid processing rules, the configuration was not reloaded: %v", err)
+	}
+	agent.SetProcessingRules(processingRules)
+	result.ProcessingRules = len(processingRules)
+
+	if adScheduler := scheduler.GetScheduler(); adScheduler != nil {
+		result.AddedSources, result.RemovedSources = adScheduler.ReloadFileConfigs(fileConfigs)
+	}
+	config.RemoveStaleServiceRateLimiters(agent.sources.GetSources())
+	log.Infof("logs-agent configuration reloaded: %d global processing rules, %d sources added, %d sources removed", result.ProcessingRules, result.AddedSources, result.RemovedSources)
+	return result, nil
+}
+
 // IsAgentRunning returns true if the logs-agent is running.
 func IsAgentRunning() bool {
 	return status.Get().IsRunning
@@ -23,6 +23,8 @@ type Message struct {
 	// Optional.
 	// Used in the Serverless Agent
 	Lambda *Lambda
+	// Optional. Attributes extracted from the content by parsing processing rules.
+	Attributes map[string]interface{}
 }
 
 // Lambda is a struct storing information about the Lambda function and function execution.
@@ -70,6 +72,19 @@ func (m *Message) GetStatus() string {
 	return m.status
 }
 
+// SetStatus sets the status of the message.
+func (m *Message) SetStatus(status string) {
+	m.status = status
+}
+
+// SetAttribute sets the value of an attribute of the message.
+func (m *Message) SetAttribute(key string, value interface{}) {
+	if m.Attributes == nil {
+		m.Attributes = make(map[string]interface{})
+	}
+	m.Attributes[key] = value
+}
+
 // GetLatency returns the latency delta from ingestion time until now
 func (m *Message) GetLatency() int64 {
 	return time.Now().UnixNano() - m.IngestionTimestamp
@@ -16,9 +16,11 @@ type Origin struct {
 	Identifier string
 	LogSource  *config.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// Fingerprint identifies the content of the file the offset belongs to.
+	Fingerprint string
+	service     string
+	source      string
+	tags        []string
 }
 
 // NewOrigin returns a new Origin
@@ -88,6 +90,14 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags appends tags to the tags of the origin.
+func (o *Origin) AddTags(tags ...string) {
+	// copy the existing tags as they can be shared with other origins
+	newTags := make([]string, 0, len(o.tags)+len(tags))
+	newTags = append(newTags, o.tags...)
+	o.tags = append(newTags, tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
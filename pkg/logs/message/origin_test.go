@@ -105,3 +105,17 @@ func TestDefaultServiceValueIsServiceFromConfig(t *testing.T) {
 	origin.SetService("bar")
 	assert.Equal(t, "bar", origin.Service())
 }
+
+func TestAddTagsDoesNotAlterSharedTags(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	shared := make([]string, 1, 10)
+	shared[0] = "foo:bar"
+	origin := NewOrigin(source)
+	origin.SetTags(shared)
+	origin.AddTags("level:error")
+	other := NewOrigin(source)
+	other.SetTags(shared)
+	other.AddTags("level:info")
+	assert.Equal(t, []string{"foo:bar", "level:error"}, origin.Tags())
+	assert.Equal(t, []string{"foo:bar", "level:info"}, other.Tags())
+}
@@ -51,6 +51,48 @@ var (
 	// TlmEncodedBytesSent is the total number of sent bytes after encoding if any
 	TlmEncodedBytesSent = telemetry.NewCounter("logs", "encoded_bytes_sent",
 		nil, "Total number of sent bytes after encoding if any")
+	// PayloadsSpooled is the total number of payloads stored in the spool
+	PayloadsSpooled = expvar.Int{}
+	// TlmPayloadsSpooled is the total number of payloads stored in the spool
+	TlmPayloadsSpooled = telemetry.NewCounter("logs", "payloads_spooled",
+		nil, "Total number of payloads stored in the spool")
+	// PayloadsReplayed is the total number of spooled payloads sent
+	PayloadsReplayed = expvar.Int{}
+	// TlmPayloadsReplayed is the total number of spooled payloads sent
+	TlmPayloadsReplayed = telemetry.NewCounter("logs", "payloads_replayed",
+		nil, "Total number of spooled payloads sent")
+	// SpooledPayloadsDropped is the total number of spooled payloads evicted or unreadable
+	SpooledPayloadsDropped = expvar.Int{}
+	// TlmSpooledPayloadsDropped is the total number of spooled payloads evicted or unreadable
+	TlmSpooledPayloadsDropped = telemetry.NewCounter("logs", "spooled_payloads_dropped",
+		nil, "Total number of spooled payloads evicted or unreadable")
+	// SpoolSizeInBytes is the disk space used by the spool
+	SpoolSizeInBytes = expvar.Int{}
+
+	// TlmProcessingRuleParsed is the total number of logs parsed per processing rule
+	TlmProcessingRuleParsed = telemetry.NewCounter("logs", "processing_rule_parsed",
+		[]string{"rule"}, "Total number of logs parsed per processing rule")
+	// TlmProcessingRuleParseErrors is the total number of logs a processing rule failed to parse
+	TlmProcessingRuleParseErrors = telemetry.NewCounter("logs", "processing_rule_parse_errors",
+		[]string{"rule"}, "Total number of logs a processing rule failed to parse")
+
+	// TlmLogMetricsSubmitted is the total number of samples submitted per log metric
+	TlmLogMetricsSubmitted = telemetry.NewCounter("logs", "log_metrics_submitted",
+		[]string{"metric"}, "Total number of samples submitted per log metric")
+	// TlmLogMetricsErrors is the total number of matching logs a log metric failed to extract a value from
+	TlmLogMetricsErrors = telemetry.NewCounter("logs", "log_metrics_errors",
+		[]string{"metric"}, "Total number of matching logs a log metric failed to extract a value from")
+
+	// LogsRateLimited is the total number of logs dropped by the rate limit of their source
+	LogsRateLimited = expvar.Int{}
+	// TlmLogsRateLimited is the total number of logs dropped per source by its rate limit
+	TlmLogsRateLimited = telemetry.NewCounter("logs", "rate_limited",
+		[]string{"source"}, "Total number of logs dropped per source by its rate limit")
+	// LogsSampledOut is the total number of logs dropped by the sampling of their source
+	LogsSampledOut = expvar.Int{}
+	// TlmLogsSampledOut is the total number of logs dropped per source by its sampling
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out",
+		[]string{"source"}, "Total number of logs dropped per source by its sampling")
 	// TODO: Add LogsCollected for the total number of collected logs.
 
 )
@@ -64,4 +106,10 @@ func init() {
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
 	LogsExpvars.Set("BytesSent", &BytesSent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
+	LogsExpvars.Set("PayloadsSpooled", &PayloadsSpooled)
+	LogsExpvars.Set("PayloadsReplayed", &PayloadsReplayed)
+	LogsExpvars.Set("SpooledPayloadsDropped", &SpooledPayloadsDropped)
+	LogsExpvars.Set("SpoolSizeInBytes", &SpoolSizeInBytes)
+	LogsExpvars.Set("LogsRateLimited", &LogsRateLimited)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
 }
@@ -6,6 +6,7 @@
 package mock
 
 import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 )
@@ -31,6 +32,9 @@ func (p *mockProvider) Stop() {}
 // Flush does nothing
 func (p *mockProvider) Flush() {}
 
+// SetProcessingRules does nothing
+func (p *mockProvider) SetProcessingRules(processingRules []*config.ProcessingRule) {}
+
 // NextPipelineChan returns the next pipeline
 func (p *mockProvider) NextPipelineChan() chan *message.Message {
 	return p.msgChan
@@ -8,7 +8,9 @@ package pipeline
 import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/webhook"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -23,8 +25,8 @@ type Pipeline struct {
 	sender    *sender.Sender
 }
 
-// NewPipeline returns a new Pipeline
-func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diagnosticMessageReceiver diagnostic.MessageReceiver, serverless bool) *Pipeline {
+// NewPipeline returns a new Pipeline, the generic destinations are shared by all the pipelines
+func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diagnosticMessageReceiver diagnostic.MessageReceiver, serverless bool, spool *sender.Spool, metricSender processor.MetricSender, genericDestinations map[string]*processor.GenericDestination) *Pipeline {
 	var destinations *client.Destinations
 	if endpoints.UseHTTP {
 		main := http.NewDestination(endpoints.Main, http.JSONContentType, destinationsContext)
@@ -34,7 +36,13 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 		}
 		destinations = client.NewDestinations(main, additionals)
 	} else {
-		main := tcp.NewDestination(endpoints.Main, endpoints.UseProto, destinationsContext)
+		var main *tcp.Destination
+		if spool != nil {
+			// let the sender spool the payloads while the intake is unreachable
+			main = tcp.NewNonBlockingDestination(endpoints.Main, endpoints.UseProto, destinationsContext)
+		} else {
+			main = tcp.NewDestination(endpoints.Main, endpoints.UseProto, destinationsContext)
+		}
 		additionals := []client.Destination{}
 		for _, endpoint := range endpoints.Additionals {
 			additionals = append(additionals, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext))
@@ -50,7 +58,7 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	} else {
 		strategy = sender.StreamStrategy
 	}
-	sender := sender.NewSender(senderChan, outputChan, destinations, strategy)
+	sender := sender.NewSender(senderChan, outputChan, destinations, strategy, spool)
 
 	var encoder processor.Encoder
 	if serverless {
@@ -64,7 +72,7 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	}
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, senderChan, processingRules, encoder, diagnosticMessageReceiver)
+	processor := processor.New(inputChan, senderChan, processingRules, encoder, diagnosticMessageReceiver, genericDestinations, metricSender)
 
 	return &Pipeline{
 		InputChan: inputChan,
@@ -73,6 +81,21 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	}
 }
 
+// newGenericDestinations returns one destination per generic endpoint, keyed by endpoint name.
+// They must be shared by the pipelines to keep the messages ordered and use a single connection.
+func newGenericDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) map[string]*processor.GenericDestination {
+	genericDestinations := make(map[string]*processor.GenericDestination)
+	for _, endpoint := range endpoints.Generics {
+		switch endpoint.Type {
+		case config.EndpointTypeSyslog:
+			genericDestinations[endpoint.Name] = processor.NewGenericDestination(syslog.NewDestination(endpoint, destinationsContext), processor.SyslogEncoder)
+		case config.EndpointTypeWebhook:
+			genericDestinations[endpoint.Name] = processor.NewGenericDestination(webhook.NewDestination(endpoint, endpoints.BatchWait, destinationsContext), processor.JSONEncoder)
+		}
+	}
+	return genericDestinations
+}
+
 // Start launches the pipeline
 func (p *Pipeline) Start() {
 	p.sender.Start()
@@ -85,6 +108,11 @@ func (p *Pipeline) Stop() {
 	p.sender.Stop()
 }
 
+// SetProcessingRules replaces the global processing rules of the pipeline.
+func (p *Pipeline) SetProcessingRules(processingRules []*config.ProcessingRule) {
+	p.processor.SetProcessingRules(processingRules)
+}
+
 // Flush flushes synchronously the processor and sender managed by this pipeline.
 func (p *Pipeline) Flush() {
 	p.processor.Flush() // flush messages in the processor into the sender
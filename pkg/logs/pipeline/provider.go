@@ -14,7 +14,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 )
 
 // Provider provides message channels
@@ -24,6 +26,8 @@ type Provider interface {
 	NextPipelineChan() chan *message.Message
 	// Flush flushes all pipeline contained in this Provider
 	Flush()
+	// SetProcessingRules replaces the global processing rules of all the pipelines
+	SetProcessingRules(processingRules []*config.ProcessingRule)
 }
 
 // provider implements providing logic
@@ -39,20 +43,29 @@ type provider struct {
 	currentPipelineIndex int32
 	destinationsContext  *client.DestinationsContext
 
-	serverless bool
+	serverless   bool
+	spool        *sender.Spool
+	metricSender processor.MetricSender
 }
 
 // NewProvider returns a new Provider
 func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, nil, nil)
+}
+
+// NewAgentProvider returns a new Provider for the core agent. When set, the spool stores on disk
+// the payloads that can't be sent while the main destination is unreachable and the metric sender
+// submits the metrics generated from the logs.
+func NewAgentProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, spool *sender.Spool, metricSender processor.MetricSender) Provider {
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, spool, metricSender)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
 func NewServerlessProvider(numberOfPipelines int, auditor auditor.Auditor, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
-	return newProvider(numberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, processingRules, endpoints, destinationsContext, true)
+	return newProvider(numberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, processingRules, endpoints, destinationsContext, true, nil, nil)
 }
 
-func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool) Provider {
+func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool, spool *sender.Spool, metricSender processor.MetricSender) Provider {
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
 		auditor:                   auditor,
@@ -62,6 +75,8 @@ func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessa
 		pipelines:                 []*Pipeline{},
 		destinationsContext:       destinationsContext,
 		serverless:                serverless,
+		spool:                     spool,
+		metricSender:              metricSender,
 	}
 }
 
@@ -70,8 +85,10 @@ func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
 
+	// the generic destinations are shared by the pipelines to keep the messages ordered
+	genericDestinations := newGenericDestinations(p.endpoints, p.destinationsContext)
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, p.spool, p.metricSender, genericDestinations)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
@@ -107,3 +124,12 @@ func (p *provider) Flush() {
 		p.Flush()
 	}
 }
+
+// SetProcessingRules replaces the global processing rules of all the pipelines,
+// the pipelines keep running so that no message is lost.
+func (p *provider) SetProcessingRules(processingRules []*config.ProcessingRule) {
+	p.processingRules = processingRules
+	for _, pipeline := range p.pipelines {
+		pipeline.SetProcessingRules(processingRules)
+	}
+}
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// GenericDestination is a destination which receives a copy of the messages
+// of the sources selecting it, encoded in the format it expects.
+type GenericDestination struct {
+	Destination client.Destination
+	Encoder     Encoder
+}
+
+// NewGenericDestination returns a new generic destination.
+func NewGenericDestination(destination client.Destination, encoder Encoder) *GenericDestination {
+	return &GenericDestination{
+		Destination: destination,
+		Encoder:     encoder,
+	}
+}
+
+// sendToGenericDestinations sends asynchronously a copy of the message
+// to the generic destinations selected by its source.
+func (p *Processor) sendToGenericDestinations(msg *message.Message, redactedMsg []byte) {
+	for _, name := range msg.Origin.LogSource.Config.Destinations {
+		destination, found := p.genericDestinations[name]
+		if !found {
+			continue
+		}
+		content, err := destination.Encoder.Encode(msg, redactedMsg)
+		if err != nil {
+			log.Debugf("Unable to encode message for destination %s: %v", name, err)
+			continue
+		}
+		destination.Destination.SendAsync(content)
+	}
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+type recordingDestination struct {
+	payloads [][]byte
+}
+
+func (d *recordingDestination) Send(payload []byte) error {
+	d.payloads = append(d.payloads, payload)
+	return nil
+}
+
+func (d *recordingDestination) SendAsync(payload []byte) {
+	d.payloads = append(d.payloads, payload)
+}
+
+type prefixEncoder struct {
+	prefix string
+}
+
+func (e *prefixEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	return append([]byte(e.prefix), redactedMsg...), nil
+}
+
+func TestSendToGenericDestinations(t *testing.T) {
+	syslog := &recordingDestination{}
+	webhook := &recordingDestination{}
+	p := &Processor{
+		genericDestinations: map[string]*GenericDestination{
+			"syslog":  NewGenericDestination(syslog, &prefixEncoder{"syslog:"}),
+			"webhook": NewGenericDestination(webhook, &prefixEncoder{"webhook:"}),
+		},
+	}
+
+	source := config.NewLogSource("", &config.LogsConfig{Destinations: []string{"syslog", "unknown"}})
+	p.sendToGenericDestinations(newMessage([]byte("hello"), source, ""), []byte("redacted"))
+	assert.Equal(t, [][]byte{[]byte("syslog:redacted")}, syslog.payloads)
+	assert.Len(t, webhook.payloads, 0)
+
+	source = config.NewLogSource("", &config.LogsConfig{})
+	p.sendToGenericDestinations(newMessage([]byte("hello"), source, ""), []byte("redacted"))
+	assert.Len(t, syslog.payloads, 1)
+	assert.Len(t, webhook.payloads, 0)
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// MetricSender submits the metrics generated from the logs,
+// it is implemented by the aggregator senders.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Gauge(metric string, value float64, hostname string, tags []string)
+	Histogram(metric string, value float64, hostname string, tags []string)
+	Commit()
+}
+
+// submitLogMetrics submits the metrics declared by the source of the message,
+// it must be called before the processing rules so that excluded lines are accounted for.
+func (p *Processor) submitLogMetrics(msg *message.Message) {
+	for _, metric := range msg.Origin.LogSource.Config.LogMetrics {
+		match := metric.Regex.FindSubmatch(msg.Content)
+		if match == nil {
+			continue
+		}
+
+		value, tags, ok := logMetricValueAndTags(metric, match)
+		if !ok {
+			metrics.TlmLogMetricsErrors.Inc(metric.Name)
+			continue
+		}
+		tags = append(tags, msg.Origin.LogSource.Config.Tags...)
+
+		switch metric.Type {
+		case config.LogMetricCount:
+			p.metricSender.Count(metric.Name, value, "", tags)
+		case config.LogMetricGauge:
+			p.metricSender.Gauge(metric.Name, value, "", tags)
+		case config.LogMetricHistogram:
+			p.metricSender.Histogram(metric.Name, value, "", tags)
+		}
+		metrics.TlmLogMetricsSubmitted.Inc(metric.Name)
+	}
+}
+
+// logMetricValueAndTags returns the value captured by the value group, 1 if the metric has none,
+// and the tags made of the tag groups, returns false if no number was captured.
+func logMetricValueAndTags(metric *config.LogMetric, match [][]byte) (float64, []string, bool) {
+	value := 1.0
+	hasValue := metric.ValueGroup == ""
+	tags := append([]string{}, metric.Tags...)
+	for i, name := range metric.Regex.SubexpNames() {
+		if name == "" || match[i] == nil {
+			continue
+		}
+		if name == metric.ValueGroup {
+			var err error
+			if value, err = strconv.ParseFloat(string(match[i]), 64); err != nil {
+				return 0, nil, false
+			}
+			hasValue = true
+		}
+		for _, group := range metric.TagGroups {
+			if group == name {
+				tags = append(tags, name+":"+string(match[i]))
+			}
+		}
+	}
+	return value, tags, hasValue
+}
+
+// MetricCommitter periodically commits the metrics generated from the logs
+// so that they are flushed by the aggregator as if they were submitted by a check.
+type MetricCommitter struct {
+	sender   MetricSender
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewMetricCommitter returns a new MetricCommitter.
+func NewMetricCommitter(sender MetricSender, interval time.Duration) *MetricCommitter {
+	return &MetricCommitter{
+		sender:   sender,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts committing the metrics.
+func (c *MetricCommitter) Start() {
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.sender.Commit()
+			case <-c.stop:
+				c.sender.Commit()
+				return
+			}
+		}
+	}()
+}
+
+// Stop commits the pending metrics and stops.
+func (c *MetricCommitter) Stop() {
+	close(c.stop)
+	<-c.done
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+type sample struct {
+	metricType string
+	name       string
+	value      float64
+	tags       []string
+}
+
+type recordingMetricSender struct {
+	samples []sample
+	commits int
+}
+
+func (s *recordingMetricSender) Count(metric string, value float64, hostname string, tags []string) {
+	s.samples = append(s.samples, sample{config.LogMetricCount, metric, value, tags})
+}
+
+func (s *recordingMetricSender) Gauge(metric string, value float64, hostname string, tags []string) {
+	s.samples = append(s.samples, sample{config.LogMetricGauge, metric, value, tags})
+}
+
+func (s *recordingMetricSender) Histogram(metric string, value float64, hostname string, tags []string) {
+	s.samples = append(s.samples, sample{config.LogMetricHistogram, metric, value, tags})
+}
+
+func (s *recordingMetricSender) Commit() {
+	s.commits++
+}
+
+func newLogMetricsSource(t *testing.T, logMetrics ...*config.LogMetric) *config.LogSource {
+	logsConfig := &config.LogsConfig{Type: config.FileType, Path: "/var/log/app.log", Tags: []string{"env:prod"}, LogMetrics: logMetrics}
+	require.NoError(t, logsConfig.Validate())
+	return config.NewLogSource("", logsConfig)
+}
+
+func TestSubmitLogMetrics(t *testing.T) {
+	sender := &recordingMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newLogMetricsSource(t,
+		&config.LogMetric{Name: "app.requests", Type: config.LogMetricCount, Pattern: `status=(?P<status>\d+)`, TagGroups: []string{"status"}, Tags: []string{"team:web"}},
+		&config.LogMetric{Name: "app.latency", Type: config.LogMetricHistogram, Pattern: `took=(?P<ms>[\d.]+)ms`, ValueGroup: "ms"},
+		&config.LogMetric{Name: "app.queue", Type: config.LogMetricGauge, Pattern: `queue=(?P<size>\S+)`, ValueGroup: "size"},
+	)
+
+	p.submitLogMetrics(message.NewMessageWithSource([]byte("GET / status=200 took=12.5ms"), message.StatusInfo, source, 0))
+	p.submitLogMetrics(message.NewMessageWithSource([]byte("queue=full"), message.StatusInfo, source, 0))
+	p.submitLogMetrics(message.NewMessageWithSource([]byte("queue=3"), message.StatusInfo, source, 0))
+
+	assert.Equal(t, []sample{
+		{config.LogMetricCount, "app.requests", 1, []string{"team:web", "status:200", "env:prod"}},
+		{config.LogMetricHistogram, "app.latency", 12.5, []string{"env:prod"}},
+		{config.LogMetricGauge, "app.queue", 3, []string{"env:prod"}},
+	}, sender.samples)
+}
+
+func TestLogMetricsAreSubmittedForExcludedLogs(t *testing.T) {
+	sender := &recordingMetricSender{}
+	inputChan := make(chan *message.Message, 1)
+	outputChan := make(chan *message.Message, 1)
+	p := New(inputChan, outputChan, []*config.ProcessingRule{newProcessingRule(config.ExcludeAtMatch, "", "ERROR")}, RawEncoder, nil, nil, sender)
+	source := newLogMetricsSource(t, &config.LogMetric{Name: "app.errors", Type: config.LogMetricCount, Pattern: "ERROR"})
+
+	p.processMessage(message.NewMessageWithSource([]byte("ERROR something failed"), message.StatusInfo, source, 0))
+	assert.Len(t, outputChan, 0)
+	assert.Equal(t, []sample{{config.LogMetricCount, "app.errors", 1, []string{"env:prod"}}}, sender.samples)
+}
+
+func TestMetricCommitterCommitsOnStop(t *testing.T) {
+	sender := &recordingMetricSender{}
+	committer := NewMetricCommitter(sender, time.Hour)
+	committer.Start()
+	committer.Stop()
+	assert.Equal(t, 1, sender.commits)
+}
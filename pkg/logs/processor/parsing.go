@@ -0,0 +1,237 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// statusAliases maps the usual level names to message statuses.
+var statusAliases = map[string]string{
+	"emerg":         message.StatusEmergency,
+	"emergency":     message.StatusEmergency,
+	"panic":         message.StatusEmergency,
+	"alert":         message.StatusAlert,
+	"crit":          message.StatusCritical,
+	"critical":      message.StatusCritical,
+	"fatal":         message.StatusCritical,
+	"err":           message.StatusError,
+	"error":         message.StatusError,
+	"warn":          message.StatusWarning,
+	"warning":       message.StatusWarning,
+	"notice":        message.StatusNotice,
+	"info":          message.StatusInfo,
+	"information":   message.StatusInfo,
+	"informational": message.StatusInfo,
+	"debug":         message.StatusDebug,
+	"trace":         message.StatusDebug,
+}
+
+// applyParsingRule extracts attributes from the content, promotes the attributes
+// configured on the rule to the status and the tags of the message and attaches
+// the remaining ones to the message. It returns the content without the excluded
+// attributes, so that they don't leave the host.
+func applyParsingRule(rule *config.ProcessingRule, msg *message.Message, content []byte) []byte {
+	var attributes map[string]interface{}
+	switch rule.Type {
+	case config.ParseJSON:
+		attributes = parseJSON(content)
+	case config.ParseKeyValue:
+		attributes = parseKeyValue(content)
+	case config.ParseRegex:
+		attributes = parseRegex(rule.Regex, content)
+	}
+	if len(attributes) == 0 {
+		metrics.TlmProcessingRuleParseErrors.Inc(rule.Name)
+		return content
+	}
+	metrics.TlmProcessingRuleParsed.Inc(rule.Name)
+
+	excluded := make(map[string]struct{}, len(rule.ExcludeAttributes))
+	for _, key := range rule.ExcludeAttributes {
+		if _, exists := attributes[key]; exists {
+			excluded[key] = struct{}{}
+			delete(attributes, key)
+		}
+	}
+	if len(excluded) > 0 {
+		content = excludeAttributes(rule, content, attributes, excluded)
+	}
+
+	if value, exists := attributes[rule.StatusAttribute]; exists {
+		if status, found := statusAliases[strings.ToLower(attributeToString(value))]; found {
+			msg.SetStatus(status)
+		}
+	}
+
+	var tags []string
+	for _, key := range rule.TagAttributes {
+		if value, exists := attributes[key]; exists {
+			tags = append(tags, key+":"+attributeToString(value))
+		}
+	}
+	if len(tags) > 0 {
+		msg.Origin.AddTags(tags...)
+	}
+
+	for key, value := range attributes {
+		msg.SetAttribute(key, value)
+	}
+	return content
+}
+
+// excludeAttributes returns the content without the excluded attributes: a JSON object is
+// encoded again with the remaining attributes, the excluded key=value pairs are removed and
+// the values captured by the excluded groups of a regular expression are erased.
+func excludeAttributes(rule *config.ProcessingRule, content []byte, attributes map[string]interface{}, excluded map[string]struct{}) []byte {
+	switch rule.Type {
+	case config.ParseJSON:
+		var encoded bytes.Buffer
+		encoder := json.NewEncoder(&encoded)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(attributes); err != nil {
+			// the attributes were decoded from JSON, this can't happen
+			return content
+		}
+		return bytes.TrimRight(encoded.Bytes(), "\n")
+	case config.ParseKeyValue:
+		redacted := make([]byte, 0, len(content))
+		last := 0
+		scanKeyValue(content, func(key string, value []byte, start, end int) {
+			if _, found := excluded[key]; found {
+				redacted = append(redacted, bytes.TrimRight(content[last:start], " ")...)
+				last = end
+			}
+		})
+		redacted = append(redacted, content[last:]...)
+		return bytes.TrimLeft(redacted, " ")
+	case config.ParseRegex:
+		match := rule.Regex.FindSubmatchIndex(content)
+		redacted := make([]byte, 0, len(content))
+		last := 0
+		for i, name := range rule.Regex.SubexpNames() {
+			if _, found := excluded[name]; !found || name == "" || match[2*i] < last {
+				continue
+			}
+			redacted = append(redacted, content[last:match[2*i]]...)
+			last = match[2*i+1]
+		}
+		return append(redacted, content[last:]...)
+	}
+	return content
+}
+
+// parseJSON returns the top level fields of a JSON object.
+func parseJSON(content []byte) map[string]interface{} {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || content[0] != '{' {
+		return nil
+	}
+	var attributes map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&attributes); err != nil {
+		return nil
+	}
+	return attributes
+}
+
+// parseKeyValue returns the key=value pairs of a logfmt-like line,
+// values can be double-quoted to contain spaces, tokens without '=' are ignored.
+func parseKeyValue(content []byte) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	scanKeyValue(content, func(key string, value []byte, start, end int) {
+		attributes[key] = string(value)
+	})
+	return attributes
+}
+
+// scanKeyValue calls fn with each key=value pair of a logfmt-like line and the
+// position of the pair in the line.
+func scanKeyValue(content []byte, fn func(key string, value []byte, start, end int)) {
+	i, n := 0, len(content)
+	for i < n {
+		// skip spaces
+		for i < n && content[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < n && content[i] != '=' && content[i] != ' ' {
+			i++
+		}
+		key := string(content[start:i])
+		if i >= n || content[i] != '=' {
+			continue
+		}
+		i++ // skip '='
+		var value []byte
+		if i < n && content[i] == '"' {
+			i++
+			for i < n && content[i] != '"' {
+				if content[i] == '\\' && i+1 < n {
+					i++
+				}
+				value = append(value, content[i])
+				i++
+			}
+			i++ // skip the closing quote
+		} else {
+			valueStart := i
+			for i < n && content[i] != ' ' {
+				i++
+			}
+			value = content[valueStart:i]
+		}
+		if key != "" {
+			end := i
+			if end > n {
+				// unterminated quoted value
+				end = n
+			}
+			fn(key, value, start, end)
+		}
+	}
+}
+
+// parseRegex returns the named capture groups of the regular expression.
+func parseRegex(re *regexp.Regexp, content []byte) map[string]interface{} {
+	match := re.FindSubmatch(content)
+	if match == nil {
+		return nil
+	}
+	attributes := make(map[string]interface{})
+	for i, name := range re.SubexpNames() {
+		if name != "" && match[i] != nil {
+			attributes[name] = string(match[i])
+		}
+	}
+	return attributes
+}
+
+// attributeToString returns the string representation of an attribute value.
+func attributeToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		if raw, err := json.Marshal(v); err == nil {
+			return string(raw)
+		}
+	}
+	return fmt.Sprint(value)
+}
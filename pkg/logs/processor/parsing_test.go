@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pb"
+)
+
+func TestParseJSON(t *testing.T) {
+	p := &Processor{}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{{
+		Type:              config.ParseJSON,
+		Name:              "json",
+		StatusAttribute:   "level",
+		TagAttributes:     []string{"user"},
+		ExcludeAttributes: []string{"password"},
+	}}}}
+
+	msg := newMessage([]byte(`{"level":"ERROR","user":"bob","password":"secret","latency":12}`), &source, "")
+	shouldProcess, redactedMsg := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, `{"latency":12,"level":"ERROR","user":"bob"}`, string(redactedMsg))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, []string{"user:bob"}, msg.Origin.Tags())
+	assert.Equal(t, map[string]interface{}{"level": "ERROR", "user": "bob", "latency": json.Number("12")}, msg.Attributes)
+
+	msg = newMessage([]byte("not json"), &source, "")
+	shouldProcess, _ = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Nil(t, msg.Attributes)
+}
+
+func TestParseKeyValue(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{
+		"level":    "warn",
+		"msg":      `connection "lost"`,
+		"trace_id": "1234",
+		"empty":    "",
+	}, parseKeyValue([]byte(`level=warn  msg="connection \"lost\"" standalone trace_id=1234 empty=`)))
+	assert.Empty(t, parseKeyValue([]byte("hello world")))
+}
+
+func TestParseRegex(t *testing.T) {
+	p := &Processor{}
+	rule := &config.ProcessingRule{
+		Type:            config.ParseRegex,
+		Name:            "regex",
+		Pattern:         `^(?P<level>\w+) user=(?P<user>\w+)`,
+		Regex:           regexp.MustCompile(`^(?P<level>\w+) user=(?P<user>\w+)`),
+		StatusAttribute: "level",
+	}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	msg := newMessage([]byte("warning user=alice logged in"), &source, "")
+	p.applyRedactingRules(msg)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, map[string]interface{}{"level": "warning", "user": "alice"}, msg.Attributes)
+
+	msg = newMessage([]byte("user logged in"), &source, "")
+	p.applyRedactingRules(msg)
+	assert.Nil(t, msg.Attributes)
+}
+
+func TestEncodersWithAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Tags: []string{"foo:bar"}})
+	msg := newMessage([]byte("message"), source, "")
+	msg.SetAttribute("user", "bob")
+	msg.SetAttribute("message", "overridden")
+
+	encoded, err := JSONEncoder.Encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	var fields map[string]interface{}
+	assert.Nil(t, json.Unmarshal(encoded, &fields))
+	assert.Equal(t, "bob", fields["user"])
+	assert.Equal(t, "message", fields["message"])
+	assert.Equal(t, "foo:bar", fields["ddtags"])
+
+	encoded, err = ProtoEncoder.Encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	log := &pb.Log{}
+	assert.Nil(t, log.Unmarshal(encoded))
+	// only the tags promoted by the parsing rules are sent
+	assert.Equal(t, []string{"foo:bar"}, log.Tags)
+}
+
+func TestExcludeAttributesFromContent(t *testing.T) {
+	p := &Processor{}
+	newSourceWithRule := func(rule *config.ProcessingRule) *config.LogSource {
+		rule.Name = rule.Type
+		rule.ExcludeAttributes = []string{"password", "token"}
+		return config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		source   *config.LogSource
+		content  string
+		expected string
+	}{
+		{
+			name:     "json",
+			source:   newSourceWithRule(&config.ProcessingRule{Type: config.ParseJSON}),
+			content:  `{"user":"bob","password":"secret","latency":12.50,"nested":{"token":"kept"}}`,
+			expected: `{"latency":12.50,"nested":{"token":"kept"},"user":"bob"}`,
+		},
+		{
+			name:     "key_value",
+			source:   newSourceWithRule(&config.ProcessingRule{Type: config.ParseKeyValue}),
+			content:  `password=secret user=bob token="a b" latency=12`,
+			expected: `user=bob latency=12`,
+		},
+		{
+			name: "regex",
+			source: newSourceWithRule(&config.ProcessingRule{
+				Type:  config.ParseRegex,
+				Regex: regexp.MustCompile(`user=(?P<user>\w+) password=(?P<password>\w+)`),
+			}),
+			content:  `login user=bob password=secret ok`,
+			expected: `login user=bob password= ok`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := newMessage([]byte(tc.content), tc.source, "")
+			shouldProcess, redactedMsg := p.applyRedactingRules(msg)
+			assert.True(t, shouldProcess)
+			assert.Equal(t, tc.expected, string(redactedMsg))
+			assert.NotContains(t, msg.Attributes, "password")
+
+			// the excluded attribute is absent from the encoded payload
+			encoded, err := JSONEncoder.Encode(msg, redactedMsg)
+			assert.Nil(t, err)
+			assert.NotContains(t, string(encoded), "secret")
+			assert.Contains(t, string(encoded), "bob")
+		})
+	}
+
+	// the content is left untouched when no excluded attribute is found
+	source := newSourceWithRule(&config.ProcessingRule{Type: config.ParseJSON})
+	_, redactedMsg := p.applyRedactingRules(newMessage([]byte(`{"user":"bob", "b":1}`), source, ""))
+	assert.Equal(t, `{"user":"bob", "b":1}`, string(redactedMsg))
+}
+
+func TestParseAfterMaskSequences(t *testing.T) {
+	p := &Processor{}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{
+			Type:          config.ParseKeyValue,
+			Name:          "kv",
+			TagAttributes: []string{"email"},
+		},
+		{
+			Type:        config.MaskSequences,
+			Name:        "mask_emails",
+			Regex:       regexp.MustCompile(`[\w.]+@[\w.]+`),
+			Placeholder: []byte("[masked]"),
+		},
+	}}}
+
+	msg := newMessage([]byte("level=info email=bob@example.com"), &source, "")
+	shouldProcess, redactedMsg := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, "level=info email=[masked]", string(redactedMsg))
+	assert.Equal(t, map[string]interface{}{"level": "info", "email": "[masked]"}, msg.Attributes)
+	assert.Equal(t, []string{"email:[masked]"}, msg.Origin.Tags())
+}
@@ -25,11 +25,15 @@ type Processor struct {
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
+	genericDestinations       map[string]*GenericDestination
+	metricSender              MetricSender
 	mu                        sync.Mutex
+	rulesLock                 sync.RWMutex
 }
 
-// New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver) *Processor {
+// New returns an initialized Processor, genericDestinations are indexed by the name
+// the sources use to select them. The log metrics are not generated when metricSender is nil.
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, genericDestinations map[string]*GenericDestination, metricSender MetricSender) *Processor {
 	return &Processor{
 		inputChan:                 inputChan,
 		outputChan:                outputChan,
@@ -37,6 +41,8 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		genericDestinations:       genericDestinations,
+		metricSender:              metricSender,
 	}
 }
 
@@ -65,6 +71,21 @@ func (p *Processor) Flush() {
 	p.mu.Unlock()
 }
 
+// SetProcessingRules replaces the global processing rules,
+// they apply to the messages processed after this call.
+func (p *Processor) SetProcessingRules(processingRules []*config.ProcessingRule) {
+	p.rulesLock.Lock()
+	defer p.rulesLock.Unlock()
+	p.processingRules = processingRules
+}
+
+// getProcessingRules returns the global processing rules.
+func (p *Processor) getProcessingRules() []*config.ProcessingRule {
+	p.rulesLock.RLock()
+	defer p.rulesLock.RUnlock()
+	return p.processingRules
+}
+
 // run starts the processing of the inputChan
 func (p *Processor) run() {
 	defer func() {
@@ -80,12 +101,26 @@ func (p *Processor) run() {
 func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
+	if p.metricSender != nil {
+		p.submitLogMetrics(msg)
+	}
+	if isRateLimited(msg) {
+		return
+	}
 	if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
+		// the status can be set by a parsing rule, sample once all the rules are applied
+		if isSampledOut(msg) {
+			return
+		}
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
 		p.diagnosticMessageReceiver.HandleMessage(*msg)
 
+		if len(p.genericDestinations) > 0 {
+			p.sendToGenericDestinations(msg, redactedMsg)
+		}
+
 		// Encode the message to its final format
 		content, err := p.encoder.Encode(msg, redactedMsg)
 		if err != nil {
@@ -101,7 +136,8 @@ func (p *Processor) processMessage(msg *message.Message) {
 // and a copy of the message with some fields redacted, depending on config
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	content := msg.Content
-	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	rules := append(p.getProcessingRules(), msg.Origin.LogSource.Config.ProcessingRules...)
+	var parsingRules []*config.ProcessingRule
 	for _, rule := range rules {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
@@ -114,7 +150,15 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.ParseJSON, config.ParseKeyValue, config.ParseRegex:
+			parsingRules = append(parsingRules, rule)
 		}
 	}
+	// parse the content once all the sequences are masked so that
+	// the extracted attributes and tags don't leak them, the excluded
+	// attributes are removed from the content
+	for _, rule := range parsingRules {
+		content = applyParsingRule(rule, msg, content)
+	}
 	return true, content
 }
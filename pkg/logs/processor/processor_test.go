@@ -138,6 +138,24 @@ func TestTruncate(t *testing.T) {
 	assert.Equal(t, []byte("hello"), redactedMessage)
 }
 
+func TestSetProcessingRules(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newProcessingRule("exclude_at_match", "", "world")}}
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("hello world"), source, ""))
+	assert.Equal(t, false, shouldProcess)
+
+	p.SetProcessingRules([]*config.ProcessingRule{newProcessingRule("mask_sequences", "[masked]", "world")})
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte("hello world"), source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("hello [masked]"), redactedMessage)
+
+	p.SetProcessingRules(nil)
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte("hello world"), source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("hello world"), redactedMessage)
+}
+
 func newProcessingRule(ruleType, replacePlaceholder, pattern string) *config.ProcessingRule {
 	return &config.ProcessingRule{
 		Type:               ruleType,
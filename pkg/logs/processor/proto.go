@@ -19,6 +19,8 @@ var ProtoEncoder Encoder = &protoEncoder{}
 type protoEncoder struct{}
 
 // Encode encodes a message into a protobuf byte array.
+// The protobuf payload has no attributes field, only the attributes promoted
+// to tags by the parsing rules are sent.
 func (p *protoEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
 	return (&pb.Log{
 		Message:   toValidUtf8(redactedMsg),
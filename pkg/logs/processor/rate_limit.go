@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"math/rand"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// randFloat64 is overridden in tests to make sampling deterministic.
+var randFloat64 = rand.Float64
+
+// isRateLimited returns true if the message exceeds the rate limit of its source,
+// in which case it is counted as rate limited.
+func isRateLimited(msg *message.Message) bool {
+	source := msg.Origin.LogSource
+	limiter := source.GetRateLimiter()
+	if limiter == nil || limiter.Allow(len(msg.Content)) {
+		return false
+	}
+	source.RateLimited.Add(1)
+	metrics.LogsRateLimited.Add(1)
+	metrics.TlmLogsRateLimited.Inc(source.Name)
+	return true
+}
+
+// isSampledOut returns true if the message is dropped by the sampling rate its source
+// configured for its status, in which case it is counted as sampled out.
+func isSampledOut(msg *message.Message) bool {
+	source := msg.Origin.LogSource
+	rate, exists := source.Config.SamplingRates[msg.GetStatus()]
+	if !exists || randFloat64() < rate {
+		return false
+	}
+	source.SampledOut.Add(1)
+	metrics.LogsSampledOut.Add(1)
+	metrics.TlmLogsSampledOut.Inc(source.Name)
+	return true
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestIsRateLimited(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: "/var/log/app.log", MaxLinesPerSecond: 2})
+
+	assert.False(t, isRateLimited(newMessage([]byte("hello"), source, "")))
+	assert.False(t, isRateLimited(newMessage([]byte("hello"), source, "")))
+	assert.True(t, isRateLimited(newMessage([]byte("hello"), source, "")))
+	assert.Equal(t, int64(1), source.RateLimited.Value())
+
+	// sources are limited independently
+	other := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: "/var/log/other.log", MaxLinesPerSecond: 2})
+	assert.False(t, isRateLimited(newMessage([]byte("hello"), other, "")))
+	assert.Equal(t, int64(0), other.RateLimited.Value())
+
+	unlimited := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: "/var/log/unlimited.log"})
+	for i := 0; i < 10; i++ {
+		assert.False(t, isRateLimited(newMessage([]byte("hello"), unlimited, "")))
+	}
+}
+
+func TestIsSampledOut(t *testing.T) {
+	defer func(f func() float64) { randFloat64 = f }(randFloat64)
+	randFloat64 = func() float64 { return 0.5 }
+
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: "/var/log/app.log", SamplingRates: map[string]float64{
+		message.StatusDebug: 0,
+		message.StatusInfo:  0.25,
+		message.StatusError: 0.75,
+	}})
+
+	assert.True(t, isSampledOut(newMessage([]byte("hello"), source, message.StatusDebug)))
+	assert.True(t, isSampledOut(newMessage([]byte("hello"), source, "")))
+	assert.False(t, isSampledOut(newMessage([]byte("hello"), source, message.StatusError)))
+	assert.False(t, isSampledOut(newMessage([]byte("hello"), source, message.StatusWarning)))
+	assert.Equal(t, int64(2), source.SampledOut.Value())
+}
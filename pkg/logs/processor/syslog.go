@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	// syslogTimestampFormat is the RFC5424 TIMESTAMP, its fraction of second is limited to 6 digits
+	syslogTimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+	// syslogStructuredDataID identifies the structured data element holding the source and the tags.
+	// A custom SD-ID must be suffixed by a private enterprise number, 32473 is the one reserved
+	// for documentation by RFC5612.
+	syslogStructuredDataID = "tags@32473"
+
+	syslogNilValue    = "-"
+	syslogMaxHostname = 255
+	syslogMaxAppName  = 48
+)
+
+// SyslogEncoder is a shared RFC5424 encoder, used by the syslog destinations.
+var SyslogEncoder Encoder = &syslogEncoder{}
+
+// syslogEncoder transforms a message into an RFC5424 syslog message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME - - [tags@32473 source="..." tags="..."] MSG
+type syslogEncoder struct{}
+
+// Encode encodes a message into an RFC5424 syslog message, without framing.
+func (s *syslogEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	encoded := make([]byte, 0, len(redactedMsg)+256)
+
+	encoded = append(encoded, message.StatusToSeverity(msg.GetStatus())...)
+	encoded = append(encoded, '1', ' ')
+	encoded = syslogTimestamp(msg).AppendFormat(encoded, syslogTimestampFormat)
+	encoded = append(encoded, ' ')
+	encoded = append(encoded, syslogHeaderField(getHostname(), syslogMaxHostname)...)
+	encoded = append(encoded, ' ')
+	encoded = append(encoded, syslogHeaderField(msg.Origin.Service(), syslogMaxAppName)...)
+	// PROCID and MSGID
+	encoded = append(encoded, " - - "...)
+	encoded = appendSyslogStructuredData(encoded, msg.Origin)
+	if len(redactedMsg) > 0 {
+		encoded = append(encoded, ' ')
+		encoded = append(encoded, toValidUtf8(redactedMsg)...)
+	}
+	return encoded, nil
+}
+
+// syslogTimestamp returns the timestamp of the message, or the time it was read
+// when the message doesn't carry one.
+func syslogTimestamp(msg *message.Message) time.Time {
+	if !msg.Timestamp.IsZero() {
+		return msg.Timestamp.UTC()
+	}
+	if msg.IngestionTimestamp > 0 {
+		return time.Unix(0, msg.IngestionTimestamp).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// syslogHeaderField returns a header field made of at most maxSize printable US-ASCII
+// characters, the other characters are replaced by underscores.
+func syslogHeaderField(value string, maxSize int) string {
+	if value == "" {
+		return syslogNilValue
+	}
+	if len(value) > maxSize {
+		value = value[:maxSize]
+	}
+	return strings.Map(func(r rune) rune {
+		if r < '!' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// appendSyslogStructuredData appends the source and the tags of the origin as structured
+// data, or the nil value when there is none.
+func appendSyslogStructuredData(encoded []byte, origin *message.Origin) []byte {
+	params := []struct{ name, value string }{
+		{"source", origin.Source()},
+		{"tags", origin.TagsToString()},
+	}
+
+	start := len(encoded)
+	for _, param := range params {
+		if param.value == "" {
+			continue
+		}
+		if len(encoded) == start {
+			encoded = append(encoded, '[')
+			encoded = append(encoded, syslogStructuredDataID...)
+		}
+		encoded = append(encoded, ' ')
+		encoded = append(encoded, param.name...)
+		encoded = append(encoded, '=', '"')
+		encoded = append(encoded, escapeSyslogParamValue(toValidUtf8([]byte(param.value)))...)
+		encoded = append(encoded, '"')
+	}
+	if len(encoded) == start {
+		return append(encoded, syslogNilValue...)
+	}
+	return append(encoded, ']')
+}
+
+// escapeSyslogParamValue escapes the characters '"', '\' and ']' of a PARAM-VALUE
+func escapeSyslogParamValue(value string) string {
+	if !strings.ContainsAny(value, "\"\\]") {
+		return value
+	}
+	var b strings.Builder
+	for _, r := range value {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
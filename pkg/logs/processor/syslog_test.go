@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/rfc5424"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/syslog"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestSyslogEncoderToCollector(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan rfc5424.Message, 2)
+	errors := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			errors <- err
+			return
+		}
+		defer conn.Close()
+		for i := 0; i < 2; i++ {
+			var m rfc5424.Message
+			if _, err := m.ReadFrom(conn); err != nil {
+				errors <- err
+				return
+			}
+			received <- m
+		}
+	}()
+
+	ctx := client.NewDestinationsContext()
+	ctx.Start()
+	defer ctx.Stop()
+	destination := syslog.NewDestination(tcp.AddrToEndPoint(listener.Addr()), ctx)
+
+	source := config.NewLogSource("", &config.LogsConfig{
+		Service:        "my service",
+		Source:         "nginx",
+		SourceCategory: "http",
+		Tags:           []string{"env:prod"},
+	})
+	msg := newMessage([]byte("<13>1 looks like syslog"), source, message.StatusError)
+	msg.Origin.SetTags([]string{`quote:"a]b\c"`})
+	msg.Timestamp = time.Date(2021, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	encoded, err := SyslogEncoder.Encode(msg, []byte("<13>1 redacted"))
+	require.NoError(t, err)
+	require.NoError(t, destination.Send(encoded))
+
+	// a message without service, source nor tags
+	msg = newMessage([]byte("hello"), config.NewLogSource("", &config.LogsConfig{}), "")
+	msg.IngestionTimestamp = time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC).UnixNano()
+	encoded, err = SyslogEncoder.Encode(msg, []byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, destination.Send(encoded))
+
+	var first, second rfc5424.Message
+	for i, m := range []*rfc5424.Message{&first, &second} {
+		select {
+		case *m = <-received:
+		case err := <-errors:
+			require.FailNow(t, "invalid frame", "frame %d: %v", i, err)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for the frames")
+		}
+	}
+
+	assert.Equal(t, rfc5424.Priority(43), first.Priority)
+	assert.True(t, time.Date(2021, 3, 4, 5, 6, 7, 123456000, time.UTC).Equal(first.Timestamp))
+	assert.Equal(t, getHostname(), first.Hostname)
+	assert.Equal(t, "my_service", first.AppName)
+	// the nil values are parsed as empty strings
+	assert.Empty(t, first.ProcessID)
+	assert.Empty(t, first.MessageID)
+	assert.Equal(t, []rfc5424.StructuredData{{
+		ID: "tags@32473",
+		Parameters: []rfc5424.SDParam{
+			{Name: "source", Value: "nginx"},
+			{Name: "tags", Value: `quote:"a]b\c",sourcecategory:http,env:prod`},
+		},
+	}}, first.StructuredData)
+	// the content is never forwarded as is, even when it looks like a syslog message
+	assert.Equal(t, "<13>1 redacted", string(first.Message))
+
+	assert.Equal(t, rfc5424.Priority(46), second.Priority)
+	assert.True(t, time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC).Equal(second.Timestamp))
+	assert.Empty(t, second.AppName)
+	assert.Empty(t, second.StructuredData)
+	assert.Equal(t, "hello", string(second.Message))
+}
+
+func TestSyslogEncoderFormat(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Service: "app"})
+	msg := newMessage([]byte("hello"), source, message.StatusWarning)
+	msg.Timestamp = time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+
+	encoded, err := SyslogEncoder.Encode(msg, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "<44>1 2021-03-04T04:06:07.000000Z "+getHostname()+" app - - - hello", string(encoded))
+}
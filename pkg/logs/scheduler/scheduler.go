@@ -8,6 +8,7 @@ package scheduler
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -33,13 +34,18 @@ var (
 type Scheduler struct {
 	sources  *logsConfig.LogSources
 	services *service.Services
+	// fileSources contains the sources created from the configuration files,
+	// indexed by the digest of their integration config.
+	fileSources map[string][]*logsConfig.LogSource
+	mu          sync.Mutex
 }
 
 // CreateScheduler creates the scheduler.
 func CreateScheduler(sources *logsConfig.LogSources, services *service.Services) {
 	adScheduler = &Scheduler{
-		sources:  sources,
-		services: services,
+		sources:     sources,
+		services:    services,
+		fileSources: make(map[string][]*logsConfig.LogSource),
 	}
 }
 
@@ -53,6 +59,8 @@ func (s *Scheduler) Stop() {
 // while an integration config can be mapped to a service when it contains an Entity.
 // An entity represents a unique identifier for a process that be reused to query logs.
 func (s *Scheduler) Schedule(configs []integration.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for _, config := range configs {
 		if !config.IsLogConfig() {
 			continue
@@ -72,6 +80,9 @@ func (s *Scheduler) Schedule(configs []integration.Config) {
 			for _, source := range sources {
 				s.sources.AddSource(source)
 			}
+			if s.isFileConfig(config) {
+				s.fileSources[config.Digest()] = sources
+			}
 		case s.newService(config):
 			entityType, _, err := s.parseEntity(config.TaggerEntity)
 			if err != nil {
@@ -98,6 +109,8 @@ func (s *Scheduler) Schedule(configs []integration.Config) {
 
 // Unschedule removes all the sources and services matching the integration configs.
 func (s *Scheduler) Unschedule(configs []integration.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for _, config := range configs {
 		if !config.IsLogConfig() || config.HasFilter(containers.LogsFilter) {
 			continue
@@ -141,6 +154,66 @@ func (s *Scheduler) Unschedule(configs []integration.Config) {
 	}
 }
 
+// ReloadFileConfigs compares the logs configs read again from the configuration files to the ones
+// scheduled so far: the sources of the configs which changed or disappeared are removed and the sources
+// of the new configs are added, the sources of the configs left unchanged keep collecting logs.
+// It returns the number of sources added and removed.
+func (s *Scheduler) ReloadFileConfigs(configs []integration.Config) (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newConfigs := make(map[string]integration.Config)
+	for _, config := range configs {
+		// the configs collected outside of autodiscovery don't have a provider yet
+		config.Provider = names.File
+		if !config.IsLogConfig() || !s.isFileConfig(config) {
+			continue
+		}
+		if config.HasFilter(containers.LogsFilter) {
+			log.Debugf("Config %s is filtered out for logs collection, ignoring it", s.configName(config))
+			continue
+		}
+		newConfigs[config.Digest()] = config
+	}
+
+	removed := 0
+	for digest, sources := range s.fileSources {
+		if _, exists := newConfigs[digest]; exists {
+			continue
+		}
+		for _, source := range sources {
+			log.Infof("Removing the logs config %v", source.Name)
+			s.sources.RemoveSource(source)
+			removed++
+		}
+		delete(s.fileSources, digest)
+	}
+
+	added := 0
+	for digest, config := range newConfigs {
+		if _, exists := s.fileSources[digest]; exists {
+			continue
+		}
+		log.Infof("Received a new logs config: %v", s.configName(config))
+		sources, err := s.toSources(config)
+		if err != nil {
+			log.Warnf("Invalid configuration: %v", err)
+			continue
+		}
+		for _, source := range sources {
+			s.sources.AddSource(source)
+			added++
+		}
+		s.fileSources[digest] = sources
+	}
+	return added, removed
+}
+
+// isFileConfig returns true if the config is defined in a configuration file and is not a template.
+func (s *Scheduler) isFileConfig(config integration.Config) bool {
+	return config.Provider == names.File && config.Entity == "" && len(config.ADIdentifiers) == 0
+}
+
 // newSources returns true if the config can be mapped to sources.
 func (s *Scheduler) newSources(config integration.Config) bool {
 	return config.Provider != ""
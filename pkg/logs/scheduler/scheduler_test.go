@@ -237,3 +237,61 @@ func TestIgnoreConfigIfLogsExcluded(t *testing.T) {
 		break
 	}
 }
+
+func TestReloadFileConfigsUpdatesOnlyChangedConfigs(t *testing.T) {
+	logSources := config.NewLogSources()
+	services := service.NewServices()
+	CreateScheduler(logSources, services)
+
+	addedSources := logSources.GetAddedForType(config.FileType)
+	removedSources := logSources.GetRemovedForType(config.FileType)
+
+	fooConfig := integration.Config{
+		Name:       "foo",
+		LogsConfig: []byte(`[{"type":"file","path":"/var/log/foo.log","service":"foo","source":"foo"}]`),
+	}
+	barConfig := integration.Config{
+		Name:       "bar",
+		LogsConfig: []byte(`[{"type":"file","path":"/var/log/bar.log","service":"bar","source":"bar"}]`),
+	}
+
+	// autodiscovery sets the provider of the configs it schedules
+	scheduledConfigs := []integration.Config{fooConfig, barConfig}
+	for i := range scheduledConfigs {
+		scheduledConfigs[i].Provider = names.File
+	}
+	go adScheduler.Schedule(scheduledConfigs)
+	fooSource := <-addedSources
+	assert.Equal(t, "foo", fooSource.Config.Service)
+	barSource := <-addedSources
+	assert.Equal(t, "bar", barSource.Config.Service)
+
+	newBarConfig := integration.Config{
+		Name:       "bar",
+		LogsConfig: []byte(`[{"type":"file","path":"/var/log/bar.log","service":"baz","source":"bar"}]`),
+	}
+
+	counts := make(chan [2]int)
+	go func() {
+		added, removed := adScheduler.ReloadFileConfigs([]integration.Config{fooConfig, newBarConfig})
+		counts <- [2]int{added, removed}
+	}()
+	assert.Equal(t, barSource, <-removedSources)
+	newBarSource := <-addedSources
+	assert.Equal(t, "baz", newBarSource.Config.Service)
+	assert.Equal(t, [2]int{1, 1}, <-counts)
+
+	sources := logSources.GetSources()
+	assert.Len(t, sources, 2)
+	assert.Contains(t, sources, fooSource)
+	assert.Contains(t, sources, newBarSource)
+
+	// removing a config removes its sources
+	go func() {
+		added, removed := adScheduler.ReloadFileConfigs([]integration.Config{newBarConfig})
+		counts <- [2]int{added, removed}
+	}()
+	assert.Equal(t, fooSource, <-removedSources)
+	assert.Equal(t, [2]int{0, 1}, <-counts)
+	assert.Equal(t, []*config.LogSource{newBarSource}, logSources.GetSources())
+}
@@ -8,6 +8,9 @@ package sender
 import (
 	"context"
 	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -21,6 +24,12 @@ type Strategy interface {
 	Flush(inputChan chan *message.Message, outputChan chan *message.Message, send func([]byte) error, mu *sync.Mutex)
 }
 
+// replayBackoff bounds the delay between two replay attempts of the spooled payloads.
+const (
+	minReplayBackoff = time.Second
+	maxReplayBackoff = 2 * time.Minute
+)
+
 // Sender sends logs to different destinations.
 type Sender struct {
 	inputChan    chan *message.Message
@@ -29,21 +38,37 @@ type Sender struct {
 	strategy     Strategy
 	done         chan struct{}
 	mu           sync.Mutex
+	// spool is optional, when set the payloads that can't be sent to the main destination
+	// are stored on disk and replayed in the background.
+	spool *Spool
+	// mainInUse is a semaphore held while sending to the main destination, which does not
+	// support concurrent sends. The sender spools its payloads instead of waiting for
+	// the replay loop to release it.
+	mainInUse  chan struct{}
+	stopReplay chan struct{}
+	replayDone chan struct{}
 }
 
-// NewSender returns a new sender.
-func NewSender(inputChan chan *message.Message, outputChan chan *message.Message, destinations *client.Destinations, strategy Strategy) *Sender {
+// NewSender returns a new sender, spool can be nil.
+func NewSender(inputChan chan *message.Message, outputChan chan *message.Message, destinations *client.Destinations, strategy Strategy, spool *Spool) *Sender {
 	return &Sender{
 		inputChan:    inputChan,
 		outputChan:   outputChan,
 		destinations: destinations,
 		strategy:     strategy,
 		done:         make(chan struct{}),
+		spool:        spool,
+		mainInUse:    make(chan struct{}, 1),
+		stopReplay:   make(chan struct{}),
+		replayDone:   make(chan struct{}),
 	}
 }
 
 // Start starts the sender.
 func (s *Sender) Start() {
+	if s.spool != nil {
+		go s.replay()
+	}
 	go s.run()
 }
 
@@ -52,6 +77,10 @@ func (s *Sender) Start() {
 func (s *Sender) Stop() {
 	close(s.inputChan)
 	<-s.done
+	if s.spool != nil {
+		close(s.stopReplay)
+		<-s.replayDone
+	}
 }
 
 // Flush sends synchronously the messages that this sender has to send.
@@ -69,20 +98,40 @@ func (s *Sender) run() {
 // send sends a payload to multiple destinations,
 // it will forever retry for the main destination unless the error is not retryable
 // and only try once for additionnal destinations.
+// When a spool is set, the payload is stored on disk instead of being retried,
+// and while the spool is not empty new payloads are spooled directly to keep them ordered.
 func (s *Sender) send(payload []byte) error {
 	for {
-		err := s.destinations.Main.Send(payload)
-		if err != nil {
-			metrics.DestinationErrors.Add(1)
-			metrics.TlmDestinationErrors.Inc()
-			if _, ok := err.(*client.RetryableError); ok {
-				// could not send the payload because of a client issue,
-				// let's retry
-				continue
+		if s.spool != nil && s.spool.Len() > 0 && s.storeInSpool(payload) {
+			break
+		}
+		if !s.tryAcquireMain() {
+			// the replay loop is sending a spooled payload, spool this one
+			// as well rather than waiting for it
+			if s.storeInSpool(payload) {
+				break
 			}
+			s.mainInUse <- struct{}{}
+		}
+		err := s.destinations.Main.Send(payload)
+		s.releaseMain()
+		if err == nil {
+			break
+		}
+		metrics.DestinationErrors.Add(1)
+		metrics.TlmDestinationErrors.Inc()
+		if _, ok := err.(*client.RetryableError); !ok {
 			return err
 		}
-		break
+		// could not send the payload because of a client issue,
+		// let's spool it if possible or retry
+		if s.spool != nil {
+			if s.storeInSpool(payload) {
+				break
+			}
+			// the main destination does not block while it is unreachable
+			time.Sleep(minReplayBackoff)
+		}
 	}
 
 	for _, destination := range s.destinations.Additionals {
@@ -94,6 +143,79 @@ func (s *Sender) send(payload []byte) error {
 	return nil
 }
 
+// tryAcquireMain returns true if the main destination was not in use and is now reserved
+// to the caller, which must release it once the payload is sent.
+func (s *Sender) tryAcquireMain() bool {
+	select {
+	case s.mainInUse <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Sender) releaseMain() {
+	<-s.mainInUse
+}
+
+// storeInSpool returns true if the payload has been durably stored in the spool.
+func (s *Sender) storeInSpool(payload []byte) bool {
+	if err := s.spool.Store(payload); err != nil {
+		log.Warnf("Could not spool payload: %v", err)
+		return false
+	}
+	return true
+}
+
+// replay sends the spooled payloads to the main destination, oldest first,
+// and backs off while the main destination is unreachable.
+func (s *Sender) replay() {
+	defer close(s.replayDone)
+	backoff := minReplayBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stopReplay:
+			return
+		case <-timer.C:
+		}
+		for {
+			entry, payload := s.spool.Next()
+			if entry == nil {
+				backoff = minReplayBackoff
+				break
+			}
+			if !s.tryAcquireMain() {
+				// the sender is using the main destination, retry later
+				s.spool.Release(entry)
+				break
+			}
+			err := s.destinations.Main.Send(payload)
+			s.releaseMain()
+			if err == nil {
+				s.spool.Commit(entry)
+				backoff = minReplayBackoff
+				continue
+			}
+			metrics.DestinationErrors.Add(1)
+			metrics.TlmDestinationErrors.Inc()
+			if _, ok := err.(*client.RetryableError); ok || shouldStopSending(err) {
+				// the main destination is still unreachable
+				s.spool.Release(entry)
+				backoff *= 2
+				if backoff > maxReplayBackoff {
+					backoff = maxReplayBackoff
+				}
+				break
+			}
+			log.Warnf("Could not send spooled payload, dropping it: %v", err)
+			s.spool.Drop(entry)
+		}
+		timer.Reset(backoff)
+	}
+}
+
 // shouldStopSending returns true if a component should stop sending logs.
 func shouldStopSending(err error) bool {
 	return err == context.Canceled
@@ -6,7 +6,11 @@
 package sender
 
 import (
+	"errors"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -36,7 +40,7 @@ func TestSender(t *testing.T) {
 	destination := tcp.AddrToDestination(l.Addr(), destinationsCtx)
 	destinations := client.NewDestinations(destination, nil)
 
-	sender := NewSender(input, output, destinations, StreamStrategy)
+	sender := NewSender(input, output, destinations, StreamStrategy, nil)
 	sender.Start()
 
 	expectedMessage := newMessage([]byte("fake line"), source, "")
@@ -69,7 +73,7 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	additionalDestination := tcp.NewDestination(config.Endpoint{Host: "dont.exist.local", Port: 0}, true, destinationsCtx)
 	destinations := client.NewDestinations(mainDestination, []client.Destination{additionalDestination})
 
-	sender := NewSender(input, output, destinations, StreamStrategy)
+	sender := NewSender(input, output, destinations, StreamStrategy, nil)
 	sender.Start()
 
 	expectedMessage1 := newMessage([]byte("fake line"), source, "")
@@ -87,3 +91,98 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	sender.Stop()
 	destinationsCtx.Stop()
 }
+
+type unreachableDestination struct {
+	sync.Mutex
+	reachable bool
+	payloads  []string
+}
+
+func (d *unreachableDestination) Send(payload []byte) error {
+	d.Lock()
+	defer d.Unlock()
+	if !d.reachable {
+		return client.NewRetryableError(errors.New("unreachable"))
+	}
+	d.payloads = append(d.payloads, string(payload))
+	return nil
+}
+
+func (d *unreachableDestination) SendAsync(payload []byte) {}
+
+func (d *unreachableDestination) setReachable() {
+	d.Lock()
+	defer d.Unlock()
+	d.reachable = true
+}
+
+func (d *unreachableDestination) getPayloads() []string {
+	d.Lock()
+	defer d.Unlock()
+	return append([]string(nil), d.payloads...)
+}
+
+func TestSenderSpoolsPayloadsWhenDestinationIsUnreachable(t *testing.T) {
+	spool, path := newTestSpool(t, 1000)
+	defer os.RemoveAll(path)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 2)
+	output := make(chan *message.Message, 2)
+
+	destination := &unreachableDestination{}
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), StreamStrategy, spool)
+	sender.Start()
+
+	// the messages are forwarded to the auditor once they are spooled
+	input <- newMessage([]byte("line 1"), source, "")
+	<-output
+	input <- newMessage([]byte("line 2"), source, "")
+	<-output
+	assert.Equal(t, 2, spool.Len())
+
+	// the spooled payloads are replayed in order once the destination is back
+	destination.setReachable()
+	assert.Eventually(t, func() bool { return spool.Len() == 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"line 1", "line 2"}, destination.getPayloads())
+
+	sender.Stop()
+}
+
+func TestSenderSpoolsPayloadsWhenTCPDestinationIsUnreachable(t *testing.T) {
+	spool, path := newTestSpool(t, 1000)
+	defer os.RemoveAll(path)
+
+	l := mock.NewMockLogsIntake(t)
+	addr := l.Addr()
+	// nothing listens on the address anymore
+	l.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 2)
+	output := make(chan *message.Message, 2)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	destination := tcp.NewNonBlockingDestination(tcp.AddrToEndPoint(addr), true, destinationsCtx)
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), StreamStrategy, spool)
+	sender.Start()
+
+	// the sender does not wait for the connection to be established
+	input <- newMessage([]byte("line 1"), source, "")
+	input <- newMessage([]byte("line 2"), source, "")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-output:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the payload was not spooled")
+		}
+	}
+	assert.Equal(t, 2, spool.Len())
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
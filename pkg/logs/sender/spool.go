@@ -0,0 +1,232 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+const (
+	spoolFileExtension     = ".spool"
+	spoolTempFileExtension = ".tmp"
+)
+
+// SpoolEntry is a payload stored in the spool.
+type SpoolEntry struct {
+	filename string
+	size     int64
+}
+
+// Spool stores on disk the payloads that could not be sent to the main destination
+// so that they can be replayed, oldest first, once the destination is reachable again.
+// When the spool is full, the oldest payloads are evicted to make room for the new ones.
+// A spool can be shared by several senders, an entry returned by Next is claimed by the
+// caller until it is either committed, dropped or released.
+type Spool struct {
+	mu                 sync.Mutex
+	path               string
+	maxSizeInBytes     int64
+	currentSizeInBytes int64
+	entries            []*SpoolEntry
+	claimed            int
+	sequence           uint64
+}
+
+// NewSpool returns a new spool storing payloads in path, it reloads the payloads
+// spooled by a previous run of the agent.
+func NewSpool(path string, maxSizeInBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+	}
+	if err := s.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Store durably writes a payload to the spool, it returns once the payload is synced on disk.
+func (s *Spool) Store(payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := int64(len(payload))
+	if err := s.makeRoomFor(size); err != nil {
+		return err
+	}
+
+	s.sequence++
+	filename := filepath.Join(s.path, fmt.Sprintf("%020d_%010d%s", time.Now().UnixNano(), s.sequence, spoolFileExtension))
+	if err := writeFileSync(filename, payload); err != nil {
+		return err
+	}
+
+	s.entries = append(s.entries, &SpoolEntry{filename: filename, size: size})
+	s.currentSizeInBytes += size
+	metrics.PayloadsSpooled.Add(1)
+	metrics.TlmPayloadsSpooled.Inc()
+	metrics.SpoolSizeInBytes.Set(s.currentSizeInBytes)
+	return nil
+}
+
+// Next claims the oldest payload of the spool, returns a nil entry if the spool has no payload available.
+// Unreadable payloads are dropped.
+func (s *Spool) Next() (*SpoolEntry, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.entries) > 0 {
+		entry := s.entries[0]
+		s.entries = s.entries[1:]
+		payload, err := ioutil.ReadFile(entry.filename)
+		if err != nil {
+			log.Warnf("Could not read spooled payload %s, dropping it: %v", entry.filename, err)
+			s.remove(entry)
+			metrics.SpooledPayloadsDropped.Add(1)
+			metrics.TlmSpooledPayloadsDropped.Inc()
+			continue
+		}
+		s.claimed++
+		return entry, payload
+	}
+	return nil, nil
+}
+
+// Commit removes a claimed payload from the spool once it has been sent.
+func (s *Spool) Commit(entry *SpoolEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed--
+	s.remove(entry)
+	metrics.PayloadsReplayed.Add(1)
+	metrics.TlmPayloadsReplayed.Inc()
+}
+
+// Drop removes a claimed payload which can't be sent from the spool.
+func (s *Spool) Drop(entry *SpoolEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed--
+	s.remove(entry)
+	metrics.SpooledPayloadsDropped.Add(1)
+	metrics.TlmSpooledPayloadsDropped.Inc()
+}
+
+// Release puts back a claimed payload at the head of the spool so that it is replayed later.
+func (s *Spool) Release(entry *SpoolEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed--
+	s.entries = append([]*SpoolEntry{entry}, s.entries...)
+}
+
+// Len returns the number of payloads in the spool, including the claimed ones.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries) + s.claimed
+}
+
+// getCurrentSizeInBytes returns the current disk space used.
+func (s *Spool) getCurrentSizeInBytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentSizeInBytes
+}
+
+// makeRoomFor evicts the oldest payloads until the payload fits in the spool.
+func (s *Spool) makeRoomFor(size int64) error {
+	if size > s.maxSizeInBytes {
+		return fmt.Errorf("the payload is too big to be spooled. Current:%v Maximum:%v", size, s.maxSizeInBytes)
+	}
+	for len(s.entries) > 0 && s.currentSizeInBytes+size > s.maxSizeInBytes {
+		entry := s.entries[0]
+		s.entries = s.entries[1:]
+		log.Infof("Maximum disk space for the logs spool is reached. Removing %s", entry.filename)
+		s.remove(entry)
+		metrics.SpooledPayloadsDropped.Add(1)
+		metrics.TlmSpooledPayloadsDropped.Inc()
+	}
+	if s.currentSizeInBytes+size > s.maxSizeInBytes {
+		return fmt.Errorf("not enough space left in the logs spool")
+	}
+	return nil
+}
+
+// remove deletes the file of an entry, the entry must not be in the entries anymore.
+func (s *Spool) remove(entry *SpoolEntry) {
+	if err := os.Remove(entry.filename); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove spooled payload %s: %v", entry.filename, err)
+	}
+	s.currentSizeInBytes -= entry.size
+	metrics.SpoolSizeInBytes.Set(s.currentSizeInBytes)
+}
+
+// reloadExistingFiles loads the payloads left by a previous run, oldest first,
+// and removes the partially written ones.
+func (s *Spool) reloadExistingFiles() error {
+	files, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+	// file names start with a fixed-width timestamp
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		filename := filepath.Join(s.path, file.Name())
+		switch {
+		case strings.HasSuffix(file.Name(), spoolTempFileExtension):
+			_ = os.Remove(filename)
+		case filepath.Ext(file.Name()) == spoolFileExtension:
+			s.entries = append(s.entries, &SpoolEntry{filename: filename, size: file.Size()})
+			s.currentSizeInBytes += file.Size()
+		}
+	}
+	if len(s.entries) > 0 {
+		log.Infof("Reloaded %d payloads from the logs spool %s", len(s.entries), s.path)
+	}
+	metrics.SpoolSizeInBytes.Set(s.currentSizeInBytes)
+	return nil
+}
+
+// writeFileSync writes the content to a temporary file, syncs it and renames it
+// so that a partially written payload is never replayed.
+func writeFileSync(filename string, content []byte) error {
+	tmpFilename := filename + spoolTempFileExtension
+	file, err := os.OpenFile(tmpFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(tmpFilename)
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
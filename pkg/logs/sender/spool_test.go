@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSpool(t *testing.T, maxSizeInBytes int64) (*Spool, string) {
+	path, err := ioutil.TempDir("", "logs-spool")
+	require.NoError(t, err)
+	spool, err := NewSpool(path, maxSizeInBytes)
+	require.NoError(t, err)
+	return spool, path
+}
+
+func TestSpoolReplaysOldestFirst(t *testing.T) {
+	spool, path := newTestSpool(t, 100)
+	defer os.RemoveAll(path)
+
+	assert.NoError(t, spool.Store([]byte("first")))
+	assert.NoError(t, spool.Store([]byte("second")))
+	assert.Equal(t, 2, spool.Len())
+	assert.Equal(t, int64(11), spool.getCurrentSizeInBytes())
+
+	entry, payload := spool.Next()
+	assert.Equal(t, "first", string(payload))
+	spool.Release(entry)
+
+	entry, payload = spool.Next()
+	assert.Equal(t, "first", string(payload))
+	assert.Equal(t, 2, spool.Len())
+	spool.Commit(entry)
+
+	entry, payload = spool.Next()
+	assert.Equal(t, "second", string(payload))
+	spool.Commit(entry)
+
+	entry, _ = spool.Next()
+	assert.Nil(t, entry)
+	assert.Equal(t, 0, spool.Len())
+	assert.Equal(t, int64(0), spool.getCurrentSizeInBytes())
+
+	files, err := ioutil.ReadDir(path)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSpoolEvictsOldestPayloads(t *testing.T) {
+	spool, path := newTestSpool(t, 10)
+	defer os.RemoveAll(path)
+
+	assert.NoError(t, spool.Store([]byte("aaaa")))
+	assert.NoError(t, spool.Store([]byte("bbbb")))
+	assert.NoError(t, spool.Store([]byte("cccc")))
+	assert.Equal(t, 2, spool.Len())
+	assert.Error(t, spool.Store([]byte("payload too big")))
+
+	_, payload := spool.Next()
+	assert.Equal(t, "bbbb", string(payload))
+}
+
+func TestSpoolReloadsExistingPayloads(t *testing.T) {
+	spool, path := newTestSpool(t, 100)
+	defer os.RemoveAll(path)
+
+	assert.NoError(t, spool.Store([]byte("first")))
+	assert.NoError(t, spool.Store([]byte("second")))
+	// a partially written payload must be ignored
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "00000000000000000000_0000000000.spool.tmp"), []byte("partial"), 0600))
+
+	spool, err := NewSpool(path, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, spool.Len())
+	assert.Equal(t, int64(11), spool.getCurrentSizeInBytes())
+
+	_, payload := spool.Next()
+	assert.Equal(t, "first", string(payload))
+
+	_, err = os.Stat(filepath.Join(path, "00000000000000000000_0000000000.spool.tmp"))
+	assert.True(t, os.IsNotExist(err))
+}
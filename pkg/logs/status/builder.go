@@ -67,9 +67,23 @@ func (b *Builder) getEndpoints() []string {
 	for _, additional := range b.endpoints.Additionals {
 		result = append(result, b.formatEndpoint(additional, "Additional: "))
 	}
+	for _, generic := range b.endpoints.Generics {
+		result = append(result, b.formatGenericEndpoint(generic))
+	}
 	return result
 }
 
+func (b *Builder) formatGenericEndpoint(endpoint config.Endpoint) string {
+	if endpoint.Type == config.EndpointTypeWebhook {
+		return fmt.Sprintf("Generic: Sending logs of the sources selecting %s as JSON to %s", endpoint.Name, endpoint.URL)
+	}
+	protocol := "TCP"
+	if endpoint.UseSSL {
+		protocol = "SSL encrypted TCP"
+	}
+	return fmt.Sprintf("Generic: Sending logs of the sources selecting %s as syslog in %s to %s on port %d", endpoint.Name, protocol, endpoint.Host, endpoint.Port)
+}
+
 func (b *Builder) formatEndpoint(endpoint config.Endpoint, prefix string) string {
 	compression := "uncompressed"
 	if endpoint.UseCompression {
@@ -125,6 +139,8 @@ func (b *Builder) getIntegrations() []Integration {
 		for _, source := range logSources {
 			sources = append(sources, Source{
 				BytesRead:          source.BytesRead.Value(),
+				RateLimited:        source.RateLimited.Value(),
+				SampledOut:         source.SampledOut.Value(),
 				AllTimeAvgLatency:  source.LatencyStats.AllTimeAvg() / int64(time.Millisecond),
 				AllTimePeakLatency: source.LatencyStats.AllTimePeak() / int64(time.Millisecond),
 				RecentAvgLatency:   source.LatencyStats.MovingAvg() / int64(time.Millisecond),
@@ -177,6 +193,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.Protocol
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -37,7 +37,7 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval) // default histogram configuration (no call to `configure`) for now
+			m[contextKey] = NewHistogramForMetric(sample.Name, interval) // configured by the matching histogram override, if any
 		case HistorateType:
 			m[contextKey] = NewHistorate(interval) // internal histogram has the configuration for now
 		case SetType:
@@ -80,3 +80,63 @@ func (m ContextMetrics) Flush(timestamp float64) ([]*Serie, map[ckey.ContextKey]
 
 	return series, errors
 }
+
+// Snapshot returns the series the metrics would flush at the timestamp, without resetting
+// them. The metrics of an unknown type are skipped.
+func (m ContextMetrics) Snapshot(timestamp float64) []*Serie {
+	var series []*Serie
+
+	for contextKey, metric := range m {
+		metricCopy := copyMetric(metric)
+		if metricCopy == nil {
+			continue
+		}
+		metricSeries, err := metricCopy.flush(timestamp)
+		if err != nil {
+			continue
+		}
+		for _, serie := range metricSeries {
+			serie.ContextKey = contextKey
+			series = append(series, serie)
+		}
+	}
+
+	return series
+}
+
+// copyMetric returns a copy of the metric which can be flushed without altering it,
+// or nil if the type of the metric is unknown
+func copyMetric(metric Metric) Metric {
+	switch m := metric.(type) {
+	case *Gauge:
+		c := *m
+		return &c
+	case *Rate:
+		c := *m
+		return &c
+	case *Count:
+		c := *m
+		return &c
+	case *MonotonicCount:
+		c := *m
+		return &c
+	case *Counter:
+		c := *m
+		return &c
+	case *Set:
+		// the flush replaces the values of the set, it doesn't modify them
+		c := *m
+		return &c
+	case *Histogram:
+		// the flush sorts the samples in place
+		c := *m
+		c.samples = append(weightSamples(nil), m.samples...)
+		return &c
+	case *Historate:
+		c := *m
+		c.histogram.samples = append(weightSamples(nil), m.histogram.samples...)
+		return &c
+	default:
+		return nil
+	}
+}
@@ -308,3 +308,40 @@ func TestContextMetricsHistorateSampling(t *testing.T) {
 		},
 		series[4])
 }
+
+func TestContextMetricsSnapshot(t *testing.T) {
+	metrics := MakeContextMetrics()
+	counterKey := ckey.ContextKey(0x1)
+	histogramKey := ckey.ContextKey(0x2)
+	setKey := ckey.ContextKey(0x3)
+
+	metrics.AddSample(counterKey, &MetricSample{Mtype: CounterType, Value: 5, SampleRate: 1}, 12340, 10)
+	metrics.AddSample(histogramKey, &MetricSample{Mtype: HistogramType, Value: 3}, 12340, 10)
+	metrics.AddSample(histogramKey, &MetricSample{Mtype: HistogramType, Value: 1}, 12341, 10)
+	metrics.AddSample(setKey, &MetricSample{Mtype: SetType, RawValue: "a"}, 12340, 10)
+	metrics.AddSample(setKey, &MetricSample{Mtype: SetType, RawValue: "b"}, 12341, 10)
+
+	// the snapshots don't reset the metrics
+	snapshot := metrics.Snapshot(12350)
+	require.Len(t, snapshot, 7)
+	assert.Equal(t, pointsBySerie(snapshot), pointsBySerie(metrics.Snapshot(12350)))
+
+	series, errs := metrics.Flush(12350)
+	assert.Len(t, errs, 0)
+	assert.Equal(t, pointsBySerie(snapshot), pointsBySerie(series))
+
+	assert.Len(t, metrics.Snapshot(12360), 0)
+}
+
+type serieKey struct {
+	contextKey ckey.ContextKey
+	nameSuffix string
+}
+
+func pointsBySerie(series []*Serie) map[serieKey][]Point {
+	points := make(map[serieKey][]Point, len(series))
+	for _, serie := range series {
+		points[serieKey{serie.ContextKey, serie.NameSuffix}] = serie.Points
+	}
+	return points
+}
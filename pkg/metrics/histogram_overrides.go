@@ -0,0 +1,205 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+// number of metric names whose matching override is cached
+const histogramOverridesCacheSize = 1000
+
+var (
+	histogramOverridesOnce sync.Once
+	histogramOverrides     *histogramOverridesConfig
+)
+
+// histogramOverridesConfig holds the per metric name histogram configurations
+type histogramOverridesConfig struct {
+	overrides []*histogramOverride
+	errors    []string
+	// matched override by metric name, nil when none matched
+	cache *lru.Cache
+}
+
+type histogramOverride struct {
+	match       string
+	regex       *regexp.Regexp
+	aggregates  []string
+	percentiles []int
+	// number of histograms configured with this override
+	histograms uint64
+}
+
+// HistogramOverridesStatus reports the histogram overrides and their configuration errors
+type HistogramOverridesStatus struct {
+	Overrides []HistogramOverrideStatus `json:"overrides"`
+	Errors    []string                  `json:"errors"`
+}
+
+// HistogramOverrideStatus reports an histogram override
+type HistogramOverrideStatus struct {
+	Match       string   `json:"match"`
+	Aggregates  []string `json:"aggregates"`
+	Percentiles []int    `json:"percentiles"`
+	Histograms  uint64   `json:"histograms"`
+}
+
+// newHistogramOverridesConfig validates the overrides, the invalid ones are reported and skipped
+func newHistogramOverridesConfig(configOverrides []config.HistogramOverride, defaultAggregates []string, defaultPercentiles []int) *histogramOverridesConfig {
+	cache, _ := lru.New(histogramOverridesCacheSize)
+	c := &histogramOverridesConfig{cache: cache}
+
+	for i, configOverride := range configOverrides {
+		override, err := newHistogramOverride(configOverride, defaultAggregates, defaultPercentiles)
+		if err != nil {
+			err = fmt.Errorf("histogram override num %d: %v", i, err)
+			log.Errorf("Skipping invalid histogram override: %v", err)
+			c.errors = append(c.errors, err.Error())
+			continue
+		}
+		c.overrides = append(c.overrides, override)
+	}
+	return c
+}
+
+func newHistogramOverride(configOverride config.HistogramOverride, defaultAggregates []string, defaultPercentiles []int) (*histogramOverride, error) {
+	if configOverride.Match == "" {
+		return nil, fmt.Errorf("match is required")
+	}
+	pattern := regexp.QuoteMeta(configOverride.Match)
+	pattern = strings.Replace(pattern, `\*`, ".*", -1)
+
+	override := &histogramOverride{
+		match:       configOverride.Match,
+		regex:       regexp.MustCompile("^" + pattern + "$"),
+		aggregates:  defaultAggregates,
+		percentiles: defaultPercentiles,
+	}
+
+	if configOverride.Aggregates != nil {
+		for _, aggregate := range configOverride.Aggregates {
+			switch aggregate {
+			case maxAgg, minAgg, medianAgg, avgAgg, sumAgg, countAgg:
+			default:
+				return nil, fmt.Errorf("unknown aggregate %q", aggregate)
+			}
+		}
+		override.aggregates = configOverride.Aggregates
+	}
+
+	if configOverride.Percentiles != nil {
+		percentiles := make([]int, 0, len(configOverride.Percentiles))
+		for _, p := range configOverride.Percentiles {
+			percentile, err := parsePercentile(p)
+			if err != nil {
+				return nil, err
+			}
+			percentiles = append(percentiles, percentile)
+		}
+		sort.Ints(percentiles)
+		override.percentiles = percentiles
+	}
+
+	if len(override.aggregates) == 0 && len(override.percentiles) == 0 {
+		return nil, fmt.Errorf("%q would not produce any serie, at least one aggregate or percentile is required", override.match)
+	}
+	return override, nil
+}
+
+// parsePercentile parses a percentile between 0 and 1 into the 1-100 range used by the histograms,
+// the percentiles which aren't a whole number of percents can't be computed and are rejected
+func parsePercentile(p string) (int, error) {
+	f, err := strconv.ParseFloat(p, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse percentile %q: %v", p, err)
+	}
+	if f <= 0 || f > 1 {
+		return 0, fmt.Errorf("percentile %q must be greater than 0 and lower than or equal to 1", p)
+	}
+	// rounding absorbs the floating point error of values such as 0.29, it must not
+	// turn 0.999 into the 100th percentile
+	percentile := math.Round(f * 100)
+	if math.Abs(f*100-percentile) > 1e-9 {
+		return 0, fmt.Errorf("percentile %q must be a multiple of 0.01", p)
+	}
+	return int(percentile), nil
+}
+
+// get returns the override matching the metric name, nil if none matches
+func (c *histogramOverridesConfig) get(name string) *histogramOverride {
+	if len(c.overrides) == 0 {
+		return nil
+	}
+	if override, ok := c.cache.Get(name); ok {
+		return override.(*histogramOverride)
+	}
+	var matched *histogramOverride
+	for _, override := range c.overrides {
+		if override.regex.MatchString(name) {
+			matched = override
+			break
+		}
+	}
+	c.cache.Add(name, matched)
+	return matched
+}
+
+func (c *histogramOverridesConfig) status() HistogramOverridesStatus {
+	status := HistogramOverridesStatus{Errors: c.errors}
+	for _, override := range c.overrides {
+		status.Overrides = append(status.Overrides, HistogramOverrideStatus{
+			Match:       override.match,
+			Aggregates:  override.aggregates,
+			Percentiles: override.percentiles,
+			Histograms:  atomic.LoadUint64(&override.histograms),
+		})
+	}
+	return status
+}
+
+func getHistogramOverrides() *histogramOverridesConfig {
+	histogramOverridesOnce.Do(func() {
+		configOverrides, err := config.GetHistogramOverrides()
+		if err != nil {
+			histogramOverrides = newHistogramOverridesConfig(nil, nil, nil)
+			histogramOverrides.errors = []string{err.Error()}
+			return
+		}
+		// the overrides fall back on the global configuration for the settings they don't set
+		defaults := NewHistogram(1)
+		histogramOverrides = newHistogramOverridesConfig(configOverrides, defaults.aggregates, defaults.percentiles)
+	})
+	return histogramOverrides
+}
+
+// NewHistogramForMetric returns a newly initialized histogram, configured with the
+// histogram override matching the metric name if any
+func NewHistogramForMetric(name string, interval int64) *Histogram {
+	h := NewHistogram(interval)
+	if override := getHistogramOverrides().get(name); override != nil {
+		atomic.AddUint64(&override.histograms, 1)
+		h.configure(override.aggregates, override.percentiles)
+	}
+	return h
+}
+
+// GetHistogramOverridesStatus returns the status of the histogram overrides
+func GetHistogramOverridesStatus() HistogramOverridesStatus {
+	return getHistogramOverrides().status()
+}
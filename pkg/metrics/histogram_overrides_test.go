@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestHistogramOverridesConfig(t *testing.T) {
+	c := newHistogramOverridesConfig([]config.HistogramOverride{
+		{Match: "myapp.latency.*", Aggregates: []string{"max", "count"}, Percentiles: []string{"0.99", "0.5"}},
+		{Match: "myapp.*", Percentiles: []string{}},
+		{Aggregates: []string{"max"}},
+		{Match: "invalid.aggregate", Aggregates: []string{"p99"}},
+		{Match: "invalid.percentile", Percentiles: []string{"95"}},
+		{Match: "invalid.percentile", Percentiles: []string{"test"}},
+		{Match: "invalid.percentile", Percentiles: []string{"0.999"}},
+		{Match: "no.serie", Aggregates: []string{}, Percentiles: []string{}},
+	}, []string{"max", "median", "avg", "count"}, []int{95})
+
+	require.Len(t, c.overrides, 2)
+	assert.Len(t, c.errors, 6)
+	assert.Contains(t, c.errors[4], `percentile "0.999" must be a multiple of 0.01`)
+
+	// the first matching override wins
+	override := c.get("myapp.latency.p1")
+	require.NotNil(t, override)
+	assert.Equal(t, []string{"max", "count"}, override.aggregates)
+	assert.Equal(t, []int{50, 99}, override.percentiles)
+
+	// the settings not overridden fall back on the defaults
+	override = c.get("myapp.size")
+	require.NotNil(t, override)
+	assert.Equal(t, []string{"max", "median", "avg", "count"}, override.aggregates)
+	assert.Equal(t, []int{}, override.percentiles)
+
+	assert.Nil(t, c.get("otherapp.latency"))
+	// the metrics not matching any override are cached as well
+	_, cached := c.cache.Get("otherapp.latency")
+	assert.True(t, cached)
+}
+
+func TestParsePercentile(t *testing.T) {
+	for _, tc := range []struct {
+		percentile string
+		expected   int
+		valid      bool
+	}{
+		{"0.5", 50, true},
+		{"0.29", 29, true},
+		{"0.95", 95, true},
+		{"1", 100, true},
+		{"0.01", 1, true},
+		{"0.999", 0, false},
+		{"0.995", 0, false},
+		{"0.005", 0, false},
+		{"0", 0, false},
+		{"1.01", 0, false},
+	} {
+		percentile, err := parsePercentile(tc.percentile)
+		if tc.valid {
+			assert.NoError(t, err, tc.percentile)
+			assert.Equal(t, tc.expected, percentile, tc.percentile)
+		} else {
+			assert.Error(t, err, tc.percentile)
+		}
+	}
+}
+
+func TestNewHistogramForMetric(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("histogram_overrides", []map[string]interface{}{
+		{"match": "myapp.latency.*", "aggregates": []string{"max"}, "percentiles": []string{"0.99"}},
+	})
+	defer func() {
+		mockConfig.Set("histogram_overrides", nil)
+		histogramOverridesOnce = sync.Once{}
+		histogramOverrides = nil
+	}()
+	histogramOverridesOnce = sync.Once{}
+
+	hist := NewHistogramForMetric("myapp.latency.p1", 10)
+	hist.addSample(&MetricSample{Value: 1}, 50)
+	hist.addSample(&MetricSample{Value: 2}, 55)
+
+	series, err := hist.flush(60)
+	require.Nil(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, ".max", series[0].NameSuffix)
+	assert.Equal(t, ".99percentile", series[1].NameSuffix)
+
+	// the histograms not matching any override use the default configuration
+	hist = NewHistogramForMetric("otherapp.latency", 10)
+	assert.Equal(t, []string{"max", "median", "avg", "count"}, hist.aggregates)
+	assert.Equal(t, []int{95}, hist.percentiles)
+
+	status := GetHistogramOverridesStatus()
+	require.Len(t, status.Overrides, 1)
+	assert.Equal(t, HistogramOverrideStatus{
+		Match:       "myapp.latency.*",
+		Aggregates:  []string{"max"},
+		Percentiles: []int{99},
+		Histograms:  1,
+	}, status.Overrides[0])
+	assert.Empty(t, status.Errors)
+}
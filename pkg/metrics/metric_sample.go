@@ -78,6 +78,13 @@ type MetricSampleContext interface {
 	GetTags(tagsBuffer []string) []string
 }
 
+// DistributionBin represents a value of a pre-aggregated distribution and
+// the number of samples it stands for
+type DistributionBin struct {
+	Value float64
+	Count uint
+}
+
 // MetricSample represents a raw metric sample
 type MetricSample struct {
 	Name            string
@@ -91,6 +98,9 @@ type MetricSample struct {
 	FlushFirstValue bool
 	OriginID        string
 	K8sOriginID     string
+	// Bins holds the values of a pre-aggregated distribution sample, Value is
+	// ignored when they are set
+	Bins []DistributionBin
 }
 
 // Implement the MetricSampleContext interface
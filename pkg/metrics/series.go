@@ -11,6 +11,7 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -368,3 +369,148 @@ func encodePoints(points []Point, stream *jsoniter.Stream) {
 	}
 	stream.WriteArrayEnd()
 }
+
+//// The following methods implement the StreamProtobufMarshaler interface
+//// for support of the enable_protobuf_stream_payload_serialization option.
+//// The payload is encoded as the MetricsPayload of agent-payload, the same as Marshal.
+
+const (
+	// field numbers of the MetricsPayload message
+	metricsPayloadSamplesField  = 1
+	metricsPayloadMetadataField = 2
+
+	// field numbers of the MetricsPayload.Sample message
+	sampleMetricField         = 1
+	sampleTypeField           = 2
+	sampleHostField           = 3
+	samplePointsField         = 4
+	sampleTagsField           = 5
+	sampleSourceTypeNameField = 6
+
+	// field numbers of the MetricsPayload.Sample.Point message
+	pointTsField    = 1
+	pointValueField = 2
+
+	protobufWireVarint  = 0
+	protobufWireFixed64 = 1
+	protobufWireBytes   = 2
+)
+
+// ProtobufHeader returns the fields written before the samples
+func (series Series) ProtobufHeader() []byte {
+	return nil
+}
+
+// ProtobufFooter returns the fields written after the samples: an empty metadata, as Marshal does
+func (series Series) ProtobufFooter() []byte {
+	return appendProtobufBytesField(nil, metricsPayloadMetadataField, nil)
+}
+
+// AppendProtobufItem appends the sample of a serie to buf
+func (series Series) AppendProtobufItem(buf []byte, i int) ([]byte, error) {
+	if i < 0 || i > len(series)-1 {
+		return buf, errors.New("out of range")
+	}
+	serie := series[i]
+	mtype := serie.MType.String()
+
+	buf = appendProtobufTag(buf, metricsPayloadSamplesField, protobufWireBytes)
+	buf = appendProtobufVarint(buf, uint64(sampleProtobufSize(serie, mtype)))
+
+	buf = appendProtobufStringField(buf, sampleMetricField, serie.Name)
+	buf = appendProtobufStringField(buf, sampleTypeField, mtype)
+	buf = appendProtobufStringField(buf, sampleHostField, serie.Host)
+	for _, p := range serie.Points {
+		buf = appendProtobufTag(buf, samplePointsField, protobufWireBytes)
+		buf = appendProtobufVarint(buf, uint64(pointProtobufSize(p)))
+		if ts := int64(p.Ts); ts != 0 {
+			buf = appendProtobufTag(buf, pointTsField, protobufWireVarint)
+			buf = appendProtobufVarint(buf, uint64(ts))
+		}
+		if p.Value != 0 {
+			buf = appendProtobufTag(buf, pointValueField, protobufWireFixed64)
+			buf = appendProtobufFixed64(buf, math.Float64bits(p.Value))
+		}
+	}
+	for _, tag := range serie.Tags {
+		// the repeated fields are written even when empty
+		buf = appendProtobufTag(buf, sampleTagsField, protobufWireBytes)
+		buf = appendProtobufVarint(buf, uint64(len(tag)))
+		buf = append(buf, tag...)
+	}
+	buf = appendProtobufStringField(buf, sampleSourceTypeNameField, serie.SourceTypeName)
+	return buf, nil
+}
+
+func sampleProtobufSize(serie *Serie, mtype string) int {
+	size := stringFieldProtobufSize(serie.Name) + stringFieldProtobufSize(mtype) + stringFieldProtobufSize(serie.Host) +
+		stringFieldProtobufSize(serie.SourceTypeName)
+	for _, p := range serie.Points {
+		pointSize := pointProtobufSize(p)
+		size += 1 + varintProtobufSize(uint64(pointSize)) + pointSize
+	}
+	for _, tag := range serie.Tags {
+		size += 1 + varintProtobufSize(uint64(len(tag))) + len(tag)
+	}
+	return size
+}
+
+func pointProtobufSize(p Point) int {
+	size := 0
+	if ts := int64(p.Ts); ts != 0 {
+		size += 1 + varintProtobufSize(uint64(ts))
+	}
+	if p.Value != 0 {
+		size += 1 + 8
+	}
+	return size
+}
+
+// stringFieldProtobufSize returns the size of a string field, omitted when empty.
+// The field numbers of the series are all encoded in a single byte.
+func stringFieldProtobufSize(s string) int {
+	if len(s) == 0 {
+		return 0
+	}
+	return 1 + varintProtobufSize(uint64(len(s))) + len(s)
+}
+
+func varintProtobufSize(v uint64) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+	return size
+}
+
+func appendProtobufTag(buf []byte, field int, wireType int) []byte {
+	return appendProtobufVarint(buf, uint64(field<<3|wireType))
+}
+
+func appendProtobufVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func appendProtobufFixed64(buf []byte, v uint64) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24), byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+func appendProtobufStringField(buf []byte, field int, s string) []byte {
+	if len(s) == 0 {
+		return buf
+	}
+	buf = appendProtobufTag(buf, field, protobufWireBytes)
+	buf = appendProtobufVarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendProtobufBytesField(buf []byte, field int, b []byte) []byte {
+	buf = appendProtobufTag(buf, field, protobufWireBytes)
+	buf = appendProtobufVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
@@ -13,6 +13,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
@@ -20,6 +21,7 @@ import (
 	agentpayload "github.com/DataDog/agent-payload/gogen"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/jsonstream"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -437,6 +439,98 @@ func TestPayloadsSeries(t *testing.T) {
 	require.Equal(t, originalLength, newLength)
 }
 
+func TestStreamProtobufMarshaler(t *testing.T) {
+	series := Series{
+		{
+			Points: []Point{
+				{Ts: 12345.0, Value: float64(21.21)},
+				{Ts: 67890.0, Value: float64(0)},
+				{Ts: -1, Value: float64(-12.12)},
+			},
+			MType:          APIGaugeType,
+			Name:           "test.metrics",
+			Host:           "localHost",
+			Tags:           []string{"tag1", "", "tag2:yes"},
+			SourceTypeName: "System",
+		},
+		{
+			Points: []Point{{Ts: 0, Value: float64(1)}},
+			MType:  APIRateType,
+			Name:   "test.metrics.empty_host",
+		},
+		{
+			Points: []Point{},
+			MType:  APICountType,
+			Name:   strings.Repeat("a", 200),
+			Host:   "localHost",
+		},
+	}
+
+	payload := series.ProtobufHeader()
+	for i := range series {
+		var err error
+		payload, err = series.AppendProtobufItem(payload, i)
+		require.NoError(t, err)
+	}
+	payload = append(payload, series.ProtobufFooter()...)
+
+	expected, err := series.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, expected, payload)
+
+	_, err = series.AppendProtobufItem(nil, len(series))
+	assert.Error(t, err)
+}
+
+func TestProtobufPayloadsSeries(t *testing.T) {
+	testSeries := Series{}
+	for i := 0; i < 30000; i++ {
+		point := Serie{
+			Points: []Point{
+				{Ts: 12345.0, Value: float64(21.21)},
+				{Ts: 67890.0, Value: float64(12.12)},
+				{Ts: 2222.0, Value: float64(22.12)},
+				{Ts: 333.0, Value: float64(32.12)},
+				{Ts: 444444.0, Value: float64(42.12)},
+				{Ts: 882787.0, Value: float64(52.12)},
+				{Ts: 99990.0, Value: float64(62.12)},
+				{Ts: 121212.0, Value: float64(72.12)},
+				{Ts: 222227.0, Value: float64(82.12)},
+				{Ts: 808080.0, Value: float64(92.12)},
+				{Ts: 9090.0, Value: float64(13.12)},
+			},
+			MType:    APIGaugeType,
+			Name:     fmt.Sprintf("test.metrics%d", i),
+			Interval: 1,
+			Host:     "localHost",
+			Tags:     []string{"tag1", "tag2:yes"},
+		}
+		testSeries = append(testSeries, &point)
+	}
+
+	builder := jsonstream.NewPayloadBuilder()
+	payloads, err := builder.BuildProtobufWithOnErrItemTooBigPolicy(testSeries, jsonstream.DropItemOnErrItemTooBig, compression.DefaultStrategy())
+	require.NoError(t, err)
+	require.True(t, len(payloads) > 1)
+
+	var samples []*agentpayload.MetricsPayload_Sample
+	for _, compressedPayload := range payloads {
+		payload, err := decompressPayload(*compressedPayload)
+		require.NoError(t, err)
+
+		metricsPayload := &agentpayload.MetricsPayload{}
+		require.NoError(t, proto.Unmarshal(payload, metricsPayload))
+		require.NotNil(t, metricsPayload.Metadata)
+		samples = append(samples, metricsPayload.Samples...)
+	}
+
+	require.Len(t, samples, len(testSeries))
+	for i, sample := range samples {
+		assert.Equal(t, testSeries[i].Name, sample.Metric)
+		require.Len(t, sample.Points, 11)
+	}
+}
+
 var result forwarder.Payloads
 
 func BenchmarkPayloadsSeries(b *testing.B) {
@@ -482,6 +576,30 @@ func BenchmarkPayloadsSeries(b *testing.B) {
 	result = r
 }
 
+func BenchmarkProtobufPayloadsSeries(b *testing.B) {
+	testSeries := Series{}
+	for i := 0; i < 400000; i++ {
+		point := Serie{
+			Points: []Point{
+				{Ts: 12345.0, Value: 1.2 * float64(i)},
+			},
+			MType:    APIGaugeType,
+			Name:     fmt.Sprintf("test.metrics%d", i),
+			Interval: 1,
+			Host:     "localHost",
+			Tags:     []string{"tag1", "tag2:yes"},
+		}
+		testSeries = append(testSeries, &point)
+	}
+
+	var r forwarder.Payloads
+	builder := jsonstream.NewPayloadBuilder()
+	for n := 0; n < b.N; n++ {
+		r, _ = builder.BuildProtobufWithOnErrItemTooBigPolicy(testSeries, jsonstream.DropItemOnErrItemTooBig, compression.DefaultStrategy())
+	}
+	result = r
+}
+
 func decompressPayload(payload []byte) ([]byte, error) {
 	r, err := zlib.NewReader(bytes.NewReader(payload))
 	if err != nil {
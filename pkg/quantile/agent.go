@@ -47,6 +47,7 @@ func (a *Agent) flush() {
 func (a *Agent) Reset() {
 	a.Sketch.Reset()
 	a.Buf = nil // TODO: pool
+	a.CountBuf = nil
 }
 
 // Insert v into the sketch.
@@ -77,6 +78,25 @@ func (a *Agent) Insert(v float64, sampleRate float64) {
 	a.flush()
 }
 
+// InsertN inserts v into the sketch n times, as done for pre-aggregated values.
+// The counts are buffered and flushed in a single pass into the sketch.
+func (a *Agent) InsertN(v float64, n uint) {
+	if n == 0 {
+		return
+	}
+
+	a.Sketch.Basic.InsertN(v, float64(n))
+	a.CountBuf = append(a.CountBuf, KeyCount{
+		k: agentConfig.key(v),
+		n: n,
+	})
+
+	if len(a.CountBuf) < agentBufCap {
+		return
+	}
+	a.flush()
+}
+
 // InsertInterpolate linearly interpolates a count from the given lower to upper bounds
 func (a *Agent) InsertInterpolate(lower float64, upper float64, count uint) {
 	keys := make([]Key, 0)
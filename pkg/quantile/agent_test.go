@@ -115,6 +115,29 @@ func TestAgentFinish(t *testing.T) {
 	})
 }
 
+func TestAgentInsertN(t *testing.T) {
+	var (
+		a   = &Agent{}
+		exp = &Agent{}
+	)
+
+	// more values than agentBufCap to go through a flush of the buffered counts
+	for i := 0; i < agentBufCap+10; i++ {
+		v := float64(i % 100)
+		a.InsertN(v, uint(i%3))
+		for j := 0; j < i%3; j++ {
+			exp.Insert(v, 1)
+		}
+	}
+
+	require.True(t, exp.Finish().ApproxEquals(a.Finish(), 1e-6))
+
+	a.InsertN(1, 0)
+	a.Reset()
+	require.True(t, a.IsEmpty())
+	require.Empty(t, a.CountBuf)
+}
+
 func TestAgentInterpolation(t *testing.T) {
 	a := &Agent{}
 
@@ -137,6 +137,7 @@ func (s *sparseStore) insertCounts(c *Config, kcs []KeyCount) {
 	sort.Slice(kcs, func(i, j int) bool {
 		return kcs[i].k < kcs[j].k
 	})
+	kcs = mergeKeyCounts(kcs)
 
 	// TODO|PERF: Add a non-allocating fast path. When every key is already contained
 	// in the sketch (and no overflow happens) we can just directly update.
@@ -185,6 +186,25 @@ func (s *sparseStore) insertCounts(c *Config, kcs []KeyCount) {
 	putBinList(tmp)
 }
 
+// mergeKeyCounts sums in place the counts of the consecutive equal keys of the sorted kcs
+// so that every key is inserted as a single bin.
+func mergeKeyCounts(kcs []KeyCount) []KeyCount {
+	if len(kcs) < 2 {
+		return kcs
+	}
+
+	n := 0
+	for _, kc := range kcs[1:] {
+		if kc.k == kcs[n].k {
+			kcs[n].n += kc.n
+			continue
+		}
+		n++
+		kcs[n] = kc
+	}
+	return kcs[:n+1]
+}
+
 func (s *sparseStore) insert(c *Config, keys []Key) {
 	s.count += len(keys)
 
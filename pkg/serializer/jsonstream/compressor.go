@@ -9,7 +9,6 @@ package jsonstream
 
 import (
 	"bytes"
-	"compress/zlib"
 	"errors"
 	"expvar"
 
@@ -81,13 +80,18 @@ var (
 
 var jsonSeparator = []byte(",")
 
+// protobufSeparator is empty as a protobuf message is the concatenation of its fields
+var protobufSeparator = []byte{}
+
 // compressor is in charge of compressing items for a single payload
 type compressor struct {
 	input               *bytes.Buffer // temporary buffer for data that has not been compressed yet
 	compressed          *bytes.Buffer // output buffer containing the compressed payload
-	zipper              *zlib.Writer
+	strategy            compression.Strategy
+	zipper              compression.StreamWriter
 	header              []byte // json header to print at the beginning of the payload
 	footer              []byte // json footer to append at the end of the payload
+	separator           []byte // separator written between the items
 	uncompressedWritten int    // uncompressed bytes written
 	firstItem           bool   // tells if the first item has been written
 	repacks             int    // numbers of time we had to pack this payload
@@ -98,6 +102,10 @@ type compressor struct {
 }
 
 func newCompressor(input, output *bytes.Buffer, header, footer []byte) (*compressor, error) {
+	return newCompressorWithSeparator(input, output, header, footer, jsonSeparator, compression.DefaultStrategy())
+}
+
+func newCompressorWithSeparator(input, output *bytes.Buffer, header, footer, separator []byte, strategy compression.Strategy) (*compressor, error) {
 	// the backend accepts payloads up to 3MB compressed / 50MB uncompressed but
 	// prefers small uncompressed payloads of ~4MB
 	maxPayloadSize := config.Datadog.GetInt("serializer_max_payload_size")
@@ -105,16 +113,18 @@ func newCompressor(input, output *bytes.Buffer, header, footer []byte) (*compres
 	c := &compressor{
 		header:              header,
 		footer:              footer,
+		separator:           separator,
+		strategy:            strategy,
 		input:               input,
 		compressed:          output,
 		firstItem:           true,
 		maxPayloadSize:      maxPayloadSize,
 		maxUncompressedSize: maxUncompressedSize,
 		maxUnzippedItemSize: maxPayloadSize - len(footer) - len(header),
-		maxZippedItemSize:   maxUncompressedSize - compression.CompressBound(len(footer)+len(header)),
+		maxZippedItemSize:   maxUncompressedSize - strategy.CompressBound(len(footer)+len(header)),
 	}
 
-	c.zipper = zlib.NewWriter(c.compressed)
+	c.zipper = strategy.NewStreamWriter(c.compressed)
 	n, err := c.zipper.Write(header)
 	c.uncompressedWritten += n
 
@@ -126,16 +136,27 @@ func newCompressor(input, output *bytes.Buffer, header, footer []byte) (*compres
 // that could actually fit after compression. That said it is probably impossible
 // to have a 2MB+ item that is valid for the backend.
 func (c *compressor) checkItemSize(data []byte) bool {
-	return len(data) < c.maxUnzippedItemSize && compression.CompressBound(len(data)) < c.maxZippedItemSize
+	return len(data) < c.maxUnzippedItemSize && c.strategy.CompressBound(len(data)) < c.maxZippedItemSize
 }
 
 // hasRoomForItem checks if the current payload has enough room to store the given item
 func (c *compressor) hasRoomForItem(item []byte) bool {
 	uncompressedDataSize := c.input.Len() + len(item)
 	if !c.firstItem {
-		uncompressedDataSize += len(jsonSeparator)
+		uncompressedDataSize += len(c.separator)
 	}
-	return compression.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
+	return c.compressedSizeBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
+}
+
+// compressedSizeBound returns the worst case size added to the compressed payload by flushing
+// the given amount of uncompressed data
+func (c *compressor) compressedSizeBound(uncompressedDataSize int) int {
+	if w, ok := c.zipper.(compression.BufferedStreamWriter); ok {
+		// the data buffered by the zipper, like the header, is compressed along with the
+		// new data, and the footer is compressed with the last data of the payload
+		return c.strategy.CompressBound(w.Buffered()+uncompressedDataSize+len(c.footer)) - len(c.footer)
+	}
+	return c.strategy.CompressBound(uncompressedDataSize)
 }
 
 // pack flushes the temporary uncompressed buffer input to the compression writer
@@ -147,7 +168,10 @@ func (c *compressor) pack() error {
 		return err
 	}
 	c.uncompressedWritten += int(n)
-	c.zipper.Flush()
+	err = c.zipper.Flush()
+	if err != nil {
+		return err
+	}
 	c.input.Reset()
 	return nil
 }
@@ -181,7 +205,7 @@ func (c *compressor) addItem(data []byte) error {
 	if c.firstItem {
 		c.firstItem = false
 	} else {
-		c.input.Write(jsonSeparator)
+		c.input.Write(c.separator)
 	}
 
 	c.input.Write(data)
@@ -203,7 +227,7 @@ func (c *compressor) close() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Add zlib footer and close
+	// Add the compression footer and close
 	err = c.zipper.Close()
 	if err != nil {
 		return nil, err
@@ -20,6 +20,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 var (
@@ -127,6 +128,29 @@ func TestOnePayloadSimple(t *testing.T) {
 	require.Equal(t, "{[A,B,C]}", payloadToString(*payloads[0]))
 }
 
+func TestTwoPayloadWithStrategy(t *testing.T) {
+	m := &dummyMarshaller{
+		items:  []string{"A", "B", "C", "D", "E", "F"},
+		header: "{[",
+		footer: "]}",
+	}
+	config.Datadog.SetDefault("serializer_max_payload_size", 42)
+	defer resetDefaults()
+
+	strategy, err := compression.NewStrategy(compression.GzipKind, compression.DefaultLevel)
+	require.NoError(t, err)
+	builder := NewPayloadBuilder()
+	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(m, DropItemOnErrItemTooBig, strategy)
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+
+	for i, expected := range []string{"{[A,B,C]}", "{[D,E,F]}"} {
+		payload, err := strategy.Decompress(nil, *payloads[i])
+		require.NoError(t, err)
+		require.Equal(t, expected, string(payload))
+	}
+}
+
 func TestMaxCompressedSizePayload(t *testing.T) {
 	m := &dummyMarshaller{
 		items:  []string{"A", "B", "C"},
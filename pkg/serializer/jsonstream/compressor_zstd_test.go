@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018-present Datadog, Inc.
+
+//+build zlib,zstd
+
+package jsonstream
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func TestZstdPayloadsMaxSize(t *testing.T) {
+	// random data doesn't compress, the header buffered by the zstd writer until the
+	// first pack must be accounted for to keep the payloads under the maximum size
+	r := rand.New(rand.NewSource(1))
+	header := make([]byte, 512)
+	r.Read(header)
+	m := &dummyMarshaller{
+		header: string(header),
+		footer: "]}",
+	}
+	for i := 0; i < 200; i++ {
+		item := make([]byte, 24)
+		r.Read(item)
+		m.items = append(m.items, hex.EncodeToString(item))
+	}
+
+	strategy, err := compression.NewStrategy(compression.ZstdKind, compression.DefaultLevel)
+	require.NoError(t, err)
+	defer resetDefaults()
+
+	for _, maxPayloadSize := range []int{700, 1024, 4096} {
+		config.Datadog.SetDefault("serializer_max_payload_size", maxPayloadSize)
+
+		builder := NewPayloadBuilder()
+		payloads, err := builder.BuildWithOnErrItemTooBigPolicy(m, DropItemOnErrItemTooBig, strategy)
+		require.NoError(t, err)
+		require.True(t, len(payloads) > 1)
+
+		var items []string
+		for _, payload := range payloads {
+			require.True(t, len(*payload) <= maxPayloadSize, "payload of %d bytes over the %d bytes limit", len(*payload), maxPayloadSize)
+
+			decompressed, err := strategy.Decompress(nil, *payload)
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(string(decompressed), m.header))
+			require.True(t, strings.HasSuffix(string(decompressed), m.footer))
+			items = append(items, strings.TrimSuffix(strings.TrimPrefix(string(decompressed), m.header), m.footer))
+		}
+		require.Equal(t, strings.Join(m.items, ","), strings.Join(items, ","))
+	}
+}
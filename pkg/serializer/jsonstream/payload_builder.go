@@ -14,6 +14,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -50,23 +51,15 @@ const (
 
 // Build serializes a metadata payload and sends it to the forwarder
 func (b *PayloadBuilder) Build(m marshaler.StreamJSONMarshaler) (forwarder.Payloads, error) {
-	return b.BuildWithOnErrItemTooBigPolicy(m, DropItemOnErrItemTooBig)
+	return b.BuildWithOnErrItemTooBigPolicy(m, DropItemOnErrItemTooBig, compression.DefaultStrategy())
 }
 
-// BuildWithOnErrItemTooBigPolicy serializes a metadata payload and sends it to the forwarder
+// BuildWithOnErrItemTooBigPolicy serializes a metadata payload, compressed with the given
+// strategy, and sends it to the forwarder
 func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	m marshaler.StreamJSONMarshaler,
-	policy OnErrItemTooBigPolicy) (forwarder.Payloads, error) {
-
-	var payloads forwarder.Payloads
-	var i int
-	itemCount := m.Len()
-	expvarsTotalCalls.Add(1)
-	tlmTotalCalls.Inc()
-
-	// Inner buffers for the compressor
-	input := bytes.NewBuffer(make([]byte, 0, b.inputSizeHint))
-	output := bytes.NewBuffer(make([]byte, 0, b.outputSizeHint))
+	policy OnErrItemTooBigPolicy,
+	strategy compression.Strategy) (forwarder.Payloads, error) {
 
 	// Temporary buffers
 	var header, footer bytes.Buffer
@@ -83,16 +76,59 @@ func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 		return nil, err
 	}
 
-	compressor, err := newCompressor(input, output, header.Bytes(), footer.Bytes())
+	writeItem := func(i int) ([]byte, error) {
+		// We keep reusing the same small buffer in the jsoniter stream. Note that we can do so
+		// because compressor.addItem copies given buffer.
+		jsonStream.Reset(nil)
+		err := m.WriteItem(jsonStream, i)
+		return jsonStream.Buffer(), err
+	}
+
+	return b.build(header.Bytes(), footer.Bytes(), jsonSeparator, m.Len(), writeItem, m.DescribeItem, policy, strategy)
+}
+
+// BuildProtobufWithOnErrItemTooBigPolicy serializes a protobuf payload item by item, splitting
+// it in several payloads the same way as the JSON payloads.
+func (b *PayloadBuilder) BuildProtobufWithOnErrItemTooBigPolicy(
+	m marshaler.StreamProtobufMarshaler,
+	policy OnErrItemTooBigPolicy,
+	strategy compression.Strategy) (forwarder.Payloads, error) {
+
+	// The same buffer is reused for every item as compressor.addItem copies it
+	var item []byte
+	writeItem := func(i int) ([]byte, error) {
+		var err error
+		item, err = m.AppendProtobufItem(item[:0], i)
+		return item, err
+	}
+
+	return b.build(m.ProtobufHeader(), m.ProtobufFooter(), protobufSeparator, m.Len(), writeItem, m.DescribeItem, policy, strategy)
+}
+
+func (b *PayloadBuilder) build(
+	header, footer, separator []byte,
+	itemCount int,
+	writeItem func(i int) ([]byte, error),
+	describeItem func(i int) string,
+	policy OnErrItemTooBigPolicy,
+	strategy compression.Strategy) (forwarder.Payloads, error) {
+
+	var payloads forwarder.Payloads
+	var i int
+	expvarsTotalCalls.Add(1)
+	tlmTotalCalls.Inc()
+
+	// Inner buffers for the compressor
+	input := bytes.NewBuffer(make([]byte, 0, b.inputSizeHint))
+	output := bytes.NewBuffer(make([]byte, 0, b.outputSizeHint))
+
+	compressor, err := newCompressorWithSeparator(input, output, header, footer, separator, strategy)
 	if err != nil {
 		return nil, err
 	}
 
 	for i < itemCount {
-		// We keep reusing the same small buffer in the jsoniter stream. Note that we can do so
-		// because compressor.addItem copies given buffer.
-		jsonStream.Reset(nil)
-		err := m.WriteItem(jsonStream, i)
+		item, err := writeItem(i)
 		if err != nil {
 			log.Warnf("error marshalling an item, skipping: %s", err)
 			i++
@@ -101,7 +137,7 @@ func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 			continue
 		}
 
-		switch compressor.addItem(jsonStream.Buffer()) {
+		switch compressor.addItem(item) {
 		case errPayloadFull:
 			expvarsPayloadFulls.Add(1)
 			tlmPayloadFull.Inc()
@@ -113,7 +149,7 @@ func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 			payloads = append(payloads, &payload)
 			input.Reset()
 			output.Reset()
-			compressor, err = newCompressor(input, output, header.Bytes(), footer.Bytes())
+			compressor, err = newCompressorWithSeparator(input, output, header, footer, separator, strategy)
 			if err != nil {
 				return nil, err
 			}
@@ -131,7 +167,7 @@ func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 		default:
 			// Unexpected error, drop the item
 			i++
-			log.Warnf("Dropping an item, %s: %s", m.DescribeItem(i), err)
+			log.Warnf("Dropping an item, %s: %s", describeItem(i), err)
 			expvarsItemDrops.Add(1)
 			tlmItemDrops.Inc()
 			continue
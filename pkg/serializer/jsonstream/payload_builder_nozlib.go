@@ -12,6 +12,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // OnErrItemTooBigPolicy defines the behavior when OnErrItemTooBig occurs.
@@ -35,6 +36,11 @@ func NewPayloadBuilder() *PayloadBuilder {
 }
 
 // BuildWithOnErrItemTooBigPolicy is not implemented when zlib is not available.
-func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(marshaler.StreamJSONMarshaler, OnErrItemTooBigPolicy) (forwarder.Payloads, error) {
+func (b *PayloadBuilder) BuildWithOnErrItemTooBigPolicy(marshaler.StreamJSONMarshaler, OnErrItemTooBigPolicy, compression.Strategy) (forwarder.Payloads, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// BuildProtobufWithOnErrItemTooBigPolicy is not implemented when zlib is not available.
+func (b *PayloadBuilder) BuildProtobufWithOnErrItemTooBigPolicy(marshaler.StreamProtobufMarshaler, OnErrItemTooBigPolicy, compression.Strategy) (forwarder.Payloads, error) {
 	return nil, fmt.Errorf("not implemented")
 }
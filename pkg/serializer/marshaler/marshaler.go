@@ -23,3 +23,15 @@ type StreamJSONMarshaler interface {
 	Len() int
 	DescribeItem(i int) string
 }
+
+// StreamProtobufMarshaler is an interface for metrics that are able to serialize themselves in a stream
+// of protobuf fields. The payload is the concatenation of the header, of the items and of the footer.
+type StreamProtobufMarshaler interface {
+	Marshaler
+	ProtobufHeader() []byte
+	ProtobufFooter() []byte
+	// AppendProtobufItem appends the item, encoded as a field of the payload message, to buf
+	AppendProtobufItem(buf []byte, i int) ([]byte, error)
+	Len() int
+	DescribeItem(i int) string
+}
@@ -74,6 +74,24 @@ func initExtraHeaders() {
 	}
 }
 
+// extraHeadersWithCompression returns the extra headers of the payloads compressed with the given
+// strategy, the headers computed by initExtraHeaders are reused for the default compression
+func extraHeadersWithCompression(headers, headersWithDefaultCompression http.Header, strategy compression.Strategy) http.Header {
+	contentEncoding := strategy.ContentEncoding()
+	if contentEncoding == compression.ContentEncoding {
+		return headersWithDefaultCompression
+	}
+
+	extraHeaders := make(http.Header)
+	for k := range headers {
+		extraHeaders.Set(k, headers.Get(k))
+	}
+	if contentEncoding != "" {
+		extraHeaders.Set("Content-Encoding", contentEncoding)
+	}
+	return extraHeaders
+}
+
 // EventsStreamJSONMarshaler handles two serialization logics.
 type EventsStreamJSONMarshaler interface {
 	marshaler.Marshaler
@@ -101,6 +119,9 @@ type Serializer struct {
 	Forwarder forwarder.Forwarder
 
 	seriesPayloadBuilder *jsonstream.PayloadBuilder
+	// payloadsCompression is shared with the forwarder, which falls back to another
+	// compression when the intake rejects the configured one
+	payloadsCompression *compression.FallbackStrategy
 
 	// Those variables allow users to blacklist any kind of payload
 	// from being sent by the agent. This was introduced for
@@ -114,21 +135,24 @@ type Serializer struct {
 	enableSketches                bool
 	enableJSONToV1Intake          bool
 	enableJSONStream              bool
+	enableProtobufStream          bool
 	enableServiceChecksJSONStream bool
 	enableEventsJSONStream        bool
 }
 
 // NewSerializer returns a new Serializer initialized
-func NewSerializer(forwarder forwarder.Forwarder) *Serializer {
+func NewSerializer(f forwarder.Forwarder) *Serializer {
 	s := &Serializer{
-		Forwarder:                     forwarder,
+		Forwarder:                     f,
 		seriesPayloadBuilder:          jsonstream.NewPayloadBuilder(),
+		payloadsCompression:           forwarder.PayloadsCompression(),
 		enableEvents:                  config.Datadog.GetBool("enable_payloads.events"),
 		enableSeries:                  config.Datadog.GetBool("enable_payloads.series"),
 		enableServiceChecks:           config.Datadog.GetBool("enable_payloads.service_checks"),
 		enableSketches:                config.Datadog.GetBool("enable_payloads.sketches"),
 		enableJSONToV1Intake:          config.Datadog.GetBool("enable_payloads.json_to_v1_intake"),
 		enableJSONStream:              jsonstream.Available && config.Datadog.GetBool("enable_stream_payload_serialization"),
+		enableProtobufStream:          jsonstream.Available && config.Datadog.GetBool("enable_protobuf_stream_payload_serialization"),
 		enableServiceChecksJSONStream: jsonstream.Available && config.Datadog.GetBool("enable_service_checks_stream_payload_serialization"),
 		enableEventsJSONStream:        jsonstream.Available && config.Datadog.GetBool("enable_events_stream_payload_serialization"),
 	}
@@ -155,25 +179,29 @@ func NewSerializer(forwarder forwarder.Forwarder) *Serializer {
 func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool, useV1API bool) (forwarder.Payloads, http.Header, error) {
 	var marshalType split.MarshalType
 	var extraHeaders http.Header
+	var payloads forwarder.Payloads
+	var err error
 
 	if useV1API {
 		marshalType = split.MarshalJSON
-		if compress {
-			extraHeaders = jsonExtraHeadersWithCompression
-		} else {
-			extraHeaders = jsonExtraHeaders
-		}
+		extraHeaders = jsonExtraHeaders
 	} else {
 		marshalType = split.Marshal
-		if compress {
-			extraHeaders = protobufExtraHeadersWithCompression
+		extraHeaders = protobufExtraHeaders
+	}
+
+	if compress {
+		strategy := s.payloadsCompression.Current()
+		if useV1API {
+			extraHeaders = extraHeadersWithCompression(jsonExtraHeaders, jsonExtraHeadersWithCompression, strategy)
 		} else {
-			extraHeaders = protobufExtraHeaders
+			extraHeaders = extraHeadersWithCompression(protobufExtraHeaders, protobufExtraHeadersWithCompression, strategy)
 		}
+		payloads, err = split.PayloadsWithStrategy(payload, strategy, marshalType)
+	} else {
+		payloads, err = split.Payloads(payload, false, marshalType)
 	}
 
-	payloads, err := split.Payloads(payload, compress, marshalType)
-
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
 	}
@@ -182,8 +210,15 @@ func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool,
 }
 
 func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy jsonstream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
-	payloads, err := s.seriesPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy)
-	return payloads, jsonExtraHeadersWithCompression, err
+	strategy := s.payloadsCompression.Current()
+	payloads, err := s.seriesPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy, strategy)
+	return payloads, extraHeadersWithCompression(jsonExtraHeaders, jsonExtraHeadersWithCompression, strategy), err
+}
+
+func (s Serializer) serializeStreamableProtobufPayload(payload marshaler.StreamProtobufMarshaler, policy jsonstream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
+	strategy := s.payloadsCompression.Current()
+	payloads, err := s.seriesPayloadBuilder.BuildProtobufWithOnErrItemTooBigPolicy(payload, policy, strategy)
+	return payloads, extraHeadersWithCompression(protobufExtraHeaders, protobufExtraHeadersWithCompression, strategy), err
 }
 
 // As events are gathered by SourceType, the serialization logic is more complex than for the other serializations.
@@ -290,8 +325,12 @@ func (s *Serializer) SendSeries(series marshaler.StreamJSONMarshaler) error {
 	var extraHeaders http.Header
 	var err error
 
+	protobufSeries, isProtobufStreamable := series.(marshaler.StreamProtobufMarshaler)
+
 	if useV1API && s.enableJSONStream {
 		seriesPayloads, extraHeaders, err = s.serializeStreamablePayload(series, jsonstream.DropItemOnErrItemTooBig)
+	} else if !useV1API && s.enableProtobufStream && isProtobufStreamable {
+		seriesPayloads, extraHeaders, err = s.serializeStreamableProtobufPayload(protobufSeries, jsonstream.DropItemOnErrItemTooBig)
 	} else {
 		seriesPayloads, extraHeaders, err = s.serializePayload(series, true, useV1API)
 	}
@@ -339,7 +378,8 @@ func (s *Serializer) SendAgentchecksMetadata(m marshaler.Marshaler) error {
 }
 
 func (s *Serializer) sendMetadata(m marshaler.Marshaler, submit func(payload forwarder.Payloads, extra http.Header) error) error {
-	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerialize(m, true, split.MarshalJSON)
+	strategy := s.payloadsCompression.Current()
+	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerializeWithStrategy(m, strategy, split.MarshalJSON)
 	if err != nil {
 		return fmt.Errorf("could not determine size of metadata payload: %s", err)
 	}
@@ -350,7 +390,7 @@ func (s *Serializer) sendMetadata(m marshaler.Marshaler, submit func(payload for
 		return fmt.Errorf("metadata payload was too big to send (%d bytes compressed, %d bytes uncompressed), metadata payloads cannot be split", len(compressedPayload), len(payload))
 	}
 
-	if err := submit(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
+	if err := submit(forwarder.Payloads{&compressedPayload}, extraHeadersWithCompression(jsonExtraHeaders, jsonExtraHeadersWithCompression, strategy)); err != nil {
 		return err
 	}
 
@@ -16,6 +16,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/jsonstream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func buildSeries(numberOfSeries int) metrics.Series {
@@ -48,6 +49,16 @@ func benchmarkJSONStream(b *testing.B, numberOfSeries int) {
 	}
 }
 
+func benchmarkProtobufStream(b *testing.B, numberOfSeries int) {
+	series := buildSeries(numberOfSeries)
+	payloadBuilder := jsonstream.NewPayloadBuilder()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		results, _ = payloadBuilder.BuildProtobufWithOnErrItemTooBigPolicy(series, jsonstream.DropItemOnErrItemTooBig, compression.DefaultStrategy())
+	}
+}
+
 func benchmarkSplit(b *testing.B, numberOfSeries int) {
 	series := buildSeries(numberOfSeries)
 	b.ResetTimer()
@@ -57,6 +68,15 @@ func benchmarkSplit(b *testing.B, numberOfSeries int) {
 	}
 }
 
+func benchmarkSplitProtobuf(b *testing.B, numberOfSeries int) {
+	series := buildSeries(numberOfSeries)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		results, _ = split.Payloads(series, true, split.Marshal)
+	}
+}
+
 func BenchmarkJSONStream1(b *testing.B)        { benchmarkJSONStream(b, 1) }
 func BenchmarkJSONStream10(b *testing.B)       { benchmarkJSONStream(b, 10) }
 func BenchmarkJSONStream100(b *testing.B)      { benchmarkJSONStream(b, 100) }
@@ -74,3 +94,21 @@ func BenchmarkSplit10000(b *testing.B)    { benchmarkSplit(b, 10000) }
 func BenchmarkSplit100000(b *testing.B)   { benchmarkSplit(b, 100000) }
 func BenchmarkSplit1000000(b *testing.B)  { benchmarkSplit(b, 1000000) }
 func BenchmarkSplit10000000(b *testing.B) { benchmarkSplit(b, 10000000) }
+
+func BenchmarkProtobufStream1(b *testing.B)        { benchmarkProtobufStream(b, 1) }
+func BenchmarkProtobufStream10(b *testing.B)       { benchmarkProtobufStream(b, 10) }
+func BenchmarkProtobufStream100(b *testing.B)      { benchmarkProtobufStream(b, 100) }
+func BenchmarkProtobufStream1000(b *testing.B)     { benchmarkProtobufStream(b, 1000) }
+func BenchmarkProtobufStream10000(b *testing.B)    { benchmarkProtobufStream(b, 10000) }
+func BenchmarkProtobufStream100000(b *testing.B)   { benchmarkProtobufStream(b, 100000) }
+func BenchmarkProtobufStream1000000(b *testing.B)  { benchmarkProtobufStream(b, 1000000) }
+func BenchmarkProtobufStream10000000(b *testing.B) { benchmarkProtobufStream(b, 10000000) }
+
+func BenchmarkSplitProtobuf1(b *testing.B)        { benchmarkSplitProtobuf(b, 1) }
+func BenchmarkSplitProtobuf10(b *testing.B)       { benchmarkSplitProtobuf(b, 10) }
+func BenchmarkSplitProtobuf100(b *testing.B)      { benchmarkSplitProtobuf(b, 100) }
+func BenchmarkSplitProtobuf1000(b *testing.B)     { benchmarkSplitProtobuf(b, 1000) }
+func BenchmarkSplitProtobuf10000(b *testing.B)    { benchmarkSplitProtobuf(b, 10000) }
+func BenchmarkSplitProtobuf100000(b *testing.B)   { benchmarkSplitProtobuf(b, 100000) }
+func BenchmarkSplitProtobuf1000000(b *testing.B)  { benchmarkSplitProtobuf(b, 1000000) }
+func BenchmarkSplitProtobuf10000000(b *testing.B) { benchmarkSplitProtobuf(b, 10000000) }
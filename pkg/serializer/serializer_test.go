@@ -19,6 +19,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/serializer/jsonstream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
@@ -339,6 +340,41 @@ func TestSendSeries(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+// testProtobufPayload can only be serialized by streaming it
+type testProtobufPayload struct {
+	testErrorPayload
+}
+
+func (p *testProtobufPayload) ProtobufHeader() []byte { return nil }
+func (p *testProtobufPayload) ProtobufFooter() []byte { return nil }
+func (p *testProtobufPayload) AppendProtobufItem(buf []byte, i int) ([]byte, error) {
+	return append(buf, protobufString...), nil
+}
+
+func TestSendSeriesProtobufStream(t *testing.T) {
+	if !jsonstream.Available {
+		t.Skip("the stream serialization is not compiled in")
+	}
+	mockConfig := config.Mock()
+
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitSeries", mock.Anything, protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	mockConfig.Set("use_v2_api.series", true)
+	defer mockConfig.Set("use_v2_api.series", nil)
+
+	s := NewSerializer(f)
+	err := s.SendSeries(&testProtobufPayload{})
+	require.Nil(t, err)
+	f.AssertExpectations(t)
+
+	// the payloads are split when the stream serialization is disabled
+	mockConfig.Set("enable_protobuf_stream_payload_serialization", false)
+	defer mockConfig.Set("enable_protobuf_stream_payload_serialization", nil)
+	s = NewSerializer(f)
+	err = s.SendSeries(&testProtobufPayload{})
+	require.NotNil(t, err)
+}
+
 func TestSendSketch(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	payloads, _ := mkPayloads(protobufString, true)
@@ -377,6 +413,31 @@ func TestSendMetadata(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+func TestSendMetadataWithCompressionStrategy(t *testing.T) {
+	gzipStrategy, err := compression.NewStrategy(compression.GzipKind, compression.DefaultLevel)
+	require.NoError(t, err)
+	gzipPayload, err := gzipStrategy.Compress(nil, jsonString)
+	require.NoError(t, err)
+	gzipHeaders := make(http.Header)
+	gzipHeaders.Set("Content-Type", jsonContentType)
+	gzipHeaders.Set("Content-Encoding", "gzip")
+
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitMetadata", forwarder.Payloads{&gzipPayload}, gzipHeaders).Return(nil).Times(1)
+	s := NewSerializer(f)
+	s.payloadsCompression = compression.NewFallbackStrategy(gzipStrategy, compression.DefaultStrategy())
+
+	payload := &testPayload{}
+	require.NoError(t, s.SendMetadata(payload))
+	f.AssertExpectations(t)
+
+	// the default compression is used once the intake rejected gzip
+	require.True(t, s.payloadsCompression.Reject("gzip"))
+	f.On("SubmitMetadata", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	require.NoError(t, s.SendMetadata(payload))
+	f.AssertExpectations(t)
+}
+
 func TestSendJSONToV1Intake(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	payload := []byte("\"test\"")
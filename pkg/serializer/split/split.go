@@ -58,7 +58,12 @@ func init() {
 // CheckSizeAndSerialize Check the size of a payload and marshall it (optionally compress it)
 // The dual role makes sense as you will never serialize without checking the size of the payload
 func CheckSizeAndSerialize(m marshaler.Marshaler, compress bool, mType MarshalType) (bool, []byte, []byte, error) {
-	compressedPayload, payload, err := serializeMarshaller(m, compress, mType)
+	return CheckSizeAndSerializeWithStrategy(m, strategyFor(compress), mType)
+}
+
+// CheckSizeAndSerializeWithStrategy is CheckSizeAndSerialize compressing the payload with the given strategy
+func CheckSizeAndSerializeWithStrategy(m marshaler.Marshaler, strategy compression.Strategy, mType MarshalType) (bool, []byte, []byte, error) {
+	compressedPayload, payload, err := serializeMarshaller(m, strategy, mType)
 	if err != nil {
 		return false, nil, nil, err
 	}
@@ -70,9 +75,14 @@ func CheckSizeAndSerialize(m marshaler.Marshaler, compress bool, mType MarshalTy
 
 // Payloads serializes a metadata payload and sends it to the forwarder
 func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarder.Payloads, error) {
+	return PayloadsWithStrategy(m, strategyFor(compress), mType)
+}
+
+// PayloadsWithStrategy is Payloads compressing the payloads with the given strategy
+func PayloadsWithStrategy(m marshaler.Marshaler, strategy compression.Strategy, mType MarshalType) (forwarder.Payloads, error) {
 	marshallers := []marshaler.Marshaler{m}
 	smallEnoughPayloads := forwarder.Payloads{}
-	tooBig, compressedPayload, _, err := CheckSizeAndSerialize(m, compress, mType)
+	tooBig, compressedPayload, _, err := CheckSizeAndSerializeWithStrategy(m, strategy, mType)
 	if err != nil {
 		return smallEnoughPayloads, err
 	}
@@ -99,7 +109,7 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 		for _, toSplit := range tempSlice {
 			var e error
 			// we have to do this every time to get the proper payload
-			compressedPayload, payload, e := serializeMarshaller(toSplit, compress, mType)
+			compressedPayload, payload, e := serializeMarshaller(toSplit, strategy, mType)
 			if e != nil {
 				return smallEnoughPayloads, e
 			}
@@ -121,7 +131,7 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 			// after the payload has been split, loop through the chunks
 			for _, chunk := range chunks {
 				// serialize the payload
-				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerialize(chunk, compress, mType)
+				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerializeWithStrategy(chunk, strategy, mType)
 				if err != nil {
 					log.Debugf("Error serializing a chunk: %s", err)
 					continue
@@ -154,21 +164,28 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 	return smallEnoughPayloads, nil
 }
 
+// strategyFor returns the strategy compressing the payloads with the compression the agent is built
+// with, or not compressing them
+func strategyFor(compress bool) compression.Strategy {
+	if compress {
+		return compression.DefaultStrategy()
+	}
+	none, _ := compression.NewStrategy(compression.NoneKind, compression.DefaultLevel)
+	return none
+}
+
 // serializeMarshaller serializes the marshaller and returns both the compressed and uncompressed payloads
-func serializeMarshaller(m marshaler.Marshaler, compress bool, mType MarshalType) ([]byte, []byte, error) {
+func serializeMarshaller(m marshaler.Marshaler, strategy compression.Strategy, mType MarshalType) ([]byte, []byte, error) {
 	var payload []byte
 	var compressedPayload []byte
 	var err error
 	payload, err = marshal(m, mType)
-	compressedPayload = payload
 	if err != nil {
 		return nil, nil, err
 	}
-	if compress {
-		compressedPayload, err = compression.Compress(nil, payload)
-		if err != nil {
-			return nil, nil, err
-		}
+	compressedPayload, err = strategy.Compress(nil, payload)
+	if err != nil {
+		return nil, nil, err
 	}
 	return compressedPayload, payload, nil
 }
@@ -97,6 +97,38 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 	require.Equal(t, originalLength, newLength)
 }
 
+func TestSplitPayloadsWithStrategy(t *testing.T) {
+	prevMaxPayloadSizeCompressed := maxPayloadSizeCompressed
+	maxPayloadSizeCompressed = 512
+	defer func() { maxPayloadSizeCompressed = prevMaxPayloadSizeCompressed }()
+
+	testSeries := metrics.Series{}
+	for i := 0; i < 50; i++ {
+		testSeries = append(testSeries, &metrics.Serie{
+			Points: []metrics.Point{{Ts: float64(12345 + i), Value: float64(i)}},
+			MType:  metrics.APIGaugeType,
+			Name:   fmt.Sprintf("test.metrics%d", i),
+			Host:   "localHost",
+		})
+	}
+
+	strategy, err := compression.NewStrategy(compression.GzipKind, compression.DefaultLevel)
+	require.NoError(t, err)
+	payloads, err := PayloadsWithStrategy(testSeries, strategy, MarshalJSON)
+	require.NoError(t, err)
+	require.True(t, len(payloads) > 1)
+
+	var count int
+	for _, payload := range payloads {
+		decompressed, err := strategy.Decompress(nil, *payload)
+		require.NoError(t, err)
+		var s = map[string]metrics.Series{}
+		require.NoError(t, json.Unmarshal(decompressed, &s))
+		count += len(s["series"])
+	}
+	require.Equal(t, len(testSeries), count)
+}
+
 var result forwarder.Payloads
 
 func BenchmarkSplitPayloadsSeries(b *testing.B) {
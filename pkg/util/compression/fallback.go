@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// FallbackStrategy compresses the payloads with a preferred strategy until the intake
+// rejects its content encoding, the fallback strategy is used from then on.
+type FallbackStrategy struct {
+	preferred Strategy
+	fallback  Strategy
+	rejected  int32
+}
+
+// NewFallbackStrategy returns a new FallbackStrategy
+func NewFallbackStrategy(preferred, fallback Strategy) *FallbackStrategy {
+	return &FallbackStrategy{
+		preferred: preferred,
+		fallback:  fallback,
+	}
+}
+
+// Current returns the strategy to compress the new payloads with
+func (s *FallbackStrategy) Current() Strategy {
+	if atomic.LoadInt32(&s.rejected) == 1 {
+		return s.fallback
+	}
+	return s.preferred
+}
+
+// Fallback returns the strategy used once the preferred one is rejected
+func (s *FallbackStrategy) Fallback() Strategy {
+	return s.fallback
+}
+
+// Reject is called when the intake rejected a payload sent with the given content encoding. It
+// returns true when the payload was compressed with the preferred strategy and can be compressed
+// again with the fallback strategy, which is then used for all the new payloads.
+func (s *FallbackStrategy) Reject(contentEncoding string) bool {
+	if contentEncoding != s.preferred.ContentEncoding() || contentEncoding == s.fallback.ContentEncoding() {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&s.rejected, 0, 1) {
+		log.Warnf("The intake does not support the %q content encoding, falling back to %q", contentEncoding, s.fallback.ContentEncoding())
+	}
+	return true
+}
+
+// Recompress decompresses a payload compressed with the preferred strategy and compresses it
+// with the fallback strategy.
+func (s *FallbackStrategy) Recompress(payload []byte) ([]byte, error) {
+	decompressed, err := s.preferred.Decompress(nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.fallback.Compress(nil, decompressed)
+}
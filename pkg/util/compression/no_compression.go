@@ -12,6 +12,9 @@ package compression
 // var instead of const to ease testing
 var ContentEncoding = ""
 
+// DefaultKind is the kind of the strategy matching the compression functions of the package
+const DefaultKind = NoneKind
+
 // Compress will not compress anything
 func Compress(dst []byte, src []byte) ([]byte, error) {
 	dst = src
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+)
+
+// Kinds of compression strategies
+const (
+	NoneKind = "none"
+	ZlibKind = "zlib"
+	GzipKind = "gzip"
+	ZstdKind = "zstd"
+)
+
+// DefaultLevel selects the default compression level of the algorithm
+const DefaultLevel = -1
+
+// StreamWriter compresses the data written to it. Flush writes the data compressed so far
+// to the underlying writer and Close terminates the compressed stream.
+type StreamWriter interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// BufferedStreamWriter is implemented by the stream writers keeping the data written since the
+// last flush uncompressed, the callers bounding the size of the output have to account for it.
+type BufferedStreamWriter interface {
+	StreamWriter
+	// Buffered returns the number of bytes written since the last flush
+	Buffered() int
+}
+
+// Strategy is a compression algorithm used to compress the payloads sent to the intake
+type Strategy interface {
+	Compress(dst []byte, src []byte) ([]byte, error)
+	Decompress(dst []byte, src []byte) ([]byte, error)
+	// CompressBound returns the worst case size needed for a destination buffer
+	CompressBound(sourceLen int) int
+	// ContentEncoding returns the HTTP header value associated with the compression,
+	// empty when the payloads are not compressed
+	ContentEncoding() string
+	NewStreamWriter(output io.Writer) StreamWriter
+}
+
+// strategies lists the strategies available in the build, by kind. The strategies
+// relying on build tags are registered by the `init` function of their file.
+var strategies = map[string]func(level int) Strategy{
+	NoneKind: newNoneStrategy,
+	GzipKind: newGzipStrategy,
+}
+
+func registerStrategy(kind string, newStrategy func(level int) Strategy) {
+	strategies[kind] = newStrategy
+}
+
+// IsAvailable returns whether the given kind of compression is available in the build
+func IsAvailable(kind string) bool {
+	_, ok := strategies[kind]
+	return ok
+}
+
+// NewStrategy returns the compression strategy of the given kind. The level is capped to the
+// levels supported by the algorithm, DefaultLevel selects its default level.
+func NewStrategy(kind string, level int) (Strategy, error) {
+	newStrategy, ok := strategies[kind]
+	if !ok {
+		return nil, fmt.Errorf("%q compression is not available in this build", kind)
+	}
+	return newStrategy(level), nil
+}
+
+// DefaultStrategy returns the strategy matching the compression the package was built with
+func DefaultStrategy() Strategy {
+	return strategies[DefaultKind](DefaultLevel)
+}
+
+// StrategyForContentEncoding returns a strategy able to decompress the payloads sent with the given
+// `Content-Encoding` header, false if none of the available strategies uses this content encoding.
+func StrategyForContentEncoding(contentEncoding string) (Strategy, bool) {
+	for _, newStrategy := range strategies {
+		if strategy := newStrategy(DefaultLevel); strategy.ContentEncoding() == contentEncoding {
+			return strategy, true
+		}
+	}
+	return nil, false
+}
+
+// noneStrategy does not compress anything
+type noneStrategy struct{}
+
+func newNoneStrategy(int) Strategy {
+	return noneStrategy{}
+}
+
+func (noneStrategy) Compress(dst []byte, src []byte) ([]byte, error) {
+	return src, nil
+}
+
+func (noneStrategy) Decompress(dst []byte, src []byte) ([]byte, error) {
+	return src, nil
+}
+
+func (noneStrategy) CompressBound(sourceLen int) int {
+	return sourceLen
+}
+
+func (noneStrategy) ContentEncoding() string {
+	return ""
+}
+
+func (noneStrategy) NewStreamWriter(output io.Writer) StreamWriter {
+	return nopStreamWriter{output}
+}
+
+type nopStreamWriter struct {
+	io.Writer
+}
+
+func (nopStreamWriter) Flush() error {
+	return nil
+}
+
+func (nopStreamWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+)
+
+// gzipStrategy compresses the payloads with gzip
+type gzipStrategy struct {
+	level int
+}
+
+func newGzipStrategy(level int) Strategy {
+	if level < gzip.DefaultCompression {
+		level = gzip.DefaultCompression
+	} else if level > gzip.BestCompression {
+		level = gzip.BestCompression
+	}
+	return &gzipStrategy{level: level}
+}
+
+// Compress will compress the data with gzip
+func (s *gzipStrategy) Compress(dst []byte, src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	// the level is always valid so the writer can't fail to be created
+	w, _ := gzip.NewWriterLevel(&b, s.level)
+	_, err := w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress will decompress the data with gzip
+func (s *gzipStrategy) Decompress(dst []byte, src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (s *gzipStrategy) CompressBound(sourceLen int) int {
+	// same bound as zlib with the bigger gzip header and trailer
+	return sourceLen + (sourceLen >> 12) + (sourceLen >> 14) + (sourceLen >> 25) + 25
+}
+
+// ContentEncoding returns the HTTP header value associated with gzip
+func (s *gzipStrategy) ContentEncoding() string {
+	return "gzip"
+}
+
+// NewStreamWriter returns a gzip writer compressing the data to the output
+func (s *gzipStrategy) NewStreamWriter(output io.Writer) StreamWriter {
+	w, _ := gzip.NewWriterLevel(output, s.level)
+	return w
+}
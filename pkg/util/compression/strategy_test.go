@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPayload = []byte(`{"series":[{"metric":"test.metrics","points":[[1593007200,1.5]],"tags":["tag1","tag2:yes"]}]}`)
+
+func TestStrategies(t *testing.T) {
+	for _, kind := range []string{NoneKind, ZlibKind, GzipKind, ZstdKind} {
+		t.Run(kind, func(t *testing.T) {
+			if !IsAvailable(kind) {
+				t.Skipf("%s compression is not available in this build", kind)
+			}
+			for _, level := range []int{DefaultLevel, 1, 100} {
+				strategy, err := NewStrategy(kind, level)
+				require.NoError(t, err)
+
+				compressed, err := strategy.Compress(nil, testPayload)
+				require.NoError(t, err)
+				assert.True(t, len(compressed) <= strategy.CompressBound(len(testPayload)))
+				decompressed, err := strategy.Decompress(nil, compressed)
+				require.NoError(t, err)
+				assert.Equal(t, testPayload, decompressed)
+
+				// the data flushed in several times is decompressed as a single payload
+				var output bytes.Buffer
+				w := strategy.NewStreamWriter(&output)
+				for _, chunk := range [][]byte{testPayload[:10], testPayload[10:50], testPayload[50:]} {
+					_, err = w.Write(chunk)
+					require.NoError(t, err)
+					require.NoError(t, w.Flush())
+				}
+				require.NoError(t, w.Close())
+				decompressed, err = strategy.Decompress(nil, output.Bytes())
+				require.NoError(t, err)
+				assert.Equal(t, testPayload, decompressed)
+
+				fromContentEncoding, ok := StrategyForContentEncoding(strategy.ContentEncoding())
+				require.True(t, ok)
+				decompressed, err = fromContentEncoding.Decompress(nil, compressed)
+				require.NoError(t, err)
+				assert.Equal(t, testPayload, decompressed)
+			}
+		})
+	}
+}
+
+func TestNewStrategyUnknownKind(t *testing.T) {
+	_, err := NewStrategy("unknown", DefaultLevel)
+	assert.Error(t, err)
+	assert.False(t, IsAvailable("unknown"))
+
+	_, ok := StrategyForContentEncoding("unknown")
+	assert.False(t, ok)
+}
+
+func TestDefaultStrategy(t *testing.T) {
+	strategy := DefaultStrategy()
+	assert.Equal(t, ContentEncoding, strategy.ContentEncoding())
+
+	compressed, err := Compress(nil, testPayload)
+	require.NoError(t, err)
+	decompressed, err := strategy.Decompress(nil, compressed)
+	require.NoError(t, err)
+	assert.Equal(t, testPayload, decompressed)
+}
+
+func TestFallbackStrategy(t *testing.T) {
+	preferred, err := NewStrategy(GzipKind, DefaultLevel)
+	require.NoError(t, err)
+	fallback, err := NewStrategy(NoneKind, DefaultLevel)
+	require.NoError(t, err)
+	s := NewFallbackStrategy(preferred, fallback)
+	assert.Equal(t, preferred, s.Current())
+
+	// only the payloads compressed with the preferred strategy can fall back
+	assert.False(t, s.Reject("deflate"))
+	assert.False(t, s.Reject(""))
+	assert.Equal(t, preferred, s.Current())
+
+	compressed, err := preferred.Compress(nil, testPayload)
+	require.NoError(t, err)
+	assert.True(t, s.Reject("gzip"))
+	assert.Equal(t, fallback, s.Current())
+	recompressed, err := s.Recompress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, testPayload, recompressed)
+
+	// the payloads compressed before the fallback can still be rejected
+	assert.True(t, s.Reject("gzip"))
+	assert.Equal(t, fallback, s.Current())
+
+	// nothing to fall back to when both strategies are the same
+	s = NewFallbackStrategy(preferred, preferred)
+	assert.False(t, s.Reject("gzip"))
+	assert.Equal(t, preferred, s.Current())
+}
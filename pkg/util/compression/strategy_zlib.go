@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build zlib
+
+package compression
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+)
+
+func init() {
+	registerStrategy(ZlibKind, newZlibStrategy)
+}
+
+// zlibStrategy compresses the payloads with zlib
+type zlibStrategy struct {
+	level int
+}
+
+func newZlibStrategy(level int) Strategy {
+	if level < zlib.DefaultCompression {
+		level = zlib.DefaultCompression
+	} else if level > zlib.BestCompression {
+		level = zlib.BestCompression
+	}
+	return &zlibStrategy{level: level}
+}
+
+// Compress will compress the data with zlib
+func (s *zlibStrategy) Compress(dst []byte, src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	// the level is always valid so the writer can't fail to be created
+	w, _ := zlib.NewWriterLevel(&b, s.level)
+	_, err := w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress will decompress the data with zlib
+func (s *zlibStrategy) Decompress(dst []byte, src []byte) ([]byte, error) {
+	return Decompress(dst, src)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (s *zlibStrategy) CompressBound(sourceLen int) int {
+	return CompressBound(sourceLen)
+}
+
+// ContentEncoding returns the HTTP header value associated with zlib
+func (s *zlibStrategy) ContentEncoding() string {
+	return "deflate"
+}
+
+// NewStreamWriter returns a zlib writer compressing the data to the output
+func (s *zlibStrategy) NewStreamWriter(output io.Writer) StreamWriter {
+	w, _ := zlib.NewWriterLevel(output, s.level)
+	return w
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build zstd
+
+package compression
+
+import (
+	"bytes"
+	"io"
+
+	zstd "github.com/DataDog/zstd"
+)
+
+const (
+	// zstdDefaultLevel is the level used by zstd.Compress
+	zstdDefaultLevel = 5
+	zstdMaxLevel     = 20
+)
+
+func init() {
+	registerStrategy(ZstdKind, newZstdStrategy)
+}
+
+// zstdStrategy compresses the payloads with zstd
+type zstdStrategy struct {
+	level int
+}
+
+func newZstdStrategy(level int) Strategy {
+	if level < 1 {
+		level = zstdDefaultLevel
+	} else if level > zstdMaxLevel {
+		level = zstdMaxLevel
+	}
+	return &zstdStrategy{level: level}
+}
+
+// Compress will compress the data with zstd
+func (s *zstdStrategy) Compress(dst []byte, src []byte) ([]byte, error) {
+	return zstd.CompressLevel(dst, src, s.level)
+}
+
+// Decompress will decompress the data with zstd
+func (s *zstdStrategy) Decompress(dst []byte, src []byte) ([]byte, error) {
+	return zstd.Decompress(dst, src)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (s *zstdStrategy) CompressBound(sourceLen int) int {
+	return zstd.CompressBound(sourceLen)
+}
+
+// ContentEncoding returns the HTTP header value associated with zstd
+func (s *zstdStrategy) ContentEncoding() string {
+	return "zstd"
+}
+
+// NewStreamWriter returns a writer compressing the data to the output with zstd
+func (s *zstdStrategy) NewStreamWriter(output io.Writer) StreamWriter {
+	return &zstdStreamWriter{output: output, level: s.level}
+}
+
+// zstdStreamWriter compresses the data written between two flushes in its own zstd frame,
+// the concatenated frames are decompressed as a single payload. The data is kept until the
+// next flush, see BufferedStreamWriter.
+type zstdStreamWriter struct {
+	output io.Writer
+	level  int
+	input  bytes.Buffer
+	frame  []byte
+}
+
+func (w *zstdStreamWriter) Write(p []byte) (int, error) {
+	return w.input.Write(p)
+}
+
+// Buffered returns the number of bytes written since the last flush
+func (w *zstdStreamWriter) Buffered() int {
+	return w.input.Len()
+}
+
+// Flush compresses the data written since the last flush and writes the frame to the output
+func (w *zstdStreamWriter) Flush() error {
+	if w.input.Len() == 0 {
+		return nil
+	}
+	frame, err := zstd.CompressLevel(w.frame[:0], w.input.Bytes(), w.level)
+	if err != nil {
+		return err
+	}
+	w.frame = frame
+	w.input.Reset()
+	_, err = w.output.Write(frame)
+	return err
+}
+
+// Close flushes the remaining data
+func (w *zstdStreamWriter) Close() error {
+	return w.Flush()
+}
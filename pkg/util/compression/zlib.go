@@ -17,6 +17,9 @@ import (
 // var instead of const to ease testing
 var ContentEncoding = "deflate"
 
+// DefaultKind is the kind of the strategy matching the compression functions of the package
+const DefaultKind = ZlibKind
+
 // Compress will compress the data with zlib
 func Compress(dst []byte, src []byte) ([]byte, error) {
 	var b bytes.Buffer
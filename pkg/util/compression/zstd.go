@@ -3,7 +3,7 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-// +build zstd
+// +build zstd,!zlib
 
 package compression
 
@@ -18,6 +18,9 @@ import (
 // var instead of const to ease testing
 var ContentEncoding = "zstd"
 
+// DefaultKind is the kind of the strategy matching the compression functions of the package
+const DefaultKind = ZstdKind
+
 // Compress will compress the data with zstd
 func Compress(dst []byte, src []byte) ([]byte, error) {
 	return zstd.Compress(dst, src)
@@ -33,6 +33,7 @@ ALL_TAGS = set(
         "systemd",
         "zk",
         "zlib",
+        "zstd",  # Allow the zstd compression of the payloads, requires CGO
     ]
 )
 
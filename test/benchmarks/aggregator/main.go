@@ -64,6 +64,22 @@ var (
 		60,
 		"duration per second.")
 
+	dogstatsdPipelines = flag.String("dogstatsd-pipelines",
+		"",
+		"if set, run the dogstatsd pipelines benchmark for this comma-separated list of pipeline counts.")
+
+	dogstatsdProducers = flag.Int("dogstatsd-producers",
+		4,
+		"number of goroutines sending samples to the dogstatsd pipelines.")
+
+	dogstatsdSamples = flag.Int("dogstatsd-samples",
+		1000000,
+		"number of samples sent by each goroutine to the dogstatsd pipelines.")
+
+	dogstatsdContexts = flag.Int("dogstatsd-contexts",
+		10000,
+		"number of contexts of the samples sent to the dogstatsd pipelines.")
+
 	flushIval = flag.Int64("flush_ival",
 		int64(aggregator.DefaultFlushInterval/time.Second),
 		"Flush interval for aggregator, in seconds")
@@ -249,6 +265,19 @@ func main() {
 	var results []datadog.Metric
 	if *memory {
 		results = benchmarkMemory(agg, sender, nbPoints, nbSeries, *memips, *duration, *branchName)
+	} else if *dogstatsdPipelines != "" {
+		pipelineCounts := []int{}
+		for _, n := range strings.Split(*dogstatsdPipelines, ",") {
+			res, err := strconv.Atoi(n)
+			if err != nil || res < 1 {
+				log.Errorf("Could not parse 'dogstatsd-pipelines' arguments '%s': %v", n, err)
+				return
+			}
+			pipelineCounts = append(pipelineCounts, res)
+		}
+
+		log.Infof("Starting dogstatsd pipelines benchmark with %v pipelines.\n\n", pipelineCounts)
+		results = benchmarkDogstatsdPipelines(s, pipelineCounts, *dogstatsdProducers, *dogstatsdSamples, *dogstatsdContexts, *branchName)
 	} else {
 		startInfo := report(nil, "")
 
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"gopkg.in/zorkian/go-datadog-api.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+)
+
+// preAllocateDogstatsdSamples creates the samples sent by a producer, spread over the given number of contexts
+func preAllocateDogstatsdSamples(n int, contexts int) []metrics.MetricSample {
+	samples := make([]metrics.MetricSample, n)
+	for i := range samples {
+		context := rand.Intn(contexts)
+		samples[i] = metrics.MetricSample{
+			Name:       "benchmark.dogstatsd.metric." + strconv.Itoa(context%100),
+			Value:      float64(rand.Intn(1024)),
+			Mtype:      metrics.GaugeType,
+			Tags:       []string{"a", "b:21", "context:" + strconv.Itoa(context)},
+			Host:       "localhost",
+			SampleRate: 1,
+		}
+	}
+	return samples
+}
+
+// sendDogstatsdSamples batches the samples by pipeline the same way the dogstatsd batcher does
+func sendDogstatsdSamples(agg *aggregator.BufferedAggregator, samples []metrics.MetricSample) {
+	pipelines, _ := agg.GetDogstatsdPipelines()
+	batches := make([][]metrics.MetricSample, len(pipelines))
+	counts := make([]int, len(pipelines))
+	for i := range batches {
+		batches[i] = agg.MetricSamplePool.GetBatch()
+	}
+
+	for _, sample := range samples {
+		shard := aggregator.ShardForSample(&sample, len(pipelines))
+		if counts[shard] == len(batches[shard]) {
+			pipelines[shard] <- batches[shard]
+			batches[shard] = agg.MetricSamplePool.GetBatch()
+			counts[shard] = 0
+		}
+		batches[shard][counts[shard]] = sample
+		counts[shard]++
+	}
+
+	for i := range batches {
+		if counts[i] > 0 {
+			pipelines[i] <- batches[i][:counts[i]]
+		}
+	}
+}
+
+// benchmarkDogstatsdPipelines measures the throughput of the dogstatsd aggregation for
+// every pipeline count, the samples are sent concurrently by the given number of producers.
+func benchmarkDogstatsdPipelines(s serializer.MetricSerializer, pipelineCounts []int, producers, samplesPerProducer, contexts int, branchName string) []datadog.Metric {
+	samples := make([][]metrics.MetricSample, producers)
+	for i := range samples {
+		samples[i] = preAllocateDogstatsdSamples(samplesPerProducer, contexts)
+	}
+	total := producers * samplesPerProducer
+
+	t := time.Now().Unix()
+	results := []datadog.Metric{}
+	for _, pipelineCount := range pipelineCounts {
+		config.Datadog.Set("dogstatsd_pipeline_count", pipelineCount)
+		pipelineAgg := aggregator.NewBufferedAggregator(s, "hostname", 0)
+		pipelineAgg.TickerChan = make(chan time.Time)
+		aggregator.SetDefaultAggregator(pipelineAgg)
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for _, producerSamples := range samples {
+			wg.Add(1)
+			go func(producerSamples []metrics.MetricSample) {
+				defer wg.Done()
+				sendDogstatsdSamples(pipelineAgg, producerSamples)
+			}(producerSamples)
+		}
+		wg.Wait()
+		for pipelineAgg.IsInputQueueEmpty() == false {
+			time.Sleep(time.Millisecond)
+		}
+		// the flush waits for the pipelines to aggregate the batches they are processing
+		series, _ := pipelineAgg.GetSeriesAndSketches(time.Now().Add(time.Minute))
+		elapsed := time.Since(start)
+		pipelineAgg.Stop()
+
+		tags := []string{
+			fmt.Sprintf("branch:%s", branchName),
+			fmt.Sprintf("pipelines:%d", pipelineCount),
+			fmt.Sprintf("producers:%d", producers),
+			fmt.Sprintf("contexts:%d", contexts),
+		}
+		throughput := float64(total) / elapsed.Seconds()
+		results = append(results, createMetric(float64(elapsed)/float64(time.Millisecond), tags, "benchmark.aggregator.dogstatsd.time", t))
+		results = append(results, createMetric(throughput, tags, "benchmark.aggregator.dogstatsd.throughput", t))
+
+		log.Infof("[%d pipelines] aggregated %d samples into %d series in %f ms | %.f samples/s", pipelineCount, total, len(series), float64(elapsed)/float64(time.Millisecond), throughput)
+	}
+	config.Datadog.Set("dogstatsd_pipeline_count", 1)
+
+	return results
+}
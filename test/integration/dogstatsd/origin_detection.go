@@ -58,7 +58,7 @@ func testUDSOriginDetection(t *testing.T) {
 	// Start DSD
 	packetsChannel := make(chan listeners.Packets)
 	sharedPacketPool := listeners.NewPacketPool(32)
-	s, err := listeners.NewUDSListener(packetsChannel, sharedPacketPool)
+	s, err := listeners.NewUDSListener(packetsChannel, sharedPacketPool, nil)
 	require.Nil(t, err)
 
 	go s.Listen()